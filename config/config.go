@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
+	_ "time/tzdata" // Embedded so Africa/Abidjan resolves on minimal images
 
 	"github.com/joho/godotenv"
 )
@@ -41,6 +43,7 @@ type Config struct {
 	// Application Settings
 	Environment       string
 	Debug             bool
+	Timezone          string
 
 	// Platform Commission Settings
 	DefaultCommissionRate float64
@@ -49,6 +52,96 @@ type Config struct {
 	// Referral Settings
 	ReferralRewardAmount  float64
 	ReferralExpiration    int // days
+
+	// Cancellation Policy Settings
+	CancellationFreeWindow         int     // minutes after acceptance
+	CancellationEnRouteFee         float64 // FCFA
+	CancellationDriverCompensation float64 // share of the fee paid to the driver
+
+	// Tracking Settings
+	ETASlipAlertMinutes      int // alert ops when the dropoff ETA slips by more than this
+	ETANotifyIntervalMinutes int // least time between two ETA SMS to the client (0 = no ETA SMS)
+
+	// Geofence Settings
+	GeofencePickupRadius  float64 // meters
+	GeofenceDropoffRadius float64 // meters
+	GeofenceDwellSeconds  int     // time inside the fence before arrival is emitted
+	GeofenceUndoSeconds   int     // time the driver has to undo an automatic arrival
+
+	// Zone Settings
+	CrossZoneFee float64 // FCFA added when pickup and dropoff are in different zones
+
+	// Surge Pricing Settings
+	SurgeEnabled        bool
+	SurgeThreshold      float64 // pending deliveries per available driver before surge starts
+	SurgeSensitivity    float64 // multiplier increase per unit of ratio above the threshold
+	SurgeMaxMultiplier  float64
+	SurgeSmoothing      float64 // weight of the new multiplier, between 0 and 1
+	SurgeRefreshSeconds int     // how long a computed multiplier is reused
+
+	// Routing Settings
+	RoutingProvider       string // haversine, osrm or graphhopper
+	RoutingBaseURL        string
+	RoutingAPIKey         string
+	RoutingProfile        string
+	RoutingTimeoutMs      int
+	RoutingCacheTTL       int // minutes
+	RoutingCachePrecision int // decimals kept when rounding coordinates for the cache key
+
+	// Geocoding Settings
+	GeocodingMinConfidence float64 // below this score the client is asked to drop a pin
+
+	// Rating Settings
+	RatingWindowHours     int     // how long after delivery both parties can rate each other
+	RatingReviewThreshold float64 // average score below which a user is sent for review
+	RatingReviewMinCount  int     // ratings needed before the average can trigger a review
+
+	// Tip Settings
+	TipWindowHours int     // how long after delivery the client can still tip
+	TipMaxAmount   float64 // highest total tip per delivery (0 = no limit)
+
+	// Bulk Import Settings
+	ImportMaxRows  int   // rows accepted in one delivery import file
+	ImportMaxBytes int64 // size limit of an import file
+
+	// Public Tracking Settings
+	TrackingShareTTLHours    int // default lifetime of a public tracking link
+	TrackingShareMaxTTLHours int // longest lifetime a client can ask for
+	PublicTrackingRateLimit  int // requests per minute and IP on the public tracking endpoint
+
+	// Contact Settings
+	DefaultPhoneCountryCode string // prefixed to local phone numbers
+	PublicBaseURL           string // base of the links sent by SMS, no link when empty
+
+	// Cash Settings
+	DriverCashLimit float64 // unremitted cash above which a driver gets no new dispatch (0 = no limit)
+
+	// Failed Attempt Settings
+	MaxDeliveryAttempts           int     // failed attempts before the parcel goes back to the sender
+	ReattemptDelayMinutes         int     // time between a failed attempt and the next one
+	ReattemptCheckIntervalSeconds int     // time between two checks for due re-attempts (0 = disabled)
+	ReturnLegRate                 float64 // share of the delivery price charged for the return to the sender
+
+	// SLA Watchdog Settings
+	SLAWatchdogIntervalSeconds int     // time between two watchdog runs (0 = disabled)
+	SLARules                   string  // STATUS:maxDurationMin:noProgressMin rules, separated by commas
+	SLADriverSilenceMinutes    int     // time without a driver position that breaches the SLA
+	SLAEscalationMinutes       int     // time after the warning before the delivery is re-dispatched
+	SLAProgressMeters          float64 // distance gained toward the next stop that counts as progress
+
+	// Location History Settings
+	LocationCompactionIntervalMinutes int // time between two compactions of the location history (0 = disabled)
+	LocationCompactAfterHours         int // age after which points are downsampled
+	LocationCompactIntervalSeconds    int // one point kept per interval once downsampled
+	LocationRetentionDays             int // age after which points are deleted (0 = kept forever)
+
+	// Dispatch Settings
+	DriverIndexCellKm      float64 // cell size of the in-memory driver spatial index
+	DispatchCandidates     int     // nearest drivers scored when dispatching a delivery
+	DispatchMaxRadiusKm    float64 // farthest driver considered for a delivery (0 = no limit)
+	DispatchWeights        string  // default factor:weight driver scoring weights, zones can override them
+	DispatchMaxEtaMinutes  float64 // ETA to pickup at which the ETA factor drops to 0
+	DispatchIdleCapMinutes float64 // idle time at which the fairness factor is full
 }
 
 var AppConfig *Config
@@ -92,6 +185,7 @@ func LoadConfig() *Config {
 		// App
 		Environment:       getEnv("ENVIRONMENT", "development"),
 		Debug:             getEnvBool("DEBUG", true),
+		Timezone:          getEnv("TIMEZONE", "Africa/Abidjan"),
 
 		// Platform
 		DefaultCommissionRate: getEnvFloat("DEFAULT_COMMISSION_RATE", 0.15), // 15%
@@ -100,12 +194,111 @@ func LoadConfig() *Config {
 		// Referral
 		ReferralRewardAmount:  getEnvFloat("REFERRAL_REWARD_AMOUNT", 1000.0), // 1000 FCFA
 		ReferralExpiration:    getEnvInt("REFERRAL_EXPIRATION", 30),          // 30 days
+
+		// Cancellation
+		CancellationFreeWindow:         getEnvInt("CANCELLATION_FREE_WINDOW", 5),              // 5 minutes
+		CancellationEnRouteFee:         getEnvFloat("CANCELLATION_EN_ROUTE_FEE", 500.0),       // 500 FCFA
+		CancellationDriverCompensation: getEnvFloat("CANCELLATION_DRIVER_COMPENSATION", 0.8), // 80%
+
+		// Tracking
+		ETASlipAlertMinutes:      getEnvInt("ETA_SLIP_ALERT_MINUTES", 10),
+		ETANotifyIntervalMinutes: getEnvInt("ETA_NOTIFY_INTERVAL_MINUTES", 10),
+
+		// Geofence
+		GeofencePickupRadius:  getEnvFloat("GEOFENCE_PICKUP_RADIUS", 80.0),  // 80 meters
+		GeofenceDropoffRadius: getEnvFloat("GEOFENCE_DROPOFF_RADIUS", 80.0), // 80 meters
+		GeofenceDwellSeconds:  getEnvInt("GEOFENCE_DWELL_SECONDS", 45),      // 45 seconds
+		GeofenceUndoSeconds:   getEnvInt("GEOFENCE_UNDO_SECONDS", 120),      // 2 minutes
+
+		// Zones
+		CrossZoneFee: getEnvFloat("CROSS_ZONE_FEE", 300.0), // 300 FCFA
+
+		// Surge
+		SurgeEnabled:        getEnvBool("SURGE_ENABLED", true),
+		SurgeThreshold:      getEnvFloat("SURGE_THRESHOLD", 1.5),
+		SurgeSensitivity:    getEnvFloat("SURGE_SENSITIVITY", 0.25),
+		SurgeMaxMultiplier:  getEnvFloat("SURGE_MAX_MULTIPLIER", 2.0), // x2
+		SurgeSmoothing:      getEnvFloat("SURGE_SMOOTHING", 0.5),
+		SurgeRefreshSeconds: getEnvInt("SURGE_REFRESH_SECONDS", 60),      // 1 minute
+
+		// Routing
+		RoutingProvider:       getEnv("ROUTING_PROVIDER", "haversine"),
+		RoutingBaseURL:        getEnv("ROUTING_BASE_URL", "https://router.project-osrm.org"),
+		RoutingAPIKey:         getEnv("ROUTING_API_KEY", ""),
+		RoutingProfile:        getEnv("ROUTING_PROFILE", "driving"),
+		RoutingTimeoutMs:      getEnvInt("ROUTING_TIMEOUT_MS", 3000),   // 3 seconds
+		RoutingCacheTTL:       getEnvInt("ROUTING_CACHE_TTL_MIN", 60),  // 1 hour
+		RoutingCachePrecision: getEnvInt("ROUTING_CACHE_PRECISION", 4), // ~11 meters
+
+		// Geocoding
+		GeocodingMinConfidence: getEnvFloat("GEOCODING_MIN_CONFIDENCE", 0.6),
+
+		// Ratings
+		RatingWindowHours:     getEnvInt("RATING_WINDOW_HOURS", 72), // 3 days
+		RatingReviewThreshold: getEnvFloat("RATING_REVIEW_THRESHOLD", 3.5),
+		RatingReviewMinCount:  getEnvInt("RATING_REVIEW_MIN_COUNT", 10),
+
+		// Tips
+		TipWindowHours: getEnvInt("TIP_WINDOW_HOURS", 24),
+		TipMaxAmount:   getEnvFloat("TIP_MAX_AMOUNT", 50000.0), // 50 000 FCFA
+
+		// Bulk import
+		ImportMaxRows:  getEnvInt("IMPORT_MAX_ROWS", 500),
+		ImportMaxBytes: int64(getEnvInt("IMPORT_MAX_BYTES", 2*1024*1024)), // 2 MB
+
+		// Public tracking links
+		TrackingShareTTLHours:    getEnvInt("TRACKING_SHARE_TTL_HOURS", 48),
+		TrackingShareMaxTTLHours: getEnvInt("TRACKING_SHARE_MAX_TTL_HOURS", 168), // 7 days
+		PublicTrackingRateLimit:  getEnvInt("PUBLIC_TRACKING_RATE_LIMIT", 30),
+
+		// Contacts and recipient SMS
+		DefaultPhoneCountryCode: getEnv("DEFAULT_PHONE_COUNTRY_CODE", "225"), // Côte d'Ivoire
+		PublicBaseURL:           getEnv("PUBLIC_BASE_URL", ""),
+
+		// Cash on delivery and driver cash
+		DriverCashLimit: getEnvFloat("DRIVER_CASH_LIMIT", 100000.0), // 100 000 FCFA
+
+		// Failed attempts and returns
+		MaxDeliveryAttempts:           getEnvInt("MAX_DELIVERY_ATTEMPTS", 2),
+		ReattemptDelayMinutes:         getEnvInt("REATTEMPT_DELAY_MINUTES", 120),
+		ReattemptCheckIntervalSeconds: getEnvInt("REATTEMPT_CHECK_INTERVAL_SECONDS", 60),
+		ReturnLegRate:                 getEnvFloat("RETURN_LEG_RATE", 0.5), // 50% of the delivery price
+
+		// SLA watchdog
+		SLAWatchdogIntervalSeconds: getEnvInt("SLA_WATCHDOG_INTERVAL_SECONDS", 60),
+		SLARules:                   getEnv("SLA_RULES", "ACCEPTED:30:10,PICKUP_IN_PROGRESS:45:10,ARRIVED_AT_PICKUP:30:0,PICKED_UP:0:15,IN_TRANSIT:0:15,ARRIVED_AT_DROPOFF:30:0,RETURNING:0:20"),
+		SLADriverSilenceMinutes:    getEnvInt("SLA_DRIVER_SILENCE_MINUTES", 10),
+		SLAEscalationMinutes:       getEnvInt("SLA_ESCALATION_MINUTES", 5),
+		SLAProgressMeters:          getEnvFloat("SLA_PROGRESS_METERS", 100.0),
+
+		// Driver location history
+		LocationCompactionIntervalMinutes: getEnvInt("LOCATION_COMPACTION_INTERVAL_MINUTES", 60),
+		LocationCompactAfterHours:         getEnvInt("LOCATION_COMPACT_AFTER_HOURS", 24),
+		LocationCompactIntervalSeconds:    getEnvInt("LOCATION_COMPACT_INTERVAL_SECONDS", 60),
+		LocationRetentionDays:             getEnvInt("LOCATION_RETENTION_DAYS", 90),
+
+		// Dispatch
+		DriverIndexCellKm:      getEnvFloat("DRIVER_INDEX_CELL_KM", 1.0),
+		DispatchCandidates:     getEnvInt("DISPATCH_CANDIDATES", 10),
+		DispatchMaxRadiusKm:    getEnvFloat("DISPATCH_MAX_RADIUS_KM", 0),
+		DispatchWeights:        getEnv("DISPATCH_WEIGHTS", "eta:0.4,rating:0.15,reliability:0.15,idle:0.1,vehicle:0.05,preference:0.05,shift:0.1"),
+		DispatchMaxEtaMinutes:  getEnvFloat("DISPATCH_MAX_ETA_MINUTES", 30.0),
+		DispatchIdleCapMinutes: getEnvFloat("DISPATCH_IDLE_CAP_MINUTES", 60.0),
 	}
 
 	AppConfig = config
 	return config
 }
 
+// Location returns the business timezone, falling back to UTC if it cannot be loaded
+func (c *Config) Location() *time.Location {
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
 // GetConfig returns the loaded configuration or loads it if not loaded
 func GetConfig() *Config {
 	if AppConfig == nil {
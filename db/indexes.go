@@ -0,0 +1,73 @@
+package db
+
+import (
+	"fmt"
+	"log"
+)
+
+// indexDefinitions lists the indexes the delivery queries rely on.
+// Compound indexes put the filter first and the default sort field last.
+var indexDefinitions = []string{
+	// Delivery lists: admin filters and client/driver histories sorted by creation date
+	`DEFINE INDEX IF NOT EXISTS delivery_created_at ON TABLE Delivery FIELDS createdAt`,
+	`DEFINE INDEX IF NOT EXISTS delivery_updated_at ON TABLE Delivery FIELDS updatedAt`,
+	`DEFINE INDEX IF NOT EXISTS delivery_final_price ON TABLE Delivery FIELDS finalPrice`,
+	`DEFINE INDEX IF NOT EXISTS delivery_status_created_at ON TABLE Delivery FIELDS status, createdAt`,
+	`DEFINE INDEX IF NOT EXISTS delivery_client_created_at ON TABLE Delivery FIELDS clientId, createdAt`,
+	`DEFINE INDEX IF NOT EXISTS delivery_driver_created_at ON TABLE Delivery FIELDS livreurId, createdAt`,
+	`DEFINE INDEX IF NOT EXISTS delivery_type ON TABLE Delivery FIELDS type`,
+	`DEFINE INDEX IF NOT EXISTS delivery_vehicle_type ON TABLE Delivery FIELDS vehicleType`,
+	`DEFINE INDEX IF NOT EXISTS delivery_payment_method ON TABLE Delivery FIELDS paymentMethod`,
+	`DEFINE INDEX IF NOT EXISTS delivery_pickup_zone ON TABLE Delivery FIELDS pickupZoneId`,
+	`DEFINE INDEX IF NOT EXISTS delivery_dropoff_zone ON TABLE Delivery FIELDS dropoffZoneId`,
+	`DEFINE INDEX IF NOT EXISTS delivery_pickup_id ON TABLE Delivery FIELDS pickupId`,
+	`DEFINE INDEX IF NOT EXISTS delivery_dropoff_id ON TABLE Delivery FIELDS dropoffId`,
+
+	// Failed attempts waiting for their re-attempt
+	`DEFINE INDEX IF NOT EXISTS delivery_status_next_attempt ON TABLE Delivery FIELDS status, nextAttemptAt`,
+
+	// Driver location history: trip replay, driver tracks and compaction of old points
+	`DEFINE INDEX IF NOT EXISTS location_point_delivery_timestamp ON TABLE LocationPoint FIELDS deliveryId, timestamp`,
+	`DEFINE INDEX IF NOT EXISTS location_point_driver_timestamp ON TABLE LocationPoint FIELDS driverId, timestamp`,
+	`DEFINE INDEX IF NOT EXISTS location_point_compacted_timestamp ON TABLE LocationPoint FIELDS compacted, timestamp`,
+
+	// Driver cash ledger: balance and history per driver
+	`DEFINE INDEX IF NOT EXISTS cash_ledger_entry_driver_created_at ON TABLE CashLedgerEntry FIELDS driverId, createdAt`,
+
+	// Driver earnings ledger: statements per driver and platform revenue
+	`DEFINE INDEX IF NOT EXISTS earning_entry_driver_created_at ON TABLE EarningEntry FIELDS driverId, createdAt`,
+	`DEFINE INDEX IF NOT EXISTS earning_entry_delivery_type ON TABLE EarningEntry FIELDS deliveryId, type`,
+	`DEFINE INDEX IF NOT EXISTS earning_entry_created_at ON TABLE EarningEntry FIELDS createdAt`,
+
+	// Shift planning: shifts under way, driver availability and online hours
+	`DEFINE INDEX IF NOT EXISTS shift_zone_start_at ON TABLE Shift FIELDS zoneId, startAt`,
+	`DEFINE INDEX IF NOT EXISTS shift_start_at ON TABLE Shift FIELDS startAt`,
+	`DEFINE INDEX IF NOT EXISTS availability_slot_driver_start_at ON TABLE AvailabilitySlot FIELDS driverId, startAt`,
+	`DEFINE INDEX IF NOT EXISTS driver_status_event_driver_created_at ON TABLE DriverStatusEvent FIELDS driverId, createdAt`,
+	`DEFINE INDEX IF NOT EXISTS driver_status_event_created_at ON TABLE DriverStatusEvent FIELDS createdAt`,
+
+	// Ratings: one per delivery and rater
+	`DEFINE INDEX IF NOT EXISTS rating_delivery_rater ON TABLE Rating FIELDS deliveryId, raterId UNIQUE`,
+
+	// Public tracking links are looked up by the hash of their token
+	`DEFINE INDEX IF NOT EXISTS tracking_share_token_hash ON TABLE TrackingShare FIELDS tokenHash UNIQUE`,
+
+	// Locations are shared between deliveries through their normalized address and coordinates
+	`DEFINE INDEX IF NOT EXISTS location_key ON TABLE Location FIELDS key UNIQUE`,
+
+	// Full-text search on addresses, edge n-grams so partial words match
+	`DEFINE ANALYZER IF NOT EXISTS address_analyzer TOKENIZERS blank, class, punct FILTERS lowercase, ascii, edgengram(2, 15)`,
+	`DEFINE INDEX IF NOT EXISTS location_address_search ON TABLE Location FIELDS address SEARCH ANALYZER address_analyzer BM25`,
+}
+
+// EnsureIndexes defines the indexes used by the application, existing ones are kept as is
+func EnsureIndexes() error {
+	for _, definition := range indexDefinitions {
+		if _, err := Query(definition, nil); err != nil {
+			return fmt.Errorf("failed to define index: %v", err)
+		}
+	}
+
+	log.Printf("Ensured %d database indexes", len(indexDefinitions))
+	return nil
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/surrealdb/surrealdb.go"
 	"github.com/ambroise1219/livraison_go/config"
@@ -204,6 +205,30 @@ func Transaction(queries []string, params []map[string]interface{}) ([]interface
 	return results, nil
 }
 
+// QueryTransaction executes the statements in a single SurrealDB transaction, nothing is written
+// if one of them fails
+func QueryTransaction(statements []string, params map[string]interface{}) error {
+	query := "BEGIN TRANSACTION;\n" + strings.Join(statements, ";\n") + ";\nCOMMIT TRANSACTION;"
+
+	result, err := Query(query, params)
+	if err != nil {
+		return err
+	}
+
+	// Every statement reports its own status
+	if resultArray, ok := result.([]interface{}); ok {
+		for i, item := range resultArray {
+			if resultData, ok := item.(map[string]interface{}); ok {
+				if status, _ := resultData["status"].(string); status != "" && status != "OK" {
+					return fmt.Errorf("transaction statement %d failed: %v", i, resultData["result"])
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 // CheckRecordExists checks if a record exists by ID
 func CheckRecordExists(table string, id string) (bool, error) {
 	query := fmt.Sprintf("SELECT id FROM %s WHERE id = $id", table)
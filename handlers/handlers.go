@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 
 	"github.com/ambroise1219/livraison_go/config"
+	"github.com/ambroise1219/livraison_go/middlewares"
 	"github.com/ambroise1219/livraison_go/models"
 	"github.com/ambroise1219/livraison_go/services"
 )
@@ -14,11 +20,39 @@ import (
 // Global validator instance
 var validate *validator.Validate
 var authService *services.AuthService
+var promoService *services.PromoService
+var deliveryService *services.DeliveryService
+var zoneService *services.ZoneService
+var surgeService *services.SurgeService
+var pricingService *services.PricingService
+var geocodingService *services.GeocodingService
+var addressService *services.AddressService
 
 // InitHandlers initializes handlers with dependencies
 func InitHandlers() {
+	cfg := config.GetConfig()
 	validate = validator.New()
-	authService = services.NewAuthService(config.GetConfig())
+	authService = services.NewAuthService(cfg)
+	promoService = services.NewPromoService(cfg)
+	zoneService = services.NewZoneService(cfg)
+	surgeService = services.NewSurgeService(cfg, zoneService)
+	pricingService = services.NewPricingService(cfg)
+	geocodingService = services.NewGeocodingService(cfg)
+	addressService = services.NewAddressService(cfg, geocodingService)
+	deliveryService = services.NewDeliveryService(cfg, promoService, zoneService, surgeService, pricingService, geocodingService, addressService)
+}
+
+// StartBackgroundJobs loads the in-memory state and starts the workers running outside of requests
+func StartBackgroundJobs() {
+	if err := deliveryService.RebuildDriverIndex(); err != nil {
+		log.Printf("Warning: failed to rebuild driver index: %v", err)
+	}
+	if err := deliveryService.FailInterruptedImports(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	deliveryService.StartSLAWatchdog()
+	deliveryService.StartReattemptScheduler()
+	deliveryService.StartLocationCompaction()
 }
 
 // Auth handlers
@@ -166,9 +200,232 @@ func UpdateVehicle(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "UpdateVehicle - TODO: Implémenter"})
 }
 
+func GetSavedAddresses(c *gin.Context) {
+	addresses, err := addressService.GetAddresses(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get addresses", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addresses": addresses})
+}
+
+func GetAddressSuggestions(c *gin.Context) {
+	suggestions, err := addressService.GetSuggestions(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get address suggestions", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+func CreateSavedAddress(c *gin.Context) {
+	var req models.CreateSavedAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	address, err := addressService.CreateAddress(c.Param("user_id"), &req)
+	if err != nil {
+		var pinErr *services.PinRequiredError
+		if errors.As(err, &pinErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Address needs a pin",
+				"details": err.Error(),
+				"geocoding": pinErr.Result,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to save address", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Address saved successfully",
+		"address": address,
+	})
+}
+
+func UpdateSavedAddress(c *gin.Context) {
+	var req models.UpdateSavedAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	address, err := addressService.UpdateAddress(c.Param("user_id"), c.Param("address_id"), &req)
+	if err != nil {
+		var pinErr *services.PinRequiredError
+		if errors.As(err, &pinErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Address needs a pin",
+				"details": err.Error(),
+				"geocoding": pinErr.Result,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update address", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Address updated successfully",
+		"address": address,
+	})
+}
+
+func DeleteSavedAddress(c *gin.Context) {
+	err := addressService.DeleteAddress(c.Param("user_id"), c.Param("address_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete address", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Address deleted successfully"})
+}
+
 // Delivery handlers
 func CreateDelivery(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "CreateDelivery - TODO: Implémenter"})
+	var req models.CreateDeliveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	clientID, _ := middlewares.GetCurrentUserID(c)
+
+	delivery, err := deliveryService.CreateDelivery(clientID, &req)
+	if err != nil {
+		// Ask the client to drop a pin when the address text is too vague
+		var pinErr *services.PinRequiredError
+		if errors.As(err, &pinErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Address needs a pin",
+				"details": err.Error(),
+				"needsPin": pinErr.Stop,
+				"geocoding": pinErr.Result,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create delivery", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Delivery created successfully",
+		"delivery": delivery,
+	})
+}
+
+func ImportDeliveries(c *gin.Context) {
+	var req models.DeliveryImportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	cfg := config.GetConfig()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.ImportMaxBytes)
+
+	// The file comes as a multipart upload or as the raw request body
+	var file io.Reader = c.Request.Body
+	filename := ""
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing import file", "details": err.Error()})
+			return
+		}
+		opened, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file", "details": err.Error()})
+			return
+		}
+		defer opened.Close()
+		file, filename = opened, fileHeader.Filename
+	}
+
+	format := models.ImportFormat(strings.ToUpper(string(req.Format)))
+	if format == "" {
+		format = models.DetectImportFormat(filename, c.ContentType())
+	}
+	if !format.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import format", "details": "format must be CSV or JSONL"})
+		return
+	}
+
+	rows, err := models.ParseDeliveryImport(format, file, cfg.ImportMaxRows)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import file", "details": err.Error()})
+		return
+	}
+
+	// Validate each row with the same rules as a single delivery
+	for i := range rows {
+		if rows[i].Request == nil {
+			continue
+		}
+		if err := validate.Struct(*rows[i].Request); err != nil {
+			var validationErrors validator.ValidationErrors
+			if errors.As(err, &validationErrors) {
+				for _, fieldErr := range validationErrors {
+					rows[i].AddError(fmt.Sprintf("%s: failed on %s", fieldErr.Field(), fieldErr.Tag()))
+				}
+			} else {
+				rows[i].AddError(err.Error())
+			}
+			continue
+		}
+		if err := rows[i].Request.Validate(); err != nil {
+			rows[i].AddError(err.Error())
+		}
+	}
+
+	clientID, _ := middlewares.GetCurrentUserID(c)
+
+	job, err := deliveryService.StartDeliveryImport(clientID, format, req.DryRun, rows)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to start import", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Import started",
+		"job":     job,
+	})
+}
+
+func GetDeliveryImport(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	job, err := deliveryService.GetDeliveryImport(c.Param("job_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to get import job", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
 }
 
 func GetDelivery(c *gin.Context) {
@@ -176,7 +433,31 @@ func GetDelivery(c *gin.Context) {
 }
 
 func UpdateDeliveryStatus(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "UpdateDeliveryStatus - TODO: Implémenter"})
+	var req models.UpdateDeliveryStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	err := deliveryService.UpdateDeliveryStatus(c.Param("delivery_id"), req.Status, userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update delivery status", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Delivery status updated successfully",
+		"status":  req.Status,
+	})
 }
 
 func AssignDelivery(c *gin.Context) {
@@ -184,7 +465,69 @@ func AssignDelivery(c *gin.Context) {
 }
 
 func CalculateDeliveryPrice(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "CalculateDeliveryPrice - TODO: Implémenter"})
+	var req models.PriceQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	quote, err := deliveryService.QuoteDelivery(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to calculate price", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quote": quote})
+}
+
+func GeocodeAddress(c *gin.Context) {
+	var req models.GeocodeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	result, err := geocodingService.Geocode(req.Address)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to geocode address", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"geocoding": result})
+}
+
+func ReverseGeocode(c *gin.Context) {
+	var req models.ReverseGeocodeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	result, err := geocodingService.Reverse(*req.Lat, *req.Lng)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reverse geocode", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"geocoding": result})
 }
 
 func GetAvailableDeliveries(c *gin.Context) {
@@ -200,101 +543,1305 @@ func AcceptDelivery(c *gin.Context) {
 }
 
 func UpdateDriverLocation(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "UpdateDriverLocation - TODO: Implémenter"})
-}
+	var req models.UpdateDriverLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
 
-func GetClientDeliveries(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetClientDeliveries - TODO: Implémenter"})
-}
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
 
-func CancelDelivery(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "CancelDelivery - TODO: Implémenter"})
-}
+	driverID, _ := middlewares.GetCurrentUserID(c)
 
-func TrackDelivery(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "TrackDelivery - TODO: Implémenter"})
-}
+	eta, err := deliveryService.UpdateDriverLocation(driverID, c.Param("delivery_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update location", "details": err.Error()})
+		return
+	}
 
-// Promo handlers
-func ValidatePromoCode(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "ValidatePromoCode - TODO: Implémenter"})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Location updated successfully",
+		"eta": eta,
+	})
 }
 
-func UsePromoCode(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "UsePromoCode - TODO: Implémenter"})
-}
+func UpdateDriverPosition(c *gin.Context) {
+	var req models.UpdateDriverLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
 
-func GetPromoHistory(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetPromoHistory - TODO: Implémenter"})
-}
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
 
-func CreateReferral(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "CreateReferral - TODO: Implémenter"})
-}
+	driverID, _ := middlewares.GetCurrentUserID(c)
 
-func GetReferralStats(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetReferralStats - TODO: Implémenter"})
-}
+	if err := deliveryService.UpdateDriverPosition(driverID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update location", "details": err.Error()})
+		return
+	}
 
-// Admin handlers
-func GetAllUsers(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetAllUsers - TODO: Implémenter"})
+	c.JSON(http.StatusOK, gin.H{"message": "Location updated successfully"})
 }
 
-func GetUserDetails(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetUserDetails - TODO: Implémenter"})
-}
+func GetClientDeliveries(c *gin.Context) {
+	req, ok := bindDeliverySearch(c)
+	if !ok {
+		return
+	}
 
-func UpdateUserRole(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "UpdateUserRole - TODO: Implémenter"})
-}
+	clientID, _ := middlewares.GetCurrentUserID(c)
 
-func DeleteUser(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "DeleteUser - TODO: Implémenter"})
-}
+	page, err := deliveryService.GetClientDeliveries(clientID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get deliveries", "details": err.Error()})
+		return
+	}
 
-func GetAllDeliveries(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetAllDeliveries - TODO: Implémenter"})
+	c.JSON(http.StatusOK, page)
 }
 
-func GetDeliveryStats(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetDeliveryStats - TODO: Implémenter"})
-}
+func GetDriverDeliveries(c *gin.Context) {
+	req, ok := bindDeliverySearch(c)
+	if !ok {
+		return
+	}
 
-func ForceAssignDelivery(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "ForceAssignDelivery - TODO: Implémenter"})
-}
+	driverID, _ := middlewares.GetCurrentUserID(c)
 
-func GetAllDrivers(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetAllDrivers - TODO: Implémenter"})
-}
+	page, err := deliveryService.GetDriverDeliveries(driverID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get deliveries", "details": err.Error()})
+		return
+	}
 
-func GetDriverStats(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetDriverStats - TODO: Implémenter"})
+	c.JSON(http.StatusOK, page)
 }
 
-func UpdateDriverStatus(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "UpdateDriverStatus - TODO: Implémenter"})
-}
+// bindDeliverySearch reads the filters of a delivery list, answering the request on failure
+func bindDeliverySearch(c *gin.Context) (*models.DeliverySearchRequest, bool) {
+	var req models.DeliverySearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "details": err.Error()})
+		return nil, false
+	}
 
-func GetAllPromotions(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetAllPromotions - TODO: Implémenter"})
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func CancelDelivery(c *gin.Context) {
+	var req models.CancelDeliveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	quote, err := deliveryService.CancelDelivery(c.Param("delivery_id"), userID, userRole, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to cancel delivery", "details": err.Error()})
+		return
+	}
+
+	message := "Cancellation quote: confirm to cancel"
+	if quote.Confirmed {
+		message = "Delivery cancelled successfully"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"cancellation": quote,
+	})
+}
+
+func GetCancellationQuote(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	quote, err := deliveryService.GetCancellationQuote(c.Param("delivery_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to compute cancellation fee", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cancellation": quote})
+}
+
+func GetWaitingTimer(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	timer, err := deliveryService.GetWaitingTimer(c.Param("delivery_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get waiting timer", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"waiting": timer})
+}
+
+func TrackDelivery(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	tracking, err := deliveryService.TrackDelivery(c.Param("delivery_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to track delivery", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tracking": tracking})
+}
+
+func CreateTrackingShare(c *gin.Context) {
+	var req models.CreateTrackingShareRequest
+	// The body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	share, err := deliveryService.CreateTrackingShare(c.Param("delivery_id"), userID, userRole, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create tracking link", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tracking link created successfully",
+		"share":   share,
+		"path":    "/api/v1/track/" + share.Token,
+	})
+}
+
+func GetTrackingShares(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	shares, err := deliveryService.GetTrackingShares(c.Param("delivery_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get tracking links", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+func RevokeTrackingShare(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	err := deliveryService.RevokeTrackingShare(c.Param("delivery_id"), c.Param("share_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to revoke tracking link", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tracking link revoked successfully"})
+}
+
+func GetPublicTracking(c *gin.Context) {
+	// Public links are not cached by shared proxies
+	c.Header("Cache-Control", "no-store")
+
+	tracking, err := deliveryService.GetPublicTracking(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tracking link not found or expired"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tracking": tracking})
+}
+
+func GetDeliveryAttempts(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	attempts, err := deliveryService.GetDeliveryAttempts(c.Param("delivery_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get delivery attempts", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attempts": attempts})
+}
+
+func GetDeliveryContacts(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	contacts, err := deliveryService.GetDeliveryContacts(c.Param("delivery_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get delivery contacts", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contacts": contacts})
+}
+
+func GetDeliveryHistory(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	history, err := deliveryService.GetDeliveryHistory(c.Param("delivery_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get delivery history", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
+
+func UndoAutoArrival(c *gin.Context) {
+	var req models.UndoAutoArrivalRequest
+	// The body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	err := deliveryService.UndoAutoArrival(c.Param("delivery_id"), driverID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to undo arrival", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Automatic arrival undone successfully"})
+}
+
+func RateDelivery(c *gin.Context) {
+	var req models.CreateRatingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	rating, err := deliveryService.RateDelivery(c.Param("delivery_id"), userID, userRole, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to rate delivery", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Rating submitted successfully",
+		"rating":  rating,
+	})
+}
+
+func GetDeliveryRatings(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	ratings, err := deliveryService.GetDeliveryRatings(c.Param("delivery_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get delivery ratings", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ratings": ratings})
+}
+
+func TipDelivery(c *gin.Context) {
+	var req models.CreateTipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	tip, err := deliveryService.TipDelivery(c.Param("delivery_id"), userID, userRole, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to tip driver", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tip sent successfully",
+		"tip":     tip,
+	})
+}
+
+func GetDeliveryReceipt(c *gin.Context) {
+	userID, _ := middlewares.GetCurrentUserID(c)
+	userRole, _ := middlewares.GetCurrentUserRole(c)
+
+	receipt, err := deliveryService.GetDeliveryReceipt(c.Param("delivery_id"), userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get receipt", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"receipt": receipt})
+}
+
+func GetDriverEarnings(c *gin.Context) {
+	var req models.DriverEarningsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	earnings, err := deliveryService.GetDriverEarnings(driverID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get earnings", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"earnings": earnings})
+}
+
+func GetDriverDailyEarnings(c *gin.Context) {
+	getDriverEarningsStatement(c, models.EarningsPeriodDaily)
+}
+
+func GetDriverWeeklyEarnings(c *gin.Context) {
+	getDriverEarningsStatement(c, models.EarningsPeriodWeekly)
+}
+
+// getDriverEarningsStatement answers with the ledger earnings of the current driver grouped by period
+func getDriverEarningsStatement(c *gin.Context, period models.EarningsPeriod) {
+	var req models.DriverEarningsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	statement, err := deliveryService.GetDriverEarningsStatement(driverID, period, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get earnings", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"earnings": statement})
+}
+
+func FailDeliveryAttempt(c *gin.Context) {
+	var req models.FailDeliveryAttemptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	attempt, err := deliveryService.FailDeliveryAttempt(c.Param("delivery_id"), driverID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to record failed attempt", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Failed attempt recorded",
+		"attempt": attempt,
+	})
+}
+
+func CollectCashOnDelivery(c *gin.Context) {
+	var req models.CollectCODRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	balance, err := deliveryService.CollectCashOnDelivery(c.Param("delivery_id"), driverID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to confirm cash collection", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cash collected successfully",
+		"balance": balance,
+	})
+}
+
+func GetDriverCashBalance(c *gin.Context) {
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	balance, err := deliveryService.GetDriverCashBalance(driverID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cash balance", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"balance": balance})
+}
+
+func GetDispatchPreferences(c *gin.Context) {
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	prefs, err := deliveryService.GetDispatchPreferences(driverID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get dispatch preferences", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+func UpdateDispatchPreferences(c *gin.Context) {
+	var req models.DriverDispatchPreferences
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	prefs, err := deliveryService.UpdateDispatchPreferences(driverID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update dispatch preferences", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dispatch preferences updated successfully", "preferences": prefs})
+}
+
+func GetAvailabilitySlots(c *gin.Context) {
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	slots, err := deliveryService.GetAvailabilitySlots(driverID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get availability", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"slots": slots})
+}
+
+func CreateAvailabilitySlot(c *gin.Context) {
+	var req models.CreateAvailabilitySlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	slot, err := deliveryService.CreateAvailabilitySlot(driverID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to publish availability", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Availability published successfully",
+		"slot":    slot,
+	})
+}
+
+func DeleteAvailabilitySlot(c *gin.Context) {
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	err := deliveryService.DeleteAvailabilitySlot(driverID, c.Param("slot_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to delete availability", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Availability deleted successfully"})
+}
+
+func GetDriverShifts(c *gin.Context) {
+	driverID, _ := middlewares.GetCurrentUserID(c)
+
+	shifts, err := deliveryService.GetDriverShifts(driverID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get shifts", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shifts": shifts})
+}
+
+// Promo handlers
+func ValidatePromoCode(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "ValidatePromoCode - TODO: Implémenter"})
+}
+
+func UsePromoCode(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "UsePromoCode - TODO: Implémenter"})
+}
+
+func GetPromoHistory(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "GetPromoHistory - TODO: Implémenter"})
+}
+
+func CreateReferral(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "CreateReferral - TODO: Implémenter"})
+}
+
+func GetReferralStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "GetReferralStats - TODO: Implémenter"})
+}
+
+// Admin handlers
+func GetAllUsers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "GetAllUsers - TODO: Implémenter"})
+}
+
+func GetUserDetails(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "GetUserDetails - TODO: Implémenter"})
+}
+
+func UpdateUserRole(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "UpdateUserRole - TODO: Implémenter"})
+}
+
+func DeleteUser(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "DeleteUser - TODO: Implémenter"})
+}
+
+func GetAllDeliveries(c *gin.Context) {
+	req, ok := bindDeliverySearch(c)
+	if !ok {
+		return
+	}
+
+	page, err := deliveryService.SearchDeliveries(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to search deliveries", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func GetDeliveryStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "GetDeliveryStats - TODO: Implémenter"})
+}
+
+func ForceAssignDelivery(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "ForceAssignDelivery - TODO: Implémenter"})
+}
+
+func GetAllDrivers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "GetAllDrivers - TODO: Implémenter"})
+}
+
+func GetDriverStats(c *gin.Context) {
+	stats, err := deliveryService.GetDriverStats(c.Param("driver_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to get driver stats", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+func GetDriversCashDue(c *gin.Context) {
+	drivers, err := deliveryService.GetDriversWithCashDue()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get drivers cash due", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"drivers": drivers})
+}
+
+func GetDriverCash(c *gin.Context) {
+	balance, err := deliveryService.GetDriverCashBalance(c.Param("driver_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cash balance", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"balance": balance})
+}
+
+func GetDispatchDecisions(c *gin.Context) {
+	decisions, err := deliveryService.GetDispatchDecisions(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to get dispatch decisions", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"decisions": decisions})
+}
+
+func GetDeliveryReplay(c *gin.Context) {
+	replay, err := deliveryService.GetDeliveryReplay(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to get delivery replay", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, replay)
+}
+
+func GetDriverTrack(c *gin.Context) {
+	var req models.DriverTrackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	track, err := deliveryService.GetDriverTrack(c.Param("driver_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get driver track", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, track)
+}
+
+func RecordCashRemittance(c *gin.Context) {
+	var req models.CreateRemittanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	adminID, _ := middlewares.GetCurrentUserID(c)
+
+	balance, err := deliveryService.RecordCashRemittance(c.Param("driver_id"), adminID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to record remittance", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Remittance recorded successfully",
+		"balance": balance,
+	})
+}
+
+func GetShifts(c *gin.Context) {
+	var req models.ShiftPeriodRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	shifts, err := deliveryService.GetShifts(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get shifts", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shifts": shifts})
+}
+
+func CreateShift(c *gin.Context) {
+	var req models.CreateShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	adminID, _ := middlewares.GetCurrentUserID(c)
+
+	shift, err := deliveryService.CreateShift(adminID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create shift", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Shift created successfully",
+		"shift":   shift,
+	})
+}
+
+func UpdateShift(c *gin.Context) {
+	var req models.UpdateShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	shift, err := deliveryService.UpdateShift(c.Param("shift_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update shift", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shift updated successfully",
+		"shift":   shift,
+	})
+}
+
+func DeleteShift(c *gin.Context) {
+	err := deliveryService.DeleteShift(c.Param("shift_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete shift", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shift deleted successfully"})
+}
+
+func GetShiftReport(c *gin.Context) {
+	var req models.ShiftPeriodRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	report, err := deliveryService.GetShiftReport(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get shift report", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+func RecordEarningAdjustment(c *gin.Context) {
+	var req models.CreateEarningAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	adminID, _ := middlewares.GetCurrentUserID(c)
+
+	entry, err := deliveryService.RecordEarningAdjustment(c.Param("driver_id"), adminID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to record earnings adjustment", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Earnings adjustment recorded successfully",
+		"entry":   entry,
+	})
+}
+
+func UpdateDriverStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "UpdateDriverStatus - TODO: Implémenter"})
+}
+
+func GetAllPromotions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "GetAllPromotions - TODO: Implémenter"})
+}
+
+func CreatePromotion(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "CreatePromotion - TODO: Implémenter"})
+}
+
+func UpdatePromotion(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "UpdatePromotion - TODO: Implémenter"})
+}
+
+func DeletePromotion(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "DeletePromotion - TODO: Implémenter"})
+}
+
+func GetPromotionStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "GetPromotionStats - TODO: Implémenter"})
+}
+
+func GetServiceZones(c *gin.Context) {
+	zones, err := zoneService.GetZones()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get zones", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"zones": zones})
+}
+
+func CreateServiceZone(c *gin.Context) {
+	var req models.CreateServiceZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	zone, err := zoneService.CreateZone(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create zone", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Zone created successfully",
+		"zone": zone,
+	})
 }
 
-func CreatePromotion(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "CreatePromotion - TODO: Implémenter"})
+func UpdateServiceZone(c *gin.Context) {
+	var req models.UpdateServiceZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	zone, err := zoneService.UpdateZone(c.Param("zone_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update zone", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Zone updated successfully",
+		"zone": zone,
+	})
 }
 
-func UpdatePromotion(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "UpdatePromotion - TODO: Implémenter"})
+func DeleteServiceZone(c *gin.Context) {
+	err := zoneService.DeleteZone(c.Param("zone_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete zone", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Zone deleted successfully"})
 }
 
-func DeletePromotion(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "DeletePromotion - TODO: Implémenter"})
+func GetGazetteerEntries(c *gin.Context) {
+	entries, err := geocodingService.GetEntries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get gazetteer", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
 }
 
-func GetPromotionStats(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetPromotionStats - TODO: Implémenter"})
+func CreateGazetteerEntry(c *gin.Context) {
+	var req models.CreateGazetteerEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	adminID, _ := middlewares.GetCurrentUserID(c)
+
+	entry, err := geocodingService.CreateEntry(adminID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create gazetteer entry", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Gazetteer entry created successfully",
+		"entry": entry,
+	})
+}
+
+func UpdateGazetteerEntry(c *gin.Context) {
+	var req models.UpdateGazetteerEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	entry, err := geocodingService.UpdateEntry(c.Param("entry_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update gazetteer entry", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Gazetteer entry updated successfully",
+		"entry": entry,
+	})
+}
+
+func DeleteGazetteerEntry(c *gin.Context) {
+	err := geocodingService.DeleteEntry(c.Param("entry_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete gazetteer entry", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Gazetteer entry deleted successfully"})
+}
+
+func GetSurgeStates(c *gin.Context) {
+	states, err := surgeService.GetSurgeStates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get surge multipliers", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"surge": states})
+}
+
+func CreateSurgeOverride(c *gin.Context) {
+	var req models.CreateSurgeOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	adminID, _ := middlewares.GetCurrentUserID(c)
+
+	override, err := surgeService.CreateOverride(adminID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create surge override", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Surge override created successfully",
+		"override": override,
+	})
+}
+
+func DeleteSurgeOverride(c *gin.Context) {
+	err := surgeService.DeleteOverride(c.Param("override_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete surge override", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Surge override deleted successfully"})
+}
+
+func GetTimeSurcharges(c *gin.Context) {
+	rules, err := pricingService.GetTimeSurcharges()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get time surcharges", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"surcharges": rules})
+}
+
+func CreateTimeSurcharge(c *gin.Context) {
+	var req models.CreateTimeSurchargeRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	rule, err := pricingService.CreateTimeSurcharge(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create time surcharge", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Time surcharge created successfully",
+		"surcharge": rule,
+	})
+}
+
+func UpdateTimeSurcharge(c *gin.Context) {
+	var req models.UpdateTimeSurchargeRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	rule, err := pricingService.UpdateTimeSurcharge(c.Param("rule_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update time surcharge", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Time surcharge updated successfully",
+		"surcharge": rule,
+	})
+}
+
+func DeleteTimeSurcharge(c *gin.Context) {
+	err := pricingService.DeleteTimeSurcharge(c.Param("rule_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete time surcharge", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Time surcharge deleted successfully"})
+}
+
+func GetHolidays(c *gin.Context) {
+	holidays, err := pricingService.GetHolidays()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get holidays", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"holidays": holidays})
+}
+
+func CreateHoliday(c *gin.Context) {
+	var req models.CreateHolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	holiday, err := pricingService.CreateHoliday(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create holiday", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Holiday created successfully",
+		"holiday": holiday,
+	})
+}
+
+func DeleteHoliday(c *gin.Context) {
+	err := pricingService.DeleteHoliday(c.Param("holiday_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete holiday", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Holiday deleted successfully"})
+}
+
+func GetPricingRules(c *gin.Context) {
+	rules, err := pricingService.GetCurrentRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pricing rules", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func CreatePricingRule(c *gin.Context) {
+	var req models.CreatePricingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	adminID, _ := middlewares.GetCurrentUserID(c)
+
+	rule, err := pricingService.CreateRuleVersion(adminID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create pricing rule", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Pricing rule version scheduled successfully",
+		"rule": rule,
+	})
+}
+
+func GetPricingRuleHistory(c *gin.Context) {
+	history, err := pricingService.GetRuleHistory(models.VehicleType(c.Param("vehicle_type")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get pricing rule history", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
+
+func CancelPricingRule(c *gin.Context) {
+	err := pricingService.CancelRuleVersion(c.Param("rule_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to cancel pricing rule", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled pricing rule cancelled successfully"})
+}
+
+func SimulatePricingRule(c *gin.Context) {
+	var req models.SimulatePricingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Validate request
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		return
+	}
+
+	simulation, err := deliveryService.SimulatePricingRule(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to simulate pricing rule", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"simulation": simulation})
 }
 
 func GetAllVehicles(c *gin.Context) {
@@ -310,7 +1857,19 @@ func GetDashboardStats(c *gin.Context) {
 }
 
 func GetRevenueStats(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "GetRevenueStats - TODO: Implémenter"})
+	var req models.DriverEarningsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	revenue, err := deliveryService.GetPlatformRevenue(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get revenue", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revenue": revenue})
 }
 
 func GetUserStats(c *gin.Context) {
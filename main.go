@@ -31,11 +31,19 @@ func main() {
 	}
 	log.Println("✅ Connexion à SurrealDB établie avec succès")
 
+	// Créer les index utilisés par les recherches
+	if err := db.EnsureIndexes(); err != nil {
+		log.Printf("⚠️ Impossible de créer les index: %v", err)
+	}
+
 	// Initialiser les handlers
 	log.Println("🔧 Initialisation des handlers...")
 	handlers.InitHandlers()
 	log.Println("✅ Handlers initialisés avec succès")
 
+	// Démarrer les tâches de fond (surveillance des SLA, compaction des positions)
+	handlers.StartBackgroundJobs()
+
 	// Configurer les routes
 	log.Println("🚀 Configuration des routes...")
 	router := routes.SetupRoutes()
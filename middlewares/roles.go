@@ -44,6 +44,11 @@ func RequireAdmin() gin.HandlerFunc {
 	return RequireRole(models.UserRoleAdmin)
 }
 
+// RequireAdminOrGestionnaire middleware pour admins ou gestionnaires
+func RequireAdminOrGestionnaire() gin.HandlerFunc {
+	return RequireRole(models.UserRoleAdmin, models.UserRoleGestionnaire)
+}
+
 // RequireDriver middleware spécifique pour les livreurs
 func RequireDriver() gin.HandlerFunc {
 	return RequireRole(models.UserRoleLivreur)
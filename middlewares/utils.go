@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -116,13 +117,16 @@ func SecurityHeadersMiddleware() gin.HandlerFunc {
 
 // RateLimitMiddleware middleware simple de limitation de débit
 func RateLimitMiddleware(maxRequests int, window time.Duration) gin.HandlerFunc {
-	// Map pour stocker les compteurs par IP
+	// Map pour stocker les compteurs par IP, partagée entre les requêtes concurrentes
 	requestCounts := make(map[string][]time.Time)
+	var mu sync.Mutex
 	
 	return func(c *gin.Context) {
 		clientIP := c.ClientIP()
 		now := time.Now()
 
+		mu.Lock()
+
 		// Nettoyer les anciennes requêtes
 		if timestamps, exists := requestCounts[clientIP]; exists {
 			var validTimestamps []time.Time
@@ -136,6 +140,7 @@ func RateLimitMiddleware(maxRequests int, window time.Duration) gin.HandlerFunc
 
 		// Vérifier si la limite est atteinte
 		if len(requestCounts[clientIP]) >= maxRequests {
+			mu.Unlock()
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "Trop de requêtes",
 				"message": fmt.Sprintf("Limite de %d requêtes par %v dépassée", maxRequests, window),
@@ -147,6 +152,7 @@ func RateLimitMiddleware(maxRequests int, window time.Duration) gin.HandlerFunc
 
 		// Ajouter la requête actuelle
 		requestCounts[clientIP] = append(requestCounts[clientIP], now)
+		mu.Unlock()
 		
 		c.Next()
 	}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Maximum number of addresses suggested to a client
+const MaxAddressSuggestions = 10
+
+// ContactPerson represents the person to ask for at an address
+type ContactPerson struct {
+	Name  string `json:"name" validate:"required,min=2,max=100"`
+	Phone string `json:"phone" validate:"required,min=8,max=15"`
+}
+
+// SavedAddress represents an address saved in a client's address book
+type SavedAddress struct {
+	ID                string         `json:"id"`
+	UserID            string         `json:"userId"`
+	Label             string         `json:"label"` // "Maison", "Boutique"...
+	Address           string         `json:"address"`
+	Lat               *float64       `json:"lat,omitempty"`
+	Lng               *float64       `json:"lng,omitempty"`
+	GeocodeConfidence *float64       `json:"geocodeConfidence,omitempty"`
+	Instructions      *string        `json:"instructions,omitempty"`
+	Contact           *ContactPerson `json:"contact,omitempty"`
+	UseCount          int            `json:"useCount"`
+	LastUsedAt        *time.Time     `json:"lastUsedAt,omitempty"`
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+}
+
+// CreateSavedAddressRequest represents request for saving an address
+type CreateSavedAddressRequest struct {
+	Label        string         `json:"label" validate:"required,min=1,max=50"`
+	Address      string         `json:"address" validate:"required,min=3,max=300"`
+	Lat          *float64       `json:"lat,omitempty" validate:"required_with=Lng,omitempty,gte=-90,lte=90"`
+	Lng          *float64       `json:"lng,omitempty" validate:"required_with=Lat,omitempty,gte=-180,lte=180"`
+	Instructions *string        `json:"instructions,omitempty" validate:"omitempty,max=500"`
+	Contact      *ContactPerson `json:"contact,omitempty"`
+}
+
+// UpdateSavedAddressRequest represents request for updating a saved address
+type UpdateSavedAddressRequest struct {
+	Label        *string        `json:"label,omitempty" validate:"omitempty,min=1,max=50"`
+	Address      *string        `json:"address,omitempty" validate:"omitempty,min=3,max=300"`
+	Lat          *float64       `json:"lat,omitempty" validate:"required_with=Lng,omitempty,gte=-90,lte=90"`
+	Lng          *float64       `json:"lng,omitempty" validate:"required_with=Lat,omitempty,gte=-180,lte=180"`
+	Instructions *string        `json:"instructions,omitempty" validate:"omitempty,max=500"`
+	Contact      *ContactPerson `json:"contact,omitempty"`
+}
+
+// AddressSuggestion represents an address proposed to the client when creating a delivery
+type AddressSuggestion struct {
+	SavedAddressID *string    `json:"savedAddressId,omitempty"` // Empty for recent addresses not in the address book
+	Label          *string    `json:"label,omitempty"`
+	Address        string     `json:"address"`
+	Lat            *float64   `json:"lat,omitempty"`
+	Lng            *float64   `json:"lng,omitempty"`
+	UseCount       int        `json:"useCount"`
+	LastUsedAt     *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// LocationKey identifies identical locations so reused addresses share one Location record.
+// Coordinates are rounded to about one meter.
+func LocationKey(address string, lat, lng *float64) string {
+	key := NormalizeAddress(address)
+	if lat != nil && lng != nil {
+		key += fmt.Sprintf("@%.5f,%.5f", *lat, *lng)
+	}
+	return key
+}
+
+// SuggestAddresses merges saved addresses and recently used ones, most recent first, without duplicates
+func SuggestAddresses(saved []SavedAddress, recent []AddressSuggestion) []AddressSuggestion {
+	suggestions := make([]AddressSuggestion, 0, MaxAddressSuggestions)
+	seen := make(map[string]bool)
+
+	for i := range saved {
+		address := &saved[i]
+		key := LocationKey(address.Address, address.Lat, address.Lng)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		seen[NormalizeAddress(address.Address)] = true
+
+		suggestions = append(suggestions, AddressSuggestion{
+			SavedAddressID: &address.ID,
+			Label:          &address.Label,
+			Address:        address.Address,
+			Lat:            address.Lat,
+			Lng:            address.Lng,
+			UseCount:       address.UseCount,
+			LastUsedAt:     address.LastUsedAt,
+		})
+	}
+
+	for _, suggestion := range recent {
+		key := LocationKey(suggestion.Address, suggestion.Lat, suggestion.Lng)
+		if seen[key] || seen[NormalizeAddress(suggestion.Address)] || strings.TrimSpace(suggestion.Address) == "" {
+			continue
+		}
+		seen[key] = true
+		suggestions = append(suggestions, suggestion)
+	}
+
+	// Saved addresses that were never used go after recently used addresses
+	sortSuggestions(suggestions)
+	if len(suggestions) > MaxAddressSuggestions {
+		suggestions = suggestions[:MaxAddressSuggestions]
+	}
+
+	return suggestions
+}
+
+func sortSuggestions(suggestions []AddressSuggestion) {
+	lastUsed := func(s AddressSuggestion) time.Time {
+		if s.LastUsedAt == nil {
+			return time.Time{}
+		}
+		return *s.LastUsedAt
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return lastUsed(suggestions[i]).After(lastUsed(suggestions[j]))
+	})
+}
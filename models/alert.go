@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// AlertType defines the ops alert type enumeration
+type AlertType string
+
+const (
+	AlertTypeETASlipped AlertType = "ETA_SLIPPED"
+	AlertTypeLowRating  AlertType = "LOW_RATING"
+	AlertTypeCashLimit  AlertType = "CASH_LIMIT"
+	AlertTypeSLABreach  AlertType = "SLA_BREACH"
+)
+
+// OpsAlert represents an alert raised for the operations team
+type OpsAlert struct {
+	ID         string     `json:"id"`
+	Type       AlertType  `json:"type"`
+	DeliveryID *string    `json:"deliveryId,omitempty"`
+	DriverID   *string    `json:"driverId,omitempty"`
+	UserID     *string    `json:"userId,omitempty"` // User concerned when the alert is not about a delivery
+	Message    string     `json:"message"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// IsResolved checks if the alert has been handled
+func (a *OpsAlert) IsResolved() bool {
+	return a.ResolvedAt != nil
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// FailedAttemptReason defines the failed delivery attempt reason code enumeration
+type FailedAttemptReason string
+
+const (
+	FailedAttemptRecipientAbsent      FailedAttemptReason = "RECIPIENT_ABSENT"
+	FailedAttemptRecipientUnreachable FailedAttemptReason = "RECIPIENT_UNREACHABLE"
+	FailedAttemptRecipientRefused     FailedAttemptReason = "RECIPIENT_REFUSED"
+	FailedAttemptWrongAddress         FailedAttemptReason = "WRONG_ADDRESS"
+	FailedAttemptAddressInaccessible  FailedAttemptReason = "ADDRESS_INACCESSIBLE"
+	FailedAttemptCODNotPaid           FailedAttemptReason = "COD_NOT_PAID"
+	FailedAttemptOther                FailedAttemptReason = "OTHER"
+)
+
+// ReturnLegCode is the price line item code of the return to the pickup address
+const ReturnLegCode = "RETURN_LEG"
+
+// DeliveryAttemptPolicy holds the configurable re-attempt and return rules
+type DeliveryAttemptPolicy struct {
+	MaxAttempts    int           `json:"maxAttempts"`    // Failed attempts before the parcel goes back to the sender
+	ReattemptDelay time.Duration `json:"reattemptDelay"` // Time between a failed attempt and the next one
+	ReturnLegRate  float64       `json:"returnLegRate"`  // Share of the delivery price charged for the return
+}
+
+// FailDeliveryAttemptRequest represents the driver reporting a failed attempt at dropoff
+type FailDeliveryAttemptRequest struct {
+	Reason    FailedAttemptReason `json:"reason" validate:"required"`
+	Comment   *string             `json:"comment,omitempty" validate:"omitempty,max=500"`
+	PhotoURLs []string            `json:"photoUrls,omitempty" validate:"omitempty,max=5,dive,url"` // Evidence: door, building, call log
+	Lat       *float64            `json:"lat,omitempty" validate:"omitempty,gte=-90,lte=90"`
+	Lng       *float64            `json:"lng,omitempty" validate:"omitempty,gte=-180,lte=180"`
+}
+
+// DeliveryAttempt represents a failed delivery attempt and its evidence
+type DeliveryAttempt struct {
+	ID                   string              `json:"id"`
+	DeliveryID           string              `json:"deliveryId"`
+	DriverID             string              `json:"driverId"`
+	Number               int                 `json:"number"` // 1 for the first attempt
+	Reason               FailedAttemptReason `json:"reason"`
+	Comment              *string             `json:"comment,omitempty"`
+	PhotoURLs            []string            `json:"photoUrls,omitempty"`
+	Lat                  *float64            `json:"lat,omitempty"`
+	Lng                  *float64            `json:"lng,omitempty"`
+	DistanceFromDropoffM *float64            `json:"distanceFromDropoffM,omitempty"` // Where the driver was when reporting
+	NextAttemptAt        *time.Time          `json:"nextAttemptAt,omitempty"`        // Nil when the parcel goes back to the sender
+	CreatedAt            time.Time           `json:"createdAt"`
+}
+
+// IsValid checks if the reason code is valid
+func (r FailedAttemptReason) IsValid() bool {
+	switch r {
+	case FailedAttemptRecipientAbsent, FailedAttemptRecipientUnreachable, FailedAttemptRecipientRefused,
+		FailedAttemptWrongAddress, FailedAttemptAddressInaccessible, FailedAttemptCODNotPaid, FailedAttemptOther:
+		return true
+	default:
+		return false
+	}
+}
+
+// AllowsReattempt checks if trying again can succeed, a refusal or a wrong address goes straight back
+func (r FailedAttemptReason) AllowsReattempt() bool {
+	return r != FailedAttemptRecipientRefused && r != FailedAttemptWrongAddress
+}
+
+// CanFailAttempt checks the driver can report a failed attempt on the delivery
+func (d *Delivery) CanFailAttempt() error {
+	switch d.Status {
+	case DeliveryStatusInTransit, DeliveryStatusArrivedAtDropoff, DeliveryStatusArrivedAtDestination:
+		return nil
+	default:
+		return fmt.Errorf("delivery cannot fail an attempt in status %s", d.Status)
+	}
+}
+
+// NextAttempt returns when to try the delivery again after a failed attempt,
+// or nil when the parcel must go back to the sender
+func (p *DeliveryAttemptPolicy) NextAttempt(failedAttempts int, reason FailedAttemptReason, now time.Time) *time.Time {
+	if !reason.AllowsReattempt() || failedAttempts >= p.MaxAttempts {
+		return nil
+	}
+
+	next := now.Add(p.ReattemptDelay)
+	return &next
+}
+
+// ReturnLegPrice returns the price of bringing the parcel back to the pickup address
+func (p *DeliveryAttemptPolicy) ReturnLegPrice(d *Delivery) float64 {
+	return math.Round(d.FinalPrice * p.ReturnLegRate)
+}
+
+// ReturnLegLineItem returns the line item added to the price when the return starts
+func ReturnLegLineItem(amount float64) PriceLineItem {
+	return PriceLineItem{
+		Code:   ReturnLegCode,
+		Label:  "Retour à l'expéditeur",
+		Amount: amount,
+	}
+}
+
+// ReturnLegCharge returns the price charged for the return, if it has been charged
+func (d *Delivery) ReturnLegCharge() (float64, bool) {
+	for _, item := range d.PriceLineItems {
+		if item.Code == ReturnLegCode {
+			return item.Amount, true
+		}
+	}
+	return 0, false
+}
+
+// ClientAttemptMessage returns the SMS text telling the client about a failed attempt or the return
+func ClientAttemptMessage(status DeliveryStatus, reason *FailedAttemptReason, nextAttemptAt *time.Time, returnPrice float64) string {
+	switch status {
+	case DeliveryStatusFailedAttempt:
+		message := "ILEX : échec de livraison"
+		if reason != nil {
+			message += fmt.Sprintf(" (%s)", *reason)
+		}
+		if nextAttemptAt != nil {
+			message += fmt.Sprintf(". Nouvelle tentative prévue à %s.", nextAttemptAt.Format("15:04"))
+		} else {
+			message += ". Le colis va vous être retourné."
+		}
+		return message
+	case DeliveryStatusReturning:
+		if returnPrice > 0 {
+			return fmt.Sprintf("ILEX : votre colis est en route vers l'adresse d'enlèvement. Retour facturé %.0f FCFA.", returnPrice)
+		}
+		return "ILEX : votre colis est en route vers l'adresse d'enlèvement."
+	case DeliveryStatusReturned:
+		return "ILEX : votre colis a été retourné à l'adresse d'enlèvement."
+	default:
+		return ""
+	}
+}
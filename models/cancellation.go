@@ -0,0 +1,166 @@
+package models
+
+import (
+	"time"
+)
+
+// CancellationReason defines the cancellation reason code enumeration
+type CancellationReason string
+
+const (
+	// Client reasons
+	CancellationReasonClientChangedMind   CancellationReason = "CLIENT_CHANGED_MIND"
+	CancellationReasonClientDriverTooLate CancellationReason = "CLIENT_DRIVER_TOO_LATE"
+	CancellationReasonClientWrongAddress  CancellationReason = "CLIENT_WRONG_ADDRESS"
+	CancellationReasonClientPriceTooHigh  CancellationReason = "CLIENT_PRICE_TOO_HIGH"
+	CancellationReasonClientOther         CancellationReason = "CLIENT_OTHER"
+
+	// Driver reasons
+	CancellationReasonDriverVehicleIssue      CancellationReason = "DRIVER_VEHICLE_ISSUE"
+	CancellationReasonDriverClientUnreachable CancellationReason = "DRIVER_CLIENT_UNREACHABLE"
+	CancellationReasonDriverPackageRefused    CancellationReason = "DRIVER_PACKAGE_REFUSED"
+	CancellationReasonDriverEmergency         CancellationReason = "DRIVER_EMERGENCY"
+	CancellationReasonDriverOther             CancellationReason = "DRIVER_OTHER"
+
+	// Admin reasons
+	CancellationReasonAdminDecision CancellationReason = "ADMIN_DECISION"
+)
+
+// CancellationPolicy holds the configurable cancellation rules
+type CancellationPolicy struct {
+	FreeWindowMin          int     `json:"freeWindowMin"`          // Minutes after acceptance during which the client cancels for free
+	EnRouteFee             float64 `json:"enRouteFee"`             // Flat fee once the driver is on the way to pickup
+	DriverCompensationRate float64 `json:"driverCompensationRate"` // Share of the fee paid to the driver
+}
+
+// CancelDeliveryRequest represents request for cancelling a delivery
+type CancelDeliveryRequest struct {
+	Reason  CancellationReason `json:"reason" validate:"required"`
+	Comment *string            `json:"comment,omitempty" validate:"omitempty,max=500"`
+	Confirm bool               `json:"confirm"` // If false, only the cancellation quote is returned
+}
+
+// CancellationQuote represents the outcome of a cancellation, shown before confirming
+type CancellationQuote struct {
+	DeliveryID         string  `json:"deliveryId"`
+	Allowed            bool    `json:"allowed"`
+	Free               bool    `json:"free"`
+	Fee                float64 `json:"fee"`
+	DriverCompensation float64 `json:"driverCompensation"`
+	Message            string  `json:"message"`
+	Confirmed          bool    `json:"confirmed"`
+}
+
+// DeliveryCancellation represents a recorded cancellation
+type DeliveryCancellation struct {
+	ID                 string             `json:"id"`
+	DeliveryID         string             `json:"deliveryId"`
+	CancelledByID      string             `json:"cancelledById"`
+	CancelledByRole    UserRole           `json:"cancelledByRole"`
+	DriverID           *string            `json:"driverId,omitempty"`
+	Reason             CancellationReason `json:"reason"`
+	Comment            *string            `json:"comment,omitempty"`
+	StatusAtCancel     DeliveryStatus     `json:"statusAtCancel"`
+	Fee                float64            `json:"fee"`
+	DriverCompensation float64            `json:"driverCompensation"`
+	CreatedAt          time.Time          `json:"createdAt"`
+}
+
+// IsValidFor checks if the reason code can be used by the given role
+func (r CancellationReason) IsValidFor(role UserRole) bool {
+	switch role {
+	case UserRoleClient:
+		return r == CancellationReasonClientChangedMind || r == CancellationReasonClientDriverTooLate ||
+			r == CancellationReasonClientWrongAddress || r == CancellationReasonClientPriceTooHigh ||
+			r == CancellationReasonClientOther
+	case UserRoleLivreur:
+		return r == CancellationReasonDriverVehicleIssue || r == CancellationReasonDriverClientUnreachable ||
+			r == CancellationReasonDriverPackageRefused || r == CancellationReasonDriverEmergency ||
+			r == CancellationReasonDriverOther
+	case UserRoleAdmin, UserRoleGestionnaire:
+		return r == CancellationReasonAdminDecision
+	default:
+		return false
+	}
+}
+
+// RequiresComment checks if the reason needs a free-text comment
+func (r CancellationReason) RequiresComment() bool {
+	return r == CancellationReasonClientOther || r == CancellationReasonDriverOther
+}
+
+// IsDriverEnRoute checks if the driver is on the way to pickup
+func (s DeliveryStatus) IsDriverEnRoute() bool {
+	return s == DeliveryStatusPickupInProgress || s == DeliveryStatusEnRoute ||
+		s == DeliveryStatusArrivedAtPickup
+}
+
+// Evaluate computes the cancellation outcome for the given role at the given time
+func (p *CancellationPolicy) Evaluate(d *Delivery, role UserRole, now time.Time) *CancellationQuote {
+	quote := &CancellationQuote{
+		DeliveryID: d.ID,
+		Allowed:    true,
+		Free:       true,
+	}
+
+	if !d.CanBeCancelled() {
+		quote.Allowed = false
+		quote.Message = "Delivery can no longer be cancelled"
+		return quote
+	}
+
+	switch role {
+	case UserRoleLivreur:
+		// Drivers never pay a fee, their cancellations count against their reliability score
+		if d.Status != DeliveryStatusAccepted && !d.Status.IsDriverEnRoute() {
+			quote.Allowed = false
+			quote.Message = "Drivers cannot cancel after pickup, contact support"
+			return quote
+		}
+		quote.Message = "The delivery will be offered to another driver"
+		return quote
+
+	case UserRoleClient:
+		if d.Status == DeliveryStatusPending || d.LivreurID == nil {
+			quote.Message = "Free cancellation: no driver assigned yet"
+			return quote
+		}
+
+		if d.Status != DeliveryStatusAccepted && !d.Status.IsDriverEnRoute() {
+			quote.Allowed = false
+			quote.Message = "The parcel has already been picked up, contact support"
+			return quote
+		}
+
+		if d.Status == DeliveryStatusAccepted && d.AcceptedAt != nil &&
+			now.Sub(*d.AcceptedAt) <= time.Duration(p.FreeWindowMin)*time.Minute {
+			quote.Message = "Free cancellation: within the free cancellation window"
+			return quote
+		}
+
+		quote.Free = false
+		quote.Fee = p.EnRouteFee
+		if quote.Fee > d.FinalPrice {
+			quote.Fee = d.FinalPrice // Never charge more than the delivery itself
+		}
+		quote.DriverCompensation = quote.Fee * p.DriverCompensationRate
+		quote.Message = "A cancellation fee applies: the driver is already on the way"
+		return quote
+
+	default:
+		// Admin cancellations are never charged to the client
+		quote.Message = "Cancelled by administration"
+		return quote
+	}
+}
+
+// WithdrawnDriverIDs returns the drivers who cancelled a delivery themselves, it is not offered to them again
+func WithdrawnDriverIDs(cancellations []DeliveryCancellation) map[string]bool {
+	withdrawn := make(map[string]bool)
+	for _, c := range cancellations {
+		if c.CancelledByRole == UserRoleLivreur {
+			withdrawn[c.CancelledByID] = true
+		}
+	}
+	return withdrawn
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// CashEntryType defines the driver cash ledger entry enumeration
+type CashEntryType string
+
+const (
+	CashEntryCODCollected CashEntryType = "COD_COLLECTED" // Goods price collected for the merchant, owed in full
+	CashEntryCashFare     CashEntryType = "CASH_FARE"     // Delivery price paid in cash, kept by the driver
+	CashEntryCommission   CashEntryType = "COMMISSION"    // Platform share of a cash fare, owed by the driver
+	CashEntryServiceFee   CashEntryType = "SERVICE_FEE"   // Platform fee on a cash fare, owed by the driver
+	CashEntryRemittance   CashEntryType = "REMITTANCE"    // Cash handed in to the platform
+)
+
+// CashLedgerEntry represents a movement of the cash a driver holds. Entries are never updated:
+// corrections are new entries.
+type CashLedgerEntry struct {
+	ID           string        `json:"id"`
+	DriverID     string        `json:"driverId"`
+	DeliveryID   *string       `json:"deliveryId,omitempty"`
+	Type         CashEntryType `json:"type"`
+	Amount       float64       `json:"amount"`              // Always positive, the type gives the direction
+	Reference    *string       `json:"reference,omitempty"` // Receipt number of a remittance
+	Note         *string       `json:"note,omitempty"`
+	RecordedByID *string       `json:"recordedById,omitempty"` // Admin who recorded a remittance
+	CreatedAt    time.Time     `json:"createdAt"`
+}
+
+// DriverCashBalance represents the cash a driver holds and what they owe the platform
+type DriverCashBalance struct {
+	DriverID       string            `json:"driverId"`
+	CashHeld       float64           `json:"cashHeld"` // Collected and not yet handed in, fares included
+	CODCollected   float64           `json:"codCollected"`
+	CashFares      float64           `json:"cashFares"`
+	CommissionOwed float64           `json:"commissionOwed"`
+	ServiceFeeOwed float64           `json:"serviceFeeOwed"`
+	Remitted       float64           `json:"remitted"`
+	AmountDue      float64           `json:"amountDue"` // Cash on delivery and commission not yet remitted
+	Limit          float64           `json:"limit"`     // 0 means no limit
+	OverLimit      bool              `json:"overLimit"` // New dispatches are blocked
+	Entries        []CashLedgerEntry `json:"entries,omitempty"`
+}
+
+// DriverCashDue represents a driver with cash to remit, for the admin list
+type DriverCashDue struct {
+	DriverID   string  `json:"driverId"`
+	DriverName string  `json:"driverName"`
+	Phone      string  `json:"phone"`
+	AmountDue  float64 `json:"amountDue"`
+	OverLimit  bool    `json:"overLimit"`
+}
+
+// CollectCODRequest represents the driver confirming the cash collected at dropoff
+type CollectCODRequest struct {
+	Amount float64 `json:"amount" validate:"gt=0"`
+}
+
+// CreateRemittanceRequest represents cash handed in by a driver
+type CreateRemittanceRequest struct {
+	Amount    float64 `json:"amount" validate:"gt=0"`
+	Reference *string `json:"reference,omitempty" validate:"omitempty,max=100"`
+	Note      *string `json:"note,omitempty" validate:"omitempty,max=500"`
+}
+
+// HasCOD checks if the driver must collect the goods price from the recipient
+func (d *Delivery) HasCOD() bool {
+	return d.CODAmount != nil && *d.CODAmount > 0
+}
+
+// RequiresCODCollection checks if the delivery cannot be completed before the cash is collected
+func (d *Delivery) RequiresCODCollection() bool {
+	return d.HasCOD() && d.CODCollectedAt == nil
+}
+
+// CanCollectCOD checks the driver can confirm the collection of the given amount
+func (d *Delivery) CanCollectCOD(amount float64) error {
+	if !d.HasCOD() {
+		return fmt.Errorf("delivery has no cash on delivery")
+	}
+	if d.CODCollectedAt != nil {
+		return fmt.Errorf("cash on delivery already collected")
+	}
+	if d.Status != DeliveryStatusArrivedAtDropoff && d.Status != DeliveryStatusArrivedAtDestination {
+		return fmt.Errorf("cash on delivery is collected at dropoff, delivery is %s", d.Status)
+	}
+	if amount != *d.CODAmount {
+		return fmt.Errorf("collected amount must be the cash on delivery amount of %.0f FCFA", *d.CODAmount)
+	}
+	return nil
+}
+
+// CashFareEntries returns the ledger entries of a delivery paid in cash: the driver keeps the fare
+// and owes the platform its commission and service fee
+func (d *Delivery) CashFareEntries(commissionRate, serviceFee float64, now time.Time) []CashLedgerEntry {
+	if d.PaymentMethod != PaymentMethodCash || d.LivreurID == nil || d.FinalPrice <= 0 {
+		return nil
+	}
+
+	earning := NewDeliveryEarning(d, commissionRate)
+	earning.ApplyServiceFee(serviceFee)
+	entries := []CashLedgerEntry{
+		{DriverID: *d.LivreurID, DeliveryID: &d.ID, Type: CashEntryCashFare, Amount: earning.Gross, CreatedAt: now},
+	}
+	if earning.Commission > 0 {
+		entries = append(entries, CashLedgerEntry{DriverID: *d.LivreurID, DeliveryID: &d.ID, Type: CashEntryCommission, Amount: earning.Commission, CreatedAt: now})
+	}
+	if earning.ServiceFee > 0 {
+		entries = append(entries, CashLedgerEntry{DriverID: *d.LivreurID, DeliveryID: &d.ID, Type: CashEntryServiceFee, Amount: earning.ServiceFee, CreatedAt: now})
+	}
+
+	return entries
+}
+
+// Due returns how much the entry changes the amount the driver owes the platform
+func (e *CashLedgerEntry) Due() float64 {
+	switch e.Type {
+	case CashEntryCODCollected, CashEntryCommission, CashEntryServiceFee:
+		return e.Amount
+	case CashEntryRemittance:
+		return -e.Amount
+	default:
+		return 0
+	}
+}
+
+// NewDriverCashBalance builds the balance of a driver from the ledger totals per entry type
+func NewDriverCashBalance(driverID string, totals map[CashEntryType]float64, limit float64) *DriverCashBalance {
+	balance := &DriverCashBalance{
+		DriverID:       driverID,
+		CODCollected:   totals[CashEntryCODCollected],
+		CashFares:      totals[CashEntryCashFare],
+		CommissionOwed: totals[CashEntryCommission],
+		ServiceFeeOwed: totals[CashEntryServiceFee],
+		Remitted:       totals[CashEntryRemittance],
+		Limit:          limit,
+	}
+
+	balance.CashHeld = balance.CODCollected + balance.CashFares - balance.Remitted
+	balance.AmountDue = balance.CODCollected + balance.CommissionOwed + balance.ServiceFeeOwed - balance.Remitted
+	balance.OverLimit = IsOverCashLimit(balance.AmountDue, limit)
+
+	return balance
+}
+
+// CanRemit checks a remittance does not exceed what the driver owes
+func (b *DriverCashBalance) CanRemit(amount float64) error {
+	if amount > b.AmountDue {
+		return fmt.Errorf("remittance of %.0f FCFA exceeds the %.0f FCFA due", amount, b.AmountDue)
+	}
+	return nil
+}
+
+// IsOverCashLimit checks if a driver owes more cash than allowed, a zero limit disables the check
+func IsOverCashLimit(amountDue, limit float64) bool {
+	return limit > 0 && amountDue > limit
+}
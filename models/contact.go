@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RecipientNotice defines the SMS sent to the recipient along a delivery
+type RecipientNotice string
+
+const (
+	RecipientNoticeEnRoute   RecipientNotice = "EN_ROUTE"
+	RecipientNoticeArrived   RecipientNotice = "ARRIVED"
+	RecipientNoticeDelivered RecipientNotice = "DELIVERED"
+)
+
+// DeliveryContacts represents the sender and recipient the driver can call
+type DeliveryContacts struct {
+	CanCall        bool           `json:"canCall"` // Phones are only given while the delivery is active
+	PickupContact  *ContactPerson `json:"pickupContact,omitempty"`
+	DropoffContact *ContactPerson `json:"dropoffContact,omitempty"`
+}
+
+// NormalizePhone returns the phone number in international format. Local numbers get the
+// default country code; spaces, dots, dashes and brackets are ignored.
+func NormalizePhone(phone, defaultCountryCode string) (string, error) {
+	cleaned := strings.NewReplacer(" ", "", ".", "", "-", "", "(", "", ")", "").Replace(strings.TrimSpace(phone))
+	if strings.HasPrefix(cleaned, "00") {
+		cleaned = "+" + cleaned[2:]
+	}
+
+	international := strings.HasPrefix(cleaned, "+")
+	digits := strings.TrimPrefix(cleaned, "+")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return "", fmt.Errorf("invalid phone number: %s", phone)
+	}
+
+	if !international {
+		digits = defaultCountryCode + digits
+	}
+
+	// E.164 numbers have at most 15 digits, country code included
+	if len(digits) < 8 || len(digits) > 15 {
+		return "", fmt.Errorf("invalid phone number: %s", phone)
+	}
+
+	return "+" + digits, nil
+}
+
+// Normalize trims the name and puts the phone in international format
+func (c *ContactPerson) Normalize(defaultCountryCode string) error {
+	phone, err := NormalizePhone(c.Phone, defaultCountryCode)
+	if err != nil {
+		return err
+	}
+
+	c.Name = strings.TrimSpace(c.Name)
+	c.Phone = phone
+	return nil
+}
+
+// RecipientNotice returns the SMS the recipient gets when the delivery reaches the status
+func (s DeliveryStatus) RecipientNotice() (RecipientNotice, bool) {
+	switch s {
+	case DeliveryStatusPickedUp, DeliveryStatusInTransit, DeliveryStatusEnRoute, DeliveryStatusDeliveryInProgress:
+		return RecipientNoticeEnRoute, true
+	case DeliveryStatusArrivedAtDropoff, DeliveryStatusArrivedAtDestination:
+		return RecipientNoticeArrived, true
+	case DeliveryStatusDelivered:
+		return RecipientNoticeDelivered, true
+	default:
+		return "", false
+	}
+}
+
+// HasSentNotice checks if the recipient already got the SMS, several statuses share one
+func (d *Delivery) HasSentNotice(notice RecipientNotice) bool {
+	for _, sent := range d.RecipientNotices {
+		if sent == notice {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive checks if a driver is on the delivery and it is not finished
+func (d *Delivery) IsActive() bool {
+	return d.LivreurID != nil &&
+		d.Status != DeliveryStatusPending &&
+		d.Status != DeliveryStatusDelivered &&
+		d.Status != DeliveryStatusCancelled &&
+		d.Status != DeliveryStatusReturned
+}
+
+// ContactsFor returns the contacts a user of the given role can see.
+// Drivers only get the phone numbers, and the call button, while the delivery is active.
+func (d *Delivery) ContactsFor(role UserRole) *DeliveryContacts {
+	contacts := &DeliveryContacts{
+		CanCall:        d.IsActive() && (role == UserRoleLivreur || role == UserRoleAdmin),
+		PickupContact:  d.PickupContact,
+		DropoffContact: d.DropoffContact,
+	}
+
+	if role == UserRoleLivreur && !contacts.CanCall {
+		contacts.PickupContact = withoutPhone(d.PickupContact)
+		contacts.DropoffContact = withoutPhone(d.DropoffContact)
+	}
+
+	return contacts
+}
+
+func withoutPhone(contact *ContactPerson) *ContactPerson {
+	if contact == nil {
+		return nil
+	}
+	return &ContactPerson{Name: contact.Name}
+}
+
+// RecipientNoticeMessage returns the SMS text sent to the recipient
+func RecipientNoticeMessage(notice RecipientNotice, driverFirstName string, trackingURL *string) string {
+	driver := "Votre livreur"
+	if driverFirstName != "" {
+		driver = driverFirstName
+	}
+
+	switch notice {
+	case RecipientNoticeEnRoute:
+		message := fmt.Sprintf("ILEX : votre colis est en route avec %s.", driver)
+		if trackingURL != nil {
+			message += " Suivez-le ici : " + *trackingURL
+		}
+		return message
+	case RecipientNoticeArrived:
+		return fmt.Sprintf("ILEX : %s est arrivé avec votre colis.", driver)
+	case RecipientNoticeDelivered:
+		return "ILEX : votre colis a été livré. Merci et à bientôt !"
+	default:
+		return ""
+	}
+}
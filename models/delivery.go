@@ -31,6 +31,9 @@ const (
 	DeliveryStatusDispatchInProgress   DeliveryStatus = "DISPATCH_IN_PROGRESS"
 	DeliveryStatusSorted               DeliveryStatus = "SORTED"
 	DeliveryStatusSortingInProgress    DeliveryStatus = "SORTING_IN_PROGRESS"
+	DeliveryStatusFailedAttempt        DeliveryStatus = "FAILED_ATTEMPT"
+	DeliveryStatusReturning            DeliveryStatus = "RETURNING"
+	DeliveryStatusReturned             DeliveryStatus = "RETURNED"
 )
 
 // DeliveryType defines the delivery type enumeration
@@ -65,10 +68,12 @@ const (
 
 // Location represents a location
 type Location struct {
-	ID      string   `json:"id"`
-	Address string   `json:"address" validate:"required"`
-	Lat     *float64 `json:"lat,omitempty" validate:"omitempty,gte=-90,lte=90"`
-	Lng     *float64 `json:"lng,omitempty" validate:"omitempty,gte=-180,lte=180"`
+	ID                string   `json:"id"`
+	Address           string   `json:"address" validate:"required"`
+	Lat               *float64 `json:"lat,omitempty" validate:"omitempty,gte=-90,lte=90"`
+	Lng               *float64 `json:"lng,omitempty" validate:"omitempty,gte=-180,lte=180"`
+	GeocodeConfidence *float64 `json:"geocodeConfidence,omitempty"` // Set when coordinates were geocoded
+	GeocodedBy        *string  `json:"geocodedBy,omitempty"`
 }
 
 // Delivery represents a delivery
@@ -90,22 +95,84 @@ type Delivery struct {
 	CreatedAt     time.Time      `json:"createdAt"`
 	UpdatedAt     time.Time      `json:"updatedAt"`
 	PaidAt        *time.Time     `json:"paidAt,omitempty"`
+	AcceptedAt    *time.Time     `json:"acceptedAt,omitempty"`
+	CancelledAt   *time.Time     `json:"cancelledAt,omitempty"`
+	DeliveredAt   *time.Time     `json:"deliveredAt,omitempty"`
+	CancellationReason  *CancellationReason `json:"cancellationReason,omitempty"`
+	CancellationFee     *float64            `json:"cancellationFee,omitempty"`
+	ArrivedAtPickupAt   *time.Time          `json:"arrivedAtPickupAt,omitempty"`
+	ArrivedAtDropoffAt  *time.Time          `json:"arrivedAtDropoffAt,omitempty"`
+	PickupWaitingMin    *float64            `json:"pickupWaitingMin,omitempty"`
+	DropoffWaitingMin   *float64            `json:"dropoffWaitingMin,omitempty"`
+	WaitingPrice        *float64            `json:"waitingPrice,omitempty"`
+	PriceLineItems      []PriceLineItem     `json:"priceLineItems,omitempty"`
+	EtaPickupAt         *time.Time          `json:"etaPickupAt,omitempty"`
+	EtaDropoffAt        *time.Time          `json:"etaDropoffAt,omitempty"`
+	EtaBaselineAt       *time.Time          `json:"etaBaselineAt,omitempty"` // First dropoff ETA given to the client
+	EtaSlipAlertedAt    *time.Time          `json:"etaSlipAlertedAt,omitempty"`
+	EtaNotifiedAt       *time.Time          `json:"etaNotifiedAt,omitempty"` // Last ETA SMS sent to the client
+	Geofence            *GeofenceState      `json:"geofence,omitempty"`
+	PickupZoneID        *string             `json:"pickupZoneId,omitempty"`
+	DropoffZoneID       *string             `json:"dropoffZoneId,omitempty"`
+	PricingRuleID       *string             `json:"pricingRuleId,omitempty"`
+	PricingRuleVersion  *int                `json:"pricingRuleVersion,omitempty"`
+	DistanceProvider    *string             `json:"distanceProvider,omitempty"` // Routing provider that produced DistanceKm
+	PickupAddressID     *string             `json:"pickupAddressId,omitempty"` // Saved address the pickup was taken from
+	DropoffAddressID    *string             `json:"dropoffAddressId,omitempty"`
+	PickupInstructions  *string             `json:"pickupInstructions,omitempty"`
+	DropoffInstructions *string             `json:"dropoffInstructions,omitempty"`
+	PickupContact       *ContactPerson      `json:"pickupContact,omitempty"`
+	DropoffContact      *ContactPerson      `json:"dropoffContact,omitempty"`
+	TipAmount           *float64            `json:"tipAmount,omitempty"` // Total tipped, outside FinalPrice
+	RecipientNotices    []RecipientNotice   `json:"recipientNotices,omitempty"` // SMS already sent to the recipient
+	CODAmount           *float64            `json:"codAmount,omitempty"` // Goods price the driver collects from the recipient
+	CODCollectedAt      *time.Time          `json:"codCollectedAt,omitempty"`
+	FailedAttempts      int                 `json:"failedAttempts,omitempty"`
+	LastAttemptReason   *FailedAttemptReason `json:"lastAttemptReason,omitempty"`
+	NextAttemptAt       *time.Time          `json:"nextAttemptAt,omitempty"` // Scheduled re-attempt after a failed one
+	ReturnedAt          *time.Time          `json:"returnedAt,omitempty"`
+	StatusChangedAt     *time.Time          `json:"statusChangedAt,omitempty"`
+	SLA                 *SLAState           `json:"sla,omitempty"` // Watchdog state in the current status
 }
 
 // CreateDeliveryRequest represents request for creating a delivery
 type CreateDeliveryRequest struct {
-	Type          DeliveryType  `json:"type" validate:"required"`
-	PickupAddress string        `json:"pickupAddress" validate:"required"`
-	PickupLat     *float64      `json:"pickupLat,omitempty" validate:"omitempty,gte=-90,lte=90"`
-	PickupLng     *float64      `json:"pickupLng,omitempty" validate:"omitempty,gte=-180,lte=180"`
-	DropoffAddress string       `json:"dropoffAddress" validate:"required"`
-	DropoffLat    *float64      `json:"dropoffLat,omitempty" validate:"omitempty,gte=-90,lte=90"`
-	DropoffLng    *float64      `json:"dropoffLng,omitempty" validate:"omitempty,gte=-180,lte=180"`
-	VehicleType   VehicleType   `json:"vehicleType" validate:"required"`
-	PaymentMethod PaymentMethod `json:"paymentMethod" validate:"required"`
-	PackageInfo   *PackageInfo  `json:"packageInfo,omitempty"`
-	MovingInfo    *MovingInfo   `json:"movingInfo,omitempty"`
-	GroupedInfo   *GroupedInfo  `json:"groupedInfo,omitempty"`
+	Type             DeliveryType   `json:"type" validate:"required"`
+	PickupAddressID  *string        `json:"pickupAddressId,omitempty"` // Saved address used instead of the raw pickup address
+	PickupAddress    string         `json:"pickupAddress" validate:"required_without=PickupAddressID"`
+	PickupLat        *float64       `json:"pickupLat,omitempty" validate:"omitempty,gte=-90,lte=90"`
+	PickupLng        *float64       `json:"pickupLng,omitempty" validate:"omitempty,gte=-180,lte=180"`
+	DropoffAddressID *string        `json:"dropoffAddressId,omitempty"`
+	DropoffAddress   string         `json:"dropoffAddress" validate:"required_without=DropoffAddressID"`
+	DropoffLat       *float64       `json:"dropoffLat,omitempty" validate:"omitempty,gte=-90,lte=90"`
+	DropoffLng       *float64       `json:"dropoffLng,omitempty" validate:"omitempty,gte=-180,lte=180"`
+	VehicleType      VehicleType    `json:"vehicleType" validate:"required"`
+	PaymentMethod    PaymentMethod  `json:"paymentMethod" validate:"required"`
+	PackageInfo      *PackageInfo   `json:"packageInfo,omitempty"`
+	MovingInfo       *MovingInfo    `json:"movingInfo,omitempty"`
+	GroupedInfo      *GroupedInfo   `json:"groupedInfo,omitempty"`
+	SenderContact    *ContactPerson `json:"senderContact,omitempty"`    // Person handing over the parcel, overrides the saved address contact
+	RecipientContact *ContactPerson `json:"recipientContact,omitempty"` // Gets SMS at key statuses
+	CODAmount        *float64       `json:"codAmount,omitempty" validate:"omitempty,gt=0"` // Cash on delivery collected for the merchant
+}
+
+// UpdateDeliveryStatusRequest represents request for moving a delivery to a new status
+type UpdateDeliveryStatusRequest struct {
+	Status DeliveryStatus `json:"status" validate:"required"`
+}
+
+// PriceQuoteRequest represents request for a price quote before creating a delivery
+type PriceQuoteRequest struct {
+	Type           DeliveryType `json:"type" validate:"required"`
+	VehicleType    VehicleType  `json:"vehicleType" validate:"required"`
+	PickupLat      *float64     `json:"pickupLat,omitempty" validate:"omitempty,gte=-90,lte=90"`
+	PickupLng      *float64     `json:"pickupLng,omitempty" validate:"omitempty,gte=-180,lte=180"`
+	DropoffLat     *float64     `json:"dropoffLat,omitempty" validate:"omitempty,gte=-90,lte=90"`
+	DropoffLng     *float64     `json:"dropoffLng,omitempty" validate:"omitempty,gte=-180,lte=180"`
+	DistanceKm     *float64     `json:"distanceKm,omitempty" validate:"omitempty,gte=0"`
+	PromoCode      *string      `json:"promoCode,omitempty"`
+	PickupAddress  *string      `json:"pickupAddress,omitempty"` // Geocoded when coordinates are missing
+	DropoffAddress *string      `json:"dropoffAddress,omitempty"`
 }
 
 // UpdateDeliveryRequest represents request for updating a delivery
@@ -192,6 +259,30 @@ type DeliveryResponse struct {
 	CreatedAt     time.Time      `json:"createdAt"`
 	UpdatedAt     time.Time      `json:"updatedAt"`
 	PaidAt        *time.Time     `json:"paidAt,omitempty"`
+	AcceptedAt    *time.Time     `json:"acceptedAt,omitempty"`
+	CancelledAt   *time.Time     `json:"cancelledAt,omitempty"`
+	DeliveredAt   *time.Time     `json:"deliveredAt,omitempty"`
+	CancellationReason  *CancellationReason `json:"cancellationReason,omitempty"`
+	CancellationFee     *float64            `json:"cancellationFee,omitempty"`
+	PickupWaitingMin    *float64            `json:"pickupWaitingMin,omitempty"`
+	DropoffWaitingMin   *float64            `json:"dropoffWaitingMin,omitempty"`
+	WaitingPrice        *float64            `json:"waitingPrice,omitempty"`
+	PriceLineItems      []PriceLineItem     `json:"priceLineItems,omitempty"`
+	EtaPickupAt         *time.Time          `json:"etaPickupAt,omitempty"`
+	EtaDropoffAt        *time.Time          `json:"etaDropoffAt,omitempty"`
+	DistanceProvider    *string             `json:"distanceProvider,omitempty"`
+	PickupInstructions  *string             `json:"pickupInstructions,omitempty"`
+	DropoffInstructions *string             `json:"dropoffInstructions,omitempty"`
+	PickupContact       *ContactPerson      `json:"pickupContact,omitempty"`
+	DropoffContact      *ContactPerson      `json:"dropoffContact,omitempty"`
+	TipAmount           *float64            `json:"tipAmount,omitempty"`
+	CanCall             bool                `json:"canCall,omitempty"` // The viewer can call the contacts
+	CODAmount           *float64            `json:"codAmount,omitempty"`
+	CODCollectedAt      *time.Time          `json:"codCollectedAt,omitempty"`
+	FailedAttempts      int                 `json:"failedAttempts,omitempty"`
+	LastAttemptReason   *FailedAttemptReason `json:"lastAttemptReason,omitempty"`
+	NextAttemptAt       *time.Time          `json:"nextAttemptAt,omitempty"`
+	ReturnedAt          *time.Time          `json:"returnedAt,omitempty"`
 	Package       *Package       `json:"package,omitempty"`
 	Moving        *MovingService `json:"moving,omitempty"`
 	Grouped       *GroupedDelivery `json:"grouped,omitempty"`
@@ -243,7 +334,8 @@ func (s DeliveryStatus) IsValid() bool {
 		DeliveryStatusUnloadingInProgress, DeliveryStatusUnloadingCompleted,
 		DeliveryStatusArrivedAtDropoff, DeliveryStatusEnRoute,
 		DeliveryStatusDispatchInProgress, DeliveryStatusSorted,
-		DeliveryStatusSortingInProgress,
+		DeliveryStatusSortingInProgress, DeliveryStatusFailedAttempt,
+		DeliveryStatusReturning, DeliveryStatusReturned,
 	}
 	
 	for _, status := range validStatuses {
@@ -290,6 +382,8 @@ func (d *Delivery) CanBeAssigned() bool {
 func (d *Delivery) CanBeCancelled() bool {
 	return d.Status != DeliveryStatusDelivered && 
 		   d.Status != DeliveryStatusCancelled &&
+		   d.Status != DeliveryStatusReturning &&
+		   d.Status != DeliveryStatusReturned &&
 		   d.PaidAt == nil
 }
 
@@ -352,5 +446,28 @@ func (d *Delivery) ToResponse() *DeliveryResponse {
 		CreatedAt:     d.CreatedAt,
 		UpdatedAt:     d.UpdatedAt,
 		PaidAt:        d.PaidAt,
+		AcceptedAt:    d.AcceptedAt,
+		CancelledAt:   d.CancelledAt,
+		DeliveredAt:   d.DeliveredAt,
+		CancellationReason:  d.CancellationReason,
+		CancellationFee:     d.CancellationFee,
+		PickupWaitingMin:    d.PickupWaitingMin,
+		DropoffWaitingMin:   d.DropoffWaitingMin,
+		WaitingPrice:        d.WaitingPrice,
+		PriceLineItems:      d.PriceLineItems,
+		EtaPickupAt:         d.EtaPickupAt,
+		EtaDropoffAt:        d.EtaDropoffAt,
+		DistanceProvider:    d.DistanceProvider,
+		PickupInstructions:  d.PickupInstructions,
+		DropoffInstructions: d.DropoffInstructions,
+		PickupContact:       d.PickupContact,
+		DropoffContact:      d.DropoffContact,
+		TipAmount:           d.TipAmount,
+		CODAmount:           d.CODAmount,
+		CODCollectedAt:      d.CODCollectedAt,
+		FailedAttempts:      d.FailedAttempts,
+		LastAttemptReason:   d.LastAttemptReason,
+		NextAttemptAt:       d.NextAttemptAt,
+		ReturnedAt:          d.ReturnedAt,
 	}
 }
@@ -0,0 +1,352 @@
+package models
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportFormat defines the file formats accepted by the bulk delivery import
+type ImportFormat string
+
+const (
+	ImportFormatCSV   ImportFormat = "CSV"
+	ImportFormatJSONL ImportFormat = "JSONL"
+)
+
+// ImportJobStatus defines the status of a bulk delivery import
+type ImportJobStatus string
+
+const (
+	ImportJobStatusPending   ImportJobStatus = "PENDING"
+	ImportJobStatusRunning   ImportJobStatus = "RUNNING"
+	ImportJobStatusCompleted ImportJobStatus = "COMPLETED"
+	ImportJobStatusFailed    ImportJobStatus = "FAILED"
+)
+
+// ImportRowStatus defines the outcome of one row of an import
+type ImportRowStatus string
+
+const (
+	ImportRowStatusValid   ImportRowStatus = "VALID"   // Dry run, the row passed the creation checks
+	ImportRowStatusInvalid ImportRowStatus = "INVALID" // The row breaks the delivery rules
+	ImportRowStatusCreated ImportRowStatus = "CREATED"
+	ImportRowStatusFailed  ImportRowStatus = "FAILED" // Valid row rejected by the creation checks, e.g. outside coverage
+)
+
+// importCSVColumns lists the accepted CSV headers, compared case insensitively
+var importCSVColumns = []string{
+	"type", "vehicletype", "paymentmethod",
+	"pickupaddress", "pickuplat", "pickuplng", "pickupaddressid",
+	"dropoffaddress", "dropofflat", "dropofflng", "dropoffaddressid",
+	"sendername", "senderphone", "recipientname", "recipientphone", "codamount",
+}
+
+// DeliveryImportRequest represents the options of a bulk delivery import
+type DeliveryImportRequest struct {
+	Format ImportFormat `form:"format"` // Detected from the file name or content type when empty
+	DryRun bool         `form:"dryRun"`
+}
+
+// DeliveryImportRow represents one row of an import and its outcome
+type DeliveryImportRow struct {
+	Line       int                    `json:"line"` // Line in the uploaded file
+	Status     ImportRowStatus        `json:"status,omitempty"`
+	Errors     []string               `json:"errors,omitempty"`
+	DeliveryID *string                `json:"deliveryId,omitempty"`
+	Request    *CreateDeliveryRequest `json:"-"`
+}
+
+// DeliveryImportJob represents a bulk delivery import running in the background
+type DeliveryImportJob struct {
+	ID            string              `json:"id"`
+	ClientID      string              `json:"clientId"`
+	Format        ImportFormat        `json:"format"`
+	DryRun        bool                `json:"dryRun"`
+	Status        ImportJobStatus     `json:"status"`
+	TotalRows     int                 `json:"totalRows"`
+	ProcessedRows int                 `json:"processedRows"`
+	ValidRows     int                 `json:"validRows"`
+	InvalidRows   int                 `json:"invalidRows"`
+	CreatedRows   int                 `json:"createdRows"`
+	FailedRows    int                 `json:"failedRows"`
+	Progress      float64             `json:"progress"` // Share of rows processed, 0 to 1
+	Rows          []DeliveryImportRow `json:"rows"`
+	Error         *string             `json:"error,omitempty"`
+	CreatedAt     time.Time           `json:"createdAt"`
+	StartedAt     *time.Time          `json:"startedAt,omitempty"`
+	CompletedAt   *time.Time          `json:"completedAt,omitempty"`
+}
+
+// IsValid checks if the import format is valid
+func (f ImportFormat) IsValid() bool {
+	return f == ImportFormatCSV || f == ImportFormatJSONL
+}
+
+// DetectImportFormat guesses the format of an uploaded file from its name and content type
+func DetectImportFormat(filename, contentType string) ImportFormat {
+	filename = strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(filename, ".jsonl"), strings.HasSuffix(filename, ".ndjson"),
+		strings.Contains(contentType, "ndjson"), strings.Contains(contentType, "jsonl"):
+		return ImportFormatJSONL
+	default:
+		return ImportFormatCSV
+	}
+}
+
+// Validate checks the enumerations the validator tags cannot express
+func (r *CreateDeliveryRequest) Validate() error {
+	if !r.Type.IsValid() {
+		return fmt.Errorf("invalid delivery type: %s", r.Type)
+	}
+	if !r.VehicleType.IsValid() {
+		return fmt.Errorf("invalid vehicle type: %s", r.VehicleType)
+	}
+	if !r.PaymentMethod.IsValid() {
+		return fmt.Errorf("invalid payment method: %s", r.PaymentMethod)
+	}
+	return nil
+}
+
+// AddError marks the row as invalid with the given reason
+func (r *DeliveryImportRow) AddError(message string) {
+	r.Status = ImportRowStatusInvalid
+	r.Errors = append(r.Errors, message)
+}
+
+// ParseDeliveryImport reads the rows of an uploaded file. Rows that cannot be read are kept with
+// their error so the report covers the whole file; only an unreadable file is an error.
+func ParseDeliveryImport(format ImportFormat, r io.Reader, maxRows int) ([]DeliveryImportRow, error) {
+	var rows []DeliveryImportRow
+	var err error
+
+	switch format {
+	case ImportFormatCSV:
+		rows, err = parseImportCSV(r)
+	case ImportFormatJSONL:
+		rows, err = parseImportJSONL(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("file has no rows")
+	}
+	if maxRows > 0 && len(rows) > maxRows {
+		return nil, fmt.Errorf("file has %d rows, the limit is %d", len(rows), maxRows)
+	}
+
+	return rows, nil
+}
+
+// parseImportCSV reads a CSV file with a header line. Spreadsheets exported with a
+// semicolon separator and decimal commas are accepted.
+func parseImportCSV(r io.Reader) ([]DeliveryImportRow, error) {
+	buffered := bufio.NewReader(r)
+	headerLine, err := buffered.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+
+	reader := csv.NewReader(io.MultiReader(strings.NewReader(headerLine), buffered))
+	if strings.Count(headerLine, ";") > strings.Count(headerLine, ",") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !isImportCSVColumn(key) {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+		columns[key] = i
+	}
+	for _, required := range []string{"type", "vehicletype", "paymentmethod"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column: %s", required)
+		}
+	}
+
+	var rows []DeliveryImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			row := DeliveryImportRow{}
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				row.Line = parseErr.StartLine
+			}
+			row.AddError(err.Error())
+			rows = append(rows, row)
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := DeliveryImportRow{Line: line}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		row.Request = csvRecordToRequest(record, columns, &row)
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func csvRecordToRequest(record []string, columns map[string]int, row *DeliveryImportRow) *CreateDeliveryRequest {
+	value := func(column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	optionalString := func(column string) *string {
+		if v := value(column); v != "" {
+			return &v
+		}
+		return nil
+	}
+	optionalFloat := func(column string) *float64 {
+		v := value(column)
+		if v == "" {
+			return nil
+		}
+		parsed, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+		if err != nil {
+			row.AddError(fmt.Sprintf("%s: invalid number %q", column, v))
+			return nil
+		}
+		return &parsed
+	}
+	optionalContact := func(nameColumn, phoneColumn string) *ContactPerson {
+		name, phone := value(nameColumn), value(phoneColumn)
+		if name == "" && phone == "" {
+			return nil
+		}
+		return &ContactPerson{Name: name, Phone: phone}
+	}
+
+	return &CreateDeliveryRequest{
+		Type:             DeliveryType(strings.ToUpper(value("type"))),
+		VehicleType:      VehicleType(strings.ToUpper(value("vehicletype"))),
+		PaymentMethod:    PaymentMethod(strings.ToUpper(value("paymentmethod"))),
+		PickupAddress:    value("pickupaddress"),
+		PickupLat:        optionalFloat("pickuplat"),
+		PickupLng:        optionalFloat("pickuplng"),
+		PickupAddressID:  optionalString("pickupaddressid"),
+		DropoffAddress:   value("dropoffaddress"),
+		DropoffLat:       optionalFloat("dropofflat"),
+		DropoffLng:       optionalFloat("dropofflng"),
+		DropoffAddressID: optionalString("dropoffaddressid"),
+		SenderContact:    optionalContact("sendername", "senderphone"),
+		RecipientContact: optionalContact("recipientname", "recipientphone"),
+		CODAmount:        optionalFloat("codamount"),
+	}
+}
+
+// parseImportJSONL reads one CreateDeliveryRequest JSON object per line
+func parseImportJSONL(r io.Reader) ([]DeliveryImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []DeliveryImportRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		row := DeliveryImportRow{Line: line}
+		var req CreateDeliveryRequest
+		if err := json.Unmarshal([]byte(text), &req); err != nil {
+			row.AddError(fmt.Sprintf("invalid JSON: %v", err))
+		} else {
+			row.Request = &req
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	return rows, nil
+}
+
+func isImportCSVColumn(name string) bool {
+	for _, column := range importCSVColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// NewDeliveryImportJob creates a pending import of the given rows
+func NewDeliveryImportJob(id, clientID string, format ImportFormat, dryRun bool, totalRows int, now time.Time) *DeliveryImportJob {
+	return &DeliveryImportJob{
+		ID:        id,
+		ClientID:  clientID,
+		Format:    format,
+		DryRun:    dryRun,
+		Status:    ImportJobStatusPending,
+		TotalRows: totalRows,
+		Rows:      make([]DeliveryImportRow, 0, totalRows),
+		CreatedAt: now,
+	}
+}
+
+// Record adds the outcome of a processed row to the job
+func (j *DeliveryImportJob) Record(row DeliveryImportRow) {
+	switch row.Status {
+	case ImportRowStatusValid:
+		j.ValidRows++
+	case ImportRowStatusInvalid:
+		j.InvalidRows++
+	case ImportRowStatusCreated:
+		j.CreatedRows++
+	case ImportRowStatusFailed:
+		j.FailedRows++
+	}
+
+	j.Rows = append(j.Rows, row)
+	j.ProcessedRows++
+	if j.TotalRows > 0 {
+		j.Progress = float64(j.ProcessedRows) / float64(j.TotalRows)
+	}
+}
+
+// IsFinished checks if the job has stopped processing rows
+func (j *DeliveryImportJob) IsFinished() bool {
+	return j.Status == ImportJobStatusCompleted || j.Status == ImportJobStatusFailed
+}
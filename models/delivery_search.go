@@ -0,0 +1,184 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Page sizes of delivery lists
+const (
+	DefaultDeliveryPageSize = 20
+	MaxDeliveryPageSize     = 100
+)
+
+// DeliverySortField defines the fields delivery lists can be sorted on
+type DeliverySortField string
+
+const (
+	DeliverySortCreatedAt  DeliverySortField = "createdAt"
+	DeliverySortUpdatedAt  DeliverySortField = "updatedAt"
+	DeliverySortFinalPrice DeliverySortField = "finalPrice"
+)
+
+// SortOrder defines the sort direction
+type SortOrder string
+
+const (
+	SortOrderAsc  SortOrder = "asc"
+	SortOrderDesc SortOrder = "desc"
+)
+
+// DeliverySearchRequest represents the filters, sort and page of a delivery list.
+// Admins can use every filter, client and driver histories are scoped to their own deliveries.
+type DeliverySearchRequest struct {
+	Status        []DeliveryStatus  `form:"status"`
+	Type          DeliveryType      `form:"type"`
+	VehicleType   VehicleType       `form:"vehicleType"`
+	ClientID      string            `form:"clientId"`
+	DriverID      string            `form:"driverId"`
+	ZoneID        string            `form:"zoneId"` // Pickup or dropoff zone
+	PaymentMethod PaymentMethod     `form:"paymentMethod"`
+	From          time.Time         `form:"from" time_format:"2006-01-02"` // Created on or after
+	To            time.Time         `form:"to" time_format:"2006-01-02"`   // Created before, exclusive
+	MinPrice      *float64          `form:"minPrice" validate:"omitempty,gte=0"`
+	MaxPrice      *float64          `form:"maxPrice" validate:"omitempty,gte=0"`
+	Query         string            `form:"q" validate:"omitempty,min=2,max=100"` // Full-text search on addresses
+	Sort          DeliverySortField `form:"sort" validate:"omitempty,oneof=createdAt updatedAt finalPrice"`
+	Order         SortOrder         `form:"order" validate:"omitempty,oneof=asc desc"`
+	Limit         int               `form:"limit" validate:"omitempty,gte=1,lte=100"`
+	Cursor        string            `form:"cursor"`
+}
+
+// DeliveryCursor represents the position after the last delivery of a page.
+// The delivery ID breaks ties so pages stay stable when sort values repeat.
+type DeliveryCursor struct {
+	Sort  DeliverySortField `json:"s"`
+	Value json.RawMessage   `json:"v"`
+	ID    string            `json:"id"`
+}
+
+// DeliveryPage represents one page of a delivery list
+type DeliveryPage struct {
+	Deliveries []*DeliveryResponse `json:"deliveries"`
+	NextCursor *string             `json:"nextCursor,omitempty"`
+	HasMore    bool                `json:"hasMore"`
+}
+
+// IsValid checks if the sort field is one delivery lists can be sorted on
+func (f DeliverySortField) IsValid() bool {
+	return f == DeliverySortCreatedAt || f == DeliverySortUpdatedAt || f == DeliverySortFinalPrice
+}
+
+// IsValid checks if the sort order is valid
+func (o SortOrder) IsValid() bool {
+	return o == SortOrderAsc || o == SortOrderDesc
+}
+
+// Normalize fills the default sort, order and page size
+func (r *DeliverySearchRequest) Normalize() {
+	if r.Sort == "" {
+		r.Sort = DeliverySortCreatedAt
+	}
+	if r.Order == "" {
+		r.Order = SortOrderDesc
+	}
+	if r.Limit <= 0 {
+		r.Limit = DefaultDeliveryPageSize
+	}
+	if r.Limit > MaxDeliveryPageSize {
+		r.Limit = MaxDeliveryPageSize
+	}
+}
+
+// Validate checks the enumerations and ranges the validator tags cannot express
+func (r *DeliverySearchRequest) Validate() error {
+	for _, status := range r.Status {
+		if !status.IsValid() {
+			return fmt.Errorf("invalid status: %s", status)
+		}
+	}
+	if r.Type != "" && !r.Type.IsValid() {
+		return fmt.Errorf("invalid delivery type: %s", r.Type)
+	}
+	if r.VehicleType != "" && !r.VehicleType.IsValid() {
+		return fmt.Errorf("invalid vehicle type: %s", r.VehicleType)
+	}
+	if r.PaymentMethod != "" && !r.PaymentMethod.IsValid() {
+		return fmt.Errorf("invalid payment method: %s", r.PaymentMethod)
+	}
+	// The sort field and order are written into the query, only known values are accepted
+	if r.Sort != "" && !r.Sort.IsValid() {
+		return fmt.Errorf("invalid sort field: %s", r.Sort)
+	}
+	if r.Order != "" && !r.Order.IsValid() {
+		return fmt.Errorf("invalid sort order: %s", r.Order)
+	}
+	if r.MinPrice != nil && r.MaxPrice != nil && *r.MaxPrice < *r.MinPrice {
+		return fmt.Errorf("maxPrice must be greater than minPrice")
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.To.After(r.From) {
+		return fmt.Errorf("to must be after from")
+	}
+	return nil
+}
+
+// SortValue returns the value of the sort field for the delivery
+func (f DeliverySortField) SortValue(d *Delivery) interface{} {
+	switch f {
+	case DeliverySortUpdatedAt:
+		return d.UpdatedAt
+	case DeliverySortFinalPrice:
+		return d.FinalPrice
+	default:
+		return d.CreatedAt
+	}
+}
+
+// EncodeDeliveryCursor returns the opaque cursor pointing after the delivery
+func EncodeDeliveryCursor(sort DeliverySortField, d *Delivery) (string, error) {
+	value, err := json.Marshal(sort.SortValue(d))
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(DeliveryCursor{Sort: sort, Value: value, ID: d.ID})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeDeliveryCursor reads a cursor and returns its typed sort value and delivery ID.
+// A cursor is only valid with the sort it was created for.
+func DecodeDeliveryCursor(sort DeliverySortField, cursor string) (interface{}, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid cursor")
+	}
+
+	var decoded DeliveryCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID == "" {
+		return nil, "", fmt.Errorf("invalid cursor")
+	}
+	if decoded.Sort != sort {
+		return nil, "", fmt.Errorf("cursor was created for sort %s", decoded.Sort)
+	}
+
+	switch sort {
+	case DeliverySortFinalPrice:
+		var value float64
+		if err := json.Unmarshal(decoded.Value, &value); err != nil {
+			return nil, "", fmt.Errorf("invalid cursor")
+		}
+		return value, decoded.ID, nil
+	default:
+		var value time.Time
+		if err := json.Unmarshal(decoded.Value, &value); err != nil {
+			return nil, "", fmt.Errorf("invalid cursor")
+		}
+		return value, decoded.ID, nil
+	}
+}
@@ -0,0 +1,286 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DispatchNewDriverRating is the rating assumed for a driver who has not been rated yet
+const DispatchNewDriverRating = 4.0
+
+// DispatchWeights holds the weight of each factor of the driver score, they need not add up to 1
+type DispatchWeights struct {
+	ETA         float64 `json:"eta" validate:"gte=0"`         // Time to reach the pickup
+	Rating      float64 `json:"rating" validate:"gte=0"`      // Average client rating
+	Reliability float64 `json:"reliability" validate:"gte=0"` // Accepted jobs not cancelled afterwards
+	Idle        float64 `json:"idle" validate:"gte=0"`        // Time since the last job, for fairness
+	Vehicle     float64 `json:"vehicle" validate:"gte=0"`     // Vehicle fit for the delivery
+	Preference  float64 `json:"preference" validate:"gte=0"`  // Driver preferred zones and delivery types
+	Shift       float64 `json:"shift" validate:"gte=0"`       // Driver on a planned shift in the pickup zone
+}
+
+// DispatchFactors holds the value of each factor of a driver score, from 0 (worst) to 1 (best)
+type DispatchFactors struct {
+	ETA         float64 `json:"eta"`
+	Rating      float64 `json:"rating"`
+	Reliability float64 `json:"reliability"`
+	Idle        float64 `json:"idle"`
+	Vehicle     float64 `json:"vehicle"`
+	Preference  float64 `json:"preference"`
+	Shift       float64 `json:"shift"`
+}
+
+// DriverDispatchPreferences holds what a driver would rather be sent on
+type DriverDispatchPreferences struct {
+	PreferredZoneIDs       []string       `json:"preferredZoneIds,omitempty" validate:"omitempty,max=20"`
+	PreferredDeliveryTypes []DeliveryType `json:"preferredDeliveryTypes,omitempty" validate:"omitempty,dive,oneof=SIMPLE EXPRESS GROUPEE DEMENAGEMENT"`
+	MaxPickupDistanceKm    *float64       `json:"maxPickupDistanceKm,omitempty" validate:"omitempty,gt=0,lte=50"` // Farther jobs are never offered
+}
+
+// DispatchScoring holds the configurable driver scoring rules
+type DispatchScoring struct {
+	Weights    DispatchWeights `json:"weights"`
+	MaxEtaMin  float64         `json:"maxEtaMin"`  // ETA at which the ETA factor drops to 0
+	IdleCapMin float64         `json:"idleCapMin"` // Idle time at which the idle factor reaches 1
+}
+
+// DispatchCandidate represents a driver considered for a delivery
+type DispatchCandidate struct {
+	Driver     *User
+	Vehicle    *Vehicle
+	DistanceKm float64
+	OnShift    bool
+}
+
+// DriverScore represents the score of one driver for a delivery and how it was reached
+type DriverScore struct {
+	DriverID     string          `json:"driverId"`
+	DistanceKm   float64         `json:"distanceKm"`
+	EtaPickupMin float64         `json:"etaPickupMin"`
+	Factors      DispatchFactors `json:"factors"`
+	Score        float64         `json:"score"` // 0-100, weighted average of the factors
+	Excluded     bool            `json:"excluded,omitempty"`
+	Reason       string          `json:"reason,omitempty"` // Why the driver was excluded
+}
+
+// DispatchDecision records the drivers scored for a delivery and the one selected
+type DispatchDecision struct {
+	ID               string          `json:"id"`
+	DeliveryID       string          `json:"deliveryId"`
+	ZoneID           *string         `json:"zoneId,omitempty"` // Pickup zone whose weights were used
+	Weights          DispatchWeights `json:"weights"`
+	Candidates       []DriverScore   `json:"candidates"` // Best first, excluded drivers last
+	SelectedDriverID *string         `json:"selectedDriverId,omitempty"`
+	CreatedAt        time.Time       `json:"createdAt"`
+}
+
+// ParseDispatchWeights reads weights written as factor:weight, separated by commas.
+// Factors left out weigh 0.
+func ParseDispatchWeights(spec string) (DispatchWeights, error) {
+	var weights DispatchWeights
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		fields := strings.Split(part, ":")
+		if len(fields) != 2 {
+			return weights, fmt.Errorf("invalid dispatch weight %q, expected factor:weight", part)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil {
+			return weights, fmt.Errorf("invalid dispatch weight %q: %v", part, err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(fields[0])) {
+		case "eta":
+			weights.ETA = value
+		case "rating":
+			weights.Rating = value
+		case "reliability":
+			weights.Reliability = value
+		case "idle":
+			weights.Idle = value
+		case "vehicle":
+			weights.Vehicle = value
+		case "preference":
+			weights.Preference = value
+		case "shift":
+			weights.Shift = value
+		default:
+			return weights, fmt.Errorf("invalid dispatch weight %q: unknown factor", part)
+		}
+	}
+
+	return weights, weights.Validate()
+}
+
+// Validate checks the weights are not negative and not all zero
+func (w DispatchWeights) Validate() error {
+	values := []float64{w.ETA, w.Rating, w.Reliability, w.Idle, w.Vehicle, w.Preference, w.Shift}
+	for _, value := range values {
+		if value < 0 {
+			return fmt.Errorf("dispatch weights cannot be negative")
+		}
+	}
+	if w.total() == 0 {
+		return fmt.Errorf("at least one dispatch weight must be positive")
+	}
+	return nil
+}
+
+func (w DispatchWeights) total() float64 {
+	return w.ETA + w.Rating + w.Reliability + w.Idle + w.Vehicle + w.Preference + w.Shift
+}
+
+// Score rates a driver for a delivery. Drivers who cannot take the delivery are excluded with the reason.
+func (p *DispatchScoring) Score(d *Delivery, c DispatchCandidate, now time.Time) DriverScore {
+	score := DriverScore{
+		DriverID:   c.Driver.ID,
+		DistanceKm: math.Round(c.DistanceKm*1000) / 1000,
+	}
+
+	if c.Vehicle == nil {
+		return score.exclude("no vehicle on file")
+	}
+	if !c.Vehicle.IsCompatibleWithDeliveryType(d.Type) {
+		return score.exclude(fmt.Sprintf("%s cannot carry a %s delivery", c.Vehicle.Type, d.Type))
+	}
+	prefs := c.Driver.DispatchPreferences
+	if prefs != nil && prefs.MaxPickupDistanceKm != nil && c.DistanceKm > *prefs.MaxPickupDistanceKm {
+		return score.exclude(fmt.Sprintf("pickup beyond the driver maximum of %.1f km", *prefs.MaxPickupDistanceKm))
+	}
+
+	score.EtaPickupMin = math.Round(GetSpeedProfile(c.Vehicle.Type).TravelMinutes(c.DistanceKm, now)*10) / 10
+	score.Factors = DispatchFactors{
+		ETA:         clamp01(1 - score.EtaPickupMin/p.MaxEtaMin),
+		Rating:      ratingFactor(c.Driver),
+		Reliability: c.Driver.ComputeReliabilityScore() / 100,
+		Idle:        p.idleFactor(c.Driver, now),
+		Vehicle:     vehicleFactor(c.Vehicle),
+		Preference:  preferenceFactor(prefs, d),
+		Shift:       shiftFactor(c.OnShift),
+	}
+	score.Score = p.Weights.weigh(score.Factors)
+
+	return score
+}
+
+// weigh returns the weighted average of the factors on a 0-100 scale
+func (w DispatchWeights) weigh(f DispatchFactors) float64 {
+	total := w.total()
+	if total == 0 {
+		return 0
+	}
+
+	sum := w.ETA*f.ETA + w.Rating*f.Rating + w.Reliability*f.Reliability +
+		w.Idle*f.Idle + w.Vehicle*f.Vehicle + w.Preference*f.Preference + w.Shift*f.Shift
+	return math.Round(sum/total*1000) / 10
+}
+
+func (p *DispatchScoring) idleFactor(driver *User, now time.Time) float64 {
+	if driver.LastJobEndedAt == nil || p.IdleCapMin <= 0 {
+		return 1
+	}
+	return clamp01(now.Sub(*driver.LastJobEndedAt).Minutes() / p.IdleCapMin)
+}
+
+func ratingFactor(driver *User) float64 {
+	if driver.RatingAverage == nil || driver.RatingCount == 0 {
+		return DispatchNewDriverRating / 5
+	}
+	return clamp01(*driver.RatingAverage / 5)
+}
+
+// vehicleFactor favors vehicles with all their papers on file
+func vehicleFactor(vehicle *Vehicle) float64 {
+	if vehicle.HasRequiredDocuments() {
+		return 1
+	}
+	return 0.5
+}
+
+// preferenceFactor returns the share of the driver preferences the delivery meets, 0.5 without preferences
+func preferenceFactor(prefs *DriverDispatchPreferences, d *Delivery) float64 {
+	if prefs == nil {
+		return 0.5
+	}
+
+	var set, met float64
+	if len(prefs.PreferredZoneIDs) > 0 {
+		set++
+		for _, zoneID := range prefs.PreferredZoneIDs {
+			if d.PickupZoneID != nil && *d.PickupZoneID == zoneID {
+				met++
+				break
+			}
+		}
+	}
+	if len(prefs.PreferredDeliveryTypes) > 0 {
+		set++
+		for _, deliveryType := range prefs.PreferredDeliveryTypes {
+			if deliveryType == d.Type {
+				met++
+				break
+			}
+		}
+	}
+
+	if set == 0 {
+		return 0.5
+	}
+	return met / set
+}
+
+// shiftFactor favors drivers on a planned shift over those who came online unplanned
+func shiftFactor(onShift bool) float64 {
+	if onShift {
+		return 1
+	}
+	return 0
+}
+
+// RankDriverScores sorts the scores best first, the closest driver winning a tie, excluded drivers last
+func RankDriverScores(scores []DriverScore) {
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Excluded != scores[j].Excluded {
+			return !scores[i].Excluded
+		}
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].DistanceKm < scores[j].DistanceKm
+	})
+}
+
+// Explain returns a one-line summary of the score, for logs
+func (s DriverScore) Explain() string {
+	if s.Excluded {
+		return fmt.Sprintf("driver %s excluded: %s", s.DriverID, s.Reason)
+	}
+	return fmt.Sprintf("driver %s scored %.1f (%.2f km, ETA %.0f min; eta %.2f, rating %.2f, reliability %.2f, idle %.2f, vehicle %.2f, preference %.2f, shift %.0f)",
+		s.DriverID, s.Score, s.DistanceKm, s.EtaPickupMin,
+		s.Factors.ETA, s.Factors.Rating, s.Factors.Reliability, s.Factors.Idle, s.Factors.Vehicle, s.Factors.Preference, s.Factors.Shift)
+}
+
+// ExcludeDriver returns the score of a driver left out before scoring
+func ExcludeDriver(driverID string, distanceKm float64, reason string) DriverScore {
+	score := DriverScore{DriverID: driverID, DistanceKm: math.Round(distanceKm*1000) / 1000}
+	return score.exclude(reason)
+}
+
+func (s DriverScore) exclude(reason string) DriverScore {
+	s.Excluded = true
+	s.Reason = reason
+	return s
+}
+
+func clamp01(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// IndexedDriver represents the last known position of a driver in the in-memory spatial index
+type IndexedDriver struct {
+	DriverID    string      `json:"driverId"`
+	Lat         float64     `json:"lat"`
+	Lng         float64     `json:"lng"`
+	VehicleType VehicleType `json:"vehicleType"`
+	IsAvailable bool        `json:"isAvailable"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+// DriverFilter restricts a spatial index query, zero values do not filter
+type DriverFilter struct {
+	VehicleType   VehicleType
+	AvailableOnly bool
+	UpdatedSince  time.Time // Leaves out positions older than this
+}
+
+// DriverMatch represents a driver found by a spatial index query
+type DriverMatch struct {
+	IndexedDriver
+	DistanceKm float64 `json:"distanceKm"` // Straight-line distance to the queried point
+}
+
+// Matches checks if the driver passes the filter
+func (f DriverFilter) Matches(d *IndexedDriver) bool {
+	if f.VehicleType != "" && d.VehicleType != f.VehicleType {
+		return false
+	}
+	if f.AvailableOnly && !d.IsAvailable {
+		return false
+	}
+	if !f.UpdatedSince.IsZero() && d.UpdatedAt.Before(f.UpdatedSince) {
+		return false
+	}
+	return true
+}
+
+// Indexed returns the spatial index entry of a driver location, which must have coordinates
+func (l *DriverLocation) Indexed() IndexedDriver {
+	return IndexedDriver{
+		DriverID:    l.DriverID,
+		Lat:         *l.Lat,
+		Lng:         *l.Lng,
+		VehicleType: l.VehicleType,
+		IsAvailable: l.IsAvailable,
+		UpdatedAt:   l.Timestamp,
+	}
+}
@@ -0,0 +1,266 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// EarningEntryType defines the driver earnings ledger entry enumeration
+type EarningEntryType string
+
+const (
+	EarningEntryFare       EarningEntryType = "FARE"        // Price of a completed delivery, before deductions
+	EarningEntryCommission EarningEntryType = "COMMISSION"  // Platform share of the fare
+	EarningEntryServiceFee EarningEntryType = "SERVICE_FEE" // Fixed platform fee per delivery
+	EarningEntryTip        EarningEntryType = "TIP"         // Paid to the driver in full
+	EarningEntryBonus      EarningEntryType = "BONUS"       // Granted by an admin
+	EarningEntryPenalty    EarningEntryType = "PENALTY"     // Applied by an admin
+)
+
+// EarningsPeriod defines how earnings are grouped in a statement
+type EarningsPeriod string
+
+const (
+	EarningsPeriodDaily  EarningsPeriod = "DAILY"
+	EarningsPeriodWeekly EarningsPeriod = "WEEKLY" // Weeks start on Monday
+)
+
+// EarningEntry represents a movement of what a driver earned. Entries are never updated:
+// corrections are new entries.
+type EarningEntry struct {
+	ID           string           `json:"id"`
+	DriverID     string           `json:"driverId"`
+	DeliveryID   *string          `json:"deliveryId,omitempty"`
+	Type         EarningEntryType `json:"type"`
+	Amount       float64          `json:"amount"` // Always positive, the type gives the direction
+	Reason       *string          `json:"reason,omitempty"`
+	RecordedByID *string          `json:"recordedById,omitempty"` // Admin who granted a bonus or applied a penalty
+	CreatedAt    time.Time        `json:"createdAt"`
+}
+
+// EarningTotals represents the earnings ledger totals over a period
+type EarningTotals struct {
+	DeliveryCount int     `json:"deliveryCount"`
+	Gross         float64 `json:"gross"`
+	Commission    float64 `json:"commission"`
+	ServiceFees   float64 `json:"serviceFees"`
+	Tips          float64 `json:"tips"`
+	Bonuses       float64 `json:"bonuses"`
+	Penalties     float64 `json:"penalties"`
+	Net           float64 `json:"net"` // What the driver takes home
+}
+
+// EarningsBucket represents the earnings of one day or week
+type EarningsBucket struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"` // Exclusive
+	EarningTotals
+}
+
+// DriverEarningsStatement represents a driver's ledger earnings day by day or week by week
+type DriverEarningsStatement struct {
+	DriverID string           `json:"driverId"`
+	Period   EarningsPeriod   `json:"period"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Totals   EarningTotals    `json:"totals"`
+	Buckets  []EarningsBucket `json:"buckets"`
+}
+
+// PlatformRevenue represents what the platform kept from the drivers' fares over a period
+type PlatformRevenue struct {
+	From    time.Time     `json:"from"`
+	To      time.Time     `json:"to"`
+	Revenue float64       `json:"revenue"` // Commission plus service fees
+	Totals  EarningTotals `json:"totals"`
+}
+
+// CreateEarningAdjustmentRequest represents an admin granting a bonus or applying a penalty to a driver
+type CreateEarningAdjustmentRequest struct {
+	Type       EarningEntryType `json:"type" validate:"required,oneof=BONUS PENALTY"`
+	Amount     float64          `json:"amount" validate:"gt=0"`
+	DeliveryID *string          `json:"deliveryId,omitempty"`
+	Reason     string           `json:"reason" validate:"required,min=3,max=500"`
+}
+
+// ApplyServiceFee deducts the platform service fee from the earning, never more than what is left
+// after commission
+func (e *DeliveryEarning) ApplyServiceFee(serviceFee float64) {
+	e.ServiceFee = math.Max(0, math.Min(serviceFee, e.Gross-e.Commission))
+	e.Net = e.Gross - e.Commission - e.ServiceFee
+}
+
+// LedgerEntries returns the earnings ledger entries of a completed delivery. The fare is always
+// recorded so the delivery is only credited once.
+func (e *DeliveryEarning) LedgerEntries(driverID string, now time.Time) []EarningEntry {
+	deliveryID := e.DeliveryID
+	entries := []EarningEntry{
+		{DriverID: driverID, DeliveryID: &deliveryID, Type: EarningEntryFare, Amount: e.Gross, CreatedAt: now},
+	}
+
+	parts := []struct {
+		entryType EarningEntryType
+		amount    float64
+	}{
+		{EarningEntryCommission, e.Commission},
+		{EarningEntryServiceFee, e.ServiceFee},
+		{EarningEntryTip, e.Tips},
+	}
+	for _, part := range parts {
+		if part.amount > 0 {
+			entries = append(entries, EarningEntry{DriverID: driverID, DeliveryID: &deliveryID, Type: part.entryType, Amount: part.amount, CreatedAt: now})
+		}
+	}
+
+	return entries
+}
+
+// Add counts an entry in the totals
+func (t *EarningTotals) Add(entryType EarningEntryType, amount float64, count int) {
+	switch entryType {
+	case EarningEntryFare:
+		t.Gross += amount
+		t.DeliveryCount += count
+	case EarningEntryCommission:
+		t.Commission += amount
+	case EarningEntryServiceFee:
+		t.ServiceFees += amount
+	case EarningEntryTip:
+		t.Tips += amount
+	case EarningEntryBonus:
+		t.Bonuses += amount
+	case EarningEntryPenalty:
+		t.Penalties += amount
+	}
+	t.Net = t.Gross - t.Commission - t.ServiceFees + t.Tips + t.Bonuses - t.Penalties
+}
+
+// IsValid checks if the earnings period is valid
+func (p EarningsPeriod) IsValid() bool {
+	return p == EarningsPeriodDaily || p == EarningsPeriodWeekly
+}
+
+// Start returns the start of the day or week the time falls in, in its location
+func (p EarningsPeriod) Start(t time.Time) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if p == EarningsPeriodWeekly {
+		// Monday is the first day of the week
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	}
+	return start
+}
+
+// Next returns the start of the following day or week
+func (p EarningsPeriod) Next(start time.Time) time.Time {
+	if p == EarningsPeriodWeekly {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// NewEarningsStatement groups the ledger entries of a driver by day or week. Every day or week of the
+// period is listed, with no earnings when nothing was recorded.
+func NewEarningsStatement(driverID string, period EarningsPeriod, from, to time.Time, entries []EarningEntry) (*DriverEarningsStatement, error) {
+	if !period.IsValid() {
+		return nil, fmt.Errorf("invalid earnings period: %s", period)
+	}
+
+	statement := &DriverEarningsStatement{
+		DriverID: driverID,
+		Period:   period,
+		From:     period.Start(from),
+		To:       to,
+		Buckets:  []EarningsBucket{},
+	}
+
+	for start := statement.From; start.Before(to); start = period.Next(start) {
+		statement.Buckets = append(statement.Buckets, EarningsBucket{Start: start, End: period.Next(start)})
+	}
+	if len(statement.Buckets) > 0 {
+		statement.To = statement.Buckets[len(statement.Buckets)-1].End
+	}
+
+	for _, entry := range entries {
+		for i := range statement.Buckets {
+			bucket := &statement.Buckets[i]
+			if !entry.CreatedAt.Before(bucket.Start) && entry.CreatedAt.Before(bucket.End) {
+				bucket.Add(entry.Type, entry.Amount, 1)
+				statement.Totals.Add(entry.Type, entry.Amount, 1)
+				break
+			}
+		}
+	}
+
+	return statement, nil
+}
+
+// NewLedgerEarnings totals the ledger entries of a driver over a period and splits them by delivery,
+// in the order the deliveries were credited
+func NewLedgerEarnings(driverID string, from, to time.Time, entries []EarningEntry) *DriverEarnings {
+	var totals EarningTotals
+	byDelivery := make(map[string]*DeliveryEarning)
+	var order []string
+
+	for _, entry := range entries {
+		totals.Add(entry.Type, entry.Amount, 1)
+
+		// Bonuses and penalties are not part of the split of a delivery
+		if entry.DeliveryID == nil || entry.Type == EarningEntryBonus || entry.Type == EarningEntryPenalty {
+			continue
+		}
+
+		earning, ok := byDelivery[*entry.DeliveryID]
+		if !ok {
+			earning = &DeliveryEarning{DeliveryID: *entry.DeliveryID}
+			byDelivery[*entry.DeliveryID] = earning
+			order = append(order, *entry.DeliveryID)
+		}
+
+		switch entry.Type {
+		case EarningEntryFare:
+			creditedAt := entry.CreatedAt
+			earning.DeliveredAt = &creditedAt
+			earning.Gross += entry.Amount
+		case EarningEntryCommission:
+			earning.Commission += entry.Amount
+		case EarningEntryServiceFee:
+			earning.ServiceFee += entry.Amount
+		case EarningEntryTip:
+			earning.Tips += entry.Amount
+		}
+		earning.Net = earning.Gross - earning.Commission - earning.ServiceFee
+	}
+
+	summary := &DriverEarnings{
+		DriverID:      driverID,
+		From:          from,
+		To:            to,
+		DeliveryCount: totals.DeliveryCount,
+		Gross:         totals.Gross,
+		Commission:    totals.Commission,
+		ServiceFees:   totals.ServiceFees,
+		Net:           totals.Gross - totals.Commission - totals.ServiceFees,
+		Tips:          totals.Tips,
+		Bonuses:       totals.Bonuses,
+		Penalties:     totals.Penalties,
+		Total:         totals.Net,
+		Deliveries:    make([]DeliveryEarning, 0, len(order)),
+	}
+	for _, deliveryID := range order {
+		summary.Deliveries = append(summary.Deliveries, *byDelivery[deliveryID])
+	}
+
+	return summary
+}
+
+// NewPlatformRevenue builds the platform revenue from the ledger totals and counts per entry type
+func NewPlatformRevenue(from, to time.Time, totals map[EarningEntryType]float64, counts map[EarningEntryType]int) *PlatformRevenue {
+	revenue := &PlatformRevenue{From: from, To: to}
+	for entryType, amount := range totals {
+		revenue.Totals.Add(entryType, amount, counts[entryType])
+	}
+	revenue.Revenue = revenue.Totals.Commission + revenue.Totals.ServiceFees
+
+	return revenue
+}
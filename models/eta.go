@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// SpeedProfile represents average urban speeds for a vehicle type
+type SpeedProfile struct {
+	VehicleType    VehicleType `json:"vehicleType"`
+	BaseSpeedKmh   float64     `json:"baseSpeedKmh"`
+	RushHourFactor float64     `json:"rushHourFactor"` // Multiplier applied to speed during rush hours
+	NightFactor    float64     `json:"nightFactor"`    // Multiplier applied to speed at night
+}
+
+// DefaultSpeedProfiles holds the speed profiles observed in Abidjan traffic
+var DefaultSpeedProfiles = map[VehicleType]SpeedProfile{
+	VehicleTypeMoto:        {VehicleType: VehicleTypeMoto, BaseSpeedKmh: 28, RushHourFactor: 0.75, NightFactor: 1.2},
+	VehicleTypeVoiture:     {VehicleType: VehicleTypeVoiture, BaseSpeedKmh: 24, RushHourFactor: 0.5, NightFactor: 1.3},
+	VehicleTypeCamionnette: {VehicleType: VehicleTypeCamionnette, BaseSpeedKmh: 20, RushHourFactor: 0.5, NightFactor: 1.25},
+}
+
+// RoadDistanceFactor converts straight-line distance to an approximate road distance
+const RoadDistanceFactor = 1.3
+
+// StopHandlingMin is the time spent at pickup before heading to dropoff
+const StopHandlingMin = 5.0
+
+// DeliveryETA represents the live ETA of a delivery
+type DeliveryETA struct {
+	DeliveryID string     `json:"deliveryId"`
+	DriverLat  float64    `json:"driverLat"`
+	DriverLng  float64    `json:"driverLng"`
+	PickupETA  *time.Time `json:"pickupEta,omitempty"`
+	PickupMin  *float64   `json:"pickupMin,omitempty"`
+	DropoffETA time.Time  `json:"dropoffEta"`
+	DropoffMin float64    `json:"dropoffMin"`
+	SlippedMin float64    `json:"slippedMin"` // Delay compared to the first ETA given to the client
+	ComputedAt time.Time  `json:"computedAt"`
+}
+
+// GetSpeedProfile returns the speed profile for a vehicle type
+func GetSpeedProfile(vehicleType VehicleType) SpeedProfile {
+	if profile, ok := DefaultSpeedProfiles[vehicleType]; ok {
+		return profile
+	}
+	return DefaultSpeedProfiles[VehicleTypeMoto]
+}
+
+// SpeedAt returns the expected speed at a given local time
+func (p SpeedProfile) SpeedAt(at time.Time) float64 {
+	hour := at.Hour()
+	weekday := at.Weekday()
+
+	switch {
+	case hour >= 22 || hour < 5:
+		return p.BaseSpeedKmh * p.NightFactor
+	case weekday != time.Saturday && weekday != time.Sunday &&
+		((hour >= 7 && hour < 10) || (hour >= 17 && hour < 20)):
+		return p.BaseSpeedKmh * p.RushHourFactor
+	default:
+		return p.BaseSpeedKmh
+	}
+}
+
+// TravelMinutes estimates travel time for a straight-line distance at a given local time
+func (p SpeedProfile) TravelMinutes(straightKm float64, at time.Time) float64 {
+	speed := p.SpeedAt(at)
+	if speed <= 0 {
+		return 0
+	}
+	return straightKm * RoadDistanceFactor / speed * 60
+}
+
+// IsBeforePickup checks if the driver still has to reach the pickup
+func (s DeliveryStatus) IsBeforePickup() bool {
+	return s == DeliveryStatusAccepted || s.IsDriverEnRoute()
+}
+
+// ShouldNotifyETA checks the client can be sent the ETA again, at most once per interval
+func (d *Delivery) ShouldNotifyETA(interval time.Duration, now time.Time) bool {
+	if interval <= 0 {
+		return false
+	}
+	return d.EtaNotifiedAt == nil || now.Sub(*d.EtaNotifiedAt) >= interval
+}
+
+// ETAUpdateMessage returns the SMS telling the client when the driver reaches the next stop
+func ETAUpdateMessage(eta *DeliveryETA) string {
+	if eta.PickupETA != nil && eta.PickupMin != nil {
+		return fmt.Sprintf("ILEX : votre livreur arrive au point de retrait vers %s (dans %.0f min).",
+			eta.PickupETA.Format("15h04"), *eta.PickupMin)
+	}
+	return fmt.Sprintf("ILEX : votre colis arrive vers %s (dans %.0f min).", eta.DropoffETA.Format("15h04"), eta.DropoffMin)
+}
+
+// DeliveryTracking represents the live tracking view of a delivery
+type DeliveryTracking struct {
+	DeliveryID     string         `json:"deliveryId"`
+	Status         DeliveryStatus `json:"status"`
+	DriverLat      *float64       `json:"driverLat,omitempty"`
+	DriverLng      *float64       `json:"driverLng,omitempty"`
+	LocationUpdate *time.Time     `json:"locationUpdatedAt,omitempty"`
+	ETA            *DeliveryETA   `json:"eta,omitempty"`
+}
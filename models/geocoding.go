@@ -0,0 +1,297 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Geocoding provider names
+const (
+	GeocoderGazetteer = "gazetteer"
+	GeocoderClient    = "client" // Pin dropped by the client
+)
+
+// Radius used for reverse geocoding when no gazetteer entry contains the point
+const reverseGeocodeMaxKm = 2.0
+
+// GazetteerKind represents the kind of place in the gazetteer
+type GazetteerKind string
+
+const (
+	GazetteerKindCommune       GazetteerKind = "COMMUNE"
+	GazetteerKindNeighbourhood GazetteerKind = "NEIGHBOURHOOD"
+	GazetteerKindStreet        GazetteerKind = "STREET"
+	GazetteerKindLandmark      GazetteerKind = "LANDMARK"
+)
+
+// Weight returns how precise a match on this kind of place is, between 0 and 1
+func (k GazetteerKind) Weight() float64 {
+	switch k {
+	case GazetteerKindLandmark:
+		return 0.95
+	case GazetteerKindStreet:
+		return 0.85
+	case GazetteerKindNeighbourhood:
+		return 0.75
+	default:
+		return 0.4
+	}
+}
+
+// GazetteerEntry represents a known neighbourhood or landmark curated by admins
+type GazetteerEntry struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name" validate:"required"`
+	Aliases   []string      `json:"aliases,omitempty"`
+	Kind      GazetteerKind `json:"kind" validate:"required"`
+	City      string        `json:"city"`
+	Lat       float64       `json:"lat"`
+	Lng       float64       `json:"lng"`
+	RadiusM   float64       `json:"radiusM"` // Approximate extent of the place
+	IsActive  bool          `json:"isActive"`
+	CreatedBy string        `json:"createdBy"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+// CreateGazetteerEntryRequest represents request for adding a place to the gazetteer
+type CreateGazetteerEntryRequest struct {
+	Name    string        `json:"name" validate:"required,min=2,max=100"`
+	Aliases []string      `json:"aliases,omitempty" validate:"omitempty,dive,min=2,max=100"`
+	Kind    GazetteerKind `json:"kind" validate:"required,oneof=COMMUNE NEIGHBOURHOOD STREET LANDMARK"`
+	City    string        `json:"city" validate:"omitempty,max=100"`
+	Lat     float64       `json:"lat" validate:"gte=-90,lte=90"`
+	Lng     float64       `json:"lng" validate:"gte=-180,lte=180"`
+	RadiusM float64       `json:"radiusM" validate:"gt=0,lte=20000"`
+}
+
+// UpdateGazetteerEntryRequest represents request for updating a gazetteer entry
+type UpdateGazetteerEntryRequest struct {
+	Name     *string        `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	Aliases  []string       `json:"aliases,omitempty" validate:"omitempty,dive,min=2,max=100"`
+	Kind     *GazetteerKind `json:"kind,omitempty" validate:"omitempty,oneof=COMMUNE NEIGHBOURHOOD STREET LANDMARK"`
+	City     *string        `json:"city,omitempty" validate:"omitempty,max=100"`
+	Lat      *float64       `json:"lat,omitempty" validate:"omitempty,gte=-90,lte=90"`
+	Lng      *float64       `json:"lng,omitempty" validate:"omitempty,gte=-180,lte=180"`
+	RadiusM  *float64       `json:"radiusM,omitempty" validate:"omitempty,gt=0,lte=20000"`
+	IsActive *bool          `json:"isActive,omitempty"`
+}
+
+// GeocodeRequest represents query parameters for forward geocoding
+type GeocodeRequest struct {
+	Address string `form:"address" validate:"required,min=2,max=300"`
+}
+
+// ReverseGeocodeRequest represents query parameters for reverse geocoding
+type ReverseGeocodeRequest struct {
+	Lat *float64 `form:"lat" validate:"required,gte=-90,lte=90"`
+	Lng *float64 `form:"lng" validate:"required,gte=-180,lte=180"`
+}
+
+// GazetteerMatch represents a gazetteer entry matched by an address
+type GazetteerMatch struct {
+	EntryID string        `json:"entryId"`
+	Name    string        `json:"name"`
+	Kind    GazetteerKind `json:"kind"`
+	Lat     float64       `json:"lat"`
+	Lng     float64       `json:"lng"`
+	Score   float64       `json:"score"`
+}
+
+// GeocodeResult represents the coordinates found for a free-text address
+type GeocodeResult struct {
+	Query      string           `json:"query"`
+	Lat        *float64         `json:"lat,omitempty"`
+	Lng        *float64         `json:"lng,omitempty"`
+	Confidence float64          `json:"confidence"` // Between 0 and 1
+	NeedsPin   bool             `json:"needsPin"`   // The client should drop a pin on the map
+	Provider   string           `json:"provider"`
+	Match      *GazetteerMatch  `json:"match,omitempty"`
+	Candidates []GazetteerMatch `json:"candidates,omitempty"`
+}
+
+// ReverseGeocodeResult represents the place found for coordinates
+type ReverseGeocodeResult struct {
+	Lat        float64         `json:"lat"`
+	Lng        float64         `json:"lng"`
+	Address    string          `json:"address,omitempty"`
+	DistanceM  float64         `json:"distanceM"`
+	Confidence float64         `json:"confidence"`
+	Provider   string          `json:"provider"`
+	Match      *GazetteerMatch `json:"match,omitempty"`
+}
+
+// addressReplacer removes French accents so "Angré" matches "angre"
+var addressReplacer = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "ô", "o", "ö", "o", "ù", "u", "û", "u", "ü", "u", "ç", "c",
+)
+
+// NormalizeAddress lowercases an address, removes accents and punctuation
+func NormalizeAddress(address string) string {
+	address = addressReplacer.Replace(strings.ToLower(address))
+	fields := strings.FieldsFunc(address, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// nameCoverage returns the share of the name tokens found in the query, 1 when the name appears as is
+func nameCoverage(query string, queryTokens map[string]bool, name string) float64 {
+	normalized := NormalizeAddress(name)
+	if normalized == "" {
+		return 0
+	}
+	if strings.Contains(" "+query+" ", " "+normalized+" ") {
+		return 1
+	}
+
+	tokens := strings.Fields(normalized)
+	found := 0
+	for _, token := range tokens {
+		if queryTokens[token] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(tokens))
+}
+
+// MatchGazetteer scores every active entry against an address, best first
+func MatchGazetteer(entries []GazetteerEntry, address string) []GazetteerMatch {
+	query := NormalizeAddress(address)
+	queryTokens := make(map[string]bool)
+	for _, token := range strings.Fields(query) {
+		queryTokens[token] = true
+	}
+
+	var matches []GazetteerMatch
+	for _, entry := range entries {
+		if !entry.IsActive {
+			continue
+		}
+
+		coverage := nameCoverage(query, queryTokens, entry.Name)
+		for _, alias := range entry.Aliases {
+			if aliasCoverage := nameCoverage(query, queryTokens, alias); aliasCoverage > coverage {
+				coverage = aliasCoverage
+			}
+		}
+		// Partial matches on half the words or less are noise
+		if coverage <= 0.5 {
+			continue
+		}
+
+		matches = append(matches, GazetteerMatch{
+			EntryID: entry.ID,
+			Name:    entry.Name,
+			Kind:    entry.Kind,
+			Lat:     entry.Lat,
+			Lng:     entry.Lng,
+			Score:   coverage * entry.Kind.Weight(),
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// Geocode picks the best gazetteer match for an address. Confidence is raised when other matches
+// contain the best one (a landmark inside the named neighbourhood) and lowered when an equally good
+// match is elsewhere.
+func Geocode(entries []GazetteerEntry, address string, minConfidence float64) *GeocodeResult {
+	result := &GeocodeResult{Query: address, Provider: GeocoderGazetteer}
+
+	matches := MatchGazetteer(entries, address)
+	if len(matches) == 0 {
+		result.NeedsPin = true
+		return result
+	}
+
+	radii := make(map[string]float64, len(entries))
+	for _, entry := range entries {
+		radii[entry.ID] = entry.RadiusM
+	}
+
+	best := matches[0]
+	bestPoint := Coordinates{Lat: best.Lat, Lng: best.Lng}
+	confidence := best.Score
+	for _, other := range matches[1:] {
+		distanceM := bestPoint.DistanceKm(Coordinates{Lat: other.Lat, Lng: other.Lng}) * 1000
+		switch {
+		case distanceM <= radii[other.EntryID]:
+			confidence += 0.1
+		case other.Score == best.Score && distanceM > radii[best.EntryID]+radii[other.EntryID]:
+			confidence /= 2
+		}
+	}
+	if confidence > 1 {
+		confidence = 1
+	}
+
+	result.Match = &best
+	result.Lat = &best.Lat
+	result.Lng = &best.Lng
+	result.Confidence = confidence
+	result.NeedsPin = confidence < minConfidence
+	if len(matches) > 5 {
+		matches = matches[:5]
+	}
+	result.Candidates = matches
+
+	return result
+}
+
+// ReverseGeocode finds the most specific gazetteer entry containing the point, or the nearest one
+func ReverseGeocode(entries []GazetteerEntry, lat, lng float64) *ReverseGeocodeResult {
+	result := &ReverseGeocodeResult{Lat: lat, Lng: lng, Provider: GeocoderGazetteer}
+	point := Coordinates{Lat: lat, Lng: lng}
+
+	var best *GazetteerEntry
+	bestDistanceM := 0.0
+	containing := false
+	for i := range entries {
+		entry := &entries[i]
+		if !entry.IsActive {
+			continue
+		}
+
+		distanceM := point.DistanceKm(Coordinates{Lat: entry.Lat, Lng: entry.Lng}) * 1000
+		inside := distanceM <= entry.RadiusM
+		switch {
+		case inside && (!containing || entry.RadiusM < best.RadiusM):
+			best, bestDistanceM, containing = entry, distanceM, true
+		case !inside && !containing && distanceM <= reverseGeocodeMaxKm*1000 && (best == nil || distanceM < bestDistanceM):
+			best, bestDistanceM = entry, distanceM
+		}
+	}
+
+	if best == nil {
+		return result
+	}
+
+	result.Address = best.Name
+	if best.City != "" {
+		result.Address += ", " + best.City
+	}
+	result.DistanceM = bestDistanceM
+	result.Match = &GazetteerMatch{EntryID: best.ID, Name: best.Name, Kind: best.Kind, Lat: best.Lat, Lng: best.Lng}
+	if containing {
+		result.Confidence = best.Kind.Weight() * (1 - bestDistanceM/best.RadiusM/2)
+	} else {
+		result.Confidence = 0.3 * (1 - bestDistanceM/(reverseGeocodeMaxKm*1000))
+	}
+	result.Match.Score = result.Confidence
+
+	return result
+}
+
+// QuoteGeocoding reports how the addresses of a quote were located
+type QuoteGeocoding struct {
+	Pickup   *GeocodeResult `json:"pickup,omitempty"`
+	Dropoff  *GeocodeResult `json:"dropoff,omitempty"`
+	NeedsPin bool           `json:"needsPin"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+// GeofenceConfig holds the geofence radii and anti-jitter thresholds
+type GeofenceConfig struct {
+	PickupRadiusM  float64       `json:"pickupRadiusM"`
+	DropoffRadiusM float64       `json:"dropoffRadiusM"`
+	Dwell          time.Duration `json:"dwell"`      // Time the driver must stay inside before arrival is emitted
+	UndoWindow     time.Duration `json:"undoWindow"` // Time the driver has to undo an automatic arrival
+}
+
+// GeofenceState tracks the driver position relative to the next stop of a delivery
+type GeofenceState struct {
+	Stop           WaitingStop     `json:"stop,omitempty"`
+	EnteredAt      *time.Time      `json:"enteredAt,omitempty"`
+	Disarmed       bool            `json:"disarmed"` // Set after an undo until the driver leaves the fence
+	AutoArrivalAt  *time.Time      `json:"autoArrivalAt,omitempty"`
+	PreviousStatus *DeliveryStatus `json:"previousStatus,omitempty"`
+}
+
+// UndoAutoArrivalRequest represents request for undoing an automatic arrival
+type UndoAutoArrivalRequest struct {
+	Note *string `json:"note,omitempty" validate:"omitempty,max=200"`
+}
+
+// RadiusFor returns the fence radius in meters for a stop
+func (g *GeofenceConfig) RadiusFor(stop WaitingStop) float64 {
+	if stop == WaitingStopDropoff {
+		return g.DropoffRadiusM
+	}
+	return g.PickupRadiusM
+}
+
+// Evaluate updates the geofence state with a new distance to the stop and reports
+// whether the driver has dwelled inside long enough to be considered arrived
+func (g *GeofenceConfig) Evaluate(state GeofenceState, stop WaitingStop, distanceM float64, now time.Time) (GeofenceState, bool) {
+	if state.Stop != stop {
+		state = GeofenceState{Stop: stop}
+	}
+
+	// Outside: reset the dwell timer and re-arm after an undo
+	if distanceM > g.RadiusFor(stop) {
+		state.EnteredAt = nil
+		state.Disarmed = false
+		return state, false
+	}
+
+	if state.Disarmed {
+		return state, false
+	}
+
+	if state.EnteredAt == nil {
+		state.EnteredAt = &now
+	}
+
+	return state, now.Sub(*state.EnteredAt) >= g.Dwell
+}
+
+// CanUndoAutoArrival checks if an automatic arrival can still be undone
+func (g *GeofenceConfig) CanUndoAutoArrival(state *GeofenceState, now time.Time) bool {
+	return state != nil && state.AutoArrivalAt != nil && state.PreviousStatus != nil &&
+		now.Sub(*state.AutoArrivalAt) <= g.UndoWindow
+}
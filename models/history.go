@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// DeliveryEvent represents an entry in the delivery status history
+type DeliveryEvent struct {
+	ID            string         `json:"id"`
+	DeliveryID    string         `json:"deliveryId"`
+	FromStatus    DeliveryStatus `json:"fromStatus"`
+	ToStatus      DeliveryStatus `json:"toStatus"`
+	ActorID       *string        `json:"actorId,omitempty"`
+	ActorRole     *UserRole      `json:"actorRole,omitempty"`
+	AutoGenerated bool           `json:"autoGenerated"` // Emitted by the system rather than a user action
+	Note          *string        `json:"note,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+}
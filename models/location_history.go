@@ -0,0 +1,142 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// MaxPlausibleSpeedKmh is the speed between two points above which the second one is taken as a GPS jump
+	MaxPlausibleSpeedKmh = 150.0
+
+	// MaxDriverTrackRange is the longest time range of a driver track request
+	MaxDriverTrackRange = 24 * time.Hour
+)
+
+// LocationPoint represents one position of the driver location history
+type LocationPoint struct {
+	ID         string    `json:"id"`
+	DriverID   string    `json:"driverId"`
+	DeliveryID *string   `json:"deliveryId,omitempty"` // Delivery the driver was working on
+	Lat        float64   `json:"lat"`
+	Lng        float64   `json:"lng"`
+	Timestamp  time.Time `json:"timestamp"`
+	Compacted  bool      `json:"compacted,omitempty"` // Already downsampled, old points only
+}
+
+// GeoJSONLineString represents a GeoJSON LineString geometry, positions are [lng, lat]
+type GeoJSONLineString struct {
+	Type        string      `json:"type"`
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+// TripReplayProperties holds the timing and distance of a replayed trip
+type TripReplayProperties struct {
+	DeliveryID        *string     `json:"deliveryId,omitempty"`
+	DriverIDs         []string    `json:"driverIds"`  // More than one when the delivery was re-dispatched
+	CoordTimes        []time.Time `json:"coordTimes"` // Timestamp of each position
+	StartedAt         *time.Time  `json:"startedAt,omitempty"`
+	EndedAt           *time.Time  `json:"endedAt,omitempty"`
+	PointCount        int         `json:"pointCount"`
+	DroppedPoints     int         `json:"droppedPoints"` // GPS jumps left out of the path
+	DistanceKm        float64     `json:"distanceKm"`    // Actual distance driven along the path
+	PlannedDistanceKm *float64    `json:"plannedDistanceKm,omitempty"`
+}
+
+// TripReplay represents a driver path as a GeoJSON Feature
+type TripReplay struct {
+	Type       string               `json:"type"`
+	Geometry   GeoJSONLineString    `json:"geometry"`
+	Properties TripReplayProperties `json:"properties"`
+}
+
+// DriverTrackRequest represents the time range of a driver location history
+type DriverTrackRequest struct {
+	From time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00" validate:"required"`
+	To   time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00" validate:"required,gtfield=From"`
+}
+
+// NewTripReplay builds the path of time-sorted points, leaving out GPS jumps
+func NewTripReplay(points []LocationPoint) *TripReplay {
+	replay := &TripReplay{
+		Type:     "Feature",
+		Geometry: GeoJSONLineString{Type: "LineString", Coordinates: [][]float64{}},
+		Properties: TripReplayProperties{
+			DriverIDs:  []string{},
+			CoordTimes: []time.Time{},
+		},
+	}
+
+	seen := make(map[string]bool)
+	var last *LocationPoint
+	for i := range points {
+		point := &points[i]
+		if last != nil && !IsPlausibleMove(*last, *point) {
+			replay.Properties.DroppedPoints++
+			continue
+		}
+
+		if last != nil {
+			replay.Properties.DistanceKm += pointCoordinates(*last).DistanceKm(pointCoordinates(*point))
+		}
+		if !seen[point.DriverID] {
+			seen[point.DriverID] = true
+			replay.Properties.DriverIDs = append(replay.Properties.DriverIDs, point.DriverID)
+		}
+
+		replay.Geometry.Coordinates = append(replay.Geometry.Coordinates, []float64{point.Lng, point.Lat})
+		replay.Properties.CoordTimes = append(replay.Properties.CoordTimes, point.Timestamp)
+		last = point
+	}
+
+	replay.Properties.PointCount = len(replay.Geometry.Coordinates)
+	replay.Properties.DistanceKm = math.Round(replay.Properties.DistanceKm*1000) / 1000
+	if replay.Properties.PointCount > 0 {
+		startedAt := replay.Properties.CoordTimes[0]
+		endedAt := replay.Properties.CoordTimes[replay.Properties.PointCount-1]
+		replay.Properties.StartedAt = &startedAt
+		replay.Properties.EndedAt = &endedAt
+	}
+
+	return replay
+}
+
+// IsPlausibleMove checks the driver could have driven from one point to the next in the time between them
+func IsPlausibleMove(from, to LocationPoint) bool {
+	distanceKm := pointCoordinates(from).DistanceKm(pointCoordinates(to))
+	hours := to.Timestamp.Sub(from.Timestamp).Hours()
+	if hours <= 0 {
+		// Same timestamp, only a negligible move is accepted
+		return distanceKm < 0.05
+	}
+	return distanceKm/hours <= MaxPlausibleSpeedKmh
+}
+
+// DownsampleTrack keeps the first point of each interval of a time-sorted track, and its last point
+// so the path still ends where the driver stopped. It returns the points to delete.
+func DownsampleTrack(points []LocationPoint, interval time.Duration) []LocationPoint {
+	var dropped []LocationPoint
+	var keptAt time.Time
+
+	for i, point := range points {
+		if i == 0 || i == len(points)-1 || point.Timestamp.Sub(keptAt) >= interval {
+			keptAt = point.Timestamp
+			continue
+		}
+		dropped = append(dropped, point)
+	}
+
+	return dropped
+}
+
+// TrackKey identifies the series a point belongs to: one per driver and delivery
+func (p *LocationPoint) TrackKey() string {
+	if p.DeliveryID == nil {
+		return p.DriverID
+	}
+	return p.DriverID + "/" + *p.DeliveryID
+}
+
+func pointCoordinates(p LocationPoint) Coordinates {
+	return Coordinates{Lat: p.Lat, Lng: p.Lng}
+}
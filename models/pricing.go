@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+)
+
+// PricingRuleStatus defines the lifecycle of a pricing rule version
+type PricingRuleStatus string
+
+const (
+	PricingRuleStatusScheduled  PricingRuleStatus = "SCHEDULED"
+	PricingRuleStatusActive     PricingRuleStatus = "ACTIVE"
+	PricingRuleStatusSuperseded PricingRuleStatus = "SUPERSEDED"
+)
+
+// PricingRuleVersion represents a pricing rule with its status in the version history
+type PricingRuleVersion struct {
+	PricingRule
+	Status PricingRuleStatus `json:"status"`
+}
+
+// SimulatePricingRequest represents request for re-pricing past deliveries under a draft rule
+type SimulatePricingRequest struct {
+	Rule       CreatePricingRuleRequest `json:"rule" validate:"required"`
+	SampleSize int                      `json:"sampleSize" validate:"omitempty,gte=1,lte=500"`
+	Since      *time.Time               `json:"since,omitempty"`
+}
+
+// SimulatedDelivery compares the charged price of a past delivery with the draft rule price
+type SimulatedDelivery struct {
+	DeliveryID     string    `json:"deliveryId"`
+	CreatedAt      time.Time `json:"createdAt"`
+	DistanceKm     float64   `json:"distanceKm"`
+	RuleVersion    *int      `json:"ruleVersion,omitempty"`
+	CurrentPrice   float64   `json:"currentPrice"`
+	SimulatedPrice float64   `json:"simulatedPrice"`
+	Difference     float64   `json:"difference"`
+}
+
+// PricingSimulation summarizes the impact of a draft rule on past deliveries
+type PricingSimulation struct {
+	VehicleType      VehicleType         `json:"vehicleType"`
+	SampleSize       int                 `json:"sampleSize"`
+	TotalCurrent     float64             `json:"totalCurrent"`
+	TotalSimulated   float64             `json:"totalSimulated"`
+	AverageChangePct float64             `json:"averageChangePct"`
+	Deliveries       []SimulatedDelivery `json:"deliveries"`
+}
+
+// NewPricingRule builds a draft rule from a request
+func NewPricingRule(req *CreatePricingRuleRequest) *PricingRule {
+	return &PricingRule{
+		VehicleType: req.VehicleType,
+		BasePrice:   req.BasePrice,
+		IncludedKm:  req.IncludedKm,
+		PerKm:       req.PerKm,
+		WaitingFree: req.WaitingFree,
+		WaitingRate: req.WaitingRate,
+		Notes:       req.Notes,
+	}
+}
+
+// VersionStatuses computes the status of every version of one vehicle type, newest first.
+// The active version is the latest one already effective, earlier ones are superseded.
+func VersionStatuses(rules []PricingRule, now time.Time) []PricingRuleVersion {
+	versions := make([]PricingRuleVersion, 0, len(rules))
+	activeFound := false
+	for _, rule := range rules {
+		status := PricingRuleStatusSuperseded
+		switch {
+		case rule.EffectiveFrom.After(now):
+			status = PricingRuleStatusScheduled
+		case !activeFound:
+			status = PricingRuleStatusActive
+			activeFound = true
+		}
+		versions = append(versions, PricingRuleVersion{PricingRule: rule, Status: status})
+	}
+	return versions
+}
+
+// Summarize computes the simulation totals
+func (s *PricingSimulation) Summarize() {
+	s.SampleSize = len(s.Deliveries)
+	s.TotalCurrent, s.TotalSimulated = 0, 0
+	for _, delivery := range s.Deliveries {
+		s.TotalCurrent += delivery.CurrentPrice
+		s.TotalSimulated += delivery.SimulatedPrice
+	}
+	if s.TotalCurrent > 0 {
+		s.AverageChangePct = (s.TotalSimulated - s.TotalCurrent) / s.TotalCurrent * 100
+	}
+}
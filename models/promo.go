@@ -121,34 +121,57 @@ type ReferralResponse struct {
 
 // PricingRule represents pricing rules for different vehicle types
 type PricingRule struct {
-	ID           string      `json:"id"`
-	VehicleType  VehicleType `json:"vehicleType" validate:"required"`
-	BasePrice    float64     `json:"basePrice" validate:"gte=0"`
-	IncludedKm   float64     `json:"includedKm" validate:"gte=0"`
-	PerKm        float64     `json:"perKm" validate:"gte=0"`
-	WaitingFree  int         `json:"waitingFree" validate:"gte=0"` // Free waiting minutes
-	WaitingRate  float64     `json:"waitingRate" validate:"gte=0"` // Price per minute after free
+	ID            string      `json:"id"`
+	VehicleType   VehicleType `json:"vehicleType" validate:"required"`
+	BasePrice     float64     `json:"basePrice" validate:"gte=0"`
+	IncludedKm    float64     `json:"includedKm" validate:"gte=0"`
+	PerKm         float64     `json:"perKm" validate:"gte=0"`
+	WaitingFree   int         `json:"waitingFree" validate:"gte=0"` // Free waiting minutes
+	WaitingRate   float64     `json:"waitingRate" validate:"gte=0"` // Price per minute after free
+	Version       int         `json:"version"`                      // Increments per vehicle type, 0 for built-in defaults
+	EffectiveFrom time.Time   `json:"effectiveFrom"`                // Version applies to quotes from this date
+	Notes         *string     `json:"notes,omitempty"`
+	CreatedBy     *string     `json:"createdBy,omitempty"`
+	CreatedAt     time.Time   `json:"createdAt"`
 }
 
 // CreatePricingRuleRequest represents request for creating a pricing rule
 type CreatePricingRuleRequest struct {
-	VehicleType VehicleType `json:"vehicleType" validate:"required"`
-	BasePrice   float64     `json:"basePrice" validate:"gte=0"`
-	IncludedKm  float64     `json:"includedKm" validate:"gte=0"`
-	PerKm       float64     `json:"perKm" validate:"gte=0"`
-	WaitingFree int         `json:"waitingFree" validate:"gte=0"`
-	WaitingRate float64     `json:"waitingRate" validate:"gte=0"`
+	VehicleType   VehicleType `json:"vehicleType" validate:"required"`
+	BasePrice     float64     `json:"basePrice" validate:"gte=0"`
+	IncludedKm    float64     `json:"includedKm" validate:"gte=0"`
+	PerKm         float64     `json:"perKm" validate:"gte=0"`
+	WaitingFree   int         `json:"waitingFree" validate:"gte=0"`
+	WaitingRate   float64     `json:"waitingRate" validate:"gte=0"`
+	EffectiveFrom *time.Time  `json:"effectiveFrom,omitempty"` // Defaults to now
+	Notes         *string     `json:"notes,omitempty" validate:"omitempty,max=500"`
 }
 
 // PriceCalculation represents the result of price calculation
 type PriceCalculation struct {
-	BasePrice     float64 `json:"basePrice"`
-	DistancePrice float64 `json:"distancePrice"`
-	WaitingPrice  float64 `json:"waitingPrice"`
-	SubTotal      float64 `json:"subTotal"`
-	PromoDiscount float64 `json:"promoDiscount"`
-	FinalPrice    float64 `json:"finalPrice"`
-	PromoCode     *string `json:"promoCode,omitempty"`
+	BasePrice        float64         `json:"basePrice"`
+	DistancePrice    float64         `json:"distancePrice"`
+	WaitingPrice     float64         `json:"waitingPrice"`
+	SubTotal         float64         `json:"subTotal"`
+	PromoDiscount    float64         `json:"promoDiscount"`
+	FinalPrice       float64         `json:"finalPrice"`
+	PromoCode        *string         `json:"promoCode,omitempty"`
+	LineItems        []PriceLineItem `json:"lineItems,omitempty"`
+	Zones            *ZoneMembership `json:"zones,omitempty"`
+	SurgeMultiplier  float64         `json:"surgeMultiplier"`
+	RuleID           string          `json:"ruleId,omitempty"`
+	RuleVersion      int             `json:"ruleVersion"`
+	DistanceKm       float64         `json:"distanceKm"`
+	DistanceProvider string          `json:"distanceProvider,omitempty"` // Routing provider that produced DistanceKm
+	Geocoding        *QuoteGeocoding `json:"geocoding,omitempty"`
+}
+
+// PriceLineItem represents an itemized surcharge or adjustment in a price breakdown
+type PriceLineItem struct {
+	Code     string   `json:"code"`
+	Label    string   `json:"label"`
+	Amount   float64  `json:"amount"`
+	Quantity *float64 `json:"quantity,omitempty"` // e.g. billed minutes
 }
 
 // PromoValidationResult represents the result of promo validation
@@ -267,10 +290,7 @@ func (pr *PricingRule) CalculatePrice(distanceKm, waitingMin float64) PriceCalcu
 	}
 	
 	// Calculate waiting price
-	if waitingMin > float64(pr.WaitingFree) {
-		extraMin := waitingMin - float64(pr.WaitingFree)
-		calc.WaitingPrice = extraMin * pr.WaitingRate
-	}
+	calc.WaitingPrice = pr.CalculateWaitingPrice(waitingMin)
 	
 	calc.SubTotal = calc.BasePrice + calc.DistancePrice + calc.WaitingPrice
 	calc.FinalPrice = calc.SubTotal - calc.PromoDiscount
@@ -282,6 +302,26 @@ func (pr *PricingRule) CalculatePrice(distanceKm, waitingMin float64) PriceCalcu
 	return calc
 }
 
+// AddLineItem adds an itemized adjustment to the subtotal and final price
+func (c *PriceCalculation) AddLineItem(item PriceLineItem) {
+	c.LineItems = append(c.LineItems, item)
+	c.SubTotal += item.Amount
+	c.FinalPrice += item.Amount
+}
+
+// BillableWaitingMinutes returns the waiting minutes charged after the free minutes
+func (pr *PricingRule) BillableWaitingMinutes(waitingMin float64) float64 {
+	if waitingMin <= float64(pr.WaitingFree) {
+		return 0
+	}
+	return waitingMin - float64(pr.WaitingFree)
+}
+
+// CalculateWaitingPrice calculates the waiting surcharge for one stop
+func (pr *PricingRule) CalculateWaitingPrice(waitingMin float64) float64 {
+	return pr.BillableWaitingMinutes(waitingMin) * pr.WaitingRate
+}
+
 // ToResponse converts Referral to ReferralResponse
 func (r *Referral) ToResponse() *ReferralResponse {
 	return &ReferralResponse{
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// RatingTag defines the quick feedback tags attached to a rating
+type RatingTag string
+
+const (
+	// Tags given by clients to drivers
+	RatingTagLate           RatingTag = "LATE"
+	RatingTagRude           RatingTag = "RUDE"
+	RatingTagDamagedPackage RatingTag = "DAMAGED_PACKAGE"
+	RatingTagUnsafeDriving  RatingTag = "UNSAFE_DRIVING"
+	RatingTagFriendly       RatingTag = "FRIENDLY"
+	RatingTagFast           RatingTag = "FAST"
+	RatingTagCareful        RatingTag = "CAREFUL"
+
+	// Tags given by drivers to clients
+	RatingTagNotReady      RatingTag = "NOT_READY"
+	RatingTagWrongAddress  RatingTag = "WRONG_ADDRESS"
+	RatingTagUnreachable   RatingTag = "UNREACHABLE"
+	RatingTagLongWait      RatingTag = "LONG_WAIT"
+	RatingTagPolite        RatingTag = "POLITE"
+	RatingTagWellPackaged  RatingTag = "WELL_PACKAGED"
+	RatingTagClearLocation RatingTag = "CLEAR_LOCATION"
+)
+
+// Rating represents the score one party of a delivery gives the other
+type Rating struct {
+	ID         string      `json:"id"`
+	DeliveryID string      `json:"deliveryId"`
+	RaterID    string      `json:"raterId"`
+	RaterRole  UserRole    `json:"raterRole"`
+	RateeID    string      `json:"rateeId"`
+	RateeRole  UserRole    `json:"rateeRole"`
+	Score      int         `json:"score"`
+	Tags       []RatingTag `json:"tags,omitempty"`
+	Comment    *string     `json:"comment,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+// CreateRatingRequest represents request for rating the other party of a delivery
+type CreateRatingRequest struct {
+	Score   int         `json:"score" validate:"required,gte=1,lte=5"`
+	Tags    []RatingTag `json:"tags,omitempty" validate:"omitempty,max=5,unique"`
+	Comment *string     `json:"comment,omitempty" validate:"omitempty,max=500"`
+}
+
+// RatingPolicy holds the configurable rating rules
+type RatingPolicy struct {
+	Window          time.Duration // How long after delivery a rating can be given
+	ReviewThreshold float64       // Average below which the user is sent for review
+	ReviewMinCount  int           // Ratings needed before the average is trusted
+}
+
+// RatingSummary represents aggregated ratings of a user
+type RatingSummary struct {
+	Average   *float64          `json:"average,omitempty"`
+	Count     int               `json:"count"`
+	TagCounts map[RatingTag]int `json:"tagCounts,omitempty"`
+	Recent    []Rating          `json:"recent,omitempty"`
+}
+
+// DriverStats represents the admin view of a driver's performance
+type DriverStats struct {
+	Driver           *UserResponse  `json:"driver"`
+	AcceptedCount    int            `json:"acceptedCount"`
+	CancelledCount   int            `json:"cancelledCount"`
+	DeliveredCount   int            `json:"deliveredCount"`
+	ReliabilityScore float64        `json:"reliabilityScore"`
+	Ratings          *RatingSummary `json:"ratings"`
+	UnderReview      bool           `json:"underReview"`
+}
+
+// IsValidFor checks if the tag can be given by the given role
+func (t RatingTag) IsValidFor(raterRole UserRole) bool {
+	switch raterRole {
+	case UserRoleClient:
+		return t == RatingTagLate || t == RatingTagRude || t == RatingTagDamagedPackage ||
+			t == RatingTagUnsafeDriving || t == RatingTagFriendly || t == RatingTagFast ||
+			t == RatingTagCareful
+	case UserRoleLivreur:
+		return t == RatingTagNotReady || t == RatingTagWrongAddress || t == RatingTagUnreachable ||
+			t == RatingTagLongWait || t == RatingTagPolite || t == RatingTagWellPackaged ||
+			t == RatingTagClearLocation
+	default:
+		return false
+	}
+}
+
+// CanRate checks the delivery can still be rated by the given role at the given time
+func (p *RatingPolicy) CanRate(d *Delivery, raterRole UserRole, now time.Time) error {
+	if d.Status != DeliveryStatusDelivered {
+		return fmt.Errorf("only delivered deliveries can be rated")
+	}
+	if raterRole == UserRoleClient && d.LivreurID == nil {
+		return fmt.Errorf("delivery has no driver to rate")
+	}
+
+	deliveredAt := d.UpdatedAt
+	if d.DeliveredAt != nil {
+		deliveredAt = *d.DeliveredAt
+	}
+	if now.Sub(deliveredAt) > p.Window {
+		return fmt.Errorf("rating window closed %s after delivery", p.Window)
+	}
+
+	return nil
+}
+
+// AddScore returns the new average and count once a score is added
+func AddScore(average *float64, count, score int) (float64, int) {
+	if average == nil || count == 0 {
+		return float64(score), 1
+	}
+
+	newCount := count + 1
+	return (*average*float64(count) + float64(score)) / float64(newCount), newCount
+}
+
+// NeedsReview checks if an average is low enough, over enough ratings, to open a review
+func (p *RatingPolicy) NeedsReview(average float64, count int) bool {
+	return count >= p.ReviewMinCount && average < p.ReviewThreshold
+}
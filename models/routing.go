@@ -0,0 +1,39 @@
+package models
+
+import "math"
+
+// Routing provider names recorded on quotes
+const (
+	RoutingProviderOSRM        = "osrm"
+	RoutingProviderGraphHopper = "graphhopper"
+	RoutingProviderHaversine   = "haversine"
+	RoutingProviderDefault     = "default" // No coordinates, fixed estimate
+	RoutingProviderClient      = "client"  // Distance supplied with the quote request
+)
+
+// Coordinates represents a WGS84 point
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// RouteResult represents a road distance and duration between two points
+type RouteResult struct {
+	DistanceKm  float64 `json:"distanceKm"`
+	DurationMin float64 `json:"durationMin"`
+	Provider    string  `json:"provider"`
+	Cached      bool    `json:"cached"`
+}
+
+// DistanceKm returns the great-circle distance to another point
+func (c Coordinates) DistanceKm(other Coordinates) float64 {
+	const R = 6371 // Earth's radius in kilometers
+
+	dLat := (other.Lat - c.Lat) * math.Pi / 180
+	dLng := (other.Lng - c.Lng) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(c.Lat*math.Pi/180)*math.Cos(other.Lat*math.Pi/180)*
+			math.Sin(dLng/2)*math.Sin(dLng/2)
+	return R * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,309 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxShiftDuration is the longest shift or availability slot that can be planned
+	MaxShiftDuration = 12 * time.Hour
+	// MaxShiftReportRange is the longest period covered by a planned vs actual report
+	MaxShiftReportRange = 31 * 24 * time.Hour
+)
+
+// AvailabilitySlot represents a period a driver says they can work
+type AvailabilitySlot struct {
+	ID        string    `json:"id"`
+	DriverID  string    `json:"driverId"`
+	ZoneID    *string   `json:"zoneId,omitempty"` // Any zone when nil
+	StartAt   time.Time `json:"startAt"`
+	EndAt     time.Time `json:"endAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Shift represents a period of work planned by an admin in a zone
+type Shift struct {
+	ID          string    `json:"id"`
+	ZoneID      string    `json:"zoneId"`
+	StartAt     time.Time `json:"startAt"`
+	EndAt       time.Time `json:"endAt"`
+	MinDrivers  int       `json:"minDrivers"` // Staffing below this is flagged
+	DriverIDs   []string  `json:"driverIds"`
+	Note        *string   `json:"note,omitempty"`
+	CreatedByID string    `json:"createdById"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// ShiftStaffing represents a shift with how well it is staffed
+type ShiftStaffing struct {
+	Shift
+	AvailableDriverIDs   []string `json:"availableDriverIds"`   // Not assigned yet, with a slot covering the shift
+	UnavailableDriverIDs []string `json:"unavailableDriverIds"` // Assigned without a slot covering the shift
+	Missing              int      `json:"missing"`
+	Understaffed         bool     `json:"understaffed"`
+}
+
+// DriverStatusEvent records a change of the real time status of a driver
+type DriverStatusEvent struct {
+	ID        string       `json:"id"`
+	DriverID  string       `json:"driverId"`
+	Status    DriverStatus `json:"status"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// TimeInterval represents a period of time, the end excluded
+type TimeInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// DriverShiftReport compares the hours planned for a driver with the hours they were online
+type DriverShiftReport struct {
+	DriverID     string   `json:"driverId"`
+	DriverName   string   `json:"driverName,omitempty"`
+	ShiftCount   int      `json:"shiftCount"`
+	PlannedHours float64  `json:"plannedHours"`
+	ActualHours  float64  `json:"actualHours"`         // Online, on shift or not
+	OnShiftHours float64  `json:"onShiftHours"`        // Online during their shifts
+	Adherence    *float64 `json:"adherence,omitempty"` // Percentage of the planned hours worked, nil without shifts
+}
+
+// ShiftReport compares planned and actual online hours over a period
+type ShiftReport struct {
+	From         time.Time           `json:"from"`
+	To           time.Time           `json:"to"`
+	ZoneID       *string             `json:"zoneId,omitempty"`
+	PlannedHours float64             `json:"plannedHours"`
+	ActualHours  float64             `json:"actualHours"`
+	OnShiftHours float64             `json:"onShiftHours"`
+	Drivers      []DriverShiftReport `json:"drivers"`
+}
+
+// CreateAvailabilitySlotRequest represents a driver publishing a period they can work
+type CreateAvailabilitySlotRequest struct {
+	ZoneID  *string   `json:"zoneId,omitempty"`
+	StartAt time.Time `json:"startAt" validate:"required"`
+	EndAt   time.Time `json:"endAt" validate:"required,gtfield=StartAt"`
+}
+
+// CreateShiftRequest represents request for planning a shift
+type CreateShiftRequest struct {
+	ZoneID     string    `json:"zoneId" validate:"required"`
+	StartAt    time.Time `json:"startAt" validate:"required"`
+	EndAt      time.Time `json:"endAt" validate:"required,gtfield=StartAt"`
+	MinDrivers int       `json:"minDrivers" validate:"gte=1,lte=500"`
+	DriverIDs  []string  `json:"driverIds,omitempty" validate:"omitempty,max=500,dive,required"`
+	Note       *string   `json:"note,omitempty" validate:"omitempty,max=500"`
+}
+
+// UpdateShiftRequest represents request for updating a shift, the driver list is replaced when given
+type UpdateShiftRequest struct {
+	StartAt    *time.Time `json:"startAt,omitempty"`
+	EndAt      *time.Time `json:"endAt,omitempty"`
+	MinDrivers *int       `json:"minDrivers,omitempty" validate:"omitempty,gte=1,lte=500"`
+	DriverIDs  []string   `json:"driverIds,omitempty" validate:"omitempty,max=500,dive,required"`
+	Note       *string    `json:"note,omitempty" validate:"omitempty,max=500"`
+}
+
+// ShiftPeriodRequest represents the period, and optionally the zone, of a shift listing or report
+type ShiftPeriodRequest struct {
+	ZoneID string    `form:"zone_id"`
+	From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00" validate:"required"`
+	To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00" validate:"required,gtfield=From"`
+}
+
+// ValidateShiftPeriod checks a shift or availability slot ends after it starts and is not too long
+func ValidateShiftPeriod(startAt, endAt time.Time) error {
+	if !endAt.After(startAt) {
+		return fmt.Errorf("end must be after start")
+	}
+	if endAt.Sub(startAt) > MaxShiftDuration {
+		return fmt.Errorf("cannot be longer than %d hours", int(MaxShiftDuration.Hours()))
+	}
+	return nil
+}
+
+// IsActive checks if the shift is under way at the given time
+func (s *Shift) IsActive(at time.Time) bool {
+	return !at.Before(s.StartAt) && at.Before(s.EndAt)
+}
+
+// HasDriver checks if the driver is assigned to the shift
+func (s *Shift) HasDriver(driverID string) bool {
+	for _, id := range s.DriverIDs {
+		if id == driverID {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlaps checks if the shift shares some time with the period
+func (s *Shift) Overlaps(startAt, endAt time.Time) bool {
+	return s.StartAt.Before(endAt) && startAt.Before(s.EndAt)
+}
+
+// Covers checks if the driver said they could work the whole shift
+func (a *AvailabilitySlot) Covers(shift *Shift) bool {
+	if a.ZoneID != nil && *a.ZoneID != shift.ZoneID {
+		return false
+	}
+	return !a.StartAt.After(shift.StartAt) && !a.EndAt.Before(shift.EndAt)
+}
+
+// NewShiftStaffing compares the drivers assigned to a shift with its minimum and the published availability
+func NewShiftStaffing(shift Shift, slots []AvailabilitySlot) ShiftStaffing {
+	staffing := ShiftStaffing{
+		Shift:                shift,
+		AvailableDriverIDs:   []string{},
+		UnavailableDriverIDs: []string{},
+	}
+
+	covered := make(map[string]bool)
+	for i := range slots {
+		if slots[i].Covers(&shift) {
+			covered[slots[i].DriverID] = true
+		}
+	}
+
+	for _, driverID := range shift.DriverIDs {
+		if !covered[driverID] {
+			staffing.UnavailableDriverIDs = append(staffing.UnavailableDriverIDs, driverID)
+		}
+	}
+	for driverID := range covered {
+		if !shift.HasDriver(driverID) {
+			staffing.AvailableDriverIDs = append(staffing.AvailableDriverIDs, driverID)
+		}
+	}
+	sort.Strings(staffing.AvailableDriverIDs)
+
+	if missing := shift.MinDrivers - len(shift.DriverIDs); missing > 0 {
+		staffing.Missing = missing
+		staffing.Understaffed = true
+	}
+
+	return staffing
+}
+
+// OnlineIntervals returns when the driver was online over the period, from their time-sorted status
+// events. The last event before the period gives the status at its start.
+func OnlineIntervals(events []DriverStatusEvent, from, to time.Time) []TimeInterval {
+	intervals := []TimeInterval{}
+	var onlineSince *time.Time
+
+	for _, event := range events {
+		at := event.CreatedAt
+		if at.Before(from) {
+			at = from
+		}
+		if !at.Before(to) {
+			break
+		}
+
+		online := event.Status != DriverStatusOffline
+		if online && onlineSince == nil {
+			onlineSince = &at
+		} else if !online && onlineSince != nil {
+			if at.After(*onlineSince) {
+				intervals = append(intervals, TimeInterval{Start: *onlineSince, End: at})
+			}
+			onlineSince = nil
+		}
+	}
+
+	if onlineSince != nil && to.After(*onlineSince) {
+		intervals = append(intervals, TimeInterval{Start: *onlineSince, End: to})
+	}
+
+	return intervals
+}
+
+// OverlapHours returns the hours the intervals share with the period
+func OverlapHours(intervals []TimeInterval, startAt, endAt time.Time) float64 {
+	var total time.Duration
+	for _, interval := range intervals {
+		start := interval.Start
+		if start.Before(startAt) {
+			start = startAt
+		}
+		end := interval.End
+		if end.After(endAt) {
+			end = endAt
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total.Hours()
+}
+
+// NewShiftReport compares, for each driver, the hours of the shifts they were assigned to with the hours
+// they were online over the period. Drivers online without a shift are reported too.
+func NewShiftReport(from, to time.Time, shifts []Shift, eventsByDriver map[string][]DriverStatusEvent) *ShiftReport {
+	report := &ShiftReport{From: from, To: to, Drivers: []DriverShiftReport{}}
+	period := []TimeInterval{{Start: from, End: to}}
+
+	driverShifts := make(map[string][]Shift)
+	for _, shift := range shifts {
+		for _, driverID := range shift.DriverIDs {
+			driverShifts[driverID] = append(driverShifts[driverID], shift)
+		}
+	}
+
+	driverIDs := make([]string, 0, len(driverShifts)+len(eventsByDriver))
+	for driverID := range driverShifts {
+		driverIDs = append(driverIDs, driverID)
+	}
+	for driverID := range eventsByDriver {
+		if _, ok := driverShifts[driverID]; !ok {
+			driverIDs = append(driverIDs, driverID)
+		}
+	}
+	sort.Strings(driverIDs)
+
+	for _, driverID := range driverIDs {
+		online := OnlineIntervals(eventsByDriver[driverID], from, to)
+		driver := DriverShiftReport{
+			DriverID:    driverID,
+			ShiftCount:  len(driverShifts[driverID]),
+			ActualHours: OverlapHours(online, from, to),
+		}
+		for _, shift := range driverShifts[driverID] {
+			driver.PlannedHours += OverlapHours(period, shift.StartAt, shift.EndAt)
+			driver.OnShiftHours += OverlapHours(online, shift.StartAt, shift.EndAt)
+		}
+		if driver.PlannedHours > 0 {
+			adherence := roundHours(driver.OnShiftHours / driver.PlannedHours * 100)
+			driver.Adherence = &adherence
+		}
+
+		// Drivers who were never online nor planned have nothing to report
+		if driver.PlannedHours == 0 && driver.ActualHours == 0 {
+			continue
+		}
+
+		report.PlannedHours += driver.PlannedHours
+		report.ActualHours += driver.ActualHours
+		report.OnShiftHours += driver.OnShiftHours
+
+		driver.PlannedHours = roundHours(driver.PlannedHours)
+		driver.ActualHours = roundHours(driver.ActualHours)
+		driver.OnShiftHours = roundHours(driver.OnShiftHours)
+		report.Drivers = append(report.Drivers, driver)
+	}
+
+	report.PlannedHours = roundHours(report.PlannedHours)
+	report.ActualHours = roundHours(report.ActualHours)
+	report.OnShiftHours = roundHours(report.OnShiftHours)
+
+	return report
+}
+
+func roundHours(hours float64) float64 {
+	return math.Round(hours*100) / 100
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SLABreachKind defines what made a delivery miss its SLA
+type SLABreachKind string
+
+const (
+	SLABreachDuration   SLABreachKind = "DURATION"    // Too long in the status
+	SLABreachNoProgress SLABreachKind = "NO_PROGRESS" // Driver not getting closer to the next stop
+	SLABreachSilent     SLABreachKind = "SILENT"      // No position from the driver
+)
+
+// SLAAction defines what the watchdog does about a delivery
+type SLAAction string
+
+const (
+	SLAActionNone       SLAAction = ""
+	SLAActionWarn       SLAAction = "WARN"       // Alert ops and ping the driver
+	SLAActionRedispatch SLAAction = "REDISPATCH" // Unassign the driver and dispatch again
+	SLAActionEscalate   SLAAction = "ESCALATE"   // Ops must step in, the driver has the parcel
+)
+
+// SLARule holds the limits of one delivery status, a zero limit is not checked
+type SLARule struct {
+	Status         DeliveryStatus `json:"status"`
+	MaxDurationMin int            `json:"maxDurationMin"`
+	NoProgressMin  int            `json:"noProgressMin"`
+}
+
+// SLAPolicy holds the configurable watchdog rules
+type SLAPolicy struct {
+	Rules          map[DeliveryStatus]SLARule `json:"rules"`
+	DriverSilence  time.Duration              `json:"driverSilence"`  // Longest time without a driver position
+	Escalation     time.Duration              `json:"escalation"`     // Time after the warning before re-dispatching
+	ProgressMeters float64                    `json:"progressMeters"` // Distance gained toward the stop that counts as progress
+}
+
+// SLAState tracks the watchdog view of a delivery in its current status
+type SLAState struct {
+	Status             DeliveryStatus `json:"status"`
+	ProgressAt         time.Time      `json:"progressAt"`                   // Last time the driver got closer to the stop
+	ProgressDistanceKm *float64       `json:"progressDistanceKm,omitempty"` // Distance to the stop at that time
+	BreachedAt         *time.Time     `json:"breachedAt,omitempty"`
+	EscalatedAt        *time.Time     `json:"escalatedAt,omitempty"`
+}
+
+// SLACheck represents the outcome of a watchdog check
+type SLACheck struct {
+	Action SLAAction
+	Breach SLABreachKind
+	Reason string
+	State  *SLAState // To save on the delivery
+}
+
+// ParseSLARules reads rules written as STATUS:maxDurationMin:noProgressMin, separated by commas
+func ParseSLARules(spec string) (map[DeliveryStatus]SLARule, error) {
+	rules := make(map[DeliveryStatus]SLARule)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		fields := strings.Split(part, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid SLA rule %q, expected STATUS:maxDurationMin:noProgressMin", part)
+		}
+
+		status := DeliveryStatus(strings.ToUpper(strings.TrimSpace(fields[0])))
+		if !status.IsValid() {
+			return nil, fmt.Errorf("invalid SLA rule %q: unknown status", part)
+		}
+
+		maxDuration, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil || maxDuration < 0 {
+			return nil, fmt.Errorf("invalid SLA rule %q: bad duration", part)
+		}
+		noProgress, err := strconv.Atoi(strings.TrimSpace(fields[2]))
+		if err != nil || noProgress < 0 {
+			return nil, fmt.Errorf("invalid SLA rule %q: bad no progress delay", part)
+		}
+
+		rules[status] = SLARule{Status: status, MaxDurationMin: maxDuration, NoProgressMin: noProgress}
+	}
+
+	return rules, nil
+}
+
+// Statuses returns the statuses watched by the policy
+func (p *SLAPolicy) Statuses() []DeliveryStatus {
+	statuses := make([]DeliveryStatus, 0, len(p.Rules))
+	for status := range p.Rules {
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Evaluate checks a delivery against the SLA of its status. distanceKm is the driver distance to the
+// next stop, nil when unknown. A breach first warns; if it lasts past the escalation delay the driver
+// is replaced, unless they already have the parcel or the delay is not theirs.
+func (p *SLAPolicy) Evaluate(d *Delivery, distanceKm *float64, lastSeenAt *time.Time, now time.Time) SLACheck {
+	rule, ok := p.Rules[d.Status]
+	if !ok || d.LivreurID == nil {
+		return SLACheck{}
+	}
+
+	since := d.StatusSince()
+	state := d.SLA
+	if state == nil || state.Status != d.Status {
+		state = &SLAState{Status: d.Status, ProgressAt: since, ProgressDistanceKm: distanceKm}
+	} else {
+		copied := *state
+		state = &copied
+	}
+
+	if distanceKm != nil && (state.ProgressDistanceKm == nil || *state.ProgressDistanceKm-*distanceKm >= p.ProgressMeters/1000) {
+		state.ProgressDistanceKm = distanceKm
+		state.ProgressAt = now
+	}
+
+	check := SLACheck{State: state}
+	seenAt := since
+	if lastSeenAt != nil && lastSeenAt.After(seenAt) {
+		seenAt = *lastSeenAt
+	}
+
+	switch {
+	case p.DriverSilence > 0 && now.Sub(seenAt) > p.DriverSilence:
+		check.Breach = SLABreachSilent
+		check.Reason = fmt.Sprintf("no driver position for %.0f min in %s", now.Sub(seenAt).Minutes(), d.Status)
+	case rule.NoProgressMin > 0 && now.Sub(state.ProgressAt) > time.Duration(rule.NoProgressMin)*time.Minute:
+		check.Breach = SLABreachNoProgress
+		check.Reason = fmt.Sprintf("driver not moving toward the next stop for %.0f min in %s (SLA %d min)", now.Sub(state.ProgressAt).Minutes(), d.Status, rule.NoProgressMin)
+	case rule.MaxDurationMin > 0 && now.Sub(since) > time.Duration(rule.MaxDurationMin)*time.Minute:
+		check.Breach = SLABreachDuration
+		check.Reason = fmt.Sprintf("%.0f min in %s (SLA %d min)", now.Sub(since).Minutes(), d.Status, rule.MaxDurationMin)
+	}
+
+	if check.Breach == "" {
+		// Back on track, a later breach starts a new warning
+		state.BreachedAt = nil
+		state.EscalatedAt = nil
+		return check
+	}
+
+	switch {
+	case state.BreachedAt == nil:
+		state.BreachedAt = &now
+		check.Action = SLAActionWarn
+	case state.EscalatedAt == nil && now.Sub(*state.BreachedAt) >= p.Escalation:
+		state.EscalatedAt = &now
+		check.Action = SLAActionEscalate
+		if d.Status.IsBeforePickup() && check.Breach != SLABreachDuration {
+			check.Action = SLAActionRedispatch
+		}
+	}
+
+	return check
+}
+
+// StatusSince returns when the delivery entered its current status, as far as it is known
+func (d *Delivery) StatusSince() time.Time {
+	if d.StatusChangedAt != nil {
+		return *d.StatusChangedAt
+	}
+	if d.Status == DeliveryStatusAccepted && d.AcceptedAt != nil {
+		return *d.AcceptedAt
+	}
+	return d.UpdatedAt
+}
+
+// NextStop returns the location the driver is heading to in the status, if any
+func (d *Delivery) NextStop() (string, bool) {
+	switch {
+	case d.Status.IsBeforePickup(), d.Status == DeliveryStatusReturning:
+		return d.PickupID, true
+	case d.Status == DeliveryStatusPickedUp, d.Status == DeliveryStatusInTransit:
+		return d.DropoffID, true
+	default:
+		return "", false
+	}
+}
+
+// SLADriverPing returns the SMS text asking the driver to move on with the delivery
+func SLADriverPing(action SLAAction) string {
+	if action == SLAActionRedispatch {
+		return "ILEX : sans progression de votre part, la livraison a été réattribuée à un autre livreur."
+	}
+	return "ILEX : votre livraison en cours n'avance plus. Merci de reprendre la course ou de contacter le support."
+}
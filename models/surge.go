@@ -0,0 +1,126 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// GlobalSurgeZoneID is used for surge computed outside any service zone
+const GlobalSurgeZoneID = "global"
+
+// SurgePolicy holds the configurable surge pricing rules
+type SurgePolicy struct {
+	Threshold     float64       `json:"threshold"`     // Pending deliveries per available driver before surge starts
+	Sensitivity   float64       `json:"sensitivity"`   // Multiplier increase per unit of ratio above the threshold
+	MaxMultiplier float64       `json:"maxMultiplier"` // Cap on the computed multiplier
+	Smoothing     float64       `json:"smoothing"`     // Weight of the new value, between 0 and 1
+	Refresh       time.Duration `json:"refresh"`       // How long a computed multiplier is reused
+}
+
+// SurgeState represents the current surge multiplier of a zone
+type SurgeState struct {
+	ZoneID            string    `json:"zoneId"`
+	Multiplier        float64   `json:"multiplier"`
+	RawMultiplier     float64   `json:"rawMultiplier"`
+	PendingDeliveries int       `json:"pendingDeliveries"`
+	AvailableDrivers  int       `json:"availableDrivers"`
+	OverrideID        *string   `json:"overrideId,omitempty"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// SurgeOverride represents an admin-forced multiplier for a zone
+type SurgeOverride struct {
+	ID         string    `json:"id"`
+	ZoneID     string    `json:"zoneId"`
+	Multiplier float64   `json:"multiplier"`
+	Reason     *string   `json:"reason,omitempty"`
+	CreatedBy  string    `json:"createdBy"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// CreateSurgeOverrideRequest represents request for forcing a surge multiplier
+type CreateSurgeOverrideRequest struct {
+	ZoneID      string  `json:"zoneId" validate:"required"`
+	Multiplier  float64 `json:"multiplier" validate:"gte=1,lte=5"`
+	DurationMin int     `json:"durationMin" validate:"required,gte=1,lte=1440"`
+	Reason      *string `json:"reason,omitempty" validate:"omitempty,max=200"`
+}
+
+// PriceQuoteLog records every quote for later pricing analysis
+type PriceQuoteLog struct {
+	ID               string       `json:"id"`
+	DeliveryID       *string      `json:"deliveryId,omitempty"` // Set when the quote became a delivery
+	ZoneID           *string      `json:"zoneId,omitempty"`
+	VehicleType      VehicleType  `json:"vehicleType"`
+	DeliveryType     DeliveryType `json:"deliveryType"`
+	DistanceKm       float64      `json:"distanceKm"`
+	SubTotal         float64      `json:"subTotal"`
+	SurgeMultiplier  float64      `json:"surgeMultiplier"`
+	FinalPrice       float64      `json:"finalPrice"`
+	DistanceProvider string       `json:"distanceProvider,omitempty"`
+	CreatedAt        time.Time    `json:"createdAt"`
+}
+
+// PriceContext holds the quote-time inputs that adjust a price beyond the pricing rule
+type PriceContext struct {
+	PickupZone     *ServiceZone
+	DropoffZone    *ServiceZone
+	Surge          *SurgeState
+	At             time.Time // Quote time in the business timezone
+	TimeSurcharges []TimeSurchargeRule
+	Holidays       []Holiday
+}
+
+// RawMultiplier computes the surge multiplier from demand and supply, capped
+func (p *SurgePolicy) RawMultiplier(pending, available int) float64 {
+	if pending == 0 {
+		return 1.0
+	}
+
+	// No driver at all counts as a single one so the ratio stays finite
+	ratio := float64(pending) / math.Max(float64(available), 1)
+	if ratio <= p.Threshold {
+		return 1.0
+	}
+
+	multiplier := 1.0 + (ratio-p.Threshold)*p.Sensitivity
+	if multiplier > p.MaxMultiplier {
+		multiplier = p.MaxMultiplier
+	}
+	return multiplier
+}
+
+// Smooth blends the new multiplier with the previous one to avoid price jumps between quotes
+func (p *SurgePolicy) Smooth(previous, raw float64) float64 {
+	if previous < 1 {
+		previous = 1
+	}
+	multiplier := previous + (raw-previous)*p.Smoothing
+	return math.Round(multiplier*100) / 100
+}
+
+// IsStale checks if the state must be recomputed
+func (s *SurgeState) IsStale(refresh time.Duration, now time.Time) bool {
+	return now.Sub(s.UpdatedAt) >= refresh
+}
+
+// IsActive checks if the override still applies
+func (o *SurgeOverride) IsActive(now time.Time) bool {
+	return now.Before(o.ExpiresAt)
+}
+
+// ApplySurge adds the surge line item on the current subtotal
+func (c *PriceCalculation) ApplySurge(multiplier float64) {
+	c.SurgeMultiplier = multiplier
+	if multiplier <= 1 {
+		return
+	}
+
+	c.AddLineItem(PriceLineItem{
+		Code:     "SURGE",
+		Label:    "Forte demande",
+		Amount:   math.Round(c.SubTotal * (multiplier - 1)),
+		Quantity: &multiplier,
+	})
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// TimeSurchargeRule represents a surcharge applied during a validity window (night, weekend, holidays)
+type TimeSurchargeRule struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	VehicleType  *VehicleType `json:"vehicleType,omitempty"` // Nil applies to every vehicle type
+	DaysOfWeek   []int        `json:"daysOfWeek,omitempty"`  // 0 = Sunday, empty means every day
+	StartTime    string       `json:"startTime,omitempty"`   // "HH:MM", empty means all day
+	EndTime      string       `json:"endTime,omitempty"`     // "HH:MM", before StartTime for overnight windows
+	HolidaysOnly bool         `json:"holidaysOnly"`
+	Percentage   float64      `json:"percentage"`  // Percentage of the subtotal
+	FixedAmount  float64      `json:"fixedAmount"` // Fixed amount in FCFA
+	IsActive     bool         `json:"isActive"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	UpdatedAt    time.Time    `json:"updatedAt"`
+}
+
+// CreateTimeSurchargeRuleRequest represents request for creating a time surcharge rule
+type CreateTimeSurchargeRuleRequest struct {
+	Name         string       `json:"name" validate:"required,min=2,max=100"`
+	VehicleType  *VehicleType `json:"vehicleType,omitempty"`
+	DaysOfWeek   []int        `json:"daysOfWeek,omitempty" validate:"omitempty,dive,gte=0,lte=6"`
+	StartTime    string       `json:"startTime,omitempty"`
+	EndTime      string       `json:"endTime,omitempty"`
+	HolidaysOnly bool         `json:"holidaysOnly"`
+	Percentage   float64      `json:"percentage" validate:"gte=0,lte=200"`
+	FixedAmount  float64      `json:"fixedAmount" validate:"gte=0"`
+	IsActive     *bool        `json:"isActive,omitempty"`
+}
+
+// UpdateTimeSurchargeRuleRequest represents request for updating a time surcharge rule
+type UpdateTimeSurchargeRuleRequest struct {
+	Name         *string  `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	DaysOfWeek   []int    `json:"daysOfWeek,omitempty" validate:"omitempty,dive,gte=0,lte=6"`
+	StartTime    *string  `json:"startTime,omitempty"`
+	EndTime      *string  `json:"endTime,omitempty"`
+	HolidaysOnly *bool    `json:"holidaysOnly,omitempty"`
+	Percentage   *float64 `json:"percentage,omitempty" validate:"omitempty,gte=0,lte=200"`
+	FixedAmount  *float64 `json:"fixedAmount,omitempty" validate:"omitempty,gte=0"`
+	IsActive     *bool    `json:"isActive,omitempty"`
+}
+
+// Holiday represents a public holiday in the pricing calendar
+type Holiday struct {
+	ID        string    `json:"id"`
+	Date      string    `json:"date"` // "YYYY-MM-DD" in the business timezone
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateHolidayRequest represents request for adding a holiday
+type CreateHolidayRequest struct {
+	Date string `json:"date" validate:"required,datetime=2006-01-02"`
+	Name string `json:"name" validate:"required,min=2,max=100"`
+}
+
+// Validate checks the rule window is well-formed
+func (r *TimeSurchargeRule) Validate() error {
+	if (r.StartTime == "") != (r.EndTime == "") {
+		return fmt.Errorf("startTime and endTime must be set together")
+	}
+	if r.StartTime != "" {
+		if _, err := time.Parse("15:04", r.StartTime); err != nil {
+			return fmt.Errorf("invalid startTime, expected HH:MM")
+		}
+		if _, err := time.Parse("15:04", r.EndTime); err != nil {
+			return fmt.Errorf("invalid endTime, expected HH:MM")
+		}
+		if r.StartTime == r.EndTime {
+			return fmt.Errorf("startTime and endTime cannot be equal")
+		}
+	}
+	if r.Percentage == 0 && r.FixedAmount == 0 {
+		return fmt.Errorf("percentage or fixedAmount is required")
+	}
+	return nil
+}
+
+// Matches checks if the rule applies at a local time. Overnight windows belong to the day they start,
+// so a Friday 22:00-06:00 rule still applies on Saturday at 02:00.
+func (r *TimeSurchargeRule) Matches(at time.Time, vehicleType VehicleType, holidays []Holiday) bool {
+	if !r.IsActive {
+		return false
+	}
+	if r.VehicleType != nil && *r.VehicleType != vehicleType {
+		return false
+	}
+
+	windowDay := at
+	if r.StartTime != "" {
+		clock := at.Format("15:04")
+		if r.StartTime < r.EndTime {
+			if clock < r.StartTime || clock >= r.EndTime {
+				return false
+			}
+		} else {
+			if clock < r.StartTime && clock >= r.EndTime {
+				return false
+			}
+			if clock < r.EndTime {
+				windowDay = at.AddDate(0, 0, -1)
+			}
+		}
+	}
+
+	if len(r.DaysOfWeek) > 0 {
+		dayMatches := false
+		for _, day := range r.DaysOfWeek {
+			if int(windowDay.Weekday()) == day {
+				dayMatches = true
+				break
+			}
+		}
+		if !dayMatches {
+			return false
+		}
+	}
+
+	if r.HolidaysOnly && !IsHoliday(holidays, windowDay) {
+		return false
+	}
+
+	return true
+}
+
+// AmountFor returns the surcharge for a subtotal
+func (r *TimeSurchargeRule) AmountFor(subTotal float64) float64 {
+	return math.Round(subTotal*r.Percentage/100) + r.FixedAmount
+}
+
+// IsHoliday checks if the local date is in the holiday calendar
+func IsHoliday(holidays []Holiday, at time.Time) bool {
+	date := at.Format("2006-01-02")
+	for _, holiday := range holidays {
+		if holiday.Date == date {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyTimeSurcharges adds a line item for every rule matching the local quote time
+func (c *PriceCalculation) ApplyTimeSurcharges(rules []TimeSurchargeRule, vehicleType VehicleType, at time.Time, holidays []Holiday) {
+	subTotal := c.SubTotal
+	for i := range rules {
+		if !rules[i].Matches(at, vehicleType, holidays) {
+			continue
+		}
+		c.AddLineItem(PriceLineItem{
+			Code:   "TIME_SURCHARGE",
+			Label:  rules[i].Name,
+			Amount: rules[i].AmountFor(subTotal),
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Tip represents a tip a client gives the driver of a delivery.
+// Tips are credited entirely to the driver and never enter the commission calculation.
+type Tip struct {
+	ID            string        `json:"id"`
+	DeliveryID    string        `json:"deliveryId"`
+	ClientID      string        `json:"clientId"`
+	DriverID      string        `json:"driverId"`
+	Amount        float64       `json:"amount"`
+	PaymentMethod PaymentMethod `json:"paymentMethod"`
+	CreatedAt     time.Time     `json:"createdAt"`
+}
+
+// CreateTipRequest represents request for tipping the driver of a delivery
+type CreateTipRequest struct {
+	Amount        float64       `json:"amount" validate:"required,gt=0"`
+	PaymentMethod PaymentMethod `json:"paymentMethod" validate:"required"`
+}
+
+// TipPolicy holds the configurable tipping rules
+type TipPolicy struct {
+	Window    time.Duration // How long after delivery a tip can still be given
+	MaxAmount float64       // Highest total tip per delivery, 0 means no limit
+}
+
+// DeliveryReceipt represents what the client paid for a delivery, tips shown apart from the price
+type DeliveryReceipt struct {
+	DeliveryID    string          `json:"deliveryId"`
+	Status        DeliveryStatus  `json:"status"`
+	PaymentMethod PaymentMethod   `json:"paymentMethod"`
+	DeliveredAt   *time.Time      `json:"deliveredAt,omitempty"`
+	LineItems     []PriceLineItem `json:"lineItems,omitempty"`
+	DeliveryTotal float64         `json:"deliveryTotal"`
+	Tips          []Tip           `json:"tips,omitempty"`
+	TipTotal      float64         `json:"tipTotal"`
+	Total         float64         `json:"total"`
+}
+
+// DeliveryEarning represents what a driver earned on one delivery
+type DeliveryEarning struct {
+	DeliveryID  string     `json:"deliveryId"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+	Gross       float64    `json:"gross"`
+	Commission  float64    `json:"commission"`
+	ServiceFee  float64    `json:"serviceFee"`
+	Net         float64    `json:"net"`
+	Tips        float64    `json:"tips"`
+}
+
+// DriverEarnings represents a driver's earnings over a period
+type DriverEarnings struct {
+	DriverID      string            `json:"driverId"`
+	From          time.Time         `json:"from"`
+	To            time.Time         `json:"to"`
+	DeliveryCount int               `json:"deliveryCount"`
+	Gross         float64           `json:"gross"`
+	Commission    float64           `json:"commission"`
+	ServiceFees   float64           `json:"serviceFees"`
+	Net           float64           `json:"net"`
+	Tips          float64           `json:"tips"`
+	Bonuses       float64           `json:"bonuses"`
+	Penalties     float64           `json:"penalties"`
+	Total         float64           `json:"total"` // Net earnings plus tips and bonuses, less penalties
+	Deliveries    []DeliveryEarning `json:"deliveries"`
+}
+
+// DriverEarningsRequest represents the period of a driver earnings query
+type DriverEarningsRequest struct {
+	From time.Time `form:"from" time_format:"2006-01-02"`
+	To   time.Time `form:"to" time_format:"2006-01-02"` // Exclusive
+}
+
+// CanTip checks a tip can be given on the delivery at the given time, with the amount already tipped.
+// Tips are accepted while the driver is on the job and during the window after delivery,
+// a parcel returned to the sender is not tipped.
+func (p *TipPolicy) CanTip(d *Delivery, alreadyTipped, amount float64, now time.Time) error {
+	if d.LivreurID == nil {
+		return fmt.Errorf("delivery has no driver to tip")
+	}
+
+	switch d.Status {
+	case DeliveryStatusPending, DeliveryStatusCancelled, DeliveryStatusReturned:
+		return fmt.Errorf("delivery cannot be tipped in status %s", d.Status)
+	case DeliveryStatusDelivered:
+		deliveredAt := d.UpdatedAt
+		if d.DeliveredAt != nil {
+			deliveredAt = *d.DeliveredAt
+		}
+		if now.Sub(deliveredAt) > p.Window {
+			return fmt.Errorf("tipping window closed %s after delivery", p.Window)
+		}
+	}
+
+	if p.MaxAmount > 0 && alreadyTipped+amount > p.MaxAmount {
+		return fmt.Errorf("tips are limited to %.0f FCFA per delivery", p.MaxAmount)
+	}
+
+	return nil
+}
+
+// NewDeliveryReceipt builds the receipt of a delivery with its tips
+func NewDeliveryReceipt(d *Delivery, tips []Tip) *DeliveryReceipt {
+	receipt := &DeliveryReceipt{
+		DeliveryID:    d.ID,
+		Status:        d.Status,
+		PaymentMethod: d.PaymentMethod,
+		DeliveredAt:   d.DeliveredAt,
+		LineItems:     d.PriceLineItems,
+		DeliveryTotal: d.FinalPrice,
+		Tips:          tips,
+	}
+
+	for _, tip := range tips {
+		receipt.TipTotal += tip.Amount
+	}
+	receipt.Total = receipt.DeliveryTotal + receipt.TipTotal
+
+	return receipt
+}
+
+// NewDeliveryEarning splits the price of a delivery between the platform and the driver.
+// The commission only applies to the price, tips go to the driver in full.
+func NewDeliveryEarning(d *Delivery, commissionRate float64) DeliveryEarning {
+	earning := DeliveryEarning{
+		DeliveryID:  d.ID,
+		DeliveredAt: d.DeliveredAt,
+		Gross:       d.FinalPrice,
+		Commission:  math.Round(d.FinalPrice * commissionRate),
+	}
+	earning.Net = earning.Gross - earning.Commission
+	if d.TipAmount != nil {
+		earning.Tips = *d.TipAmount
+	}
+
+	return earning
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Random bytes in a tracking share token
+const shareTokenBytes = 24
+
+// TrackingShare represents a public tracking link of a delivery, for a recipient without an account.
+// Only the hash of the token is stored, the token itself is shown once when the link is created.
+type TrackingShare struct {
+	ID         string     `json:"id"`
+	DeliveryID string     `json:"deliveryId"`
+	CreatedBy  string     `json:"createdBy"`
+	TokenHash  string     `json:"-"`
+	Token      string     `json:"token,omitempty"` // Only set on creation
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// CreateTrackingShareRequest represents request for creating a public tracking link
+type CreateTrackingShareRequest struct {
+	ExpiresInHours *int `json:"expiresInHours,omitempty" validate:"omitempty,gte=1"`
+}
+
+// PublicVehicle represents what a recipient sees of the vehicle, enough to recognise it
+type PublicVehicle struct {
+	Type                  VehicleType `json:"type"`
+	Marque                *string     `json:"marque,omitempty"`
+	Modele                *string     `json:"modele,omitempty"`
+	Couleur               *string     `json:"couleur,omitempty"`
+	PlaqueImmatriculation *string     `json:"plaqueImmatriculation,omitempty"`
+}
+
+// PublicTracking represents the limited view of a delivery behind a tracking link.
+// It carries no address, phone number, price or client data.
+type PublicTracking struct {
+	Status            DeliveryStatus `json:"status"`
+	DriverFirstName   *string        `json:"driverFirstName,omitempty"`
+	Vehicle           *PublicVehicle `json:"vehicle,omitempty"`
+	DropoffETA        *time.Time     `json:"dropoffEta,omitempty"`
+	DropoffMin        *float64       `json:"dropoffMin,omitempty"`
+	DriverLat         *float64       `json:"driverLat,omitempty"` // Only while the parcel is on its way
+	DriverLng         *float64       `json:"driverLng,omitempty"`
+	LocationUpdatedAt *time.Time     `json:"locationUpdatedAt,omitempty"`
+	DeliveredAt       *time.Time     `json:"deliveredAt,omitempty"`
+	ExpiresAt         time.Time      `json:"expiresAt"`
+}
+
+// GenerateShareToken returns a new unguessable token and its hash
+func GenerateShareToken() (string, string, error) {
+	buf := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %v", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashShareToken(token), nil
+}
+
+// HashShareToken returns the stored form of a token
+func HashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsActive checks if the link can still be used
+func (s *TrackingShare) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// CanBeShared checks if a tracking link can be created for the delivery
+func (d *Delivery) CanBeShared() bool {
+	return d.Status != DeliveryStatusDelivered && d.Status != DeliveryStatusCancelled && d.Status != DeliveryStatusReturned
+}
+
+// SharesLivePosition checks if the driver position is shown to the recipient in this status
+func (s DeliveryStatus) SharesLivePosition() bool {
+	switch s {
+	case DeliveryStatusPickedUp, DeliveryStatusInTransit, DeliveryStatusDeliveryInProgress,
+		DeliveryStatusEnRoute, DeliveryStatusArrivedAtDropoff:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewPublicTracking builds the recipient view from the full tracking of a delivery
+func NewPublicTracking(d *Delivery, driver *User, vehicle *Vehicle, tracking *DeliveryTracking, share *TrackingShare) *PublicTracking {
+	public := &PublicTracking{
+		Status:      d.Status,
+		DeliveredAt: d.DeliveredAt,
+		ExpiresAt:   share.ExpiresAt,
+	}
+
+	// Finished deliveries only show their outcome
+	if !d.CanBeShared() {
+		return public
+	}
+
+	if driver != nil && driver.FirstName != "" {
+		firstName := driver.FirstName
+		public.DriverFirstName = &firstName
+	}
+	if vehicle != nil {
+		public.Vehicle = &PublicVehicle{
+			Type:                  vehicle.Type,
+			Marque:                vehicle.Marque,
+			Modele:                vehicle.Modele,
+			Couleur:               vehicle.Couleur,
+			PlaqueImmatriculation: vehicle.PlaqueImmatriculation,
+		}
+	}
+
+	if tracking == nil {
+		return public
+	}
+	if tracking.ETA != nil {
+		dropoffETA, dropoffMin := tracking.ETA.DropoffETA, tracking.ETA.DropoffMin
+		public.DropoffETA = &dropoffETA
+		public.DropoffMin = &dropoffMin
+	}
+	if d.Status.SharesLivePosition() {
+		public.DriverLat = tracking.DriverLat
+		public.DriverLng = tracking.DriverLng
+		public.LocationUpdatedAt = tracking.LocationUpdate
+	}
+
+	return public
+}
@@ -51,6 +51,15 @@ type User struct {
 	LastKnownLat              *float64   `json:"lastKnownLat,omitempty" validate:"omitempty,gte=-90,lte=90"`
 	LastKnownLng              *float64   `json:"lastKnownLng,omitempty" validate:"omitempty,gte=-180,lte=180"`
 	LastSeenAt                *time.Time `json:"lastSeenAt,omitempty"`
+	DriverAcceptedCount       int        `json:"driverAcceptedCount"`
+	DriverCancelledCount      int        `json:"driverCancelledCount"`
+	ReliabilityScore          *float64   `json:"reliabilityScore,omitempty"`
+	RatingAverage             *float64   `json:"ratingAverage,omitempty"`
+	RatingCount               int        `json:"ratingCount"`
+	RatingReviewOpenedAt      *time.Time `json:"ratingReviewOpenedAt,omitempty"` // Set when a low average triggered a review
+	CashDue                   float64    `json:"cashDue"` // Cash on delivery and commission not yet remitted, mirrors the cash ledger
+	LastJobEndedAt            *time.Time `json:"lastJobEndedAt,omitempty"` // End of the last delivery, for dispatch fairness
+	DispatchPreferences       *DriverDispatchPreferences `json:"dispatchPreferences,omitempty"`
 }
 
 // CreateUserRequest represents request for creating a user
@@ -105,6 +114,9 @@ type UserResponse struct {
 	LastKnownLat            *float64      `json:"lastKnownLat,omitempty"`
 	LastKnownLng            *float64      `json:"lastKnownLng,omitempty"`
 	LastSeenAt              *time.Time    `json:"lastSeenAt,omitempty"`
+	ReliabilityScore        *float64      `json:"reliabilityScore,omitempty"`
+	RatingAverage           *float64      `json:"ratingAverage,omitempty"`
+	RatingCount             int           `json:"ratingCount"`
 }
 
 // IsValidRole checks if the role is valid
@@ -119,6 +131,11 @@ func (s DriverStatus) IsValid() bool {
 		   s == DriverStatusBusy || s == DriverStatusAvailable
 }
 
+// IsAvailable checks if a driver with this status can be offered deliveries
+func (s DriverStatus) IsAvailable() bool {
+	return s == DriverStatusOnline || s == DriverStatusAvailable
+}
+
 // CanAcceptDeliveries checks if user can accept deliveries
 func (u *User) CanAcceptDeliveries() bool {
 	return u.Role == UserRoleLivreur && 
@@ -142,6 +159,19 @@ func (u *User) IsAdmin() bool {
 	return u.Role == UserRoleAdmin || u.Role == UserRoleGestionnaire
 }
 
+// ComputeReliabilityScore returns the driver reliability score (0-100) based on cancellations after acceptance
+func (u *User) ComputeReliabilityScore() float64 {
+	if u.DriverAcceptedCount == 0 {
+		return 100
+	}
+
+	score := 100 * (1 - float64(u.DriverCancelledCount)/float64(u.DriverAcceptedCount))
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
 // GetFullName returns the full name of the user
 func (u *User) GetFullName() string {
 	return u.FirstName + " " + u.LastName
@@ -166,6 +196,8 @@ func (u *User) ToResponse() *UserResponse {
 		IsProfileCompleted:      u.IsProfileCompleted,
 		IsDriverComplete:        u.IsDriverComplete,
 		IsDriverVehiculeComplete: u.IsDriverVehiculeComplete,
+		RatingAverage:           u.RatingAverage,
+		RatingCount:             u.RatingCount,
 	}
 
 	// Only include driver-specific fields for drivers
@@ -174,6 +206,7 @@ func (u *User) ToResponse() *UserResponse {
 		resp.LastKnownLat = u.LastKnownLat
 		resp.LastKnownLng = u.LastKnownLng
 		resp.LastSeenAt = u.LastSeenAt
+		resp.ReliabilityScore = u.ReliabilityScore
 	}
 
 	return resp
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+)
+
+// WaitingStop defines where the driver is waiting
+type WaitingStop string
+
+const (
+	WaitingStopPickup  WaitingStop = "PICKUP"
+	WaitingStopDropoff WaitingStop = "DROPOFF"
+)
+
+// WaitingTimer represents the live waiting clock shown to the client
+type WaitingTimer struct {
+	DeliveryID       string      `json:"deliveryId"`
+	Active           bool        `json:"active"`
+	Stop             WaitingStop `json:"stop,omitempty"`
+	StartedAt        *time.Time  `json:"startedAt,omitempty"`
+	ElapsedMin       float64     `json:"elapsedMin"`
+	FreeMin          int         `json:"freeMin"`
+	RemainingFreeMin float64     `json:"remainingFreeMin"`
+	RatePerMin       float64     `json:"ratePerMin"`
+	CurrentCharge    float64     `json:"currentCharge"`
+	Warning          *string     `json:"warning,omitempty"`
+}
+
+// WaitingStopFor returns the stop a driver waits at for the given status, if any
+func WaitingStopFor(status DeliveryStatus) (WaitingStop, bool) {
+	switch status {
+	case DeliveryStatusArrivedAtPickup:
+		return WaitingStopPickup, true
+	case DeliveryStatusArrivedAtDropoff:
+		return WaitingStopDropoff, true
+	default:
+		return "", false
+	}
+}
+
+// WaitingMinutes returns the total measured waiting time across stops
+func (d *Delivery) WaitingMinutes() float64 {
+	total := 0.0
+	if d.PickupWaitingMin != nil {
+		total += *d.PickupWaitingMin
+	}
+	if d.DropoffWaitingMin != nil {
+		total += *d.DropoffWaitingMin
+	}
+	return total
+}
+
+// NewWaitingTimer builds the waiting clock for a stop from the pricing rule
+func NewWaitingTimer(deliveryID string, rule *PricingRule, stop WaitingStop, startedAt, now time.Time) *WaitingTimer {
+	elapsed := now.Sub(startedAt).Minutes()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	timer := &WaitingTimer{
+		DeliveryID:    deliveryID,
+		Active:        true,
+		Stop:          stop,
+		StartedAt:     &startedAt,
+		ElapsedMin:    elapsed,
+		FreeMin:       rule.WaitingFree,
+		RatePerMin:    rule.WaitingRate,
+		CurrentCharge: rule.CalculateWaitingPrice(elapsed),
+	}
+
+	timer.RemainingFreeMin = float64(rule.WaitingFree) - elapsed
+	if timer.RemainingFreeMin <= 0 {
+		timer.RemainingFreeMin = 0
+		warning := "Free waiting time is over: waiting is now charged per minute"
+		timer.Warning = &warning
+	}
+
+	return timer
+}
@@ -0,0 +1,226 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// GeoJSONPolygon represents a GeoJSON Polygon geometry, positions are [lng, lat]
+type GeoJSONPolygon struct {
+	Type        string        `json:"type" validate:"required,eq=Polygon"`
+	Coordinates [][][]float64 `json:"coordinates" validate:"required,min=1"` // Outer ring first, then holes
+}
+
+// ServiceZone represents an admin-managed area where deliveries are operated
+type ServiceZone struct {
+	ID              string           `json:"id"`
+	Name            string           `json:"name" validate:"required"`
+	Polygon         GeoJSONPolygon   `json:"polygon" validate:"required"`
+	IsActive        bool             `json:"isActive"`
+	PriceAdjustment float64          `json:"priceAdjustment"`           // Percentage of the subtotal, can be negative
+	FixedAdjustment float64          `json:"fixedAdjustment"`           // Fixed amount in FCFA, can be negative
+	DispatchWeights *DispatchWeights `json:"dispatchWeights,omitempty"` // Driver scoring weights, the default ones when nil
+	CreatedAt       time.Time        `json:"createdAt"`
+	UpdatedAt       time.Time        `json:"updatedAt"`
+}
+
+// CreateServiceZoneRequest represents request for creating a service zone
+type CreateServiceZoneRequest struct {
+	Name            string           `json:"name" validate:"required,min=2,max=100"`
+	Polygon         GeoJSONPolygon   `json:"polygon" validate:"required"`
+	IsActive        *bool            `json:"isActive,omitempty"`
+	PriceAdjustment float64          `json:"priceAdjustment" validate:"gte=-100,lte=100"`
+	FixedAdjustment float64          `json:"fixedAdjustment"`
+	DispatchWeights *DispatchWeights `json:"dispatchWeights,omitempty"`
+}
+
+// UpdateServiceZoneRequest represents request for updating a service zone
+type UpdateServiceZoneRequest struct {
+	Name            *string          `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	Polygon         *GeoJSONPolygon  `json:"polygon,omitempty"`
+	IsActive        *bool            `json:"isActive,omitempty"`
+	PriceAdjustment *float64         `json:"priceAdjustment,omitempty" validate:"omitempty,gte=-100,lte=100"`
+	FixedAdjustment *float64         `json:"fixedAdjustment,omitempty"`
+	DispatchWeights *DispatchWeights `json:"dispatchWeights,omitempty"`
+}
+
+// ZoneRef is the short zone description exposed on quotes
+type ZoneRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ZoneMembership describes which zones the pickup and dropoff fall into
+type ZoneMembership struct {
+	PickupZone     *ZoneRef `json:"pickupZone,omitempty"`
+	DropoffZone    *ZoneRef `json:"dropoffZone,omitempty"`
+	PickupCovered  bool     `json:"pickupCovered"`
+	DropoffCovered bool     `json:"dropoffCovered"`
+	Covered        bool     `json:"covered"`
+	CrossZone      bool     `json:"crossZone"`
+	Message        string   `json:"message,omitempty"`
+}
+
+// Validate checks the polygon is a well-formed GeoJSON polygon
+func (p *GeoJSONPolygon) Validate() error {
+	if p.Type != "Polygon" {
+		return fmt.Errorf("geometry type must be Polygon")
+	}
+	if len(p.Coordinates) == 0 {
+		return fmt.Errorf("polygon has no rings")
+	}
+
+	for i, ring := range p.Coordinates {
+		if len(ring) < 4 {
+			return fmt.Errorf("ring %d must have at least 4 positions", i)
+		}
+		for _, position := range ring {
+			if len(position) < 2 {
+				return fmt.Errorf("ring %d has an invalid position", i)
+			}
+			if position[0] < -180 || position[0] > 180 || position[1] < -90 || position[1] > 90 {
+				return fmt.Errorf("ring %d has out of range coordinates", i)
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return fmt.Errorf("ring %d is not closed", i)
+		}
+	}
+
+	return nil
+}
+
+// Contains checks if a point is inside the outer ring and outside every hole
+func (p *GeoJSONPolygon) Contains(lat, lng float64) bool {
+	if len(p.Coordinates) == 0 || !ringContains(p.Coordinates[0], lat, lng) {
+		return false
+	}
+
+	for _, hole := range p.Coordinates[1:] {
+		if ringContains(hole, lat, lng) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ringContains uses ray casting on a ring of [lng, lat] positions
+func ringContains(ring [][]float64, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Ref returns the short zone description
+func (z *ServiceZone) Ref() *ZoneRef {
+	return &ZoneRef{ID: z.ID, Name: z.Name}
+}
+
+// AdjustmentFor returns the zone price adjustment for a subtotal
+func (z *ServiceZone) AdjustmentFor(subTotal float64) float64 {
+	return subTotal*z.PriceAdjustment/100 + z.FixedAdjustment
+}
+
+// FindZone returns the first active zone containing the point
+func FindZone(zones []ServiceZone, lat, lng float64) *ServiceZone {
+	for i := range zones {
+		if zones[i].IsActive && zones[i].Polygon.Contains(lat, lng) {
+			return &zones[i]
+		}
+	}
+	return nil
+}
+
+// ResolveZoneMembership finds the pickup and dropoff zones. With no active zone configured,
+// or without coordinates, coverage cannot be checked and the address is considered covered.
+func ResolveZoneMembership(zones []ServiceZone, pickupLat, pickupLng, dropoffLat, dropoffLng *float64) (*ZoneMembership, *ServiceZone, *ServiceZone) {
+	membership := &ZoneMembership{PickupCovered: true, DropoffCovered: true}
+
+	hasActive := false
+	for _, zone := range zones {
+		if zone.IsActive {
+			hasActive = true
+			break
+		}
+	}
+
+	if !hasActive {
+		membership.Covered = true
+		return membership, nil, nil
+	}
+
+	var pickupZone, dropoffZone *ServiceZone
+	if pickupLat != nil && pickupLng != nil {
+		pickupZone = FindZone(zones, *pickupLat, *pickupLng)
+		membership.PickupCovered = pickupZone != nil
+		if pickupZone != nil {
+			membership.PickupZone = pickupZone.Ref()
+		}
+	}
+	if dropoffLat != nil && dropoffLng != nil {
+		dropoffZone = FindZone(zones, *dropoffLat, *dropoffLng)
+		membership.DropoffCovered = dropoffZone != nil
+		if dropoffZone != nil {
+			membership.DropoffZone = dropoffZone.Ref()
+		}
+	}
+
+	membership.Covered = membership.PickupCovered && membership.DropoffCovered
+	membership.CrossZone = pickupZone != nil && dropoffZone != nil && pickupZone.ID != dropoffZone.ID
+
+	switch {
+	case !membership.PickupCovered && !membership.DropoffCovered:
+		membership.Message = "Pickup and dropoff addresses are outside our service area"
+	case !membership.PickupCovered:
+		membership.Message = "Pickup address is outside our service area"
+	case !membership.DropoffCovered:
+		membership.Message = "Dropoff address is outside our service area"
+	}
+
+	return membership, pickupZone, dropoffZone
+}
+
+// ApplyZoneAdjustments adds the pickup and dropoff zone adjustments and the cross-zone fee
+func (c *PriceCalculation) ApplyZoneAdjustments(pickupZone, dropoffZone *ServiceZone, crossZoneFee float64) {
+	subTotal := c.SubTotal
+
+	zones := []*ServiceZone{pickupZone}
+	if dropoffZone != nil && (pickupZone == nil || dropoffZone.ID != pickupZone.ID) {
+		zones = append(zones, dropoffZone)
+	}
+
+	for _, zone := range zones {
+		if zone == nil {
+			continue
+		}
+		if amount := zone.AdjustmentFor(subTotal); amount != 0 {
+			c.AddLineItem(PriceLineItem{
+				Code:   "ZONE_ADJUSTMENT",
+				Label:  "Ajustement zone " + zone.Name,
+				Amount: amount,
+			})
+		}
+	}
+
+	if pickupZone != nil && dropoffZone != nil && pickupZone.ID != dropoffZone.ID && crossZoneFee != 0 {
+		c.AddLineItem(PriceLineItem{
+			Code:   "CROSS_ZONE",
+			Label:  "Trajet inter-zones",
+			Amount: crossZoneFee,
+		})
+	}
+
+	// A zone discount never takes the price below zero
+	if c.FinalPrice < 0 {
+		c.FinalPrice = 0
+	}
+}
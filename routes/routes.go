@@ -3,6 +3,7 @@ package routes
 import (
 	"time"
 
+	"github.com/ambroise1219/livraison_go/config"
 	"github.com/ambroise1219/livraison_go/handlers"
 	"github.com/ambroise1219/livraison_go/middlewares"
 	"github.com/gin-gonic/gin"
@@ -72,6 +73,13 @@ func setupPublicRoutes(rg *gin.RouterGroup) {
 		// Valider un code promo (optionnel: authentifié pour l'associer à un utilisateur)
 		promo.POST("/validate", middlewares.OptionalAuthMiddleware(), handlers.ValidatePromoCode)
 	}
+
+	// Suivi public par lien partagé (destinataires sans compte), avec sa propre limite de débit
+	track := rg.Group("/track")
+	track.Use(middlewares.RateLimitMiddleware(config.GetConfig().PublicTrackingRateLimit, time.Minute))
+	{
+		track.GET("/:token", handlers.GetPublicTracking)
+	}
 }
 
 // setupProtectedRoutes configure les routes protégées
@@ -124,6 +132,13 @@ func setupUserRoutes(rg *gin.RouterGroup) {
 		users.GET("/:user_id/vehicles", middlewares.RequireResourceOwner("user_id"), middlewares.RequireDriverOrAdmin(), handlers.GetUserVehicles)
 		users.POST("/:user_id/vehicles", middlewares.RequireResourceOwner("user_id"), middlewares.RequireDriver(), handlers.CreateVehicle)
 		users.PUT("/:user_id/vehicles/:vehicle_id", middlewares.RequireResourceOwner("user_id"), middlewares.RequireDriver(), handlers.UpdateVehicle)
+		
+		// Carnet d'adresses du client (adresses enregistrées et suggestions récentes)
+		users.GET("/:user_id/addresses", middlewares.RequireResourceOwner("user_id"), handlers.GetSavedAddresses)
+		users.GET("/:user_id/addresses/suggestions", middlewares.RequireResourceOwner("user_id"), handlers.GetAddressSuggestions)
+		users.POST("/:user_id/addresses", middlewares.RequireResourceOwner("user_id"), handlers.CreateSavedAddress)
+		users.PUT("/:user_id/addresses/:address_id", middlewares.RequireResourceOwner("user_id"), handlers.UpdateSavedAddress)
+		users.DELETE("/:user_id/addresses/:address_id", middlewares.RequireResourceOwner("user_id"), handlers.DeleteSavedAddress)
 	}
 }
 
@@ -134,9 +149,36 @@ func setupDeliveryRoutes(rg *gin.RouterGroup) {
 		// Création de livraison (clients seulement)
 		delivery.POST("/", middlewares.RequireClientOrAdmin(), handlers.CreateDelivery)
 		
+		// Import en masse (CSV ou JSON lines) traité en arrière-plan, dryRun=true pour valider seulement
+		delivery.POST("/import", middlewares.RequireClient(), handlers.ImportDeliveries)
+		delivery.GET("/import/:job_id", handlers.GetDeliveryImport)
+		
+		// Géocodage d'une adresse libre et géocodage inverse (quartiers et repères connus)
+		delivery.GET("/geocode", handlers.GeocodeAddress)
+		delivery.GET("/geocode/reverse", handlers.ReverseGeocode)
+		
 		// Récupération des détails d'une livraison
 		delivery.GET("/:delivery_id", handlers.GetDelivery) // Validation de propriété dans le handler
 		
+		// Chronomètre d'attente du livreur (client, livreur assigné ou admin)
+		delivery.GET("/:delivery_id/waiting", handlers.GetWaitingTimer)
+		
+		// Historique des statuts (client, livreur assigné ou admin)
+		delivery.GET("/:delivery_id/history", handlers.GetDeliveryHistory)
+		
+		// Expéditeur et destinataire (téléphones visibles du livreur seulement pendant la course)
+		delivery.GET("/:delivery_id/contacts", handlers.GetDeliveryContacts)
+		
+		// Tentatives de livraison échouées (motif, preuves, prochaine tentative)
+		delivery.GET("/:delivery_id/attempts", handlers.GetDeliveryAttempts)
+		
+		// Notation croisée client/livreur après livraison
+		delivery.POST("/:delivery_id/rating", handlers.RateDelivery)
+		delivery.GET("/:delivery_id/ratings", handlers.GetDeliveryRatings)
+		
+		// Reçu (prix et pourboires séparés)
+		delivery.GET("/:delivery_id/receipt", handlers.GetDeliveryReceipt)
+		
 		// Mise à jour du statut (livreurs et admins)
 		delivery.PATCH("/:delivery_id/status", middlewares.RequireDriverOrAdmin(), handlers.UpdateDeliveryStatus)
 		
@@ -153,11 +195,47 @@ func setupDeliveryRoutes(rg *gin.RouterGroup) {
 			// Livraisons assignées au livreur
 			driverRoutes.GET("/assigned", handlers.GetAssignedDeliveries)
 			
+			// Historique des livraisons du livreur (filtres et pagination par curseur)
+			driverRoutes.GET("/history", handlers.GetDriverDeliveries)
+			
+			// Gains du livreur (commission et pourboires séparés, relevés par jour ou par semaine)
+			driverRoutes.GET("/earnings", handlers.GetDriverEarnings)
+			driverRoutes.GET("/earnings/daily", handlers.GetDriverDailyEarnings)
+			driverRoutes.GET("/earnings/weekly", handlers.GetDriverWeeklyEarnings)
+			
+			// Espèces détenues (paiement à la livraison, commission due)
+			driverRoutes.GET("/cash", handlers.GetDriverCashBalance)
+			
+			// Préférences de dispatch (zones, types de livraison, distance maximale)
+			driverRoutes.GET("/preferences", handlers.GetDispatchPreferences)
+			driverRoutes.PUT("/preferences", handlers.UpdateDispatchPreferences)
+			
+			// Disponibilités publiées et créneaux planifiés du livreur
+			driverRoutes.GET("/availability", handlers.GetAvailabilitySlots)
+			driverRoutes.POST("/availability", handlers.CreateAvailabilitySlot)
+			driverRoutes.DELETE("/availability/:slot_id", handlers.DeleteAvailabilitySlot)
+			driverRoutes.GET("/shifts", handlers.GetDriverShifts)
+			
+			// Position du livreur hors livraison, pour être proposé au dispatch
+			driverRoutes.POST("/location", handlers.UpdateDriverPosition)
+			
 			// Accepter une livraison
 			driverRoutes.POST("/:delivery_id/accept", handlers.AcceptDelivery)
 			
 			// Mettre à jour la position
 			driverRoutes.POST("/:delivery_id/location", handlers.UpdateDriverLocation)
+			
+			// Annuler une arrivée détectée automatiquement (geofence)
+			driverRoutes.POST("/:delivery_id/arrival/undo", handlers.UndoAutoArrival)
+			
+			// Confirmer l'encaissement du paiement à la livraison
+			driverRoutes.POST("/:delivery_id/cod/collect", handlers.CollectCashOnDelivery)
+			
+			// Signaler une tentative échouée (nouvelle tentative ou retour à l'expéditeur)
+			driverRoutes.POST("/:delivery_id/attempt/fail", handlers.FailDeliveryAttempt)
+			
+			// Se désister d'une livraison (motif obligatoire)
+			driverRoutes.POST("/:delivery_id/cancel", handlers.CancelDelivery)
 		}
 		
 		// Routes spécifiques aux clients
@@ -167,11 +245,22 @@ func setupDeliveryRoutes(rg *gin.RouterGroup) {
 			// Livraisons du client
 			clientRoutes.GET("/", handlers.GetClientDeliveries)
 			
-			// Annuler une livraison
+			// Frais d'annulation avant confirmation
+			clientRoutes.GET("/:delivery_id/cancel/quote", handlers.GetCancellationQuote)
+			
+			// Annuler une livraison (motif obligatoire, confirm=false renvoie les frais)
 			clientRoutes.POST("/:delivery_id/cancel", handlers.CancelDelivery)
 			
 			// Suivre une livraison
 			clientRoutes.GET("/:delivery_id/track", handlers.TrackDelivery)
+			
+			// Liens de suivi publics pour le destinataire (expirables et révocables)
+			clientRoutes.POST("/:delivery_id/share", handlers.CreateTrackingShare)
+			clientRoutes.GET("/:delivery_id/shares", handlers.GetTrackingShares)
+			clientRoutes.DELETE("/:delivery_id/shares/:share_id", handlers.RevokeTrackingShare)
+			
+			// Pourboire au livreur (pendant la course ou dans le délai après livraison)
+			clientRoutes.POST("/:delivery_id/tip", handlers.TipDelivery)
 		}
 	}
 }
@@ -200,6 +289,9 @@ func setupPromoRoutes(rg *gin.RouterGroup) {
 
 // setupAdminRoutes configure les routes administrateur
 func setupAdminRoutes(rg *gin.RouterGroup) {
+	// Annulation d'une livraison, ouverte aussi aux gestionnaires (hors du groupe réservé aux admins)
+	rg.POST("/admin/deliveries/:delivery_id/cancel", middlewares.RequireAdminOrGestionnaire(), handlers.CancelDelivery)
+
 	admin := rg.Group("/admin")
 	admin.Use(middlewares.RequireAdmin())
 	{
@@ -218,6 +310,8 @@ func setupAdminRoutes(rg *gin.RouterGroup) {
 			deliveries.GET("/", handlers.GetAllDeliveries)
 			deliveries.GET("/stats", handlers.GetDeliveryStats)
 			deliveries.POST("/:delivery_id/assign/:driver_id", handlers.ForceAssignDelivery)
+			deliveries.GET("/:delivery_id/replay", handlers.GetDeliveryReplay) // Trajet GeoJSON horodaté et distance parcourue
+			deliveries.GET("/:delivery_id/dispatch", handlers.GetDispatchDecisions) // Scores des livreurs à chaque dispatch
 		}
 		
 		// Gestion des livreurs
@@ -226,6 +320,21 @@ func setupAdminRoutes(rg *gin.RouterGroup) {
 			drivers.GET("/", handlers.GetAllDrivers)
 			drivers.GET("/:driver_id/stats", handlers.GetDriverStats)
 			drivers.PUT("/:driver_id/status", handlers.UpdateDriverStatus)
+			drivers.GET("/cash", handlers.GetDriversCashDue)
+			drivers.GET("/:driver_id/cash", handlers.GetDriverCash)
+			drivers.POST("/:driver_id/cash/remittances", handlers.RecordCashRemittance)
+			drivers.POST("/:driver_id/earnings/adjustments", handlers.RecordEarningAdjustment) // Bonus ou pénalité
+			drivers.GET("/:driver_id/track", handlers.GetDriverTrack) // Positions sur une période (from/to RFC3339, 24 h max)
+		}
+		
+		// Planning des livreurs (créneaux par zone, effectif minimum, prévu / réalisé)
+		shifts := admin.Group("/shifts")
+		{
+			shifts.GET("/", handlers.GetShifts)
+			shifts.POST("/", handlers.CreateShift)
+			shifts.GET("/report", handlers.GetShiftReport)
+			shifts.PUT("/:shift_id", handlers.UpdateShift)
+			shifts.DELETE("/:shift_id", handlers.DeleteShift)
 		}
 		
 		// Gestion des promotions
@@ -238,6 +347,49 @@ func setupAdminRoutes(rg *gin.RouterGroup) {
 			promotions.GET("/:promo_id/stats", handlers.GetPromotionStats)
 		}
 		
+		// Zones de service (polygones GeoJSON et ajustements de prix)
+		zones := admin.Group("/zones")
+		{
+			zones.GET("/", handlers.GetServiceZones)
+			zones.POST("/", handlers.CreateServiceZone)
+			zones.PUT("/:zone_id", handlers.UpdateServiceZone)
+			zones.DELETE("/:zone_id", handlers.DeleteServiceZone)
+		}
+		
+		// Répertoire des quartiers et repères utilisé pour le géocodage
+		gazetteer := admin.Group("/gazetteer")
+		{
+			gazetteer.GET("/", handlers.GetGazetteerEntries)
+			gazetteer.POST("/", handlers.CreateGazetteerEntry)
+			gazetteer.PUT("/:entry_id", handlers.UpdateGazetteerEntry)
+			gazetteer.DELETE("/:entry_id", handlers.DeleteGazetteerEntry)
+		}
+		
+		// Majoration dynamique (offre/demande par zone et forçages admin)
+		surge := admin.Group("/surge")
+		{
+			surge.GET("/", handlers.GetSurgeStates)
+			surge.POST("/overrides", handlers.CreateSurgeOverride)
+			surge.DELETE("/overrides/:override_id", handlers.DeleteSurgeOverride)
+		}
+		
+		// Tarification (versions des règles, majorations horaires et jours fériés)
+		pricing := admin.Group("/pricing")
+		{
+			pricing.GET("/rules", handlers.GetPricingRules)
+			pricing.POST("/rules", handlers.CreatePricingRule)
+			pricing.GET("/rules/:vehicle_type/history", handlers.GetPricingRuleHistory)
+			pricing.DELETE("/rules/:rule_id", handlers.CancelPricingRule)
+			pricing.POST("/simulate", handlers.SimulatePricingRule)
+			pricing.GET("/surcharges", handlers.GetTimeSurcharges)
+			pricing.POST("/surcharges", handlers.CreateTimeSurcharge)
+			pricing.PUT("/surcharges/:rule_id", handlers.UpdateTimeSurcharge)
+			pricing.DELETE("/surcharges/:rule_id", handlers.DeleteTimeSurcharge)
+			pricing.GET("/holidays", handlers.GetHolidays)
+			pricing.POST("/holidays", handlers.CreateHoliday)
+			pricing.DELETE("/holidays/:holiday_id", handlers.DeleteHoliday)
+		}
+		
 		// Gestion des véhicules
 		vehicles := admin.Group("/vehicles")
 		{
@@ -249,7 +401,7 @@ func setupAdminRoutes(rg *gin.RouterGroup) {
 		stats := admin.Group("/stats")
 		{
 			stats.GET("/dashboard", handlers.GetDashboardStats)
-			stats.GET("/revenue", handlers.GetRevenueStats)
+			stats.GET("/revenue", handlers.GetRevenueStats) // Commission et frais de service (from/to en YYYY-MM-DD)
 			stats.GET("/users", handlers.GetUserStats)
 		}
 	}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/config"
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// Number of past deliveries scanned for recently used addresses
+const recentDeliveriesScanned = 20
+
+type AddressService struct {
+	config   *config.Config
+	geocoder Geocoder
+}
+
+func NewAddressService(cfg *config.Config, geocoder Geocoder) *AddressService {
+	return &AddressService{
+		config:   cfg,
+		geocoder: geocoder,
+	}
+}
+
+// CreateAddress saves an address in the user's address book, geocoding it when no pin was dropped
+func (s *AddressService) CreateAddress(userID string, req *models.CreateSavedAddressRequest) (*models.SavedAddress, error) {
+	address := &models.SavedAddress{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		Label:        strings.TrimSpace(req.Label),
+		Address:      strings.TrimSpace(req.Address),
+		Lat:          req.Lat,
+		Lng:          req.Lng,
+		Instructions: req.Instructions,
+		Contact:      req.Contact,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if err := s.locate(address); err != nil {
+		return nil, err
+	}
+
+	query := `CREATE SavedAddress SET 
+		id = $id,
+		userId = $userId,
+		label = $label,
+		address = $address,
+		lat = $lat,
+		lng = $lng,
+		geocodeConfidence = $geocodeConfidence,
+		instructions = $instructions,
+		contact = $contact,
+		useCount = 0,
+		createdAt = $createdAt,
+		updatedAt = $updatedAt`
+
+	params := map[string]interface{}{
+		"id":                address.ID,
+		"userId":            address.UserID,
+		"label":             address.Label,
+		"address":           address.Address,
+		"lat":               address.Lat,
+		"lng":               address.Lng,
+		"geocodeConfidence": address.GeocodeConfidence,
+		"instructions":      address.Instructions,
+		"contact":           address.Contact,
+		"createdAt":         address.CreatedAt,
+		"updatedAt":         address.UpdatedAt,
+	}
+
+	_, err := db.Query(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save address: %v", err)
+	}
+
+	return address, nil
+}
+
+// UpdateAddress updates the given fields of a saved address
+func (s *AddressService) UpdateAddress(userID, addressID string, req *models.UpdateSavedAddressRequest) (*models.SavedAddress, error) {
+	address, err := s.GetAddress(userID, addressID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Label != nil {
+		address.Label = strings.TrimSpace(*req.Label)
+	}
+	if req.Address != nil {
+		address.Address = strings.TrimSpace(*req.Address)
+		// A new address text without a new pin must be geocoded again
+		address.Lat, address.Lng, address.GeocodeConfidence = nil, nil, nil
+	}
+	if req.Lat != nil && req.Lng != nil {
+		address.Lat, address.Lng, address.GeocodeConfidence = req.Lat, req.Lng, nil
+	}
+	if req.Instructions != nil {
+		address.Instructions = req.Instructions
+	}
+	if req.Contact != nil {
+		address.Contact = req.Contact
+	}
+	address.UpdatedAt = time.Now()
+
+	if err := s.locate(address); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE SavedAddress SET 
+		label = $label, 
+		address = $address, 
+		lat = $lat, 
+		lng = $lng, 
+		geocodeConfidence = $geocodeConfidence, 
+		instructions = $instructions, 
+		contact = $contact, 
+		updatedAt = $updatedAt 
+		WHERE id = $addressId AND userId = $userId`
+
+	params := map[string]interface{}{
+		"addressId":         address.ID,
+		"userId":            userID,
+		"label":             address.Label,
+		"address":           address.Address,
+		"lat":               address.Lat,
+		"lng":               address.Lng,
+		"geocodeConfidence": address.GeocodeConfidence,
+		"instructions":      address.Instructions,
+		"contact":           address.Contact,
+		"updatedAt":         address.UpdatedAt,
+	}
+
+	_, err = db.Query(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update address: %v", err)
+	}
+
+	return address, nil
+}
+
+// DeleteAddress removes a saved address, deliveries keep their own copy of the location
+func (s *AddressService) DeleteAddress(userID, addressID string) error {
+	if _, err := s.GetAddress(userID, addressID); err != nil {
+		return err
+	}
+
+	_, err := db.Query(`DELETE SavedAddress WHERE id = $addressId AND userId = $userId`, map[string]interface{}{
+		"addressId": addressID,
+		"userId":    userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete address: %v", err)
+	}
+
+	return nil
+}
+
+// GetAddress returns a saved address belonging to the user
+func (s *AddressService) GetAddress(userID, addressID string) (*models.SavedAddress, error) {
+	query := `SELECT * FROM SavedAddress WHERE id = $addressId AND userId = $userId LIMIT 1`
+	params := map[string]interface{}{
+		"addressId": addressID,
+		"userId":    userID,
+	}
+
+	result, err := db.QuerySingle(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("address not found: %v", err)
+	}
+
+	addressData, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("address not found")
+	}
+
+	return s.parseSavedAddressFromMap(addressData), nil
+}
+
+// GetAddresses returns the user's address book, most used first
+func (s *AddressService) GetAddresses(userID string) ([]models.SavedAddress, error) {
+	query := `SELECT * FROM SavedAddress WHERE userId = $userId ORDER BY useCount DESC, label ASC`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"userId": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get addresses: %v", err)
+	}
+
+	addresses := make([]models.SavedAddress, 0, len(results))
+	for _, result := range results {
+		if addressData, ok := result.(map[string]interface{}); ok {
+			addresses = append(addresses, *s.parseSavedAddressFromMap(addressData))
+		}
+	}
+
+	return addresses, nil
+}
+
+// GetSuggestions returns saved and recently used addresses to prefill a new delivery
+func (s *AddressService) GetSuggestions(userID string) ([]models.AddressSuggestion, error) {
+	saved, err := s.GetAddresses(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recent, err := s.getRecentAddresses(userID)
+	if err != nil {
+		// Saved addresses are still worth suggesting
+		log.Printf("Warning: failed to get recent addresses for user %s: %v", userID, err)
+	}
+
+	return models.SuggestAddresses(saved, recent), nil
+}
+
+// MarkUsed records that a saved address was used for a delivery
+func (s *AddressService) MarkUsed(addressID string, usedAt time.Time) {
+	query := `UPDATE SavedAddress SET useCount += 1, lastUsedAt = $usedAt WHERE id = $addressId`
+	_, err := db.Query(query, map[string]interface{}{
+		"addressId": addressID,
+		"usedAt":    usedAt,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to update saved address usage: %v", err)
+	}
+}
+
+// locate geocodes an address without a pin, the client must drop one when confidence is low
+func (s *AddressService) locate(address *models.SavedAddress) error {
+	if address.Lat != nil && address.Lng != nil {
+		return nil
+	}
+
+	result, err := s.geocoder.Geocode(address.Address)
+	if err != nil {
+		return fmt.Errorf("failed to geocode address: %v", err)
+	}
+	if result.NeedsPin {
+		return &PinRequiredError{Stop: "saved", Result: result}
+	}
+
+	address.Lat, address.Lng, address.GeocodeConfidence = result.Lat, result.Lng, &result.Confidence
+	return nil
+}
+
+// getRecentAddresses returns the pickup and dropoff addresses of the user's latest deliveries
+func (s *AddressService) getRecentAddresses(userID string) ([]models.AddressSuggestion, error) {
+	query := `SELECT pickupId, dropoffId, createdAt FROM Delivery WHERE clientId = $userId ORDER BY createdAt DESC LIMIT $limit`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"userId": userID,
+		"limit":  recentDeliveriesScanned,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var recent []models.AddressSuggestion
+	seen := make(map[string]bool)
+	for _, result := range results {
+		deliveryData, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		usedAt := parseTimePtr(deliveryData, "createdAt")
+		for _, locationID := range []string{parseString(deliveryData, "pickupId"), parseString(deliveryData, "dropoffId")} {
+			if locationID == "" || seen[locationID] {
+				continue
+			}
+			seen[locationID] = true
+
+			location, err := getLocation(locationID)
+			if err != nil {
+				continue
+			}
+			recent = append(recent, models.AddressSuggestion{
+				Address:    location.Address,
+				Lat:        location.Lat,
+				Lng:        location.Lng,
+				UseCount:   1,
+				LastUsedAt: usedAt,
+			})
+		}
+	}
+
+	return recent, nil
+}
+
+func (s *AddressService) parseSavedAddressFromMap(data map[string]interface{}) *models.SavedAddress {
+	address := &models.SavedAddress{
+		ID:                parseString(data, "id"),
+		UserID:            parseString(data, "userId"),
+		Label:             parseString(data, "label"),
+		Address:           parseString(data, "address"),
+		Lat:               parseFloatPtr(data, "lat"),
+		Lng:               parseFloatPtr(data, "lng"),
+		GeocodeConfidence: parseFloatPtr(data, "geocodeConfidence"),
+		Contact:           parseContactPerson(data, "contact"),
+		UseCount:          int(parseFloat(data, "useCount")),
+		LastUsedAt:        parseTimePtr(data, "lastUsedAt"),
+	}
+
+	address.Instructions = parseStringPtr(data, "instructions")
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		address.CreatedAt = *createdAt
+	}
+	if updatedAt := parseTimePtr(data, "updatedAt"); updatedAt != nil {
+		address.UpdatedAt = *updatedAt
+	}
+
+	return address
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// FailDeliveryAttempt records a failed attempt at dropoff with its evidence. Depending on the reason
+// and the attempts already made, a re-attempt is scheduled or the parcel goes back to the sender.
+func (s *DeliveryService) FailDeliveryAttempt(deliveryID, driverID string, req *models.FailDeliveryAttemptRequest) (*models.DeliveryAttempt, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, driverID, models.UserRoleLivreur); err != nil {
+		return nil, err
+	}
+	if !req.Reason.IsValid() {
+		return nil, fmt.Errorf("invalid failed attempt reason: %s", req.Reason)
+	}
+	if err := delivery.CanFailAttempt(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	attempt := &models.DeliveryAttempt{
+		ID:         uuid.New().String(),
+		DeliveryID: deliveryID,
+		DriverID:   driverID,
+		Number:     delivery.FailedAttempts + 1,
+		Reason:     req.Reason,
+		Comment:    req.Comment,
+		PhotoURLs:  req.PhotoURLs,
+		Lat:        req.Lat,
+		Lng:        req.Lng,
+		CreatedAt:  now,
+	}
+	attempt.NextAttemptAt = s.attemptPolicy().NextAttempt(attempt.Number, req.Reason, now)
+
+	// Keep how far the driver was from the dropoff as evidence of the visit
+	if req.Lat != nil && req.Lng != nil {
+		dropoff, err := s.getLocationByID(delivery.DropoffID)
+		if err == nil && dropoff.Lat != nil && dropoff.Lng != nil {
+			distance := math.Round(models.Coordinates{Lat: *req.Lat, Lng: *req.Lng}.DistanceKm(models.Coordinates{Lat: *dropoff.Lat, Lng: *dropoff.Lng}) * 1000)
+			attempt.DistanceFromDropoffM = &distance
+		}
+	}
+
+	if err := s.saveDeliveryAttempt(attempt); err != nil {
+		return nil, fmt.Errorf("failed to save delivery attempt: %v", err)
+	}
+
+	query := `UPDATE Delivery SET failedAttempts = $failedAttempts, lastAttemptReason = $reason, nextAttemptAt = $nextAttemptAt WHERE id = $deliveryId`
+	_, err = db.Query(query, map[string]interface{}{
+		"deliveryId":     deliveryID,
+		"failedAttempts": attempt.Number,
+		"reason":         string(req.Reason),
+		"nextAttemptAt":  attempt.NextAttemptAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update delivery attempts: %v", err)
+	}
+	delivery.FailedAttempts = attempt.Number
+	delivery.LastAttemptReason = &attempt.Reason
+	delivery.NextAttemptAt = attempt.NextAttemptAt
+
+	note := string(req.Reason)
+	err = s.applyStatusTransition(delivery, models.DeliveryStatusFailedAttempt, driverID, models.UserRoleLivreur, false, &note)
+	if err != nil {
+		return nil, err
+	}
+
+	// A scheduled re-attempt is started by the re-attempt scheduler
+	if attempt.NextAttemptAt == nil {
+		s.startReturn(deliveryID, driverID, fmt.Sprintf("attempt %d failed: %s", attempt.Number, req.Reason))
+	}
+
+	return attempt, nil
+}
+
+// GetDeliveryAttempts returns the failed attempts of a delivery, the first one first
+func (s *DeliveryService) GetDeliveryAttempts(deliveryID, userID string, userRole models.UserRole) ([]models.DeliveryAttempt, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT * FROM DeliveryAttempt WHERE deliveryId = $deliveryId ORDER BY createdAt ASC`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"deliveryId": deliveryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery attempts: %v", err)
+	}
+
+	attempts := make([]models.DeliveryAttempt, 0, len(results))
+	for _, result := range results {
+		if attemptData, ok := result.(map[string]interface{}); ok {
+			attempts = append(attempts, *parseDeliveryAttemptFromMap(attemptData))
+		}
+	}
+
+	return attempts, nil
+}
+
+// StartReattemptScheduler starts the re-attempts that are due at the configured interval. The
+// schedule is read from the deliveries so re-attempts survive a restart.
+func (s *DeliveryService) StartReattemptScheduler() {
+	if s.config.ReattemptCheckIntervalSeconds <= 0 {
+		log.Println("Re-attempt scheduler disabled")
+		return
+	}
+
+	interval := time.Duration(s.config.ReattemptCheckIntervalSeconds) * time.Second
+	go func() {
+		// Catch up on the re-attempts that fell due while the server was down
+		s.startDueReattempts(time.Now())
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			s.startDueReattempts(now)
+		}
+	}()
+
+	log.Printf("Re-attempt scheduler started, checking every %s", interval)
+}
+
+// startDueReattempts sends the drivers back to the dropoff of the failed attempts whose re-attempt is due
+func (s *DeliveryService) startDueReattempts(now time.Time) {
+	query := `SELECT * FROM Delivery WHERE status = $status AND nextAttemptAt != NONE AND nextAttemptAt <= $now`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"status": string(models.DeliveryStatusFailedAttempt),
+		"now":    now,
+	})
+	if err != nil {
+		log.Printf("Warning: re-attempt scheduler failed to get deliveries: %v", err)
+		return
+	}
+
+	for _, result := range results {
+		deliveryData, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		delivery := s.parseDeliveryFromMap(deliveryData)
+		if delivery.LivreurID == nil {
+			continue
+		}
+
+		note := fmt.Sprintf("re-attempt %d", delivery.FailedAttempts+1)
+		err = s.applyStatusTransition(delivery, models.DeliveryStatusInTransit, *delivery.LivreurID, models.UserRoleLivreur, true, &note)
+		if err != nil {
+			log.Printf("Warning: failed to start re-attempt of delivery %s: %v", delivery.ID, err)
+		}
+	}
+}
+
+// startReturn sends the parcel back to the pickup address after the last failed attempt
+func (s *DeliveryService) startReturn(deliveryID, driverID, note string) {
+	current, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		log.Printf("Warning: failed to start return of delivery %s: %v", deliveryID, err)
+		return
+	}
+
+	err = s.applyStatusTransition(current, models.DeliveryStatusReturning, driverID, models.UserRoleLivreur, true, &note)
+	if err != nil {
+		log.Printf("Warning: failed to start return of delivery %s: %v", deliveryID, err)
+	}
+}
+
+// applyReturnLegCharge adds the return to the price, once
+func (s *DeliveryService) applyReturnLegCharge(delivery *models.Delivery) error {
+	if _, charged := delivery.ReturnLegCharge(); charged {
+		return nil
+	}
+
+	amount := s.attemptPolicy().ReturnLegPrice(delivery)
+	if amount <= 0 {
+		return nil
+	}
+
+	lineItems := append(delivery.PriceLineItems, models.ReturnLegLineItem(amount))
+	finalPrice := delivery.FinalPrice + amount
+
+	query := `UPDATE Delivery SET priceLineItems = $lineItems, finalPrice = $finalPrice, updatedAt = $updatedAt WHERE id = $deliveryId`
+	_, err := db.Query(query, map[string]interface{}{
+		"deliveryId": delivery.ID,
+		"lineItems":  lineItems,
+		"finalPrice": finalPrice,
+		"updatedAt":  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	delivery.PriceLineItems = lineItems
+	delivery.FinalPrice = finalPrice
+
+	log.Printf("Delivery %s: return leg %.0f FCFA, final price %.0f FCFA", delivery.ID, amount, finalPrice)
+	return nil
+}
+
+// handleDeliveryReturned settles a delivery brought back to the sender like a completed one
+func (s *DeliveryService) handleDeliveryReturned(delivery *models.Delivery) error {
+	err := s.applyWaitingCharges(delivery)
+	if err != nil {
+		log.Printf("Warning: failed to apply waiting charges: %v", err)
+	}
+
+	// The sender pays a cash fare, return included, when getting the parcel back, and the driver
+	// is credited with it like for a completed delivery
+	return s.settleDelivery(delivery)
+}
+
+// notifyClientOfAttempt tells the client about a failed attempt and each step of the return
+func (s *DeliveryService) notifyClientOfAttempt(delivery *models.Delivery, status models.DeliveryStatus) {
+	if status != models.DeliveryStatusFailedAttempt && status != models.DeliveryStatusReturning && status != models.DeliveryStatusReturned {
+		return
+	}
+
+	client, err := s.getUserByID(delivery.ClientID)
+	if err != nil {
+		log.Printf("Warning: failed to notify client of delivery %s: %v", delivery.ID, err)
+		return
+	}
+
+	var nextAttemptAt *time.Time
+	if delivery.NextAttemptAt != nil {
+		local := delivery.NextAttemptAt.In(s.config.Location())
+		nextAttemptAt = &local
+	}
+	returnPrice, _ := delivery.ReturnLegCharge()
+
+	message := models.ClientAttemptMessage(status, delivery.LastAttemptReason, nextAttemptAt, returnPrice)
+	if err := s.sms.Send(client.Phone, message); err != nil {
+		log.Printf("Warning: failed to send %s SMS for delivery %s: %v", status, delivery.ID, err)
+	}
+}
+
+func (s *DeliveryService) attemptPolicy() *models.DeliveryAttemptPolicy {
+	return &models.DeliveryAttemptPolicy{
+		MaxAttempts:    s.config.MaxDeliveryAttempts,
+		ReattemptDelay: time.Duration(s.config.ReattemptDelayMinutes) * time.Minute,
+		ReturnLegRate:  s.config.ReturnLegRate,
+	}
+}
+
+func (s *DeliveryService) saveDeliveryAttempt(attempt *models.DeliveryAttempt) error {
+	query := `CREATE DeliveryAttempt SET
+		id = $id,
+		deliveryId = $deliveryId,
+		driverId = $driverId,
+		number = $number,
+		reason = $reason,
+		comment = $comment,
+		photoUrls = $photoUrls,
+		lat = $lat,
+		lng = $lng,
+		distanceFromDropoffM = $distanceFromDropoffM,
+		nextAttemptAt = $nextAttemptAt,
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":                   attempt.ID,
+		"deliveryId":           attempt.DeliveryID,
+		"driverId":             attempt.DriverID,
+		"number":               attempt.Number,
+		"reason":               string(attempt.Reason),
+		"comment":              attempt.Comment,
+		"photoUrls":            attempt.PhotoURLs,
+		"lat":                  attempt.Lat,
+		"lng":                  attempt.Lng,
+		"distanceFromDropoffM": attempt.DistanceFromDropoffM,
+		"nextAttemptAt":        attempt.NextAttemptAt,
+		"createdAt":            attempt.CreatedAt,
+	}
+
+	_, err := db.Query(query, params)
+	return err
+}
+
+func parseDeliveryAttemptFromMap(data map[string]interface{}) *models.DeliveryAttempt {
+	attempt := &models.DeliveryAttempt{
+		ID:                   parseString(data, "id"),
+		DeliveryID:           parseString(data, "deliveryId"),
+		DriverID:             parseString(data, "driverId"),
+		Number:               int(parseFloat(data, "number")),
+		Reason:               models.FailedAttemptReason(parseString(data, "reason")),
+		Comment:              parseStringPtr(data, "comment"),
+		Lat:                  parseFloatPtr(data, "lat"),
+		Lng:                  parseFloatPtr(data, "lng"),
+		DistanceFromDropoffM: parseFloatPtr(data, "distanceFromDropoffM"),
+		NextAttemptAt:        parseTimePtr(data, "nextAttemptAt"),
+	}
+
+	if rawURLs, ok := data["photoUrls"].([]interface{}); ok {
+		for _, rawURL := range rawURLs {
+			if url, ok := rawURL.(string); ok {
+				attempt.PhotoURLs = append(attempt.PhotoURLs, url)
+			}
+		}
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		attempt.CreatedAt = *createdAt
+	}
+
+	return attempt
+}
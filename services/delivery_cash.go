@@ -0,0 +1,284 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// recentCashEntriesShown is the number of ledger entries returned with a driver cash balance
+const recentCashEntriesShown = 50
+
+// CollectCashOnDelivery records the cash the driver collected from the recipient at dropoff.
+// The delivery can only be completed once the cash on delivery is collected.
+func (s *DeliveryService) CollectCashOnDelivery(deliveryID, driverID string, req *models.CollectCODRequest) (*models.DriverCashBalance, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, driverID, models.UserRoleLivreur); err != nil {
+		return nil, err
+	}
+	if err := delivery.CanCollectCOD(req.Amount); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry := models.CashLedgerEntry{
+		ID:         uuid.New().String(),
+		DriverID:   driverID,
+		DeliveryID: &delivery.ID,
+		Type:       models.CashEntryCODCollected,
+		Amount:     req.Amount,
+		CreatedAt:  now,
+	}
+
+	// Confirmed with the ledger entry so a failed write can be retried and two calls cannot both collect
+	statements := []string{
+		`LET $collected = (UPDATE Delivery SET codCollectedAt = $collectedAt, updatedAt = $collectedAt WHERE id = $deliveryId AND codCollectedAt = NONE)`,
+		`IF array::len($collected) = 0 {
+			THROW "cash on delivery already collected"
+		}`,
+	}
+	params := map[string]interface{}{
+		"deliveryId":  deliveryID,
+		"collectedAt": now,
+	}
+	if err := s.recordCashEntriesWith(statements, params, []models.CashLedgerEntry{entry}); err != nil {
+		return nil, fmt.Errorf("failed to record cash collection: %v", err)
+	}
+
+	return s.GetDriverCashBalance(driverID)
+}
+
+// GetDriverCashBalance returns the cash a driver holds, what they owe and their latest ledger entries
+func (s *DeliveryService) GetDriverCashBalance(driverID string) (*models.DriverCashBalance, error) {
+	query := `SELECT type, math::sum(amount) AS total FROM CashLedgerEntry WHERE driverId = $driverId GROUP BY type`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"driverId": driverID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cash ledger totals: %v", err)
+	}
+
+	totals := make(map[models.CashEntryType]float64)
+	for _, result := range results {
+		if totalData, ok := result.(map[string]interface{}); ok {
+			totals[models.CashEntryType(parseString(totalData, "type"))] = parseFloat(totalData, "total")
+		}
+	}
+
+	balance := models.NewDriverCashBalance(driverID, totals, s.config.DriverCashLimit)
+
+	balance.Entries, err = s.getCashEntries(driverID, recentCashEntriesShown)
+	if err != nil {
+		return nil, err
+	}
+
+	return balance, nil
+}
+
+// RecordCashRemittance records cash handed in by a driver to an admin
+func (s *DeliveryService) RecordCashRemittance(driverID, adminID string, req *models.CreateRemittanceRequest) (*models.DriverCashBalance, error) {
+	driver, err := s.getUserByID(driverID)
+	if err != nil {
+		return nil, fmt.Errorf("driver not found: %v", err)
+	}
+	if !driver.IsDriver() {
+		return nil, fmt.Errorf("user is not a driver")
+	}
+
+	balance, err := s.GetDriverCashBalance(driverID)
+	if err != nil {
+		return nil, err
+	}
+	if err := balance.CanRemit(req.Amount); err != nil {
+		return nil, err
+	}
+
+	entry := &models.CashLedgerEntry{
+		DriverID:     driverID,
+		Type:         models.CashEntryRemittance,
+		Amount:       req.Amount,
+		Reference:    req.Reference,
+		Note:         req.Note,
+		RecordedByID: &adminID,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.recordCashEntry(entry); err != nil {
+		return nil, fmt.Errorf("failed to record remittance: %v", err)
+	}
+
+	log.Printf("Driver %s remitted %.0f FCFA to %s", driverID, req.Amount, adminID)
+	return s.GetDriverCashBalance(driverID)
+}
+
+// GetDriversWithCashDue lists the drivers who owe cash, the largest amounts first
+func (s *DeliveryService) GetDriversWithCashDue() ([]models.DriverCashDue, error) {
+	query := `SELECT * FROM User WHERE role = $role AND cashDue > 0 ORDER BY cashDue DESC`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"role": string(models.UserRoleLivreur),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drivers with cash due: %v", err)
+	}
+
+	drivers := make([]models.DriverCashDue, 0, len(results))
+	for _, result := range results {
+		if userData, ok := result.(map[string]interface{}); ok {
+			driver := s.parseUserFromMap(userData)
+			drivers = append(drivers, models.DriverCashDue{
+				DriverID:   driver.ID,
+				DriverName: driver.GetFullName(),
+				Phone:      driver.Phone,
+				AmountDue:  driver.CashDue,
+				OverLimit:  s.isOverCashLimit(driver),
+			})
+		}
+	}
+
+	return drivers, nil
+}
+
+// recordCashFare adds the fare of a delivery paid in cash to the driver ledger
+func (s *DeliveryService) recordCashFare(delivery *models.Delivery) error {
+	return s.recordCashEntries(delivery.CashFareEntries(s.config.DefaultCommissionRate, s.config.DefaultServiceFee, time.Now()))
+}
+
+// recordCashEntry saves a ledger entry and keeps the amount due on the driver up to date
+// for dispatch. Ops are alerted when the entry takes the driver over the cash limit.
+func (s *DeliveryService) recordCashEntry(entry *models.CashLedgerEntry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	return s.recordCashEntries([]models.CashLedgerEntry{*entry})
+}
+
+// recordCashEntries saves the ledger entries of a driver and adds what they owe to the amount due,
+// in one transaction so the amount due never drifts from the ledger
+func (s *DeliveryService) recordCashEntries(entries []models.CashLedgerEntry) error {
+	return s.recordCashEntriesWith(nil, map[string]interface{}{}, entries)
+}
+
+// recordCashEntriesWith runs the given statements first, in the same transaction as the ledger entries
+func (s *DeliveryService) recordCashEntriesWith(statements []string, params map[string]interface{}, entries []models.CashLedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	driverID := entries[0].DriverID
+	params["driverId"] = driverID
+
+	due := 0.0
+	for i, entry := range entries {
+		if entry.ID == "" {
+			entry.ID = uuid.New().String()
+		}
+		due += entry.Due()
+
+		name := fmt.Sprintf("entry%d", i)
+		statements = append(statements, fmt.Sprintf("CREATE CashLedgerEntry CONTENT $%s", name))
+		params[name] = map[string]interface{}{
+			"id":           entry.ID,
+			"driverId":     entry.DriverID,
+			"deliveryId":   entry.DeliveryID,
+			"type":         string(entry.Type),
+			"amount":       entry.Amount,
+			"reference":    entry.Reference,
+			"note":         entry.Note,
+			"recordedById": entry.RecordedByID,
+			"createdAt":    entry.CreatedAt,
+		}
+	}
+
+	if due != 0 {
+		statements = append(statements, `UPDATE User SET cashDue += $due WHERE id = $driverId`)
+		params["due"] = due
+	}
+
+	if err := db.QueryTransaction(statements, params); err != nil {
+		return err
+	}
+
+	if due > 0 {
+		s.checkCashLimit(driverID, due)
+	}
+
+	return nil
+}
+
+// checkCashLimit alerts the operations team when a driver just went over the cash limit
+func (s *DeliveryService) checkCashLimit(driverID string, added float64) {
+	driver, err := s.getUserByID(driverID)
+	if err != nil {
+		log.Printf("Warning: failed to check cash limit of driver %s: %v", driverID, err)
+		return
+	}
+
+	if !s.isOverCashLimit(driver) || models.IsOverCashLimit(driver.CashDue-added, s.config.DriverCashLimit) {
+		return
+	}
+
+	alert := &models.OpsAlert{
+		ID:        uuid.New().String(),
+		Type:      models.AlertTypeCashLimit,
+		DriverID:  &driver.ID,
+		UserID:    &driver.ID,
+		Message:   fmt.Sprintf("Driver %s owes %.0f FCFA, over the %.0f FCFA cash limit: no new dispatch until remittance", driver.GetFullName(), driver.CashDue, s.config.DriverCashLimit),
+		CreatedAt: time.Now(),
+	}
+	if err := saveOpsAlert(alert); err != nil {
+		log.Printf("Warning: failed to raise cash limit alert: %v", err)
+	}
+}
+
+// isOverCashLimit checks if the driver must remit cash before getting new deliveries
+func (s *DeliveryService) isOverCashLimit(driver *models.User) bool {
+	return models.IsOverCashLimit(driver.CashDue, s.config.DriverCashLimit)
+}
+
+func (s *DeliveryService) getCashEntries(driverID string, limit int) ([]models.CashLedgerEntry, error) {
+	query := `SELECT * FROM CashLedgerEntry WHERE driverId = $driverId ORDER BY createdAt DESC LIMIT $limit`
+
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"driverId": driverID,
+		"limit":    limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cash ledger entries: %v", err)
+	}
+
+	entries := make([]models.CashLedgerEntry, 0, len(results))
+	for _, result := range results {
+		if entryData, ok := result.(map[string]interface{}); ok {
+			entries = append(entries, *parseCashEntryFromMap(entryData))
+		}
+	}
+
+	return entries, nil
+}
+
+func parseCashEntryFromMap(data map[string]interface{}) *models.CashLedgerEntry {
+	entry := &models.CashLedgerEntry{
+		ID:           parseString(data, "id"),
+		DriverID:     parseString(data, "driverId"),
+		DeliveryID:   parseStringPtr(data, "deliveryId"),
+		Type:         models.CashEntryType(parseString(data, "type")),
+		Amount:       parseFloat(data, "amount"),
+		Reference:    parseStringPtr(data, "reference"),
+		Note:         parseStringPtr(data, "note"),
+		RecordedByID: parseStringPtr(data, "recordedById"),
+	}
+
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		entry.CreatedAt = *createdAt
+	}
+
+	return entry
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// normalizeRequestContacts validates the phones of the sender and recipient given on creation
+func (s *DeliveryService) normalizeRequestContacts(req *models.CreateDeliveryRequest) error {
+	if req.SenderContact != nil {
+		if err := req.SenderContact.Normalize(s.config.DefaultPhoneCountryCode); err != nil {
+			return fmt.Errorf("invalid sender contact: %v", err)
+		}
+	}
+	if req.RecipientContact != nil {
+		if err := req.RecipientContact.Normalize(s.config.DefaultPhoneCountryCode); err != nil {
+			return fmt.Errorf("invalid recipient contact: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetDeliveryContacts returns the sender and recipient of a delivery. The assigned driver
+// only gets their phone numbers while the delivery is active.
+func (s *DeliveryService) GetDeliveryContacts(deliveryID, userID string, userRole models.UserRole) (*models.DeliveryContacts, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	return delivery.ContactsFor(userRole), nil
+}
+
+// notifyRecipient sends the recipient an SMS when the delivery reaches a key status, once per notice
+func (s *DeliveryService) notifyRecipient(delivery *models.Delivery, status models.DeliveryStatus) {
+	notice, ok := status.RecipientNotice()
+	if !ok || delivery.DropoffContact == nil || delivery.HasSentNotice(notice) {
+		return
+	}
+
+	driverFirstName := ""
+	if delivery.LivreurID != nil {
+		if driver, err := s.getUserByID(*delivery.LivreurID); err == nil {
+			driverFirstName = driver.FirstName
+		}
+	}
+
+	// The recipient has no account, the en route SMS carries a public tracking link
+	var trackingURL *string
+	if notice == models.RecipientNoticeEnRoute && s.config.PublicBaseURL != "" {
+		share, err := s.saveTrackingShare(delivery.ID, delivery.ClientID, s.config.TrackingShareTTLHours)
+		if err != nil {
+			log.Printf("Warning: failed to create tracking link for recipient of delivery %s: %v", delivery.ID, err)
+		} else {
+			url := strings.TrimRight(s.config.PublicBaseURL, "/") + "/api/v1/track/" + share.Token
+			trackingURL = &url
+		}
+	}
+
+	message := models.RecipientNoticeMessage(notice, driverFirstName, trackingURL)
+	if err := s.sms.Send(delivery.DropoffContact.Phone, message); err != nil {
+		log.Printf("Warning: failed to send %s SMS for delivery %s: %v", notice, delivery.ID, err)
+		return
+	}
+
+	notices := append(delivery.RecipientNotices, notice)
+	query := `UPDATE Delivery SET recipientNotices = $notices WHERE id = $deliveryId`
+	_, err := db.Query(query, map[string]interface{}{
+		"deliveryId": delivery.ID,
+		"notices":    notices,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to record %s SMS for delivery %s: %v", notice, delivery.ID, err)
+	}
+}
+
+func parseRecipientNotices(data map[string]interface{}, key string) []models.RecipientNotice {
+	rawNotices, ok := data[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	notices := make([]models.RecipientNotice, 0, len(rawNotices))
+	for _, rawNotice := range rawNotices {
+		if notice, ok := rawNotice.(string); ok {
+			notices = append(notices, models.RecipientNotice(notice))
+		}
+	}
+	return notices
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// GetDispatchDecisions returns how the drivers were scored each time the delivery was dispatched, last first
+func (s *DeliveryService) GetDispatchDecisions(deliveryID string) ([]models.DispatchDecision, error) {
+	if _, err := s.getDeliveryByID(deliveryID); err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	query := `SELECT * FROM DispatchDecision WHERE deliveryId = $deliveryId ORDER BY createdAt DESC`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"deliveryId": deliveryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispatch decisions: %v", err)
+	}
+
+	decisions := make([]models.DispatchDecision, 0, len(results))
+	for _, result := range results {
+		if decisionData, ok := result.(map[string]interface{}); ok {
+			decisions = append(decisions, *parseDispatchDecisionFromMap(decisionData))
+		}
+	}
+
+	return decisions, nil
+}
+
+// GetDispatchPreferences returns the dispatch preferences of a driver
+func (s *DeliveryService) GetDispatchPreferences(driverID string) (*models.DriverDispatchPreferences, error) {
+	driver, err := s.getUserByID(driverID)
+	if err != nil {
+		return nil, fmt.Errorf("driver not found: %v", err)
+	}
+
+	if driver.DispatchPreferences == nil {
+		return &models.DriverDispatchPreferences{}, nil
+	}
+	return driver.DispatchPreferences, nil
+}
+
+// UpdateDispatchPreferences replaces the dispatch preferences of a driver
+func (s *DeliveryService) UpdateDispatchPreferences(driverID string, prefs *models.DriverDispatchPreferences) (*models.DriverDispatchPreferences, error) {
+	for _, zoneID := range prefs.PreferredZoneIDs {
+		if _, err := s.zoneService.GetZone(zoneID); err != nil {
+			return nil, fmt.Errorf("unknown zone %s", zoneID)
+		}
+	}
+
+	query := `UPDATE User SET dispatchPreferences = $preferences, updatedAt = $updatedAt WHERE id = $driverId`
+	_, err := db.Query(query, map[string]interface{}{
+		"driverId":    driverID,
+		"preferences": prefs,
+		"updatedAt":   time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update dispatch preferences: %v", err)
+	}
+
+	return prefs, nil
+}
+
+// scoreDispatchCandidates scores the drivers found near the pickup with the weights of the pickup zone.
+// It returns the decision, best driver first, and the drivers that could be loaded by ID.
+func (s *DeliveryService) scoreDispatchCandidates(delivery *models.Delivery, matches []models.DriverMatch, now time.Time) (*models.DispatchDecision, map[string]*models.User) {
+	scoring := s.dispatchScoring(delivery)
+	decision := &models.DispatchDecision{
+		ID:         uuid.New().String(),
+		DeliveryID: delivery.ID,
+		ZoneID:     delivery.PickupZoneID,
+		Weights:    scoring.Weights,
+		Candidates: make([]models.DriverScore, 0, len(matches)),
+		CreatedAt:  now,
+	}
+	drivers := make(map[string]*models.User, len(matches))
+	onShift := s.onShiftDrivers(delivery, now)
+	withdrawn := s.withdrawnDrivers(delivery.ID)
+
+	// The index only knows positions, the driver record has the final say
+	for _, match := range matches {
+		driver, err := s.getUserByID(match.DriverID)
+		if err != nil {
+			decision.Candidates = append(decision.Candidates, models.ExcludeDriver(match.DriverID, match.DistanceKm, "driver not found"))
+			continue
+		}
+		drivers[driver.ID] = driver
+
+		if withdrawn[driver.ID] {
+			decision.Candidates = append(decision.Candidates, models.ExcludeDriver(driver.ID, match.DistanceKm, "cancelled this delivery"))
+			continue
+		}
+		if !driver.CanAcceptDeliveries() {
+			decision.Candidates = append(decision.Candidates, models.ExcludeDriver(driver.ID, match.DistanceKm, "not accepting deliveries"))
+			continue
+		}
+		if s.isOverCashLimit(driver) {
+			decision.Candidates = append(decision.Candidates, models.ExcludeDriver(driver.ID, match.DistanceKm, "over the cash limit"))
+			continue
+		}
+
+		vehicle, err := s.getDriverVehicle(driver.ID)
+		if err != nil {
+			vehicle = nil
+		}
+
+		decision.Candidates = append(decision.Candidates, scoring.Score(delivery, models.DispatchCandidate{
+			Driver:     driver,
+			Vehicle:    vehicle,
+			DistanceKm: match.DistanceKm,
+			OnShift:    onShift[driver.ID],
+		}, now))
+	}
+
+	models.RankDriverScores(decision.Candidates)
+	if len(decision.Candidates) > 0 && !decision.Candidates[0].Excluded {
+		decision.SelectedDriverID = &decision.Candidates[0].DriverID
+	}
+
+	return decision, drivers
+}
+
+// withdrawnDrivers returns the drivers who already cancelled the delivery
+func (s *DeliveryService) withdrawnDrivers(deliveryID string) map[string]bool {
+	cancellations, err := s.getDeliveryCancellations(deliveryID)
+	if err != nil {
+		log.Printf("Warning: failed to load cancellations of delivery %s: %v", deliveryID, err)
+		return map[string]bool{}
+	}
+	return models.WithdrawnDriverIDs(cancellations)
+}
+
+// dispatchScoring returns the scoring rules, with the weights of the pickup zone when it has its own
+func (s *DeliveryService) dispatchScoring(delivery *models.Delivery) *models.DispatchScoring {
+	scoring := &models.DispatchScoring{
+		MaxEtaMin:  s.config.DispatchMaxEtaMinutes,
+		IdleCapMin: s.config.DispatchIdleCapMinutes,
+	}
+
+	weights, err := models.ParseDispatchWeights(s.config.DispatchWeights)
+	if err != nil {
+		log.Printf("Warning: invalid dispatch weights, ranking by ETA only: %v", err)
+		weights = models.DispatchWeights{ETA: 1}
+	}
+	scoring.Weights = weights
+
+	if delivery.PickupZoneID != nil {
+		zone, err := s.zoneService.GetZone(*delivery.PickupZoneID)
+		if err == nil && zone.DispatchWeights != nil {
+			scoring.Weights = *zone.DispatchWeights
+		}
+	}
+
+	return scoring
+}
+
+// releaseDriver makes a driver available again at the end of a job
+func (s *DeliveryService) releaseDriver(driverID string) error {
+	err := s.updateDriverStatus(driverID, models.DriverStatusAvailable)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Query(`UPDATE User SET lastJobEndedAt = $endedAt WHERE id = $driverId`, map[string]interface{}{
+		"driverId": driverID,
+		"endedAt":  time.Now(),
+	})
+	return err
+}
+
+func (s *DeliveryService) saveDispatchDecision(decision *models.DispatchDecision) error {
+	query := `CREATE DispatchDecision SET
+		id = $id,
+		deliveryId = $deliveryId,
+		zoneId = $zoneId,
+		weights = $weights,
+		candidates = $candidates,
+		selectedDriverId = $selectedDriverId,
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":               decision.ID,
+		"deliveryId":       decision.DeliveryID,
+		"zoneId":           decision.ZoneID,
+		"weights":          decision.Weights,
+		"candidates":       decision.Candidates,
+		"selectedDriverId": decision.SelectedDriverID,
+		"createdAt":        decision.CreatedAt,
+	}
+
+	_, err := db.Query(query, params)
+	return err
+}
+
+func parseDispatchDecisionFromMap(data map[string]interface{}) *models.DispatchDecision {
+	decision := &models.DispatchDecision{
+		ID:               parseString(data, "id"),
+		DeliveryID:       parseString(data, "deliveryId"),
+		ZoneID:           parseStringPtr(data, "zoneId"),
+		SelectedDriverID: parseStringPtr(data, "selectedDriverId"),
+		Candidates:       []models.DriverScore{},
+	}
+
+	if weights := parseDispatchWeights(data, "weights"); weights != nil {
+		decision.Weights = *weights
+	}
+	rawCandidates, _ := data["candidates"].([]interface{})
+	for _, rawCandidate := range rawCandidates {
+		candidateData, ok := rawCandidate.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		score := models.DriverScore{
+			DriverID:     parseString(candidateData, "driverId"),
+			DistanceKm:   parseFloat(candidateData, "distanceKm"),
+			EtaPickupMin: parseFloat(candidateData, "etaPickupMin"),
+			Score:        parseFloat(candidateData, "score"),
+			Reason:       parseString(candidateData, "reason"),
+		}
+		score.Excluded, _ = candidateData["excluded"].(bool)
+		if factorsData, ok := candidateData["factors"].(map[string]interface{}); ok {
+			score.Factors = models.DispatchFactors{
+				ETA:         parseFloat(factorsData, "eta"),
+				Rating:      parseFloat(factorsData, "rating"),
+				Reliability: parseFloat(factorsData, "reliability"),
+				Idle:        parseFloat(factorsData, "idle"),
+				Vehicle:     parseFloat(factorsData, "vehicle"),
+				Preference:  parseFloat(factorsData, "preference"),
+				Shift:       parseFloat(factorsData, "shift"),
+			}
+		}
+		decision.Candidates = append(decision.Candidates, score)
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		decision.CreatedAt = *createdAt
+	}
+
+	return decision
+}
+
+func parseDispatchWeights(data map[string]interface{}, key string) *models.DispatchWeights {
+	weightsData, ok := data[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	return &models.DispatchWeights{
+		ETA:         parseFloat(weightsData, "eta"),
+		Rating:      parseFloat(weightsData, "rating"),
+		Reliability: parseFloat(weightsData, "reliability"),
+		Idle:        parseFloat(weightsData, "idle"),
+		Vehicle:     parseFloat(weightsData, "vehicle"),
+		Preference:  parseFloat(weightsData, "preference"),
+		Shift:       parseFloat(weightsData, "shift"),
+	}
+}
+
+func parseDispatchPreferences(data map[string]interface{}, key string) *models.DriverDispatchPreferences {
+	prefsData, ok := data[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	prefs := &models.DriverDispatchPreferences{
+		MaxPickupDistanceKm: parseFloatPtr(prefsData, "maxPickupDistanceKm"),
+	}
+	if rawZoneIDs, ok := prefsData["preferredZoneIds"].([]interface{}); ok {
+		for _, rawZoneID := range rawZoneIDs {
+			if zoneID, ok := rawZoneID.(string); ok {
+				prefs.PreferredZoneIDs = append(prefs.PreferredZoneIDs, zoneID)
+			}
+		}
+	}
+	if rawTypes, ok := prefsData["preferredDeliveryTypes"].([]interface{}); ok {
+		for _, rawType := range rawTypes {
+			if deliveryType, ok := rawType.(string); ok {
+				prefs.PreferredDeliveryTypes = append(prefs.PreferredDeliveryTypes, models.DeliveryType(deliveryType))
+			}
+		}
+	}
+	return prefs
+}
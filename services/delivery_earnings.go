@@ -0,0 +1,276 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// Default number of days and weeks of an earnings statement
+const (
+	defaultEarningsDays  = 7
+	defaultEarningsWeeks = 4
+)
+
+// GetDriverEarningsStatement returns the ledger earnings of a driver day by day or week by week.
+// The period is widened to whole days or weeks. Without dates the last 7 days or 4 weeks are returned.
+func (s *DeliveryService) GetDriverEarningsStatement(driverID string, period models.EarningsPeriod, req *models.DriverEarningsRequest) (*models.DriverEarningsStatement, error) {
+	from, to, err := earningsStatementPeriod(period, req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.getEarningEntries(driverID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewEarningsStatement(driverID, period, from, to, entries)
+}
+
+// RecordEarningAdjustment grants a bonus to a driver or applies a penalty, with the reason
+func (s *DeliveryService) RecordEarningAdjustment(driverID, adminID string, req *models.CreateEarningAdjustmentRequest) (*models.EarningEntry, error) {
+	driver, err := s.getUserByID(driverID)
+	if err != nil {
+		return nil, fmt.Errorf("driver not found: %v", err)
+	}
+	if !driver.IsDriver() {
+		return nil, fmt.Errorf("user is not a driver")
+	}
+
+	if req.DeliveryID != nil {
+		delivery, err := s.getDeliveryByID(*req.DeliveryID)
+		if err != nil {
+			return nil, fmt.Errorf("delivery not found: %v", err)
+		}
+		if delivery.LivreurID == nil || *delivery.LivreurID != driverID {
+			return nil, fmt.Errorf("delivery was not made by this driver")
+		}
+	}
+
+	reason := req.Reason
+	entry := &models.EarningEntry{
+		DriverID:     driverID,
+		DeliveryID:   req.DeliveryID,
+		Type:         req.Type,
+		Amount:       req.Amount,
+		Reason:       &reason,
+		RecordedByID: &adminID,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.recordEarningEntry(entry); err != nil {
+		return nil, fmt.Errorf("failed to record earnings adjustment: %v", err)
+	}
+
+	log.Printf("Driver %s: %s of %.0f FCFA recorded by %s", driverID, req.Type, req.Amount, adminID)
+	return entry, nil
+}
+
+// GetPlatformRevenue returns the commission and service fees kept by the platform over a period.
+// Without dates the last 7 days are returned.
+func (s *DeliveryService) GetPlatformRevenue(req *models.DriverEarningsRequest) (*models.PlatformRevenue, error) {
+	from, to, err := earningsStatementPeriod(models.EarningsPeriodDaily, req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT type, math::sum(amount) AS total, count() AS count FROM EarningEntry
+		WHERE createdAt >= $from
+		AND createdAt < $to
+		GROUP BY type`
+
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"from": from,
+		"to":   to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get earnings ledger totals: %v", err)
+	}
+
+	totals := make(map[models.EarningEntryType]float64)
+	counts := make(map[models.EarningEntryType]int)
+	for _, result := range results {
+		if totalData, ok := result.(map[string]interface{}); ok {
+			entryType := models.EarningEntryType(parseString(totalData, "type"))
+			totals[entryType] = parseFloat(totalData, "total")
+			counts[entryType] = int(parseFloat(totalData, "count"))
+		}
+	}
+
+	return models.NewPlatformRevenue(from, to, totals, counts), nil
+}
+
+// recordDeliveryEarnings credits the driver earnings ledger with the split of a completed delivery.
+// All entries are written in one transaction, a delivery already credited is left as is.
+func (s *DeliveryService) recordDeliveryEarnings(delivery *models.Delivery) error {
+	if delivery.LivreurID == nil {
+		return nil
+	}
+
+	count, err := db.CountRecords("EarningEntry", "deliveryId = $deliveryId AND type = $type", map[string]interface{}{
+		"deliveryId": delivery.ID,
+		"type":       string(models.EarningEntryFare),
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	now := time.Now()
+	earning := models.NewDeliveryEarning(delivery, s.config.DefaultCommissionRate)
+	earning.ApplyServiceFee(s.config.DefaultServiceFee)
+	earning.Tips = 0 // Read from the delivery inside the transaction, a tip may have come in since it was loaded
+
+	// The fare is checked again inside the transaction in case the delivery is completed twice at once.
+	// Marking the delivery credited makes a tip saved at the same time conflict instead of being missed.
+	statements := []string{
+		`IF (SELECT count() FROM EarningEntry WHERE deliveryId = $deliveryId AND type = $fareType GROUP ALL)[0].count > 0 {
+			THROW "delivery already credited"
+		}`,
+		`UPDATE Delivery SET earningsCreditedAt = $creditedAt WHERE id = $deliveryId`,
+		`LET $tips = (SELECT VALUE tipAmount FROM Delivery WHERE id = $deliveryId)[0] ?? 0`,
+		`IF $tips > 0 {
+			CREATE EarningEntry SET id = $tipEntryId, driverId = $driverId, deliveryId = $deliveryId, type = $tipType, amount = $tips, createdAt = $creditedAt
+		}`,
+	}
+	params := map[string]interface{}{
+		"deliveryId": delivery.ID,
+		"fareType":   string(models.EarningEntryFare),
+		"creditedAt": now,
+		"driverId":   *delivery.LivreurID,
+		"tipEntryId": uuid.New().String(),
+		"tipType":    string(models.EarningEntryTip),
+	}
+
+	for i, entry := range earning.LedgerEntries(*delivery.LivreurID, now) {
+		entry := entry
+		entry.ID = uuid.New().String()
+
+		name := fmt.Sprintf("entry%d", i)
+		statements = append(statements, fmt.Sprintf("CREATE EarningEntry CONTENT $%s", name))
+		params[name] = earningEntryContent(&entry)
+	}
+
+	return db.QueryTransaction(statements, params)
+}
+
+// recordCancellationCompensation credits the driver with their share of a cancellation fee, as a bonus
+func (s *DeliveryService) recordCancellationCompensation(driverID, deliveryID string, amount float64, now time.Time) error {
+	reason := "cancellation compensation"
+	return s.recordEarningEntry(&models.EarningEntry{
+		DriverID:   driverID,
+		DeliveryID: &deliveryID,
+		Type:       models.EarningEntryBonus,
+		Amount:     amount,
+		Reason:     &reason,
+		CreatedAt:  now,
+	})
+}
+
+// recordEarningEntry saves an earnings ledger entry, entries are never updated nor deleted
+func (s *DeliveryService) recordEarningEntry(entry *models.EarningEntry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+
+	_, err := db.Query(`CREATE EarningEntry CONTENT $entry`, map[string]interface{}{
+		"entry": earningEntryContent(entry),
+	})
+	return err
+}
+
+// earningEntryContent returns the stored fields of an earnings ledger entry
+func earningEntryContent(entry *models.EarningEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           entry.ID,
+		"driverId":     entry.DriverID,
+		"deliveryId":   entry.DeliveryID,
+		"type":         string(entry.Type),
+		"amount":       entry.Amount,
+		"reason":       entry.Reason,
+		"recordedById": entry.RecordedByID,
+		"createdAt":    entry.CreatedAt,
+	}
+}
+
+// getEarningEntries returns the earnings ledger entries of a driver over a period, oldest first
+func (s *DeliveryService) getEarningEntries(driverID string, from, to time.Time) ([]models.EarningEntry, error) {
+	query := `SELECT * FROM EarningEntry
+		WHERE driverId = $driverId
+		AND createdAt >= $from
+		AND createdAt < $to
+		ORDER BY createdAt ASC`
+
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"driverId": driverID,
+		"from":     from,
+		"to":       to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get earnings ledger entries: %v", err)
+	}
+
+	entries := make([]models.EarningEntry, 0, len(results))
+	for _, result := range results {
+		if entryData, ok := result.(map[string]interface{}); ok {
+			entries = append(entries, *parseEarningEntryFromMap(entryData))
+		}
+	}
+
+	return entries, nil
+}
+
+// earningsStatementPeriod returns the period of a statement widened to whole days or weeks
+func earningsStatementPeriod(period models.EarningsPeriod, req *models.DriverEarningsRequest, now time.Time) (time.Time, time.Time, error) {
+	// Without an end the current day or week is included
+	to := req.To
+	if to.IsZero() {
+		to = period.Next(period.Start(now))
+	} else if start := period.Start(to); !start.Equal(to) {
+		to = period.Next(start)
+	}
+
+	from := req.From
+	if from.IsZero() {
+		if period == models.EarningsPeriodWeekly {
+			from = to.AddDate(0, 0, -7*defaultEarningsWeeks)
+		} else {
+			from = to.AddDate(0, 0, -defaultEarningsDays)
+		}
+	}
+	from = period.Start(from)
+
+	if !to.After(from) {
+		return from, to, fmt.Errorf("period end must be after its start")
+	}
+	if to.Sub(from) > maxEarningsPeriod {
+		return from, to, fmt.Errorf("period cannot be longer than %d days", int(maxEarningsPeriod.Hours()/24))
+	}
+
+	return from, to, nil
+}
+
+func parseEarningEntryFromMap(data map[string]interface{}) *models.EarningEntry {
+	entry := &models.EarningEntry{
+		ID:           parseString(data, "id"),
+		DriverID:     parseString(data, "driverId"),
+		DeliveryID:   parseStringPtr(data, "deliveryId"),
+		Type:         models.EarningEntryType(parseString(data, "type")),
+		Amount:       parseFloat(data, "amount"),
+		Reason:       parseStringPtr(data, "reason"),
+		RecordedByID: parseStringPtr(data, "recordedById"),
+	}
+
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		entry.CreatedAt = *createdAt
+	}
+
+	return entry
+}
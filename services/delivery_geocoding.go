@@ -0,0 +1,55 @@
+package services
+
+import (
+	"log"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// geocodeAddress returns the coordinates of a delivery address. Coordinates sent by the client
+// (a dropped pin) are trusted as is, otherwise the address text is geocoded.
+func (s *DeliveryService) geocodeAddress(address string, lat, lng *float64) *models.GeocodeResult {
+	if lat != nil && lng != nil {
+		return &models.GeocodeResult{
+			Query:      address,
+			Lat:        lat,
+			Lng:        lng,
+			Confidence: 1,
+			Provider:   models.GeocoderClient,
+		}
+	}
+
+	result, err := s.geocoder.Geocode(address)
+	if err != nil {
+		log.Printf("Warning: failed to geocode address %q: %v", address, err)
+		return &models.GeocodeResult{Query: address, NeedsPin: true, Provider: models.GeocoderGazetteer}
+	}
+
+	return result
+}
+
+// geocodeQuoteAddresses fills the missing quote coordinates from the addresses when the
+// geocoding is confident enough, and reports which address needs a pin
+func (s *DeliveryService) geocodeQuoteAddresses(req *models.PriceQuoteRequest) *models.QuoteGeocoding {
+	if req.PickupAddress == nil && req.DropoffAddress == nil {
+		return nil
+	}
+
+	geocoding := &models.QuoteGeocoding{}
+	if req.PickupAddress != nil {
+		geocoding.Pickup = s.geocodeAddress(*req.PickupAddress, req.PickupLat, req.PickupLng)
+		if !geocoding.Pickup.NeedsPin {
+			req.PickupLat, req.PickupLng = geocoding.Pickup.Lat, geocoding.Pickup.Lng
+		}
+		geocoding.NeedsPin = geocoding.Pickup.NeedsPin
+	}
+	if req.DropoffAddress != nil {
+		geocoding.Dropoff = s.geocodeAddress(*req.DropoffAddress, req.DropoffLat, req.DropoffLng)
+		if !geocoding.Dropoff.NeedsPin {
+			req.DropoffLat, req.DropoffLng = geocoding.Dropoff.Lat, geocoding.Dropoff.Lng
+		}
+		geocoding.NeedsPin = geocoding.NeedsPin || geocoding.Dropoff.NeedsPin
+	}
+
+	return geocoding
+}
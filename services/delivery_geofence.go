@@ -0,0 +1,150 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// UndoAutoArrival reverts an automatic arrival emitted by the geofence, within the undo window
+func (s *DeliveryService) UndoAutoArrival(deliveryID, driverID string, req *models.UndoAutoArrivalRequest) error {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if delivery.LivreurID == nil || *delivery.LivreurID != driverID {
+		return fmt.Errorf("unauthorized: delivery not assigned to you")
+	}
+
+	stop, ok := models.WaitingStopFor(delivery.Status)
+	state := delivery.Geofence
+	if !ok || state == nil || state.Stop != stop {
+		return fmt.Errorf("no automatic arrival to undo")
+	}
+
+	if !s.geofenceConfig().CanUndoAutoArrival(state, time.Now()) {
+		return fmt.Errorf("undo window expired")
+	}
+
+	previousStatus := *state.PreviousStatus
+
+	// Stay disarmed until the driver leaves the fence, otherwise the arrival would fire again
+	nextState := models.GeofenceState{
+		Stop:     stop,
+		Disarmed: true,
+	}
+
+	arrivedField := "arrivedAtPickupAt"
+	if stop == models.WaitingStopDropoff {
+		arrivedField = "arrivedAtDropoffAt"
+	}
+
+	// The SLA clock restarts in the restored status, like any other status change
+	now := time.Now()
+	query := `UPDATE Delivery SET status = $status, ` + arrivedField + ` = NONE, geofence = $geofence,
+		statusChangedAt = $updatedAt, sla = NONE, updatedAt = $updatedAt WHERE id = $deliveryId`
+	params := map[string]interface{}{
+		"deliveryId": deliveryID,
+		"status":     string(previousStatus),
+		"geofence":   nextState,
+		"updatedAt":  now,
+	}
+
+	_, err = db.Query(query, params)
+	if err != nil {
+		return fmt.Errorf("failed to undo arrival: %v", err)
+	}
+
+	note := "Automatic arrival undone by driver"
+	if req != nil && req.Note != nil && *req.Note != "" {
+		note = *req.Note
+	}
+	role := models.UserRoleLivreur
+	s.recordDeliveryEvent(deliveryID, delivery.Status, previousStatus, &driverID, &role, false, &note)
+
+	return nil
+}
+
+// evaluateGeofence checks the driver position against the next stop and emits the arrival
+// status once the driver has dwelled inside the fence long enough. Returns true if it did.
+func (s *DeliveryService) evaluateGeofence(delivery *models.Delivery, driverID string, lat, lng float64, now time.Time) bool {
+	var stop models.WaitingStop
+	var target models.DeliveryStatus
+	var locationID string
+
+	switch {
+	case delivery.Status.IsBeforePickup() && delivery.Status != models.DeliveryStatusArrivedAtPickup:
+		stop, target, locationID = models.WaitingStopPickup, models.DeliveryStatusArrivedAtPickup, delivery.PickupID
+	case s.isHeadingToDropoff(delivery.Status):
+		stop, target, locationID = models.WaitingStopDropoff, models.DeliveryStatusArrivedAtDropoff, delivery.DropoffID
+	default:
+		return false
+	}
+
+	if !s.isValidStatusTransition(delivery.Status, target, models.UserRoleLivreur) {
+		return false
+	}
+
+	location, err := s.getLocationByID(locationID)
+	if err != nil || location.Lat == nil || location.Lng == nil {
+		return false
+	}
+
+	state := models.GeofenceState{}
+	if delivery.Geofence != nil {
+		state = *delivery.Geofence
+	}
+
+	distanceM := models.Coordinates{Lat: lat, Lng: lng}.DistanceKm(models.Coordinates{Lat: *location.Lat, Lng: *location.Lng}) * 1000
+	nextState, arrived := s.geofenceConfig().Evaluate(state, stop, distanceM, now)
+
+	if arrived {
+		note := fmt.Sprintf("Geofence: driver stayed within %.0f m of the %s stop", distanceM, stop)
+		err = s.applyStatusTransition(delivery, target, driverID, models.UserRoleLivreur, true, &note)
+		if err != nil {
+			log.Printf("Warning: failed to emit automatic arrival for delivery %s: %v", delivery.ID, err)
+			return false
+		}
+
+		previousStatus := delivery.Status
+		nextState.EnteredAt = nil
+		nextState.AutoArrivalAt = &now
+		nextState.PreviousStatus = &previousStatus
+	}
+
+	err = s.saveGeofenceState(delivery.ID, &nextState)
+	if err != nil {
+		log.Printf("Warning: failed to save geofence state for delivery %s: %v", delivery.ID, err)
+	}
+
+	if arrived {
+		delivery.Status = target
+	}
+	delivery.Geofence = &nextState
+
+	return arrived
+}
+
+func (s *DeliveryService) geofenceConfig() *models.GeofenceConfig {
+	return &models.GeofenceConfig{
+		PickupRadiusM:  s.config.GeofencePickupRadius,
+		DropoffRadiusM: s.config.GeofenceDropoffRadius,
+		Dwell:          time.Duration(s.config.GeofenceDwellSeconds) * time.Second,
+		UndoWindow:     time.Duration(s.config.GeofenceUndoSeconds) * time.Second,
+	}
+}
+
+func (s *DeliveryService) saveGeofenceState(deliveryID string, state *models.GeofenceState) error {
+	query := `UPDATE Delivery SET geofence = $geofence WHERE id = $deliveryId`
+	params := map[string]interface{}{
+		"deliveryId": deliveryID,
+		"geofence":   state,
+	}
+
+	_, err := db.Query(query, params)
+	return err
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// GetDeliveryHistory returns the status history of a delivery, oldest first
+func (s *DeliveryService) GetDeliveryHistory(deliveryID, userID string, userRole models.UserRole) ([]models.DeliveryEvent, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT * FROM DeliveryEvent WHERE deliveryId = $deliveryId ORDER BY createdAt ASC`
+	params := map[string]interface{}{
+		"deliveryId": deliveryID,
+	}
+
+	results, err := db.QueryMultiple(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery history: %v", err)
+	}
+
+	events := make([]models.DeliveryEvent, 0, len(results))
+	for _, result := range results {
+		if eventData, ok := result.(map[string]interface{}); ok {
+			events = append(events, *s.parseDeliveryEventFromMap(eventData))
+		}
+	}
+
+	return events, nil
+}
+
+// recordDeliveryEvent appends a status change to the delivery history, failures are only logged
+func (s *DeliveryService) recordDeliveryEvent(deliveryID string, from, to models.DeliveryStatus, actorID *string, actorRole *models.UserRole, autoGenerated bool, note *string) {
+	event := &models.DeliveryEvent{
+		ID:            uuid.New().String(),
+		DeliveryID:    deliveryID,
+		FromStatus:    from,
+		ToStatus:      to,
+		ActorID:       actorID,
+		ActorRole:     actorRole,
+		AutoGenerated: autoGenerated,
+		Note:          note,
+		CreatedAt:     time.Now(),
+	}
+
+	query := `CREATE DeliveryEvent SET 
+		id = $id,
+		deliveryId = $deliveryId,
+		fromStatus = $fromStatus,
+		toStatus = $toStatus,
+		actorId = $actorId,
+		actorRole = $actorRole,
+		autoGenerated = $autoGenerated,
+		note = $note,
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":            event.ID,
+		"deliveryId":    event.DeliveryID,
+		"fromStatus":    string(event.FromStatus),
+		"toStatus":      string(event.ToStatus),
+		"actorId":       event.ActorID,
+		"actorRole":     event.ActorRole,
+		"autoGenerated": event.AutoGenerated,
+		"note":          event.Note,
+		"createdAt":     event.CreatedAt,
+	}
+
+	_, err := db.Query(query, params)
+	if err != nil {
+		log.Printf("Warning: failed to record history for delivery %s: %v", deliveryID, err)
+	}
+}
+
+func (s *DeliveryService) parseDeliveryEventFromMap(data map[string]interface{}) *models.DeliveryEvent {
+	event := &models.DeliveryEvent{
+		ID:         parseString(data, "id"),
+		DeliveryID: parseString(data, "deliveryId"),
+		FromStatus: models.DeliveryStatus(parseString(data, "fromStatus")),
+		ToStatus:   models.DeliveryStatus(parseString(data, "toStatus")),
+	}
+
+	event.AutoGenerated, _ = data["autoGenerated"].(bool)
+	if actorID, ok := data["actorId"].(string); ok && actorID != "" {
+		event.ActorID = &actorID
+	}
+	if role, ok := data["actorRole"].(string); ok && role != "" {
+		actorRole := models.UserRole(role)
+		event.ActorRole = &actorRole
+	}
+	if note, ok := data["note"].(string); ok && note != "" {
+		event.Note = &note
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		event.CreatedAt = *createdAt
+	}
+
+	return event
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// Rows processed between two progress saves of an import job
+const importProgressEvery = 10
+
+// StartDeliveryImport saves an import job for the parsed and validated rows and processes it in the
+// background. Valid rows are created as deliveries of the client, or only checked and priced on a dry run.
+func (s *DeliveryService) StartDeliveryImport(clientID string, format models.ImportFormat, dryRun bool, rows []models.DeliveryImportRow) (*models.DeliveryImportJob, error) {
+	client, err := s.getUserByID(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("client not found: %v", err)
+	}
+	if !client.IsClient() {
+		return nil, fmt.Errorf("only clients can import deliveries")
+	}
+
+	job := models.NewDeliveryImportJob(uuid.New().String(), clientID, format, dryRun, len(rows), time.Now())
+	if err := s.saveImportJob(job); err != nil {
+		return nil, fmt.Errorf("failed to save import job: %v", err)
+	}
+
+	go s.runDeliveryImport(job, rows)
+
+	return job, nil
+}
+
+// GetDeliveryImport returns the progress and row report of an import job
+func (s *DeliveryService) GetDeliveryImport(jobID, userID string, userRole models.UserRole) (*models.DeliveryImportJob, error) {
+	query := `SELECT * FROM DeliveryImportJob WHERE id = $jobId LIMIT 1`
+	result, err := db.QuerySingle(query, map[string]interface{}{
+		"jobId": jobID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("import job not found: %v", err)
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("import job not found")
+	}
+
+	job := parseImportJobFromMap(data)
+	if userRole != models.UserRoleAdmin && job.ClientID != userID {
+		return nil, fmt.Errorf("access denied to this import job")
+	}
+
+	return job, nil
+}
+
+// FailInterruptedImports marks the jobs left pending or running by a restart as failed. Their rows
+// only lived in the memory of the stopped process, so they cannot be resumed.
+func (s *DeliveryService) FailInterruptedImports() error {
+	query := `UPDATE DeliveryImportJob SET status = $failed, error = $error, completedAt = $now
+		WHERE status IN [$pending, $running]`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"failed":  string(models.ImportJobStatusFailed),
+		"pending": string(models.ImportJobStatusPending),
+		"running": string(models.ImportJobStatusRunning),
+		"error":   "import interrupted by a server restart, import the rows not reported as created again",
+		"now":     time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark interrupted import jobs as failed: %v", err)
+	}
+
+	if len(results) > 0 {
+		log.Printf("%d interrupted delivery import jobs marked as failed", len(results))
+	}
+	return nil
+}
+
+// runDeliveryImport processes the rows one by one, saving the progress as it goes. A dry run runs
+// the same checks as a creation so both report the same rows. Express deliveries are dispatched one
+// after the other once every row is created, not each on its own as rows come.
+func (s *DeliveryService) runDeliveryImport(job *models.DeliveryImportJob, rows []models.DeliveryImportRow) {
+	defer func() {
+		if r := recover(); r != nil {
+			message := fmt.Sprintf("import stopped: %v", r)
+			job.Error = &message
+			s.finishImportJob(job, models.ImportJobStatusFailed)
+		}
+	}()
+
+	now := time.Now()
+	job.Status = models.ImportJobStatusRunning
+	job.StartedAt = &now
+	s.updateImportJob(job)
+
+	var express []string
+	for i := range rows {
+		row := rows[i]
+
+		switch {
+		case row.Status == models.ImportRowStatusInvalid:
+			// Rejected while reading or validating the file
+		case job.DryRun:
+			if _, err := s.ValidateDelivery(job.ClientID, row.Request); err != nil {
+				row.Status = models.ImportRowStatusFailed
+				row.Errors = append(row.Errors, err.Error())
+			} else {
+				row.Status = models.ImportRowStatusValid
+			}
+		default:
+			delivery, err := s.createDelivery(job.ClientID, row.Request, false)
+			if err != nil {
+				row.Status = models.ImportRowStatusFailed
+				row.Errors = append(row.Errors, err.Error())
+			} else {
+				row.Status = models.ImportRowStatusCreated
+				row.DeliveryID = &delivery.ID
+				if delivery.Type == models.DeliveryTypeExpress {
+					express = append(express, delivery.ID)
+				}
+			}
+		}
+
+		job.Record(row)
+		if job.ProcessedRows%importProgressEvery == 0 {
+			s.updateImportJob(job)
+		}
+	}
+
+	s.finishImportJob(job, models.ImportJobStatusCompleted)
+
+	for _, deliveryID := range express {
+		if err := s.AutoAssignDelivery(deliveryID); err != nil {
+			log.Printf("Warning: failed to dispatch imported delivery %s: %v", deliveryID, err)
+		}
+	}
+}
+
+func (s *DeliveryService) finishImportJob(job *models.DeliveryImportJob, status models.ImportJobStatus) {
+	now := time.Now()
+	job.Status = status
+	job.CompletedAt = &now
+	s.updateImportJob(job)
+
+	log.Printf("Delivery import %s %s: %d created, %d valid, %d invalid, %d failed",
+		job.ID, status, job.CreatedRows, job.ValidRows, job.InvalidRows, job.FailedRows)
+}
+
+func (s *DeliveryService) saveImportJob(job *models.DeliveryImportJob) error {
+	query := `CREATE DeliveryImportJob SET 
+		id = $id,
+		clientId = $clientId,
+		format = $format,
+		dryRun = $dryRun,
+		status = $status,
+		totalRows = $totalRows,
+		processedRows = 0,
+		validRows = 0,
+		invalidRows = 0,
+		createdRows = 0,
+		failedRows = 0,
+		progress = 0,
+		rows = [],
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":        job.ID,
+		"clientId":  job.ClientID,
+		"format":    string(job.Format),
+		"dryRun":    job.DryRun,
+		"status":    string(job.Status),
+		"totalRows": job.TotalRows,
+		"createdAt": job.CreatedAt,
+	}
+
+	_, err := db.Query(query, params)
+	return err
+}
+
+// updateImportJob saves the progress of a job, failures only delay what the progress endpoint shows
+func (s *DeliveryService) updateImportJob(job *models.DeliveryImportJob) {
+	query := `UPDATE DeliveryImportJob SET 
+		status = $status,
+		processedRows = $processedRows,
+		validRows = $validRows,
+		invalidRows = $invalidRows,
+		createdRows = $createdRows,
+		failedRows = $failedRows,
+		progress = $progress,
+		rows = $rows,
+		error = $error,
+		startedAt = $startedAt,
+		completedAt = $completedAt
+		WHERE id = $jobId`
+
+	params := map[string]interface{}{
+		"jobId":         job.ID,
+		"status":        string(job.Status),
+		"processedRows": job.ProcessedRows,
+		"validRows":     job.ValidRows,
+		"invalidRows":   job.InvalidRows,
+		"createdRows":   job.CreatedRows,
+		"failedRows":    job.FailedRows,
+		"progress":      job.Progress,
+		"rows":          job.Rows,
+		"error":         job.Error,
+		"startedAt":     job.StartedAt,
+		"completedAt":   job.CompletedAt,
+	}
+
+	if _, err := db.Query(query, params); err != nil {
+		log.Printf("Warning: failed to save progress of import job %s: %v", job.ID, err)
+	}
+}
+
+func parseImportJobFromMap(data map[string]interface{}) *models.DeliveryImportJob {
+	job := &models.DeliveryImportJob{
+		ID:            parseString(data, "id"),
+		ClientID:      parseString(data, "clientId"),
+		Format:        models.ImportFormat(parseString(data, "format")),
+		Status:        models.ImportJobStatus(parseString(data, "status")),
+		TotalRows:     int(parseFloat(data, "totalRows")),
+		ProcessedRows: int(parseFloat(data, "processedRows")),
+		ValidRows:     int(parseFloat(data, "validRows")),
+		InvalidRows:   int(parseFloat(data, "invalidRows")),
+		CreatedRows:   int(parseFloat(data, "createdRows")),
+		FailedRows:    int(parseFloat(data, "failedRows")),
+		Progress:      parseFloat(data, "progress"),
+		Error:         parseStringPtr(data, "error"),
+		StartedAt:     parseTimePtr(data, "startedAt"),
+		CompletedAt:   parseTimePtr(data, "completedAt"),
+	}
+	job.DryRun, _ = data["dryRun"].(bool)
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		job.CreatedAt = *createdAt
+	}
+
+	job.Rows = []models.DeliveryImportRow{}
+	if rawRows, ok := data["rows"].([]interface{}); ok {
+		for _, rawRow := range rawRows {
+			rowData, ok := rawRow.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			row := models.DeliveryImportRow{
+				Line:       int(parseFloat(rowData, "line")),
+				Status:     models.ImportRowStatus(parseString(rowData, "status")),
+				DeliveryID: parseStringPtr(rowData, "deliveryId"),
+			}
+			if rawErrors, ok := rowData["errors"].([]interface{}); ok {
+				for _, rawError := range rawErrors {
+					if message, ok := rawError.(string); ok {
+						row.Errors = append(row.Errors, message)
+					}
+				}
+			}
+			job.Rows = append(job.Rows, row)
+		}
+	}
+
+	return job
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// Batch sizes of the compaction: points loaded at once, and points deleted or marked per query
+const (
+	locationCompactBatch = 5000
+	locationDeleteBatch  = 500
+)
+
+// GetDeliveryReplay returns the path driven for a delivery as a GeoJSON LineString, with the
+// timestamp of each position and the actual distance driven
+func (s *DeliveryService) GetDeliveryReplay(deliveryID string) (*models.TripReplay, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	query := `SELECT * FROM LocationPoint WHERE deliveryId = $deliveryId ORDER BY timestamp ASC`
+	points, err := s.getLocationPoints(query, map[string]interface{}{
+		"deliveryId": deliveryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery path: %v", err)
+	}
+
+	replay := models.NewTripReplay(points)
+	replay.Properties.DeliveryID = &delivery.ID
+	replay.Properties.PlannedDistanceKm = delivery.DistanceKm
+
+	return replay, nil
+}
+
+// GetDriverTrack returns the path of a driver over a time range, all deliveries included
+func (s *DeliveryService) GetDriverTrack(driverID string, req *models.DriverTrackRequest) (*models.TripReplay, error) {
+	if req.To.Sub(req.From) > models.MaxDriverTrackRange {
+		return nil, fmt.Errorf("time range cannot exceed %s", models.MaxDriverTrackRange)
+	}
+
+	if _, err := s.getUserByID(driverID); err != nil {
+		return nil, fmt.Errorf("driver not found: %v", err)
+	}
+
+	query := `SELECT * FROM LocationPoint WHERE driverId = $driverId AND timestamp >= $from AND timestamp < $to ORDER BY timestamp ASC`
+	points, err := s.getLocationPoints(query, map[string]interface{}{
+		"driverId": driverID,
+		"from":     req.From,
+		"to":       req.To,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver track: %v", err)
+	}
+
+	return models.NewTripReplay(points), nil
+}
+
+// StartLocationCompaction downsamples and purges the old location history at the configured interval
+func (s *DeliveryService) StartLocationCompaction() {
+	if s.config.LocationCompactionIntervalMinutes <= 0 {
+		log.Println("Location history compaction disabled")
+		return
+	}
+
+	interval := time.Duration(s.config.LocationCompactionIntervalMinutes) * time.Minute
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			if err := s.compactLocationHistory(now); err != nil {
+				log.Printf("Warning: failed to compact location history: %v", err)
+			}
+		}
+	}()
+
+	log.Printf("Location history compaction started, running every %s", interval)
+}
+
+// compactLocationHistory deletes the points past retention, then keeps one point per interval of
+// each driver and delivery track among the points old enough to be compacted.
+// Points are compacted in batches, oldest first, so a long backlog is never loaded at once.
+func (s *DeliveryService) compactLocationHistory(now time.Time) error {
+	if s.config.LocationRetentionDays > 0 {
+		_, err := db.Query(`DELETE LocationPoint WHERE timestamp < $cutoff`, map[string]interface{}{
+			"cutoff": now.AddDate(0, 0, -s.config.LocationRetentionDays),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to purge location history: %v", err)
+		}
+	}
+
+	cutoff := now.Add(-time.Duration(s.config.LocationCompactAfterHours) * time.Hour)
+	interval := time.Duration(s.config.LocationCompactIntervalSeconds) * time.Second
+	query := `SELECT * FROM LocationPoint WHERE compacted = false AND timestamp < $cutoff ORDER BY timestamp ASC LIMIT $limit`
+
+	// Each batch is marked compacted once done, so the next query starts where the last one stopped
+	compacted, removed := 0, 0
+	for {
+		points, err := s.getLocationPoints(query, map[string]interface{}{
+			"cutoff": cutoff,
+			"limit":  locationCompactBatch,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get points to compact: %v", err)
+		}
+		if len(points) == 0 {
+			break
+		}
+
+		dropped, err := s.compactLocationPoints(points, interval)
+		if err != nil {
+			return err
+		}
+		compacted += len(points)
+		removed += dropped
+
+		if len(points) < locationCompactBatch {
+			break
+		}
+	}
+
+	if compacted > 0 {
+		log.Printf("Location history compacted: %d of %d points removed", removed, compacted)
+	}
+	return nil
+}
+
+// compactLocationPoints downsamples a batch of time-sorted points, deletes the dropped ones and
+// marks the others compacted. It returns the number of points deleted.
+func (s *DeliveryService) compactLocationPoints(points []models.LocationPoint, interval time.Duration) (int, error) {
+	// Downsample each track on its own so every delivery keeps its start and end
+	tracks := make(map[string][]models.LocationPoint)
+	for _, point := range points {
+		key := point.TrackKey()
+		tracks[key] = append(tracks[key], point)
+	}
+
+	dropped := make(map[string]bool)
+	for _, track := range tracks {
+		for _, point := range models.DownsampleTrack(track, interval) {
+			dropped[point.ID] = true
+		}
+	}
+
+	droppedIDs := make([]string, 0, len(dropped))
+	keptIDs := make([]string, 0, len(points)-len(dropped))
+	for _, point := range points {
+		if dropped[point.ID] {
+			droppedIDs = append(droppedIDs, point.ID)
+		} else {
+			keptIDs = append(keptIDs, point.ID)
+		}
+	}
+
+	if err := updateLocationPoints(`DELETE LocationPoint WHERE id IN $ids`, droppedIDs); err != nil {
+		return 0, fmt.Errorf("failed to delete downsampled points: %v", err)
+	}
+	if err := updateLocationPoints(`UPDATE LocationPoint SET compacted = true WHERE id IN $ids`, keptIDs); err != nil {
+		return 0, fmt.Errorf("failed to mark compacted points: %v", err)
+	}
+
+	return len(droppedIDs), nil
+}
+
+// updateLocationPoints runs the query on the points, a few hundred IDs at a time
+func updateLocationPoints(query string, ids []string) error {
+	for start := 0; start < len(ids); start += locationDeleteBatch {
+		end := start + locationDeleteBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		_, err := db.Query(query, map[string]interface{}{
+			"ids": ids[start:end],
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordLocationPoint appends a driver position to the location history
+func (s *DeliveryService) recordLocationPoint(driverID string, deliveryID *string, lat, lng float64, timestamp time.Time) error {
+	query := `CREATE LocationPoint SET
+		id = $id,
+		driverId = $driverId,
+		deliveryId = $deliveryId,
+		lat = $lat,
+		lng = $lng,
+		timestamp = $timestamp,
+		compacted = false`
+
+	params := map[string]interface{}{
+		"id":         uuid.New().String(),
+		"driverId":   driverID,
+		"deliveryId": deliveryID,
+		"lat":        lat,
+		"lng":        lng,
+		"timestamp":  timestamp,
+	}
+
+	_, err := db.Query(query, params)
+	return err
+}
+
+func (s *DeliveryService) getLocationPoints(query string, params map[string]interface{}) ([]models.LocationPoint, error) {
+	results, err := db.QueryMultiple(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]models.LocationPoint, 0, len(results))
+	for _, result := range results {
+		if pointData, ok := result.(map[string]interface{}); ok {
+			points = append(points, *parseLocationPointFromMap(pointData))
+		}
+	}
+
+	return points, nil
+}
+
+func parseLocationPointFromMap(data map[string]interface{}) *models.LocationPoint {
+	point := &models.LocationPoint{
+		ID:         parseString(data, "id"),
+		DriverID:   parseString(data, "driverId"),
+		DeliveryID: parseStringPtr(data, "deliveryId"),
+		Lat:        parseFloat(data, "lat"),
+		Lng:        parseFloat(data, "lng"),
+	}
+
+	point.Compacted, _ = data["compacted"].(bool)
+	if timestamp := parseTimePtr(data, "timestamp"); timestamp != nil {
+		point.Timestamp = *timestamp
+	}
+
+	return point
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// Number of recent ratings shown in driver stats
+const recentRatingsShown = 10
+
+// RateDelivery records the score a client gives the driver, or the driver gives the client,
+// once the delivery is delivered. Each party rates a delivery once, within the rating window.
+func (s *DeliveryService) RateDelivery(deliveryID, raterID string, raterRole models.UserRole, req *models.CreateRatingRequest) (*models.Rating, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if raterRole != models.UserRoleClient && raterRole != models.UserRoleLivreur {
+		return nil, fmt.Errorf("only the client and the driver can rate a delivery")
+	}
+	if err := s.checkDeliveryAccess(delivery, raterID, raterRole); err != nil {
+		return nil, err
+	}
+	if err := s.ratingPolicy().CanRate(delivery, raterRole, time.Now()); err != nil {
+		return nil, err
+	}
+
+	for _, tag := range req.Tags {
+		if !tag.IsValidFor(raterRole) {
+			return nil, fmt.Errorf("tag %s cannot be used by %s", tag, raterRole)
+		}
+	}
+
+	// Only one rating per delivery and rater
+	count, err := db.CountRecords("Rating", "deliveryId = $deliveryId AND raterId = $raterId", map[string]interface{}{
+		"deliveryId": deliveryID,
+		"raterId":    raterID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing rating: %v", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("delivery already rated")
+	}
+
+	rating := &models.Rating{
+		ID:         uuid.New().String(),
+		DeliveryID: deliveryID,
+		RaterID:    raterID,
+		RaterRole:  raterRole,
+		RateeID:    delivery.ClientID,
+		RateeRole:  models.UserRoleClient,
+		Score:      req.Score,
+		Tags:       req.Tags,
+		Comment:    req.Comment,
+		CreatedAt:  time.Now(),
+	}
+	if raterRole == models.UserRoleClient {
+		rating.RateeID = *delivery.LivreurID
+		rating.RateeRole = models.UserRoleLivreur
+	}
+
+	if err := s.saveRating(rating); err != nil {
+		return nil, fmt.Errorf("failed to save rating: %v", err)
+	}
+
+	if err := s.checkRatingReview(rating.RateeID); err != nil {
+		log.Printf("Warning: failed to check rating review for user %s: %v", rating.RateeID, err)
+	}
+
+	return rating, nil
+}
+
+// GetDeliveryRatings returns the ratings given on a delivery
+func (s *DeliveryService) GetDeliveryRatings(deliveryID, userID string, userRole models.UserRole) ([]models.Rating, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	return s.queryRatings(`SELECT * FROM Rating WHERE deliveryId = $deliveryId ORDER BY createdAt ASC`, map[string]interface{}{
+		"deliveryId": deliveryID,
+	})
+}
+
+// GetDriverStats returns the performance of a driver for the admin dashboard
+func (s *DeliveryService) GetDriverStats(driverID string) (*models.DriverStats, error) {
+	driver, err := s.getUserByID(driverID)
+	if err != nil {
+		return nil, fmt.Errorf("driver not found: %v", err)
+	}
+	if !driver.IsDriver() {
+		return nil, fmt.Errorf("user is not a driver")
+	}
+
+	delivered, err := db.CountRecords("Delivery", "livreurId = $driverId AND status = $status", map[string]interface{}{
+		"driverId": driverID,
+		"status":   string(models.DeliveryStatusDelivered),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count delivered deliveries: %v", err)
+	}
+
+	ratings, err := s.queryRatings(`SELECT * FROM Rating WHERE rateeId = $driverId ORDER BY createdAt DESC`, map[string]interface{}{
+		"driverId": driverID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.RatingSummary{
+		Average:   driver.RatingAverage,
+		Count:     driver.RatingCount,
+		TagCounts: make(map[models.RatingTag]int),
+	}
+	for _, rating := range ratings {
+		for _, tag := range rating.Tags {
+			summary.TagCounts[tag]++
+		}
+	}
+	if len(ratings) > recentRatingsShown {
+		ratings = ratings[:recentRatingsShown]
+	}
+	summary.Recent = ratings
+
+	return &models.DriverStats{
+		Driver:           driver.ToResponse(),
+		AcceptedCount:    driver.DriverAcceptedCount,
+		CancelledCount:   driver.DriverCancelledCount,
+		DeliveredCount:   int(delivered),
+		ReliabilityScore: driver.ComputeReliabilityScore(),
+		Ratings:          summary,
+		UnderReview:      driver.RatingReviewOpenedAt != nil,
+	}, nil
+}
+
+// checkRatingReview opens a review when the average of the ratee gets too low
+func (s *DeliveryService) checkRatingReview(rateeID string) error {
+	ratee, err := s.getUserByID(rateeID)
+	if err != nil {
+		return err
+	}
+
+	if ratee.RatingAverage == nil || ratee.RatingReviewOpenedAt != nil {
+		return nil
+	}
+	if s.ratingPolicy().NeedsReview(*ratee.RatingAverage, ratee.RatingCount) {
+		s.openRatingReview(ratee, *ratee.RatingAverage, ratee.RatingCount)
+	}
+
+	return nil
+}
+
+// openRatingReview flags the user and alerts the operations team, once per review
+func (s *DeliveryService) openRatingReview(user *models.User, average float64, count int) {
+	now := time.Now()
+	_, err := db.Query(`UPDATE User SET ratingReviewOpenedAt = $openedAt WHERE id = $userId`, map[string]interface{}{
+		"userId":   user.ID,
+		"openedAt": now,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to flag user %s for rating review: %v", user.ID, err)
+		return
+	}
+
+	alert := &models.OpsAlert{
+		ID:        uuid.New().String(),
+		Type:      models.AlertTypeLowRating,
+		UserID:    &user.ID,
+		Message:   fmt.Sprintf("%s %s has an average rating of %.2f over %d ratings", user.Role, user.GetFullName(), average, count),
+		CreatedAt: now,
+	}
+	if user.IsDriver() {
+		alert.DriverID = &user.ID
+	}
+
+	if err := saveOpsAlert(alert); err != nil {
+		log.Printf("Warning: failed to raise low rating alert: %v", err)
+	}
+}
+
+func (s *DeliveryService) ratingPolicy() *models.RatingPolicy {
+	return &models.RatingPolicy{
+		Window:          time.Duration(s.config.RatingWindowHours) * time.Hour,
+		ReviewThreshold: s.config.RatingReviewThreshold,
+		ReviewMinCount:  s.config.RatingReviewMinCount,
+	}
+}
+
+// saveRating saves the rating and adds its score to the ratee's average in one transaction, so
+// concurrent ratings are neither lost nor counted twice. The average is computed like models.AddScore.
+func (s *DeliveryService) saveRating(rating *models.Rating) error {
+	statements := []string{
+		`CREATE Rating SET 
+		id = $id,
+		deliveryId = $deliveryId,
+		raterId = $raterId,
+		raterRole = $raterRole,
+		rateeId = $rateeId,
+		rateeRole = $rateeRole,
+		score = $score,
+		tags = $tags,
+		comment = $comment,
+		createdAt = $createdAt`,
+		// The average is set first, while the count still holds the previous value
+		`UPDATE User SET
+		ratingAverage = ((ratingAverage ?? 0.0) * (ratingCount ?? 0) + $score) / ((ratingCount ?? 0) + 1),
+		ratingCount = (ratingCount ?? 0) + 1
+		WHERE id = $rateeId`,
+	}
+
+	params := map[string]interface{}{
+		"id":         rating.ID,
+		"deliveryId": rating.DeliveryID,
+		"raterId":    rating.RaterID,
+		"raterRole":  string(rating.RaterRole),
+		"rateeId":    rating.RateeID,
+		"rateeRole":  string(rating.RateeRole),
+		"score":      rating.Score,
+		"tags":       rating.Tags,
+		"comment":    rating.Comment,
+		"createdAt":  rating.CreatedAt,
+	}
+
+	return db.QueryTransaction(statements, params)
+}
+
+func (s *DeliveryService) queryRatings(query string, params map[string]interface{}) ([]models.Rating, error) {
+	results, err := db.QueryMultiple(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ratings: %v", err)
+	}
+
+	ratings := make([]models.Rating, 0, len(results))
+	for _, result := range results {
+		if ratingData, ok := result.(map[string]interface{}); ok {
+			ratings = append(ratings, *parseRatingFromMap(ratingData))
+		}
+	}
+
+	return ratings, nil
+}
+
+func parseRatingFromMap(data map[string]interface{}) *models.Rating {
+	rating := &models.Rating{
+		ID:         parseString(data, "id"),
+		DeliveryID: parseString(data, "deliveryId"),
+		RaterID:    parseString(data, "raterId"),
+		RaterRole:  models.UserRole(parseString(data, "raterRole")),
+		RateeID:    parseString(data, "rateeId"),
+		RateeRole:  models.UserRole(parseString(data, "rateeRole")),
+		Score:      int(parseFloat(data, "score")),
+		Comment:    parseStringPtr(data, "comment"),
+	}
+
+	if tags, ok := data["tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			if value, ok := tag.(string); ok {
+				rating.Tags = append(rating.Tags, models.RatingTag(value))
+			}
+		}
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		rating.CreatedAt = *createdAt
+	}
+
+	return rating
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// SearchDeliveries returns one page of deliveries matching the filters, for the admin list
+// and the client and driver histories
+func (s *DeliveryService) SearchDeliveries(req *models.DeliverySearchRequest) (*models.DeliveryPage, error) {
+	return s.searchDeliveries(req, models.UserRoleAdmin)
+}
+
+// searchDeliveries runs the search and shows the contacts the viewer is allowed to see
+func (s *DeliveryService) searchDeliveries(req *models.DeliverySearchRequest, viewerRole models.UserRole) (*models.DeliveryPage, error) {
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	query, params, err := BuildDeliverySearchQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := db.QueryMultiple(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search deliveries: %v", err)
+	}
+
+	deliveries := make([]*models.Delivery, 0, len(results))
+	for _, result := range results {
+		if deliveryData, ok := result.(map[string]interface{}); ok {
+			deliveries = append(deliveries, s.parseDeliveryFromMap(deliveryData))
+		}
+	}
+
+	// One extra delivery was fetched to know if there is a next page
+	page := &models.DeliveryPage{Deliveries: make([]*models.DeliveryResponse, 0, req.Limit)}
+	if len(deliveries) > req.Limit {
+		deliveries = deliveries[:req.Limit]
+		page.HasMore = true
+
+		cursor, err := models.EncodeDeliveryCursor(req.Sort, deliveries[len(deliveries)-1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode cursor: %v", err)
+		}
+		page.NextCursor = &cursor
+	}
+
+	locations := s.getLocationsByID(deliveries)
+	for _, delivery := range deliveries {
+		response := delivery.ToResponse()
+		response.Pickup = locations[delivery.PickupID]
+		response.Dropoff = locations[delivery.DropoffID]
+		contacts := delivery.ContactsFor(viewerRole)
+		response.PickupContact, response.DropoffContact = contacts.PickupContact, contacts.DropoffContact
+		response.CanCall = contacts.CanCall
+		page.Deliveries = append(page.Deliveries, response)
+	}
+
+	return page, nil
+}
+
+// GetClientDeliveries returns the delivery history of a client
+func (s *DeliveryService) GetClientDeliveries(clientID string, req *models.DeliverySearchRequest) (*models.DeliveryPage, error) {
+	req.ClientID = clientID
+	return s.searchDeliveries(req, models.UserRoleClient)
+}
+
+// GetDriverDeliveries returns the delivery history of a driver
+func (s *DeliveryService) GetDriverDeliveries(driverID string, req *models.DeliverySearchRequest) (*models.DeliveryPage, error) {
+	req.DriverID = driverID
+	return s.searchDeliveries(req, models.UserRoleLivreur)
+}
+
+// BuildDeliverySearchQuery builds the SurrealQL query of a normalized delivery search.
+// Pages use keyset pagination on the sort field and the delivery ID, and fetch one extra row.
+func BuildDeliverySearchQuery(req *models.DeliverySearchRequest) (string, map[string]interface{}, error) {
+	if !req.Sort.IsValid() {
+		return "", nil, fmt.Errorf("invalid sort field: %s", req.Sort)
+	}
+
+	var conditions []string
+	params := map[string]interface{}{}
+
+	if len(req.Status) == 1 {
+		conditions = append(conditions, "status = $status")
+		params["status"] = string(req.Status[0])
+	} else if len(req.Status) > 1 {
+		statuses := make([]string, 0, len(req.Status))
+		for _, status := range req.Status {
+			statuses = append(statuses, string(status))
+		}
+		conditions = append(conditions, "status IN $statuses")
+		params["statuses"] = statuses
+	}
+	if req.Type != "" {
+		conditions = append(conditions, "type = $type")
+		params["type"] = string(req.Type)
+	}
+	if req.VehicleType != "" {
+		conditions = append(conditions, "vehicleType = $vehicleType")
+		params["vehicleType"] = string(req.VehicleType)
+	}
+	if req.ClientID != "" {
+		conditions = append(conditions, "clientId = $clientId")
+		params["clientId"] = req.ClientID
+	}
+	if req.DriverID != "" {
+		conditions = append(conditions, "livreurId = $driverId")
+		params["driverId"] = req.DriverID
+	}
+	if req.ZoneID != "" {
+		conditions = append(conditions, "(pickupZoneId = $zoneId OR dropoffZoneId = $zoneId)")
+		params["zoneId"] = req.ZoneID
+	}
+	if req.PaymentMethod != "" {
+		conditions = append(conditions, "paymentMethod = $paymentMethod")
+		params["paymentMethod"] = string(req.PaymentMethod)
+	}
+	if !req.From.IsZero() {
+		conditions = append(conditions, "createdAt >= $from")
+		params["from"] = req.From
+	}
+	if !req.To.IsZero() {
+		conditions = append(conditions, "createdAt < $to")
+		params["to"] = req.To
+	}
+	if req.MinPrice != nil {
+		conditions = append(conditions, "finalPrice >= $minPrice")
+		params["minPrice"] = *req.MinPrice
+	}
+	if req.MaxPrice != nil {
+		conditions = append(conditions, "finalPrice <= $maxPrice")
+		params["maxPrice"] = *req.MaxPrice
+	}
+	if search := strings.TrimSpace(req.Query); search != "" {
+		// Uses the full-text index on Location.address
+		conditions = append(conditions, `(pickupId IN (SELECT VALUE id FROM Location WHERE address @@ $search)
+			OR dropoffId IN (SELECT VALUE id FROM Location WHERE address @@ $search))`)
+		params["search"] = search
+	}
+
+	comparison := "<"
+	direction := "DESC"
+	if req.Order == models.SortOrderAsc {
+		comparison = ">"
+		direction = "ASC"
+	}
+
+	if req.Cursor != "" {
+		value, id, err := models.DecodeDeliveryCursor(req.Sort, req.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s $cursorValue OR (%[1]s = $cursorValue AND id %[2]s $cursorId))", req.Sort, comparison))
+		params["cursorValue"] = value
+		params["cursorId"] = id
+	}
+
+	query := "SELECT * FROM Delivery"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %d", req.Sort, direction, direction, req.Limit+1)
+
+	return query, params, nil
+}
+
+// getLocationsByID loads the pickup and dropoff locations of a page of deliveries in one query
+func (s *DeliveryService) getLocationsByID(deliveries []*models.Delivery) map[string]*models.Location {
+	locations := make(map[string]*models.Location)
+	if len(deliveries) == 0 {
+		return locations
+	}
+
+	ids := make([]string, 0, len(deliveries)*2)
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.PickupID, delivery.DropoffID)
+	}
+
+	results, err := db.QueryMultiple(`SELECT * FROM Location WHERE id IN $locationIds`, map[string]interface{}{
+		"locationIds": ids,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to load delivery locations: %v", err)
+		return locations
+	}
+
+	for _, result := range results {
+		if locationData, ok := result.(map[string]interface{}); ok {
+			location := parseLocationFromMap(locationData)
+			locations[location.ID] = location
+		}
+	}
+
+	return locations
+}
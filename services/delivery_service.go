@@ -3,7 +3,7 @@ package services
 import (
 	"fmt"
 	"log"
-	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,55 +14,73 @@ import (
 )
 
 type DeliveryService struct {
-	config      *config.Config
-	promoService *PromoService
+	config         *config.Config
+	promoService   *PromoService
+	zoneService    *ZoneService
+	surgeService   *SurgeService
+	pricingService *PricingService
+	router         RoutingProvider
+	geocoder       Geocoder
+	addressService *AddressService
+	sms            SMSSender
+	driverIndex    *DriverIndex
 }
 
-func NewDeliveryService(cfg *config.Config, promoService *PromoService) *DeliveryService {
+func NewDeliveryService(cfg *config.Config, promoService *PromoService, zoneService *ZoneService, surgeService *SurgeService, pricingService *PricingService, geocoder Geocoder, addressService *AddressService) *DeliveryService {
 	return &DeliveryService{
-		config:       cfg,
-		promoService: promoService,
+		config:         cfg,
+		promoService:   promoService,
+		zoneService:    zoneService,
+		surgeService:   surgeService,
+		pricingService: pricingService,
+		router:         NewRoutingProvider(cfg),
+		geocoder:       geocoder,
+		addressService: addressService,
+		sms:            NewSMSSender(cfg),
+		driverIndex:    NewDriverIndex(cfg.DriverIndexCellKm),
 	}
 }
 
+// deliveryDraft holds what a delivery creation checked and priced before anything is saved
+type deliveryDraft struct {
+	client         *models.User
+	pickupSaved    *models.SavedAddress
+	dropoffSaved   *models.SavedAddress
+	pickupGeocode  *models.GeocodeResult
+	dropoffGeocode *models.GeocodeResult
+	pickupZone     *models.ServiceZone
+	dropoffZone    *models.ServiceZone
+	route          *models.RouteResult
+	priceContext   *models.PriceContext
+	pricing        models.PriceCalculation
+}
+
 // CreateDelivery creates a new delivery with price calculation
 func (s *DeliveryService) CreateDelivery(clientID string, req *models.CreateDeliveryRequest) (*models.DeliveryResponse, error) {
-	// Validate client exists and is a client
-	client, err := s.getUserByID(clientID)
+	return s.createDelivery(clientID, req, true)
+}
+
+// createDelivery creates a delivery, express deliveries are only dispatched right away with autoAssign
+func (s *DeliveryService) createDelivery(clientID string, req *models.CreateDeliveryRequest, autoAssign bool) (*models.DeliveryResponse, error) {
+	draft, err := s.prepareDelivery(clientID, req)
 	if err != nil {
-		return nil, fmt.Errorf("client not found: %v", err)
-	}
-	
-	if !client.IsClient() {
-		return nil, fmt.Errorf("only clients can create deliveries")
+		return nil, err
 	}
+	client, pickupSaved, dropoffSaved := draft.client, draft.pickupSaved, draft.dropoffSaved
+	pickupZone, dropoffZone, route, priceContext, pricing := draft.pickupZone, draft.dropoffZone, draft.route, draft.priceContext, draft.pricing
+	distance, duration := route.DistanceKm, route.DurationMin
 
 	// Create pickup and dropoff locations
-	pickupLocation, err := s.createLocation(req.PickupAddress, req.PickupLat, req.PickupLng)
+	pickupLocation, err := s.findOrCreateLocation(req.PickupAddress, draft.pickupGeocode)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pickup location: %v", err)
 	}
 
-	dropoffLocation, err := s.createLocation(req.DropoffAddress, req.DropoffLat, req.DropoffLng)
+	dropoffLocation, err := s.findOrCreateLocation(req.DropoffAddress, draft.dropoffGeocode)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create dropoff location: %v", err)
 	}
 
-	// Calculate distance and duration
-	distance, duration, err := s.calculateDistanceAndDuration(pickupLocation, dropoffLocation)
-	if err != nil {
-		log.Printf("Warning: failed to calculate distance: %v", err)
-		// Use default values
-		distance = 5.0  // 5 km default
-		duration = 30.0 // 30 minutes default
-	}
-
-	// Calculate price based on pricing rules
-	pricing, err := s.calculateDeliveryPrice(req.VehicleType, distance, 0, req.Type)
-	if err != nil {
-		return nil, fmt.Errorf("failed to calculate price: %v", err)
-	}
-
 	// Create delivery record
 	delivery := &models.Delivery{
 		ID:            uuid.New().String(),
@@ -80,6 +98,34 @@ func (s *DeliveryService) CreateDelivery(clientID string, req *models.CreateDeli
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
+	delivery.PriceLineItems = pricing.LineItems
+	delivery.PricingRuleID = &pricing.RuleID
+	delivery.PricingRuleVersion = &pricing.RuleVersion
+	delivery.DistanceProvider = &route.Provider
+	if pickupSaved != nil {
+		delivery.PickupAddressID = &pickupSaved.ID
+		delivery.PickupInstructions = pickupSaved.Instructions
+		delivery.PickupContact = pickupSaved.Contact
+	}
+	if dropoffSaved != nil {
+		delivery.DropoffAddressID = &dropoffSaved.ID
+		delivery.DropoffInstructions = dropoffSaved.Instructions
+		delivery.DropoffContact = dropoffSaved.Contact
+	}
+	// Contacts given with the delivery take precedence over the saved ones
+	if req.SenderContact != nil {
+		delivery.PickupContact = req.SenderContact
+	}
+	if req.RecipientContact != nil {
+		delivery.DropoffContact = req.RecipientContact
+	}
+	delivery.CODAmount = req.CODAmount
+	if pickupZone != nil {
+		delivery.PickupZoneID = &pickupZone.ID
+	}
+	if dropoffZone != nil {
+		delivery.DropoffZoneID = &dropoffZone.ID
+	}
 
 	// Save delivery to database
 	err = s.saveDelivery(delivery)
@@ -87,6 +133,15 @@ func (s *DeliveryService) CreateDelivery(clientID string, req *models.CreateDeli
 		return nil, fmt.Errorf("failed to save delivery: %v", err)
 	}
 
+	s.logPriceQuote(&pricing, req.VehicleType, req.Type, distance, priceContext, &delivery.ID)
+
+	if pickupSaved != nil {
+		s.addressService.MarkUsed(pickupSaved.ID, delivery.CreatedAt)
+	}
+	if dropoffSaved != nil {
+		s.addressService.MarkUsed(dropoffSaved.ID, delivery.CreatedAt)
+	}
+
 	// Handle special delivery types
 	if req.PackageInfo != nil {
 		err = s.createPackage(delivery.ID, req.PackageInfo)
@@ -110,7 +165,7 @@ func (s *DeliveryService) CreateDelivery(clientID string, req *models.CreateDeli
 	}
 
 	// Auto-assign if express delivery
-	if req.Type == models.DeliveryTypeExpress {
+	if autoAssign && req.Type == models.DeliveryTypeExpress {
 		go s.AutoAssignDelivery(delivery.ID)
 	}
 
@@ -122,6 +177,94 @@ func (s *DeliveryService) CreateDelivery(clientID string, req *models.CreateDeli
 	return response, nil
 }
 
+// ValidateDelivery runs the checks of a delivery creation and prices it without saving anything
+func (s *DeliveryService) ValidateDelivery(clientID string, req *models.CreateDeliveryRequest) (*models.PriceCalculation, error) {
+	draft, err := s.prepareDelivery(clientID, req)
+	if err != nil {
+		return nil, err
+	}
+	return &draft.pricing, nil
+}
+
+// prepareDelivery checks a delivery creation and prices it: client, saved addresses, geocoding,
+// service coverage and pricing rule. Nothing is saved.
+func (s *DeliveryService) prepareDelivery(clientID string, req *models.CreateDeliveryRequest) (*deliveryDraft, error) {
+	// Validate client exists and is a client
+	client, err := s.getUserByID(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("client not found: %v", err)
+	}
+
+	if !client.IsClient() {
+		return nil, fmt.Errorf("only clients can create deliveries")
+	}
+
+	if err := s.normalizeRequestContacts(req); err != nil {
+		return nil, err
+	}
+
+	draft := &deliveryDraft{client: client}
+
+	// Saved addresses replace the raw pickup and dropoff fields
+	if req.PickupAddressID != nil {
+		draft.pickupSaved, err = s.addressService.GetAddress(clientID, *req.PickupAddressID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pickup address: %v", err)
+		}
+		req.PickupAddress, req.PickupLat, req.PickupLng = draft.pickupSaved.Address, draft.pickupSaved.Lat, draft.pickupSaved.Lng
+	}
+	if req.DropoffAddressID != nil {
+		draft.dropoffSaved, err = s.addressService.GetAddress(clientID, *req.DropoffAddressID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dropoff address: %v", err)
+		}
+		req.DropoffAddress, req.DropoffLat, req.DropoffLng = draft.dropoffSaved.Address, draft.dropoffSaved.Lat, draft.dropoffSaved.Lng
+	}
+
+	// Locate free-text addresses, the client must drop a pin when we are not confident enough
+	draft.pickupGeocode = s.geocodeAddress(req.PickupAddress, req.PickupLat, req.PickupLng)
+	if draft.pickupGeocode.NeedsPin {
+		return nil, &PinRequiredError{Stop: "pickup", Result: draft.pickupGeocode}
+	}
+
+	draft.dropoffGeocode = s.geocodeAddress(req.DropoffAddress, req.DropoffLat, req.DropoffLng)
+	if draft.dropoffGeocode.NeedsPin {
+		return nil, &PinRequiredError{Stop: "dropoff", Result: draft.dropoffGeocode}
+	}
+
+	// Reject addresses outside the service zones
+	zones, pickupZone, dropoffZone, err := s.zoneService.ResolveMembership(draft.pickupGeocode.Lat, draft.pickupGeocode.Lng, draft.dropoffGeocode.Lat, draft.dropoffGeocode.Lng)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check service coverage: %v", err)
+	}
+
+	if !zones.Covered {
+		return nil, fmt.Errorf("outside service area: %s", zones.Message)
+	}
+	draft.pickupZone, draft.dropoffZone = pickupZone, dropoffZone
+
+	// Calculate distance and duration
+	pickup := &models.Location{Lat: draft.pickupGeocode.Lat, Lng: draft.pickupGeocode.Lng}
+	dropoff := &models.Location{Lat: draft.dropoffGeocode.Lat, Lng: draft.dropoffGeocode.Lng}
+	draft.route, err = s.calculateDistanceAndDuration(pickup, dropoff)
+	if err != nil {
+		log.Printf("Warning: failed to calculate distance: %v", err)
+		// Use default values
+		draft.route = &models.RouteResult{DistanceKm: 5.0, DurationMin: 30.0, Provider: models.RoutingProviderDefault}
+	}
+
+	// Calculate price based on pricing rules
+	draft.priceContext = s.buildPriceContext(pickupZone, dropoffZone)
+	draft.pricing, err = s.calculateDeliveryPrice(req.VehicleType, draft.route.DistanceKm, 0, req.Type, draft.priceContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate price: %v", err)
+	}
+	draft.pricing.DistanceKm = draft.route.DistanceKm
+	draft.pricing.DistanceProvider = draft.route.Provider
+
+	return draft, nil
+}
+
 // AutoAssignDelivery automatically assigns delivery to best available driver
 func (s *DeliveryService) AutoAssignDelivery(deliveryID string) error {
 	delivery, err := s.getDeliveryByID(deliveryID)
@@ -166,6 +309,11 @@ func (s *DeliveryService) AssignDeliveryToDriver(deliveryID, driverID string) er
 		return fmt.Errorf("driver cannot accept deliveries")
 	}
 
+	// Drivers holding too much cash must remit it first
+	if s.isOverCashLimit(driver) {
+		return fmt.Errorf("driver must remit %.0f FCFA of cash before new deliveries", driver.CashDue)
+	}
+
 	// Check driver vehicle compatibility
 	vehicle, err := s.getDriverVehicle(driverID)
 	if err != nil {
@@ -177,12 +325,14 @@ func (s *DeliveryService) AssignDeliveryToDriver(deliveryID, driverID string) er
 	}
 
 	// Update delivery
-	query := `UPDATE Delivery SET livreurId = $driverId, status = $status, updatedAt = $updatedAt WHERE id = $deliveryId`
+	now := time.Now()
+	query := `UPDATE Delivery SET livreurId = $driverId, status = $status, acceptedAt = $acceptedAt, statusChangedAt = $acceptedAt, sla = NONE, updatedAt = $updatedAt WHERE id = $deliveryId`
 	params := map[string]interface{}{
 		"deliveryId": deliveryID,
 		"driverId":   driverID,
 		"status":     string(models.DeliveryStatusAccepted),
-		"updatedAt":  time.Now(),
+		"acceptedAt": now,
+		"updatedAt":  now,
 	}
 
 	_, err = db.Query(query, params)
@@ -190,6 +340,14 @@ func (s *DeliveryService) AssignDeliveryToDriver(deliveryID, driverID string) er
 		return fmt.Errorf("failed to assign delivery: %v", err)
 	}
 
+	// Track accepted jobs for the driver reliability score
+	_, err = db.Query(`UPDATE User SET driverAcceptedCount += 1 WHERE id = $driverId`, map[string]interface{}{
+		"driverId": driverID,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to update driver accepted count: %v", err)
+	}
+
 	// Update driver status
 	err = s.updateDriverStatus(driverID, models.DriverStatusBusy)
 	if err != nil {
@@ -209,35 +367,84 @@ func (s *DeliveryService) UpdateDeliveryStatus(deliveryID string, status models.
 		return fmt.Errorf("delivery not found: %v", err)
 	}
 
+	// Cancellations need a reason code and go through the cancellation policy
+	if status == models.DeliveryStatusCancelled {
+		return fmt.Errorf("cancellation requires a reason code, use the cancel endpoint")
+	}
+	if status == models.DeliveryStatusFailedAttempt {
+		return fmt.Errorf("a failed attempt requires a reason code, use the failed attempt endpoint")
+	}
+
+	// Completing a delivery writes the driver cash and earnings ledgers, only its own driver can move it
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return err
+	}
+
 	// Validate status transition
 	if !s.isValidStatusTransition(delivery.Status, status, userRole) {
 		return fmt.Errorf("invalid status transition from %s to %s", delivery.Status, status)
 	}
 
-	// Update delivery
-	query := `UPDATE Delivery SET status = $status, updatedAt = $updatedAt WHERE id = $deliveryId`
+	if status == models.DeliveryStatusDelivered && delivery.RequiresCODCollection() {
+		return fmt.Errorf("cash on delivery of %.0f FCFA must be collected first", *delivery.CODAmount)
+	}
+
+	return s.applyStatusTransition(delivery, status, userID, userRole, false, nil)
+}
+
+// applyStatusTransition persists an already validated status change, records it in the
+// delivery history and runs the status-specific logic
+func (s *DeliveryService) applyStatusTransition(delivery *models.Delivery, status models.DeliveryStatus, userID string, userRole models.UserRole, autoGenerated bool, note *string) error {
+	now := time.Now()
+	// The SLA clock restarts in the new status
+	query := `UPDATE Delivery SET status = $status, statusChangedAt = $updatedAt, sla = NONE, updatedAt = $updatedAt`
 	params := map[string]interface{}{
-		"deliveryId": deliveryID,
+		"deliveryId": delivery.ID,
 		"status":     string(status),
-		"updatedAt":  time.Now(),
+		"updatedAt":  now,
 	}
 
-	_, err = db.Query(query, params)
+	// Start or stop the waiting clock
+	query += s.applyWaitingClock(delivery, status, now, params)
+	if status == models.DeliveryStatusDelivered {
+		query += `, deliveredAt = $deliveredAt`
+		params["deliveredAt"] = now
+	}
+	if status == models.DeliveryStatusReturned {
+		query += `, returnedAt = $returnedAt`
+		params["returnedAt"] = now
+	}
+	// The scheduled re-attempt is over once the delivery leaves the failed attempt
+	if delivery.Status == models.DeliveryStatusFailedAttempt {
+		query += `, nextAttemptAt = NONE`
+	}
+	query += ` WHERE id = $deliveryId`
+
+	_, err := db.Query(query, params)
 	if err != nil {
 		return fmt.Errorf("failed to update delivery status: %v", err)
 	}
 
+	s.recordDeliveryEvent(delivery.ID, delivery.Status, status, &userID, &userRole, autoGenerated, note)
+
 	// Handle status-specific logic
 	switch status {
+	case models.DeliveryStatusArrivedAtPickup, models.DeliveryStatusArrivedAtDropoff:
+		s.scheduleWaitingWarning(delivery, status, now)
 	case models.DeliveryStatusDelivered:
 		err = s.handleDeliveryCompleted(delivery)
 		if err != nil {
 			log.Printf("Warning: failed to handle delivery completion: %v", err)
 		}
-	case models.DeliveryStatusCancelled:
-		err = s.handleDeliveryCancelled(delivery)
+	case models.DeliveryStatusReturning:
+		err = s.applyReturnLegCharge(delivery)
+		if err != nil {
+			log.Printf("Warning: failed to charge the return leg: %v", err)
+		}
+	case models.DeliveryStatusReturned:
+		err = s.handleDeliveryReturned(delivery)
 		if err != nil {
-			log.Printf("Warning: failed to handle delivery cancellation: %v", err)
+			log.Printf("Warning: failed to handle delivery return: %v", err)
 		}
 	}
 
@@ -247,10 +454,107 @@ func (s *DeliveryService) UpdateDeliveryStatus(deliveryID string, status models.
 	return nil
 }
 
+// GetCancellationQuote returns what cancelling the delivery now would cost, without cancelling it
+func (s *DeliveryService) GetCancellationQuote(deliveryID, userID string, userRole models.UserRole) (*models.CancellationQuote, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	return s.cancellationPolicy().Evaluate(delivery, userRole, time.Now()), nil
+}
+
+// CancelDelivery cancels a delivery with a mandatory reason code.
+// Without confirmation only the quote is returned so the fee can be shown first.
+func (s *DeliveryService) CancelDelivery(deliveryID, userID string, userRole models.UserRole, req *models.CancelDeliveryRequest) (*models.CancellationQuote, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	// Validate reason code
+	if !req.Reason.IsValidFor(userRole) {
+		return nil, fmt.Errorf("invalid cancellation reason %s for role %s", req.Reason, userRole)
+	}
+
+	if req.Reason.RequiresComment() && (req.Comment == nil || strings.TrimSpace(*req.Comment) == "") {
+		return nil, fmt.Errorf("a comment is required for reason %s", req.Reason)
+	}
+
+	quote := s.cancellationPolicy().Evaluate(delivery, userRole, time.Now())
+	if !quote.Allowed {
+		return nil, fmt.Errorf("delivery cannot be cancelled: %s", quote.Message)
+	}
+
+	// Validate status transition before quoting so a quote is never refused on confirmation
+	if !s.isValidStatusTransition(delivery.Status, models.DeliveryStatusCancelled, userRole) {
+		return nil, fmt.Errorf("invalid status transition from %s to %s", delivery.Status, models.DeliveryStatusCancelled)
+	}
+
+	if !req.Confirm {
+		return quote, nil
+	}
+
+	quote, err = s.handleDeliveryCancelled(delivery, userID, userRole, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel delivery: %v", err)
+	}
+
+	quote.Confirmed = true
+	return quote, nil
+}
+
+// QuoteDelivery prices a delivery before it is created, including the zone membership of both addresses.
+// Addresses outside coverage are not an error so the app can grey them out.
+func (s *DeliveryService) QuoteDelivery(req *models.PriceQuoteRequest) (*models.PriceCalculation, error) {
+	geocoding := s.geocodeQuoteAddresses(req)
+
+	zones, pickupZone, dropoffZone, err := s.zoneService.ResolveMembership(req.PickupLat, req.PickupLng, req.DropoffLat, req.DropoffLng)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check service coverage: %v", err)
+	}
+
+	route := &models.RouteResult{DistanceKm: 5.0, Provider: models.RoutingProviderDefault} // 5 km default
+	if req.DistanceKm != nil {
+		route = &models.RouteResult{DistanceKm: *req.DistanceKm, Provider: models.RoutingProviderClient}
+	} else {
+		pickup := &models.Location{Lat: req.PickupLat, Lng: req.PickupLng}
+		dropoff := &models.Location{Lat: req.DropoffLat, Lng: req.DropoffLng}
+		if result, err := s.calculateDistanceAndDuration(pickup, dropoff); err == nil {
+			route = result
+		} else {
+			log.Printf("Warning: failed to calculate distance: %v", err)
+		}
+	}
+	distance := route.DistanceKm
+
+	priceContext := s.buildPriceContext(pickupZone, dropoffZone)
+	calculation, err := s.CalculateDeliveryPriceWithPromo(req.VehicleType, distance, 0, req.Type, req.PromoCode, priceContext)
+	if err != nil {
+		return nil, err
+	}
+
+	calculation.Zones = zones
+	calculation.DistanceKm = distance
+	calculation.DistanceProvider = route.Provider
+	calculation.Geocoding = geocoding
+	s.logPriceQuote(calculation, req.VehicleType, req.Type, distance, priceContext, nil)
+
+	return calculation, nil
+}
+
 // CalculateDeliveryPriceWithPromo calculates final price with promo code
-func (s *DeliveryService) CalculateDeliveryPriceWithPromo(vehicleType models.VehicleType, distance, waiting float64, deliveryType models.DeliveryType, promoCode *string) (*models.PriceCalculation, error) {
+func (s *DeliveryService) CalculateDeliveryPriceWithPromo(vehicleType models.VehicleType, distance, waiting float64, deliveryType models.DeliveryType, promoCode *string, priceContext *models.PriceContext) (*models.PriceCalculation, error) {
 	// Calculate base price
-	calculation, err := s.calculateDeliveryPrice(vehicleType, distance, waiting, deliveryType)
+	calculation, err := s.calculateDeliveryPrice(vehicleType, distance, waiting, deliveryType, priceContext)
 	if err != nil {
 		return nil, err
 	}
@@ -276,13 +580,33 @@ func (s *DeliveryService) CalculateDeliveryPriceWithPromo(vehicleType models.Veh
 
 // Helper methods
 
-func (s *DeliveryService) calculateDeliveryPrice(vehicleType models.VehicleType, distance, waiting float64, deliveryType models.DeliveryType) (models.PriceCalculation, error) {
+func (s *DeliveryService) calculateDeliveryPrice(vehicleType models.VehicleType, distance, waiting float64, deliveryType models.DeliveryType, priceContext *models.PriceContext) (models.PriceCalculation, error) {
 	// Get pricing rule for vehicle type
 	pricingRule, err := s.getPricingRule(vehicleType)
 	if err != nil {
 		return models.PriceCalculation{}, fmt.Errorf("pricing rule not found: %v", err)
 	}
 
+	calculation := s.calculateRulePrice(pricingRule, distance, waiting, deliveryType)
+
+	if priceContext != nil {
+		// Apply surge before zone adjustments so fixed zone fees are not multiplied
+		if priceContext.Surge != nil {
+			calculation.ApplySurge(priceContext.Surge.Multiplier)
+		}
+
+		// Apply night, weekend and holiday surcharges
+		calculation.ApplyTimeSurcharges(priceContext.TimeSurcharges, vehicleType, priceContext.At, priceContext.Holidays)
+
+		// Apply zone adjustments
+		calculation.ApplyZoneAdjustments(priceContext.PickupZone, priceContext.DropoffZone, s.config.CrossZoneFee)
+	}
+
+	return calculation, nil
+}
+
+// calculateRulePrice prices a delivery with a rule version and the delivery type multipliers only
+func (s *DeliveryService) calculateRulePrice(pricingRule *models.PricingRule, distance, waiting float64, deliveryType models.DeliveryType) models.PriceCalculation {
 	// Calculate base price
 	calculation := pricingRule.CalculatePrice(distance, waiting)
 
@@ -298,119 +622,254 @@ func (s *DeliveryService) calculateDeliveryPrice(vehicleType models.VehicleType,
 
 	calculation.SubTotal = calculation.BasePrice + calculation.DistancePrice + calculation.WaitingPrice
 	calculation.FinalPrice = calculation.SubTotal - calculation.PromoDiscount
+	calculation.SurgeMultiplier = 1.0
+	calculation.RuleID = pricingRule.ID
+	calculation.RuleVersion = pricingRule.Version
 
-	return calculation, nil
+	return calculation
 }
 
-func (s *DeliveryService) findBestDriverForDelivery(delivery *models.Delivery) (*models.User, error) {
-	// Get pickup location
-	pickupLocation, err := s.getLocationByID(delivery.PickupID)
+// buildPriceContext gathers the zones, the current surge of the pickup zone and the
+// time surcharges for a quote made now
+func (s *DeliveryService) buildPriceContext(pickupZone, dropoffZone *models.ServiceZone) *models.PriceContext {
+	priceContext := &models.PriceContext{
+		PickupZone:  pickupZone,
+		DropoffZone: dropoffZone,
+		At:          time.Now().In(s.config.Location()),
+	}
+
+	timeSurcharges, err := s.pricingService.GetTimeSurcharges()
 	if err != nil {
-		return nil, fmt.Errorf("pickup location not found: %v", err)
+		log.Printf("Warning: failed to load time surcharges, pricing without them: %v", err)
+	} else {
+		priceContext.TimeSurcharges = timeSurcharges
 	}
 
-	// Find available drivers with compatible vehicle
-	query := `
-		SELECT u.*, dl.lat, dl.lng, dl.timestamp 
-		FROM User u
-		JOIN DriverLocation dl ON u.id = dl.driverId
-		JOIN Vehicle v ON u.id = v.userId
-		WHERE u.role = 'LIVREUR'
-		AND u.driverStatus IN ['ONLINE', 'AVAILABLE']
-		AND u.is_driver_complete = true
-		AND u.is_driver_vehicule_complete = true
-		AND dl.isAvailable = true
-		AND v.type = $vehicleType
-		ORDER BY dl.timestamp DESC`
+	holidays, err := s.pricingService.GetHolidays()
+	if err != nil {
+		log.Printf("Warning: failed to load holidays: %v", err)
+	} else {
+		priceContext.Holidays = holidays
+	}
+
+	surge, err := s.surgeService.GetSurge(pickupZone)
+	if err != nil {
+		log.Printf("Warning: failed to compute surge, pricing without it: %v", err)
+	} else {
+		priceContext.Surge = surge
+	}
+
+	return priceContext
+}
+
+// logPriceQuote records the quote and its surge multiplier for later analysis
+func (s *DeliveryService) logPriceQuote(calculation *models.PriceCalculation, vehicleType models.VehicleType, deliveryType models.DeliveryType, distance float64, priceContext *models.PriceContext, deliveryID *string) {
+	quoteLog := &models.PriceQuoteLog{
+		ID:               uuid.New().String(),
+		DeliveryID:       deliveryID,
+		VehicleType:      vehicleType,
+		DeliveryType:     deliveryType,
+		DistanceKm:       distance,
+		SubTotal:         calculation.SubTotal,
+		SurgeMultiplier:  calculation.SurgeMultiplier,
+		FinalPrice:       calculation.FinalPrice,
+		DistanceProvider: calculation.DistanceProvider,
+		CreatedAt:        time.Now(),
+	}
+	if priceContext != nil && priceContext.Surge != nil {
+		quoteLog.ZoneID = &priceContext.Surge.ZoneID
+	}
+
+	query := `CREATE PriceQuoteLog SET 
+		id = $id,
+		deliveryId = $deliveryId,
+		zoneId = $zoneId,
+		vehicleType = $vehicleType,
+		deliveryType = $deliveryType,
+		distanceKm = $distanceKm,
+		subTotal = $subTotal,
+		surgeMultiplier = $surgeMultiplier,
+		finalPrice = $finalPrice,
+		distanceProvider = $distanceProvider,
+		createdAt = $createdAt`
 
 	params := map[string]interface{}{
-		"vehicleType": string(delivery.VehicleType),
+		"id":              quoteLog.ID,
+		"deliveryId":       quoteLog.DeliveryID,
+		"zoneId":           quoteLog.ZoneID,
+		"vehicleType":      string(quoteLog.VehicleType),
+		"deliveryType":     string(quoteLog.DeliveryType),
+		"distanceKm":       quoteLog.DistanceKm,
+		"subTotal":         quoteLog.SubTotal,
+		"surgeMultiplier":  quoteLog.SurgeMultiplier,
+		"finalPrice":       quoteLog.FinalPrice,
+		"distanceProvider": quoteLog.DistanceProvider,
+		"createdAt":        quoteLog.CreatedAt,
 	}
 
-	results, err := db.QueryMultiple(query, params)
+	_, err := db.Query(query, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query available drivers: %v", err)
+		log.Printf("Warning: failed to log price quote: %v", err)
 	}
+}
 
-	if len(results) == 0 {
-		return nil, fmt.Errorf("no available drivers found")
+func (s *DeliveryService) findBestDriverForDelivery(delivery *models.Delivery) (*models.User, error) {
+	// Get pickup location
+	pickupLocation, err := s.getLocationByID(delivery.PickupID)
+	if err != nil {
+		return nil, fmt.Errorf("pickup location not found: %v", err)
 	}
 
-	// Find closest driver
-	var bestDriver *models.User
-	var minDistance float64 = math.MaxFloat64
+	if pickupLocation.Lat == nil || pickupLocation.Lng == nil {
+		return nil, fmt.Errorf("pickup location has no coordinates")
+	}
 
-	for _, result := range results {
-		driverData, ok := result.(map[string]interface{})
-		if !ok {
-			continue
+	// Nearest available drivers with the vehicle type, from the in-memory index
+	filter := models.DriverFilter{VehicleType: delivery.VehicleType, AvailableOnly: true}
+	var matches []models.DriverMatch
+	if s.config.DispatchMaxRadiusKm > 0 {
+		matches = s.driverIndex.Within(*pickupLocation.Lat, *pickupLocation.Lng, s.config.DispatchMaxRadiusKm, filter)
+		if len(matches) > s.config.DispatchCandidates {
+			matches = matches[:s.config.DispatchCandidates]
 		}
+	} else {
+		matches = s.driverIndex.Nearest(*pickupLocation.Lat, *pickupLocation.Lng, s.config.DispatchCandidates, filter)
+	}
 
-		driver := s.parseUserFromMap(driverData)
-		driverLat, _ := driverData["lat"].(float64)
-		driverLng, _ := driverData["lng"].(float64)
-
-		if pickupLocation.Lat != nil && pickupLocation.Lng != nil {
-			distance := s.calculateHaversineDistance(
-				*pickupLocation.Lat, *pickupLocation.Lng,
-				driverLat, driverLng,
-			)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no available drivers found")
+	}
 
-			if distance < minDistance {
-				minDistance = distance
-				bestDriver = driver
-			}
-		} else if bestDriver == nil {
-			bestDriver = driver // Fallback if no coordinates
-		}
+	// Score the candidates and keep the decision so ops can tell why a driver got the job
+	decision, drivers := s.scoreDispatchCandidates(delivery, matches, time.Now())
+	if err := s.saveDispatchDecision(decision); err != nil {
+		log.Printf("Warning: failed to save dispatch decision for delivery %s: %v", delivery.ID, err)
 	}
 
-	if bestDriver == nil {
+	if decision.SelectedDriverID == nil {
+		log.Printf("Dispatch of delivery %s: no suitable driver among %d candidates", delivery.ID, len(decision.Candidates))
 		return nil, fmt.Errorf("no suitable driver found")
 	}
 
-	return bestDriver, nil
+	log.Printf("Dispatch of delivery %s: %s", delivery.ID, decision.Candidates[0].Explain())
+	return drivers[*decision.SelectedDriverID], nil
 }
 
-func (s *DeliveryService) calculateHaversineDistance(lat1, lng1, lat2, lng2 float64) float64 {
-	const R = 6371 // Earth radius in kilometers
+// RebuildDriverIndex loads the last known driver positions into the in-memory spatial index.
+// Drivers without a DriverLocation record are seeded from the position kept on their profile,
+// availability always comes from the driver status.
+func (s *DeliveryService) RebuildDriverIndex() error {
+	results, err := db.QueryMultiple(`SELECT * FROM User WHERE role = $role`, map[string]interface{}{
+		"role": string(models.UserRoleLivreur),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get drivers: %v", err)
+	}
 
-	// Convert degrees to radians
-	lat1Rad := lat1 * math.Pi / 180
-	lng1Rad := lng1 * math.Pi / 180
-	lat2Rad := lat2 * math.Pi / 180
-	lng2Rad := lng2 * math.Pi / 180
+	profiles := make(map[string]*models.User, len(results))
+	for _, result := range results {
+		if data, ok := result.(map[string]interface{}); ok {
+			driver := s.parseUserFromMap(data)
+			profiles[driver.ID] = driver
+		}
+	}
+
+	results, err = db.QueryMultiple(`SELECT * FROM DriverLocation`, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get driver locations: %v", err)
+	}
+
+	drivers := make([]models.IndexedDriver, 0, len(profiles))
+	indexed := make(map[string]bool)
+	for _, result := range results {
+		data, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		location := parseDriverLocationFromMap(data)
+		if location.Lat == nil || location.Lng == nil {
+			continue
+		}
+		entry := location.Indexed()
+		if driver, ok := profiles[location.DriverID]; ok {
+			entry.IsAvailable = driver.DriverStatus.IsAvailable()
+		}
+		drivers = append(drivers, entry)
+		indexed[location.DriverID] = true
+	}
 
-	// Haversine formula
-	dlat := lat2Rad - lat1Rad
-	dlng := lng2Rad - lng1Rad
+	for _, driver := range profiles {
+		if indexed[driver.ID] || driver.LastKnownLat == nil || driver.LastKnownLng == nil {
+			continue
+		}
 
-	a := math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Sin(dlng/2)*math.Sin(dlng/2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+		entry := models.IndexedDriver{
+			DriverID:    driver.ID,
+			Lat:         *driver.LastKnownLat,
+			Lng:         *driver.LastKnownLng,
+			IsAvailable: driver.DriverStatus.IsAvailable(),
+		}
+		if driver.LastSeenAt != nil {
+			entry.UpdatedAt = *driver.LastSeenAt
+		}
+		if vehicle, err := s.getDriverVehicle(driver.ID); err == nil {
+			entry.VehicleType = vehicle.Type
+		}
+		drivers = append(drivers, entry)
+	}
 
-	return R * c
+	s.driverIndex.Load(drivers)
+	log.Printf("Driver index rebuilt with %d drivers", s.driverIndex.Len())
+	return nil
 }
 
 func (s *DeliveryService) isValidStatusTransition(from, to models.DeliveryStatus, userRole models.UserRole) bool {
 	// Define valid transitions based on user role
 	validTransitions := map[models.UserRole]map[models.DeliveryStatus][]models.DeliveryStatus{
 		models.UserRoleClient: {
-			models.DeliveryStatusPending: {models.DeliveryStatusCancelled},
+			models.DeliveryStatusPending:          {models.DeliveryStatusCancelled},
+			models.DeliveryStatusAccepted:         {models.DeliveryStatusCancelled},
+			models.DeliveryStatusPickupInProgress: {models.DeliveryStatusCancelled},
+			models.DeliveryStatusArrivedAtPickup:  {models.DeliveryStatusCancelled},
+			models.DeliveryStatusEnRoute:          {models.DeliveryStatusCancelled},
 		},
 		models.UserRoleLivreur: {
-			models.DeliveryStatusAccepted:           {models.DeliveryStatusPickupInProgress, models.DeliveryStatusCancelled},
-			models.DeliveryStatusPickupInProgress:   {models.DeliveryStatusPickedUp},
-			models.DeliveryStatusPickedUp:           {models.DeliveryStatusInTransit},
+			models.DeliveryStatusAccepted:           {models.DeliveryStatusPickupInProgress, models.DeliveryStatusArrivedAtPickup, models.DeliveryStatusCancelled},
+			models.DeliveryStatusPickupInProgress:   {models.DeliveryStatusArrivedAtPickup, models.DeliveryStatusPickedUp, models.DeliveryStatusCancelled},
+			models.DeliveryStatusArrivedAtPickup:    {models.DeliveryStatusPickedUp, models.DeliveryStatusCancelled},
+			models.DeliveryStatusEnRoute:            {models.DeliveryStatusArrivedAtPickup, models.DeliveryStatusPickedUp, models.DeliveryStatusCancelled},
+			models.DeliveryStatusPickedUp:           {models.DeliveryStatusInTransit, models.DeliveryStatusArrivedAtDropoff},
 			models.DeliveryStatusInTransit:          {models.DeliveryStatusArrivedAtDropoff},
 			models.DeliveryStatusArrivedAtDropoff:   {models.DeliveryStatusDelivered},
+			models.DeliveryStatusFailedAttempt:      {models.DeliveryStatusInTransit, models.DeliveryStatusReturning},
+			models.DeliveryStatusReturning:          {models.DeliveryStatusReturned},
 		},
 		models.UserRoleAdmin: {
 			// Admin can transition to any status
 			models.DeliveryStatusPending:    {models.DeliveryStatusAccepted, models.DeliveryStatusCancelled},
 			models.DeliveryStatusAccepted:   {models.DeliveryStatusPickupInProgress, models.DeliveryStatusCancelled},
+			models.DeliveryStatusPickupInProgress: {models.DeliveryStatusCancelled},
+			models.DeliveryStatusArrivedAtPickup:  {models.DeliveryStatusCancelled},
+			models.DeliveryStatusEnRoute:          {models.DeliveryStatusCancelled},
 			models.DeliveryStatusPickedUp:   {models.DeliveryStatusInTransit, models.DeliveryStatusCancelled},
 			models.DeliveryStatusInTransit:  {models.DeliveryStatusDelivered, models.DeliveryStatusCancelled},
+			models.DeliveryStatusArrivedAtDropoff: {models.DeliveryStatusCancelled},
 			models.DeliveryStatusDelivered:  {models.DeliveryStatusCancelled},
+			models.DeliveryStatusFailedAttempt: {models.DeliveryStatusInTransit, models.DeliveryStatusReturning, models.DeliveryStatusCancelled},
+			models.DeliveryStatusReturning:     {models.DeliveryStatusReturned},
+		},
+		models.UserRoleGestionnaire: {
+			// Managers can only cancel, with the ADMIN_DECISION reason
+			models.DeliveryStatusPending:          {models.DeliveryStatusCancelled},
+			models.DeliveryStatusAccepted:         {models.DeliveryStatusCancelled},
+			models.DeliveryStatusPickupInProgress: {models.DeliveryStatusCancelled},
+			models.DeliveryStatusArrivedAtPickup:  {models.DeliveryStatusCancelled},
+			models.DeliveryStatusEnRoute:          {models.DeliveryStatusCancelled},
+			models.DeliveryStatusPickedUp:         {models.DeliveryStatusCancelled},
+			models.DeliveryStatusInTransit:        {models.DeliveryStatusCancelled},
+			models.DeliveryStatusArrivedAtDropoff: {models.DeliveryStatusCancelled},
+			models.DeliveryStatusFailedAttempt:    {models.DeliveryStatusCancelled},
 		},
 	}
 
@@ -429,20 +888,63 @@ func (s *DeliveryService) isValidStatusTransition(from, to models.DeliveryStatus
 }
 
 // Database helper methods
-func (s *DeliveryService) createLocation(address string, lat, lng *float64) (*models.Location, error) {
+
+// findOrCreateLocation reuses the Location record of an identical address so repeated
+// addresses do not pile up duplicates
+func (s *DeliveryService) findOrCreateLocation(address string, geocode *models.GeocodeResult) (*models.Location, error) {
+	key := models.LocationKey(address, geocode.Lat, geocode.Lng)
+
+	if location, err := s.getLocationByKey(key); err == nil {
+		return location, nil
+	}
+
+	location, err := s.createLocation(address, key, geocode)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key is unique: when the same location was created at the same time, the stored one wins
+	if stored, err := s.getLocationByKey(key); err == nil {
+		return stored, nil
+	}
+	return location, nil
+}
+
+func (s *DeliveryService) getLocationByKey(key string) (*models.Location, error) {
+	result, err := db.QuerySingle(`SELECT * FROM Location WHERE key = $key LIMIT 1`, map[string]interface{}{
+		"key": key,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no result found")
+	}
+
+	return parseLocationFromMap(data), nil
+}
+
+func (s *DeliveryService) createLocation(address, key string, geocode *models.GeocodeResult) (*models.Location, error) {
 	location := &models.Location{
-		ID:      uuid.New().String(),
-		Address: address,
-		Lat:     lat,
-		Lng:     lng,
+		ID:                uuid.New().String(),
+		Address:           address,
+		Lat:               geocode.Lat,
+		Lng:               geocode.Lng,
+		GeocodeConfidence: &geocode.Confidence,
+		GeocodedBy:        &geocode.Provider,
 	}
 
-	query := `CREATE Location SET id = $id, address = $address, lat = $lat, lng = $lng`
+	query := `CREATE Location SET id = $id, key = $key, address = $address, lat = $lat, lng = $lng, geocodeConfidence = $geocodeConfidence, geocodedBy = $geocodedBy`
 	params := map[string]interface{}{
-		"id":      location.ID,
-		"address": location.Address,
-		"lat":     location.Lat,
-		"lng":     location.Lng,
+		"id":                location.ID,
+		"key":               key,
+		"address":           location.Address,
+		"lat":               location.Lat,
+		"lng":               location.Lng,
+		"geocodeConfidence": location.GeocodeConfidence,
+		"geocodedBy":        location.GeocodedBy,
 	}
 
 	_, err := db.Query(query, params)
@@ -467,24 +969,50 @@ func (s *DeliveryService) saveDelivery(delivery *models.Delivery) error {
 		basePrice = $basePrice,
 		finalPrice = $finalPrice,
 		paymentMethod = $paymentMethod,
+		priceLineItems = $priceLineItems,
+		pickupZoneId = $pickupZoneId,
+		dropoffZoneId = $dropoffZoneId,
+		pricingRuleId = $pricingRuleId,
+		pricingRuleVersion = $pricingRuleVersion,
+		distanceProvider = $distanceProvider,
+		pickupAddressId = $pickupAddressId,
+		dropoffAddressId = $dropoffAddressId,
+		pickupInstructions = $pickupInstructions,
+		dropoffInstructions = $dropoffInstructions,
+		pickupContact = $pickupContact,
+		dropoffContact = $dropoffContact,
+		codAmount = $codAmount,
 		createdAt = $createdAt,
 		updatedAt = $updatedAt`
 
 	params := map[string]interface{}{
-		"id":            delivery.ID,
-		"clientId":      delivery.ClientID,
-		"status":        string(delivery.Status),
-		"type":          string(delivery.Type),
-		"pickupId":      delivery.PickupID,
-		"dropoffId":     delivery.DropoffID,
-		"distanceKm":    delivery.DistanceKm,
-		"durationMin":   delivery.DurationMin,
-		"vehicleType":   string(delivery.VehicleType),
-		"basePrice":     delivery.BasePrice,
-		"finalPrice":    delivery.FinalPrice,
-		"paymentMethod": string(delivery.PaymentMethod),
-		"createdAt":     delivery.CreatedAt,
-		"updatedAt":     delivery.UpdatedAt,
+		"id":                  delivery.ID,
+		"clientId":            delivery.ClientID,
+		"status":              string(delivery.Status),
+		"type":                string(delivery.Type),
+		"pickupId":            delivery.PickupID,
+		"dropoffId":           delivery.DropoffID,
+		"distanceKm":          delivery.DistanceKm,
+		"durationMin":         delivery.DurationMin,
+		"vehicleType":         string(delivery.VehicleType),
+		"basePrice":           delivery.BasePrice,
+		"finalPrice":          delivery.FinalPrice,
+		"paymentMethod":       string(delivery.PaymentMethod),
+		"priceLineItems":      delivery.PriceLineItems,
+		"pickupZoneId":        delivery.PickupZoneID,
+		"dropoffZoneId":       delivery.DropoffZoneID,
+		"pricingRuleId":       delivery.PricingRuleID,
+		"pricingRuleVersion":  delivery.PricingRuleVersion,
+		"distanceProvider":    delivery.DistanceProvider,
+		"pickupAddressId":     delivery.PickupAddressID,
+		"dropoffAddressId":    delivery.DropoffAddressID,
+		"pickupInstructions":  delivery.PickupInstructions,
+		"dropoffInstructions": delivery.DropoffInstructions,
+		"pickupContact":       delivery.PickupContact,
+		"dropoffContact":      delivery.DropoffContact,
+		"codAmount":           delivery.CODAmount,
+		"createdAt":           delivery.CreatedAt,
+		"updatedAt":           delivery.UpdatedAt,
 	}
 
 	_, err := db.Query(query, params)
@@ -509,99 +1037,656 @@ func (s *DeliveryService) createGroupedDelivery(deliveryID string, groupedInfo *
 	return nil
 }
 
-func (s *DeliveryService) calculateDistanceAndDuration(pickup, dropoff *models.Location) (float64, float64, error) {
-	// Implementation for calculating distance and duration
-	// Could integrate with mapping APIs
+// calculateDistanceAndDuration asks the routing provider for the road distance between both locations
+func (s *DeliveryService) calculateDistanceAndDuration(pickup, dropoff *models.Location) (*models.RouteResult, error) {
 	if pickup.Lat != nil && pickup.Lng != nil && dropoff.Lat != nil && dropoff.Lng != nil {
-		distance := s.calculateHaversineDistance(*pickup.Lat, *pickup.Lng, *dropoff.Lat, *dropoff.Lng)
-		duration := distance * 3 // Rough estimate: 3 minutes per km
-		return distance, duration, nil
+		return s.router.Route(
+			models.Coordinates{Lat: *pickup.Lat, Lng: *pickup.Lng},
+			models.Coordinates{Lat: *dropoff.Lat, Lng: *dropoff.Lng},
+		)
 	}
-	return 5.0, 30.0, nil // Default values
+	return &models.RouteResult{DistanceKm: 5.0, DurationMin: 30.0, Provider: models.RoutingProviderDefault}, nil // Default values
 }
 
 func (s *DeliveryService) getPricingRule(vehicleType models.VehicleType) (*models.PricingRule, error) {
-	// Implementation for getting pricing rules
-	// Default pricing rules
-	switch vehicleType {
-	case models.VehicleTypeMoto:
-		return &models.PricingRule{
-			ID:          "moto-rule",
-			VehicleType: models.VehicleTypeMoto,
-			BasePrice:   1000,
-			IncludedKm:  3,
-			PerKm:       200,
-			WaitingFree: 5,
-			WaitingRate: 50,
-		}, nil
-	case models.VehicleTypeVoiture:
-		return &models.PricingRule{
-			ID:          "voiture-rule",
-			VehicleType: models.VehicleTypeVoiture,
-			BasePrice:   2000,
-			IncludedKm:  5,
-			PerKm:       300,
-			WaitingFree: 10,
-			WaitingRate: 100,
-		}, nil
-	case models.VehicleTypeCamionnette:
-		return &models.PricingRule{
-			ID:          "camionnette-rule",
-			VehicleType: models.VehicleTypeCamionnette,
-			BasePrice:   5000,
-			IncludedKm:  10,
-			PerKm:       500,
-			WaitingFree: 15,
-			WaitingRate: 200,
-		}, nil
-	}
-	return nil, fmt.Errorf("pricing rule not found for vehicle type: %s", vehicleType)
+	return s.pricingService.GetEffectiveRule(vehicleType, time.Now())
+}
+
+// getDeliveryPricingRule returns the rule version the delivery was priced with, so later
+// charges such as waiting time follow the same rule
+func (s *DeliveryService) getDeliveryPricingRule(delivery *models.Delivery) (*models.PricingRule, error) {
+	if delivery.PricingRuleID != nil {
+		rule, err := s.pricingService.GetRuleByID(*delivery.PricingRuleID)
+		if err == nil {
+			return rule, nil
+		}
+		log.Printf("Warning: pricing rule %s of delivery %s not found, using current rule: %v", *delivery.PricingRuleID, delivery.ID, err)
+	}
+	return s.getPricingRule(delivery.VehicleType)
 }
 
 func (s *DeliveryService) getDeliveryByID(deliveryID string) (*models.Delivery, error) {
-	// Implementation for getting delivery by ID
-	return nil, nil
+	query := `SELECT * FROM Delivery WHERE id = $deliveryId LIMIT 1`
+	params := map[string]interface{}{
+		"deliveryId": deliveryID,
+	}
+
+	result, err := db.QuerySingle(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no result found")
+	}
+
+	return s.parseDeliveryFromMap(data), nil
 }
 
 func (s *DeliveryService) getUserByID(userID string) (*models.User, error) {
-	// Implementation for getting user by ID
-	return nil, nil
+	query := `SELECT * FROM User WHERE id = $userId LIMIT 1`
+	params := map[string]interface{}{
+		"userId": userID,
+	}
+
+	result, err := db.QuerySingle(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no result found")
+	}
+
+	return s.parseUserFromMap(data), nil
 }
 
 func (s *DeliveryService) getDriverVehicle(driverID string) (*models.Vehicle, error) {
-	// Implementation for getting driver vehicle
-	return nil, nil
+	query := `SELECT * FROM Vehicle WHERE userId = $userId LIMIT 1`
+	result, err := db.QuerySingle(query, map[string]interface{}{
+		"userId": driverID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no result found")
+	}
+
+	vehicle := &models.Vehicle{
+		ID:                    parseString(data, "id"),
+		Type:                  models.VehicleType(parseString(data, "type")),
+		UserID:                parseString(data, "userId"),
+		Nom:                   parseStringPtr(data, "nom"),
+		PlaqueImmatriculation: parseStringPtr(data, "plaqueImmatriculation"),
+		Couleur:               parseStringPtr(data, "couleur"),
+		Marque:                parseStringPtr(data, "marque"),
+		Modele:                parseStringPtr(data, "modele"),
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		vehicle.CreatedAt = *createdAt
+	}
+
+	return vehicle, nil
 }
 
 func (s *DeliveryService) getLocationByID(locationID string) (*models.Location, error) {
-	// Implementation for getting location by ID
-	return nil, nil
+	return getLocation(locationID)
+}
+
+func getLocation(locationID string) (*models.Location, error) {
+	query := `SELECT * FROM Location WHERE id = $locationId LIMIT 1`
+	params := map[string]interface{}{
+		"locationId": locationID,
+	}
+
+	result, err := db.QuerySingle(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no result found")
+	}
+
+	return parseLocationFromMap(data), nil
+}
+
+func parseLocationFromMap(data map[string]interface{}) *models.Location {
+	location := &models.Location{
+		ID:                parseString(data, "id"),
+		Address:           parseString(data, "address"),
+		Lat:               parseFloatPtr(data, "lat"),
+		Lng:               parseFloatPtr(data, "lng"),
+		GeocodeConfidence: parseFloatPtr(data, "geocodeConfidence"),
+	}
+	if geocodedBy := parseString(data, "geocodedBy"); geocodedBy != "" {
+		location.GeocodedBy = &geocodedBy
+	}
+
+	return location
 }
 
 func (s *DeliveryService) updateDriverStatus(driverID string, status models.DriverStatus) error {
-	// Implementation for updating driver status
+	// Only changes are kept in the status history
+	changed := true
+	if driver, err := s.getUserByID(driverID); err == nil {
+		changed = driver.DriverStatus != status
+	}
+
+	now := time.Now()
+	query := `UPDATE User SET driverStatus = $status, updatedAt = $updatedAt WHERE id = $driverId`
+	_, err := db.Query(query, map[string]interface{}{
+		"driverId":  driverID,
+		"status":    string(status),
+		"updatedAt": now,
+	})
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		if err := s.recordDriverStatusEvent(driverID, status, now); err != nil {
+			log.Printf("Warning: failed to record status change of driver %s: %v", driverID, err)
+		}
+	}
+
+	// Keep the last position in line so a restart and surge supply counts see the same availability
+	_, err = db.Query(`UPDATE DriverLocation SET isAvailable = $isAvailable WHERE driverId = $driverId`, map[string]interface{}{
+		"driverId":    driverID,
+		"isAvailable": status.IsAvailable(),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.driverIndex.SetAvailable(driverID, status.IsAvailable())
 	return nil
 }
 
 func (s *DeliveryService) handleDeliveryCompleted(delivery *models.Delivery) error {
-	// Implementation for handling delivery completion
+	// Recalculate the final price with the measured waiting time
+	err := s.applyWaitingCharges(delivery)
+	if err != nil {
+		// The cash fare must still be recorded, at the price without waiting
+		log.Printf("Warning: failed to apply waiting charges: %v", err)
+	}
+
+	return s.settleDelivery(delivery)
+}
+
+// settleDelivery frees the driver and records the fare of a finished delivery in the cash and
+// earnings ledgers
+func (s *DeliveryService) settleDelivery(delivery *models.Delivery) error {
+	// The driver is free again whatever happens to the ledgers
+	if delivery.LivreurID != nil {
+		err := s.releaseDriver(*delivery.LivreurID)
+		if err != nil {
+			log.Printf("Warning: failed to update driver status: %v", err)
+		}
+	}
+
+	var ledgerErrors []string
+
+	// A fare paid in cash stays with the driver, who owes the commission
+	err := s.recordCashFare(delivery)
+	if err != nil {
+		ledgerErrors = append(ledgerErrors, fmt.Sprintf("failed to record cash fare: %v", err))
+	}
+
+	// Credit the driver with the fare, less the commission and service fee
+	err = s.recordDeliveryEarnings(delivery)
+	if err != nil {
+		ledgerErrors = append(ledgerErrors, fmt.Sprintf("failed to record delivery earnings: %v", err))
+	}
+
+	if len(ledgerErrors) > 0 {
+		return fmt.Errorf("%s", strings.Join(ledgerErrors, "; "))
+	}
+
 	return nil
 }
 
-func (s *DeliveryService) handleDeliveryCancelled(delivery *models.Delivery) error {
-	// Implementation for handling delivery cancellation
+// unassignedDeliveryClauses clears what belonged to the previous driver when a delivery goes back to dispatch,
+// so the next driver gets a fresh ETA promise, slip alert, geofence and SLA clock
+const unassignedDeliveryClauses = `livreurId = NONE, acceptedAt = NONE, sla = NONE,
+	etaPickupAt = NONE, etaDropoffAt = NONE, etaBaselineAt = NONE, etaSlipAlertedAt = NONE,
+	geofence = NONE, arrivedAtPickupAt = NONE`
+
+// handleDeliveryCancelled applies the cancellation policy, records the cancellation and frees the driver.
+// A driver cancelling only withdraws from the job: the delivery goes back to dispatch.
+func (s *DeliveryService) handleDeliveryCancelled(delivery *models.Delivery, userID string, userRole models.UserRole, req *models.CancelDeliveryRequest) (*models.CancellationQuote, error) {
+	now := time.Now()
+	quote := s.cancellationPolicy().Evaluate(delivery, userRole, now)
+
+	var query string
+	params := map[string]interface{}{
+		"deliveryId": delivery.ID,
+		"updatedAt":  now,
+	}
+
+	if userRole == models.UserRoleLivreur {
+		query = `UPDATE Delivery SET status = $status, ` + unassignedDeliveryClauses + `, statusChangedAt = $updatedAt, updatedAt = $updatedAt WHERE id = $deliveryId`
+		params["status"] = string(models.DeliveryStatusPending)
+	} else {
+		query = `UPDATE Delivery SET 
+			status = $status, 
+			cancelledAt = $cancelledAt, 
+			cancellationReason = $reason, 
+			cancellationFee = $fee, 
+			updatedAt = $updatedAt 
+			WHERE id = $deliveryId`
+		params["status"] = string(models.DeliveryStatusCancelled)
+		params["cancelledAt"] = now
+		params["reason"] = string(req.Reason)
+		params["fee"] = quote.Fee
+	}
+
+	_, err := db.Query(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update delivery: %v", err)
+	}
+
+	note := string(req.Reason)
+	s.recordDeliveryEvent(delivery.ID, delivery.Status, models.DeliveryStatus(params["status"].(string)), &userID, &userRole, false, &note)
+
+	cancellation := &models.DeliveryCancellation{
+		ID:                 uuid.New().String(),
+		DeliveryID:         delivery.ID,
+		CancelledByID:      userID,
+		CancelledByRole:    userRole,
+		DriverID:           delivery.LivreurID,
+		Reason:             req.Reason,
+		Comment:            req.Comment,
+		StatusAtCancel:     delivery.Status,
+		Fee:                quote.Fee,
+		DriverCompensation: quote.DriverCompensation,
+		CreatedAt:          now,
+	}
+
+	err = s.saveCancellation(cancellation)
+	if err != nil {
+		log.Printf("Warning: failed to record cancellation: %v", err)
+	}
+
+	if delivery.LivreurID != nil && quote.DriverCompensation > 0 {
+		err = s.recordCancellationCompensation(*delivery.LivreurID, delivery.ID, quote.DriverCompensation, now)
+		if err != nil {
+			log.Printf("Warning: failed to record driver compensation of delivery %s: %v", delivery.ID, err)
+		}
+	}
+
+	// Free the driver
+	if delivery.LivreurID != nil {
+		err = s.releaseDriver(*delivery.LivreurID)
+		if err != nil {
+			log.Printf("Warning: failed to update driver status: %v", err)
+		}
+	}
+
+	if userRole == models.UserRoleLivreur {
+		err = s.recordDriverCancellation(userID)
+		if err != nil {
+			log.Printf("Warning: failed to update driver reliability: %v", err)
+		}
+
+		go s.AutoAssignDelivery(delivery.ID)
+	}
+
+	log.Printf("Delivery %s cancelled by %s (%s): reason %s, fee %.0f FCFA", delivery.ID, userID, userRole, req.Reason, quote.Fee)
+	return quote, nil
+}
+
+// checkDeliveryAccess ensures only the client, the assigned driver or an admin can access a delivery
+func (s *DeliveryService) checkDeliveryAccess(delivery *models.Delivery, userID string, userRole models.UserRole) error {
+	switch userRole {
+	case models.UserRoleClient:
+		if delivery.ClientID != userID {
+			return fmt.Errorf("unauthorized: not your delivery")
+		}
+	case models.UserRoleLivreur:
+		if delivery.LivreurID == nil || *delivery.LivreurID != userID {
+			return fmt.Errorf("unauthorized: delivery not assigned to you")
+		}
+	case models.UserRoleAdmin, models.UserRoleGestionnaire:
+		// Admins can access any delivery
+	default:
+		return fmt.Errorf("unauthorized")
+	}
 	return nil
 }
 
+func (s *DeliveryService) cancellationPolicy() *models.CancellationPolicy {
+	return &models.CancellationPolicy{
+		FreeWindowMin:          s.config.CancellationFreeWindow,
+		EnRouteFee:             s.config.CancellationEnRouteFee,
+		DriverCompensationRate: s.config.CancellationDriverCompensation,
+	}
+}
+
+func (s *DeliveryService) saveCancellation(cancellation *models.DeliveryCancellation) error {
+	query := `CREATE DeliveryCancellation SET 
+		id = $id,
+		deliveryId = $deliveryId,
+		cancelledById = $cancelledById,
+		cancelledByRole = $cancelledByRole,
+		driverId = $driverId,
+		reason = $reason,
+		comment = $comment,
+		statusAtCancel = $statusAtCancel,
+		fee = $fee,
+		driverCompensation = $driverCompensation,
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":                 cancellation.ID,
+		"deliveryId":         cancellation.DeliveryID,
+		"cancelledById":      cancellation.CancelledByID,
+		"cancelledByRole":    string(cancellation.CancelledByRole),
+		"driverId":           cancellation.DriverID,
+		"reason":             string(cancellation.Reason),
+		"comment":            cancellation.Comment,
+		"statusAtCancel":     string(cancellation.StatusAtCancel),
+		"fee":                cancellation.Fee,
+		"driverCompensation": cancellation.DriverCompensation,
+		"createdAt":          cancellation.CreatedAt,
+	}
+
+	_, err := db.Query(query, params)
+	return err
+}
+
+func (s *DeliveryService) getDeliveryCancellations(deliveryID string) ([]models.DeliveryCancellation, error) {
+	query := `SELECT * FROM DeliveryCancellation WHERE deliveryId = $deliveryId ORDER BY createdAt ASC`
+
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"deliveryId": deliveryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cancellations: %v", err)
+	}
+
+	cancellations := make([]models.DeliveryCancellation, 0, len(results))
+	for _, result := range results {
+		if cancellationData, ok := result.(map[string]interface{}); ok {
+			cancellations = append(cancellations, *parseCancellationFromMap(cancellationData))
+		}
+	}
+
+	return cancellations, nil
+}
+
+func parseCancellationFromMap(data map[string]interface{}) *models.DeliveryCancellation {
+	cancellation := &models.DeliveryCancellation{
+		ID:                 parseString(data, "id"),
+		DeliveryID:         parseString(data, "deliveryId"),
+		CancelledByID:      parseString(data, "cancelledById"),
+		CancelledByRole:    models.UserRole(parseString(data, "cancelledByRole")),
+		DriverID:           parseStringPtr(data, "driverId"),
+		Reason:             models.CancellationReason(parseString(data, "reason")),
+		Comment:            parseStringPtr(data, "comment"),
+		StatusAtCancel:     models.DeliveryStatus(parseString(data, "statusAtCancel")),
+		Fee:                parseFloat(data, "fee"),
+		DriverCompensation: parseFloat(data, "driverCompensation"),
+	}
+
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		cancellation.CreatedAt = *createdAt
+	}
+
+	return cancellation
+}
+
+// recordDriverCancellation counts a cancellation after acceptance and refreshes the reliability score
+func (s *DeliveryService) recordDriverCancellation(driverID string) error {
+	_, err := db.Query(`UPDATE User SET driverCancelledCount += 1 WHERE id = $driverId`, map[string]interface{}{
+		"driverId": driverID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The score is computed from the stored counts, which include cancellations recorded at the same time
+	driver, err := s.getUserByID(driverID)
+	if err != nil {
+		return fmt.Errorf("driver not found: %v", err)
+	}
+
+	_, err = db.Query(`UPDATE User SET reliabilityScore = $score WHERE id = $driverId`, map[string]interface{}{
+		"driverId": driverID,
+		"score":    driver.ComputeReliabilityScore(),
+	})
+	return err
+}
+
 func (s *DeliveryService) sendAssignmentNotifications(delivery *models.Delivery, driver *models.User) {
 	// Implementation for sending assignment notifications
 }
 
 func (s *DeliveryService) sendStatusUpdateNotifications(delivery *models.Delivery, status models.DeliveryStatus) {
 	// Implementation for sending status update notifications
+	s.notifyRecipient(delivery, status)
+	s.notifyClientOfAttempt(delivery, status)
 }
 
 func (s *DeliveryService) parseUserFromMap(data map[string]interface{}) *models.User {
-	// Implementation for parsing user from map
+	user := &models.User{
+		ID:        parseString(data, "id"),
+		Phone:     parseString(data, "phone"),
+		Role:      models.UserRole(parseString(data, "role")),
+		FirstName: parseString(data, "firstName"),
+		LastName:  parseString(data, "lastName"),
+	}
+
+	user.IsDriverComplete, _ = data["is_driver_complete"].(bool)
+	user.IsDriverVehiculeComplete, _ = data["is_driver_vehicule_complete"].(bool)
+	user.DriverStatus = models.DriverStatus(parseString(data, "driverStatus"))
+	user.LastKnownLat = parseFloatPtr(data, "lastKnownLat")
+	user.LastKnownLng = parseFloatPtr(data, "lastKnownLng")
+	user.LastSeenAt = parseTimePtr(data, "lastSeenAt")
+	user.DriverAcceptedCount = int(parseFloat(data, "driverAcceptedCount"))
+	user.DriverCancelledCount = int(parseFloat(data, "driverCancelledCount"))
+	user.ReliabilityScore = parseFloatPtr(data, "reliabilityScore")
+	user.RatingAverage = parseFloatPtr(data, "ratingAverage")
+	user.RatingCount = int(parseFloat(data, "ratingCount"))
+	user.RatingReviewOpenedAt = parseTimePtr(data, "ratingReviewOpenedAt")
+	user.CashDue = parseFloat(data, "cashDue")
+	user.LastJobEndedAt = parseTimePtr(data, "lastJobEndedAt")
+	user.DispatchPreferences = parseDispatchPreferences(data, "dispatchPreferences")
+
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		user.CreatedAt = *createdAt
+	}
+	if updatedAt := parseTimePtr(data, "updatedAt"); updatedAt != nil {
+		user.UpdatedAt = *updatedAt
+	}
+
+	return user
+}
+
+func (s *DeliveryService) parseDeliveryFromMap(data map[string]interface{}) *models.Delivery {
+	delivery := &models.Delivery{
+		ID:              parseString(data, "id"),
+		ClientID:        parseString(data, "clientId"),
+		Status:          models.DeliveryStatus(parseString(data, "status")),
+		Type:            models.DeliveryType(parseString(data, "type")),
+		PickupID:        parseString(data, "pickupId"),
+		DropoffID:       parseString(data, "dropoffId"),
+		DistanceKm:      parseFloatPtr(data, "distanceKm"),
+		DurationMin:     parseFloatPtr(data, "durationMin"),
+		VehicleType:     models.VehicleType(parseString(data, "vehicleType")),
+		BasePrice:       parseFloatPtr(data, "basePrice"),
+		WaitingMin:      parseFloatPtr(data, "waitingMin"),
+		FinalPrice:      parseFloat(data, "finalPrice"),
+		PaymentMethod:   models.PaymentMethod(parseString(data, "paymentMethod")),
+		PaidAt:          parseTimePtr(data, "paidAt"),
+		AcceptedAt:      parseTimePtr(data, "acceptedAt"),
+		CancelledAt:     parseTimePtr(data, "cancelledAt"),
+		DeliveredAt:     parseTimePtr(data, "deliveredAt"),
+		CancellationFee: parseFloatPtr(data, "cancellationFee"),
+	}
+
+	delivery.ArrivedAtPickupAt = parseTimePtr(data, "arrivedAtPickupAt")
+	delivery.ArrivedAtDropoffAt = parseTimePtr(data, "arrivedAtDropoffAt")
+	delivery.PickupWaitingMin = parseFloatPtr(data, "pickupWaitingMin")
+	delivery.DropoffWaitingMin = parseFloatPtr(data, "dropoffWaitingMin")
+	delivery.WaitingPrice = parseFloatPtr(data, "waitingPrice")
+	delivery.PriceLineItems = parsePriceLineItems(data, "priceLineItems")
+	delivery.EtaPickupAt = parseTimePtr(data, "etaPickupAt")
+	delivery.EtaDropoffAt = parseTimePtr(data, "etaDropoffAt")
+	delivery.EtaBaselineAt = parseTimePtr(data, "etaBaselineAt")
+	delivery.EtaSlipAlertedAt = parseTimePtr(data, "etaSlipAlertedAt")
+	delivery.EtaNotifiedAt = parseTimePtr(data, "etaNotifiedAt")
+	delivery.Geofence = parseGeofenceState(data, "geofence")
+	delivery.StatusChangedAt = parseTimePtr(data, "statusChangedAt")
+	delivery.SLA = parseSLAState(data, "sla")
+	if pickupZoneID := parseString(data, "pickupZoneId"); pickupZoneID != "" {
+		delivery.PickupZoneID = &pickupZoneID
+	}
+	if dropoffZoneID := parseString(data, "dropoffZoneId"); dropoffZoneID != "" {
+		delivery.DropoffZoneID = &dropoffZoneID
+	}
+	if distanceProvider := parseString(data, "distanceProvider"); distanceProvider != "" {
+		delivery.DistanceProvider = &distanceProvider
+	}
+	delivery.PickupAddressID = parseStringPtr(data, "pickupAddressId")
+	delivery.DropoffAddressID = parseStringPtr(data, "dropoffAddressId")
+	delivery.PickupInstructions = parseStringPtr(data, "pickupInstructions")
+	delivery.DropoffInstructions = parseStringPtr(data, "dropoffInstructions")
+	delivery.PickupContact = parseContactPerson(data, "pickupContact")
+	delivery.DropoffContact = parseContactPerson(data, "dropoffContact")
+	delivery.TipAmount = parseFloatPtr(data, "tipAmount")
+	delivery.RecipientNotices = parseRecipientNotices(data, "recipientNotices")
+	delivery.CODAmount = parseFloatPtr(data, "codAmount")
+	delivery.CODCollectedAt = parseTimePtr(data, "codCollectedAt")
+	delivery.FailedAttempts = int(parseFloat(data, "failedAttempts"))
+	delivery.NextAttemptAt = parseTimePtr(data, "nextAttemptAt")
+	delivery.ReturnedAt = parseTimePtr(data, "returnedAt")
+	if reason := parseString(data, "lastAttemptReason"); reason != "" {
+		lastAttemptReason := models.FailedAttemptReason(reason)
+		delivery.LastAttemptReason = &lastAttemptReason
+	}
+	if pricingRuleID := parseString(data, "pricingRuleId"); pricingRuleID != "" {
+		delivery.PricingRuleID = &pricingRuleID
+	}
+	if version, ok := data["pricingRuleVersion"].(float64); ok {
+		pricingRuleVersion := int(version)
+		delivery.PricingRuleVersion = &pricingRuleVersion
+	}
+
+	if livreurID, ok := data["livreurId"].(string); ok && livreurID != "" {
+		delivery.LivreurID = &livreurID
+	}
+	if reason, ok := data["cancellationReason"].(string); ok && reason != "" {
+		cancellationReason := models.CancellationReason(reason)
+		delivery.CancellationReason = &cancellationReason
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		delivery.CreatedAt = *createdAt
+	}
+	if updatedAt := parseTimePtr(data, "updatedAt"); updatedAt != nil {
+		delivery.UpdatedAt = *updatedAt
+	}
+
+	return delivery
+}
+
+// SurrealDB result parsing helpers
+
+func parseString(data map[string]interface{}, key string) string {
+	switch value := data[key].(type) {
+	case string:
+		return value
+	case nil:
+		return ""
+	default:
+		// Record IDs can come back as objects, use their string representation
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func parseStringPtr(data map[string]interface{}, key string) *string {
+	if value := parseString(data, key); value != "" {
+		return &value
+	}
+	return nil
+}
+
+func parseFloat(data map[string]interface{}, key string) float64 {
+	value, _ := data[key].(float64)
+	return value
+}
+
+func parseFloatPtr(data map[string]interface{}, key string) *float64 {
+	if value, ok := data[key].(float64); ok {
+		return &value
+	}
+	return nil
+}
+
+func parsePriceLineItems(data map[string]interface{}, key string) []models.PriceLineItem {
+	rawItems, ok := data[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	items := make([]models.PriceLineItem, 0, len(rawItems))
+	for _, rawItem := range rawItems {
+		itemData, ok := rawItem.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		items = append(items, models.PriceLineItem{
+			Code:     parseString(itemData, "code"),
+			Label:    parseString(itemData, "label"),
+			Amount:   parseFloat(itemData, "amount"),
+			Quantity: parseFloatPtr(itemData, "quantity"),
+		})
+	}
+	return items
+}
+
+func parseGeofenceState(data map[string]interface{}, key string) *models.GeofenceState {
+	stateData, ok := data[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	state := &models.GeofenceState{
+		Stop:          models.WaitingStop(parseString(stateData, "stop")),
+		EnteredAt:     parseTimePtr(stateData, "enteredAt"),
+		AutoArrivalAt: parseTimePtr(stateData, "autoArrivalAt"),
+	}
+	state.Disarmed, _ = stateData["disarmed"].(bool)
+	if previous, ok := stateData["previousStatus"].(string); ok && previous != "" {
+		previousStatus := models.DeliveryStatus(previous)
+		state.PreviousStatus = &previousStatus
+	}
+	return state
+}
+
+func parseContactPerson(data map[string]interface{}, key string) *models.ContactPerson {
+	contactData, ok := data[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	return &models.ContactPerson{
+		Name:  parseString(contactData, "name"),
+		Phone: parseString(contactData, "phone"),
+	}
+}
+
+func parseTimePtr(data map[string]interface{}, key string) *time.Time {
+	if value, ok := data[key].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			return &parsed
+		}
+	}
 	return nil
 }
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// ErrTrackingLinkNotFound is returned for unknown, expired and revoked links alike
+// so the public endpoint does not reveal which tokens existed
+var ErrTrackingLinkNotFound = fmt.Errorf("tracking link not found or expired")
+
+// CreateTrackingShare creates a public tracking link the client can send to the recipient
+func (s *DeliveryService) CreateTrackingShare(deliveryID, userID string, userRole models.UserRole, req *models.CreateTrackingShareRequest) (*models.TrackingShare, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return nil, err
+	}
+	if !delivery.CanBeShared() {
+		return nil, fmt.Errorf("delivery is %s and can no longer be shared", delivery.Status)
+	}
+
+	hours := s.config.TrackingShareTTLHours
+	if req.ExpiresInHours != nil {
+		hours = *req.ExpiresInHours
+	}
+	if hours > s.config.TrackingShareMaxTTLHours {
+		return nil, fmt.Errorf("tracking links cannot last more than %d hours", s.config.TrackingShareMaxTTLHours)
+	}
+
+	share, err := s.saveTrackingShare(deliveryID, userID, hours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save tracking link: %v", err)
+	}
+
+	return share, nil
+}
+
+// saveTrackingShare creates a tracking link and returns it with its token
+func (s *DeliveryService) saveTrackingShare(deliveryID, createdBy string, hours int) (*models.TrackingShare, error) {
+	token, tokenHash, err := models.GenerateShareToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	share := &models.TrackingShare{
+		ID:         uuid.New().String(),
+		DeliveryID: deliveryID,
+		CreatedBy:  createdBy,
+		TokenHash:  tokenHash,
+		Token:      token,
+		ExpiresAt:  now.Add(time.Duration(hours) * time.Hour),
+		CreatedAt:  now,
+	}
+
+	query := `CREATE TrackingShare SET 
+		id = $id,
+		deliveryId = $deliveryId,
+		createdBy = $createdBy,
+		tokenHash = $tokenHash,
+		expiresAt = $expiresAt,
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":         share.ID,
+		"deliveryId": share.DeliveryID,
+		"createdBy":  share.CreatedBy,
+		"tokenHash":  share.TokenHash,
+		"expiresAt":  share.ExpiresAt,
+		"createdAt":  share.CreatedAt,
+	}
+
+	if _, err := db.Query(query, params); err != nil {
+		return nil, err
+	}
+
+	return share, nil
+}
+
+// GetTrackingShares returns the tracking links of a delivery, without their tokens
+func (s *DeliveryService) GetTrackingShares(deliveryID, userID string, userRole models.UserRole) ([]models.TrackingShare, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT * FROM TrackingShare WHERE deliveryId = $deliveryId ORDER BY createdAt DESC`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"deliveryId": deliveryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracking links: %v", err)
+	}
+
+	shares := make([]models.TrackingShare, 0, len(results))
+	for _, result := range results {
+		if shareData, ok := result.(map[string]interface{}); ok {
+			shares = append(shares, *parseTrackingShareFromMap(shareData))
+		}
+	}
+
+	return shares, nil
+}
+
+// RevokeTrackingShare disables a tracking link before it expires
+func (s *DeliveryService) RevokeTrackingShare(deliveryID, shareID, userID string, userRole models.UserRole) error {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return err
+	}
+
+	count, err := db.CountRecords("TrackingShare", "id = $shareId AND deliveryId = $deliveryId", map[string]interface{}{
+		"shareId":    shareID,
+		"deliveryId": deliveryID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find tracking link: %v", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("tracking link not found")
+	}
+
+	query := `UPDATE TrackingShare SET revokedAt = $revokedAt WHERE id = $shareId AND revokedAt = NONE`
+	_, err = db.Query(query, map[string]interface{}{
+		"shareId":   shareID,
+		"revokedAt": time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke tracking link: %v", err)
+	}
+
+	return nil
+}
+
+// GetPublicTracking returns the limited recipient view of the delivery behind a tracking link
+func (s *DeliveryService) GetPublicTracking(token string) (*models.PublicTracking, error) {
+	query := `SELECT * FROM TrackingShare WHERE tokenHash = $tokenHash LIMIT 1`
+	result, err := db.QuerySingle(query, map[string]interface{}{
+		"tokenHash": models.HashShareToken(token),
+	})
+	if err != nil {
+		return nil, ErrTrackingLinkNotFound
+	}
+
+	shareData, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, ErrTrackingLinkNotFound
+	}
+
+	share := parseTrackingShareFromMap(shareData)
+	if !share.IsActive(time.Now()) {
+		return nil, ErrTrackingLinkNotFound
+	}
+
+	delivery, err := s.getDeliveryByID(share.DeliveryID)
+	if err != nil {
+		return nil, ErrTrackingLinkNotFound
+	}
+
+	var driver *models.User
+	var vehicle *models.Vehicle
+	var tracking *models.DeliveryTracking
+	if delivery.LivreurID != nil && delivery.CanBeShared() {
+		driver, err = s.getUserByID(*delivery.LivreurID)
+		if err != nil {
+			log.Printf("Warning: failed to get driver %s for public tracking: %v", *delivery.LivreurID, err)
+		}
+		vehicle, err = s.getDriverVehicle(*delivery.LivreurID)
+		if err != nil {
+			log.Printf("Warning: failed to get vehicle of driver %s for public tracking: %v", *delivery.LivreurID, err)
+		}
+		tracking = s.buildTracking(delivery)
+	}
+
+	return models.NewPublicTracking(delivery, driver, vehicle, tracking, share), nil
+}
+
+func parseTrackingShareFromMap(data map[string]interface{}) *models.TrackingShare {
+	share := &models.TrackingShare{
+		ID:         parseString(data, "id"),
+		DeliveryID: parseString(data, "deliveryId"),
+		CreatedBy:  parseString(data, "createdBy"),
+		TokenHash:  parseString(data, "tokenHash"),
+		RevokedAt:  parseTimePtr(data, "revokedAt"),
+	}
+
+	if expiresAt := parseTimePtr(data, "expiresAt"); expiresAt != nil {
+		share.ExpiresAt = *expiresAt
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		share.CreatedAt = *createdAt
+	}
+
+	return share
+}
@@ -0,0 +1,596 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// CreateAvailabilitySlot publishes a period the driver can work, in a zone or anywhere
+func (s *DeliveryService) CreateAvailabilitySlot(driverID string, req *models.CreateAvailabilitySlotRequest) (*models.AvailabilitySlot, error) {
+	if err := models.ValidateShiftPeriod(req.StartAt, req.EndAt); err != nil {
+		return nil, fmt.Errorf("invalid availability slot: %v", err)
+	}
+	now := time.Now()
+	if !req.EndAt.After(now) {
+		return nil, fmt.Errorf("availability slot is already over")
+	}
+	if req.ZoneID != nil {
+		if _, err := s.zoneService.GetZone(*req.ZoneID); err != nil {
+			return nil, fmt.Errorf("unknown zone %s", *req.ZoneID)
+		}
+	}
+
+	count, err := db.CountRecords("AvailabilitySlot", "driverId = $driverId AND startAt < $endAt AND endAt > $startAt", map[string]interface{}{
+		"driverId": driverID,
+		"startAt":  req.StartAt,
+		"endAt":    req.EndAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check availability slots: %v", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("availability slot overlaps another one")
+	}
+
+	slot := &models.AvailabilitySlot{
+		ID:        uuid.New().String(),
+		DriverID:  driverID,
+		ZoneID:    req.ZoneID,
+		StartAt:   req.StartAt,
+		EndAt:     req.EndAt,
+		CreatedAt: now,
+	}
+
+	query := `CREATE AvailabilitySlot SET
+		id = $id,
+		driverId = $driverId,
+		zoneId = $zoneId,
+		startAt = $startAt,
+		endAt = $endAt,
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":        slot.ID,
+		"driverId":  slot.DriverID,
+		"zoneId":    slot.ZoneID,
+		"startAt":   slot.StartAt,
+		"endAt":     slot.EndAt,
+		"createdAt": slot.CreatedAt,
+	}
+
+	if _, err := db.Query(query, params); err != nil {
+		return nil, fmt.Errorf("failed to create availability slot: %v", err)
+	}
+
+	return slot, nil
+}
+
+// GetAvailabilitySlots returns the availability slots of a driver that are not over yet
+func (s *DeliveryService) GetAvailabilitySlots(driverID string) ([]models.AvailabilitySlot, error) {
+	query := `SELECT * FROM AvailabilitySlot WHERE driverId = $driverId AND endAt > $now ORDER BY startAt ASC`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"driverId": driverID,
+		"now":      time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability slots: %v", err)
+	}
+
+	return parseAvailabilitySlots(results), nil
+}
+
+// DeleteAvailabilitySlot withdraws an availability slot of the driver
+func (s *DeliveryService) DeleteAvailabilitySlot(driverID, slotID string) error {
+	count, err := db.CountRecords("AvailabilitySlot", "id = $slotId AND driverId = $driverId", map[string]interface{}{
+		"slotId":   slotID,
+		"driverId": driverID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find availability slot: %v", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("availability slot not found")
+	}
+
+	_, err = db.Query(`DELETE AvailabilitySlot WHERE id = $slotId`, map[string]interface{}{
+		"slotId": slotID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete availability slot: %v", err)
+	}
+
+	return nil
+}
+
+// GetDriverShifts returns the shifts a driver is assigned to that are not over yet
+func (s *DeliveryService) GetDriverShifts(driverID string) ([]models.Shift, error) {
+	query := `SELECT * FROM Shift WHERE driverIds CONTAINS $driverId AND endAt > $now ORDER BY startAt ASC`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"driverId": driverID,
+		"now":      time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shifts: %v", err)
+	}
+
+	return parseShifts(results), nil
+}
+
+// CreateShift plans a shift in a zone with the minimum number of drivers it needs
+func (s *DeliveryService) CreateShift(adminID string, req *models.CreateShiftRequest) (*models.ShiftStaffing, error) {
+	if _, err := s.zoneService.GetZone(req.ZoneID); err != nil {
+		return nil, fmt.Errorf("unknown zone %s", req.ZoneID)
+	}
+
+	now := time.Now()
+	shift := &models.Shift{
+		ID:          uuid.New().String(),
+		ZoneID:      req.ZoneID,
+		StartAt:     req.StartAt,
+		EndAt:       req.EndAt,
+		MinDrivers:  req.MinDrivers,
+		DriverIDs:   uniqueStrings(req.DriverIDs),
+		Note:        req.Note,
+		CreatedByID: adminID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.checkShift(shift); err != nil {
+		return nil, err
+	}
+
+	query := `CREATE Shift SET
+		id = $id,
+		zoneId = $zoneId,
+		startAt = $startAt,
+		endAt = $endAt,
+		minDrivers = $minDrivers,
+		driverIds = $driverIds,
+		note = $note,
+		createdById = $createdById,
+		createdAt = $createdAt,
+		updatedAt = $updatedAt`
+
+	params := map[string]interface{}{
+		"id":          shift.ID,
+		"zoneId":      shift.ZoneID,
+		"startAt":     shift.StartAt,
+		"endAt":       shift.EndAt,
+		"minDrivers":  shift.MinDrivers,
+		"driverIds":   shift.DriverIDs,
+		"note":        shift.Note,
+		"createdById": shift.CreatedByID,
+		"createdAt":   shift.CreatedAt,
+		"updatedAt":   shift.UpdatedAt,
+	}
+
+	if _, err := db.Query(query, params); err != nil {
+		return nil, fmt.Errorf("failed to create shift: %v", err)
+	}
+
+	return s.shiftStaffing(shift)
+}
+
+// UpdateShift changes the period, minimum or drivers of a shift
+func (s *DeliveryService) UpdateShift(shiftID string, req *models.UpdateShiftRequest) (*models.ShiftStaffing, error) {
+	shift, err := s.getShiftByID(shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.StartAt != nil {
+		shift.StartAt = *req.StartAt
+	}
+	if req.EndAt != nil {
+		shift.EndAt = *req.EndAt
+	}
+	if req.MinDrivers != nil {
+		shift.MinDrivers = *req.MinDrivers
+	}
+	if req.DriverIDs != nil {
+		shift.DriverIDs = uniqueStrings(req.DriverIDs)
+	}
+	if req.Note != nil {
+		shift.Note = req.Note
+	}
+	shift.UpdatedAt = time.Now()
+
+	if err := s.checkShift(shift); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE Shift SET
+		startAt = $startAt,
+		endAt = $endAt,
+		minDrivers = $minDrivers,
+		driverIds = $driverIds,
+		note = $note,
+		updatedAt = $updatedAt
+		WHERE id = $shiftId`
+
+	params := map[string]interface{}{
+		"shiftId":    shift.ID,
+		"startAt":    shift.StartAt,
+		"endAt":      shift.EndAt,
+		"minDrivers": shift.MinDrivers,
+		"driverIds":  shift.DriverIDs,
+		"note":       shift.Note,
+		"updatedAt":  shift.UpdatedAt,
+	}
+
+	if _, err := db.Query(query, params); err != nil {
+		return nil, fmt.Errorf("failed to update shift: %v", err)
+	}
+
+	return s.shiftStaffing(shift)
+}
+
+// DeleteShift removes a shift that has not started yet
+func (s *DeliveryService) DeleteShift(shiftID string) error {
+	shift, err := s.getShiftByID(shiftID)
+	if err != nil {
+		return err
+	}
+	if !shift.StartAt.After(time.Now()) {
+		return fmt.Errorf("shift has already started")
+	}
+
+	_, err = db.Query(`DELETE Shift WHERE id = $shiftId`, map[string]interface{}{
+		"shiftId": shiftID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete shift: %v", err)
+	}
+
+	return nil
+}
+
+// GetShifts returns the shifts over a period, with their staffing against the published availability
+func (s *DeliveryService) GetShifts(req *models.ShiftPeriodRequest) ([]models.ShiftStaffing, error) {
+	if req.To.Sub(req.From) > models.MaxShiftReportRange {
+		return nil, fmt.Errorf("period cannot be longer than %d days", int(models.MaxShiftReportRange.Hours()/24))
+	}
+
+	shifts, err := s.getShiftsBetween(req.From, req.To, req.ZoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT * FROM AvailabilitySlot WHERE startAt < $to AND endAt > $from`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"from": req.From,
+		"to":   req.To,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability slots: %v", err)
+	}
+	slots := parseAvailabilitySlots(results)
+
+	staffing := make([]models.ShiftStaffing, 0, len(shifts))
+	for _, shift := range shifts {
+		staffing = append(staffing, models.NewShiftStaffing(shift, slots))
+	}
+
+	return staffing, nil
+}
+
+// GetShiftReport compares the hours planned for each driver with the hours they were online
+func (s *DeliveryService) GetShiftReport(req *models.ShiftPeriodRequest) (*models.ShiftReport, error) {
+	if req.To.Sub(req.From) > models.MaxShiftReportRange {
+		return nil, fmt.Errorf("period cannot be longer than %d days", int(models.MaxShiftReportRange.Hours()/24))
+	}
+
+	// Hours still to come are neither worked nor missed yet
+	to := req.To
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+	if !to.After(req.From) {
+		return nil, fmt.Errorf("period has not started yet")
+	}
+
+	shifts, err := s.getShiftsBetween(req.From, to, req.ZoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.getDriverStatusEvents(req.From, to, shifts, req.ZoneID != "")
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.NewShiftReport(req.From, to, shifts, events)
+	if req.ZoneID != "" {
+		report.ZoneID = &req.ZoneID
+	}
+	for i := range report.Drivers {
+		if driver, err := s.getUserByID(report.Drivers[i].DriverID); err == nil {
+			report.Drivers[i].DriverName = driver.GetFullName()
+		}
+	}
+
+	return report, nil
+}
+
+// onShiftDrivers returns the drivers on a shift under way in the pickup zone, in any zone without one
+func (s *DeliveryService) onShiftDrivers(delivery *models.Delivery, now time.Time) map[string]bool {
+	zoneID := ""
+	if delivery.PickupZoneID != nil {
+		zoneID = *delivery.PickupZoneID
+	}
+
+	shifts, err := s.getShiftsBetween(now, now.Add(time.Second), zoneID)
+	if err != nil {
+		log.Printf("Warning: failed to get shifts under way: %v", err)
+		return nil
+	}
+
+	onShift := make(map[string]bool)
+	for _, shift := range shifts {
+		for _, driverID := range shift.DriverIDs {
+			onShift[driverID] = true
+		}
+	}
+	return onShift
+}
+
+// recordDriverStatusEvent keeps the status changes of a driver to measure their online hours
+func (s *DeliveryService) recordDriverStatusEvent(driverID string, status models.DriverStatus, at time.Time) error {
+	query := `CREATE DriverStatusEvent SET
+		id = $id,
+		driverId = $driverId,
+		status = $status,
+		createdAt = $createdAt`
+
+	_, err := db.Query(query, map[string]interface{}{
+		"id":        uuid.New().String(),
+		"driverId":  driverID,
+		"status":    string(status),
+		"createdAt": at,
+	})
+	return err
+}
+
+// checkShift checks the period of a shift and that its drivers exist and are not planned elsewhere at the same time
+func (s *DeliveryService) checkShift(shift *models.Shift) error {
+	if err := models.ValidateShiftPeriod(shift.StartAt, shift.EndAt); err != nil {
+		return fmt.Errorf("invalid shift: %v", err)
+	}
+
+	for _, driverID := range shift.DriverIDs {
+		driver, err := s.getUserByID(driverID)
+		if err != nil {
+			return fmt.Errorf("driver %s not found", driverID)
+		}
+		if !driver.IsDriver() {
+			return fmt.Errorf("user %s is not a driver", driverID)
+		}
+	}
+
+	overlapping, err := s.getShiftsBetween(shift.StartAt, shift.EndAt, "")
+	if err != nil {
+		return err
+	}
+	for _, other := range overlapping {
+		if other.ID == shift.ID {
+			continue
+		}
+		for _, driverID := range shift.DriverIDs {
+			if other.HasDriver(driverID) {
+				return fmt.Errorf("driver %s is already on shift %s at that time", driverID, other.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// shiftStaffing returns the staffing of a shift against the availability covering it
+func (s *DeliveryService) shiftStaffing(shift *models.Shift) (*models.ShiftStaffing, error) {
+	query := `SELECT * FROM AvailabilitySlot WHERE startAt <= $startAt AND endAt >= $endAt`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"startAt": shift.StartAt,
+		"endAt":   shift.EndAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability slots: %v", err)
+	}
+
+	staffing := models.NewShiftStaffing(*shift, parseAvailabilitySlots(results))
+	return &staffing, nil
+}
+
+// getShiftsBetween returns the shifts overlapping the period, in one zone when given
+func (s *DeliveryService) getShiftsBetween(from, to time.Time, zoneID string) ([]models.Shift, error) {
+	query := `SELECT * FROM Shift WHERE startAt < $to AND endAt > $from`
+	params := map[string]interface{}{
+		"from": from,
+		"to":   to,
+	}
+	if zoneID != "" {
+		query += ` AND zoneId = $zoneId`
+		params["zoneId"] = zoneID
+	}
+	query += ` ORDER BY startAt ASC`
+
+	results, err := db.QueryMultiple(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shifts: %v", err)
+	}
+
+	return parseShifts(results), nil
+}
+
+// getDriverStatusEvents returns the time-sorted status events of each driver over the period, preceded
+// by their last event before it. When onlyPlanned is set, only the drivers of the shifts are returned.
+func (s *DeliveryService) getDriverStatusEvents(from, to time.Time, shifts []models.Shift, onlyPlanned bool) (map[string][]models.DriverStatusEvent, error) {
+	events := make(map[string][]models.DriverStatusEvent)
+
+	query := `SELECT * FROM DriverStatusEvent WHERE createdAt >= $from AND createdAt < $to ORDER BY createdAt ASC`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"from": from,
+		"to":   to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver status events: %v", err)
+	}
+	for _, result := range results {
+		if eventData, ok := result.(map[string]interface{}); ok {
+			event := parseDriverStatusEventFromMap(eventData)
+			events[event.DriverID] = append(events[event.DriverID], *event)
+		}
+	}
+
+	planned := make(map[string]bool)
+	for _, shift := range shifts {
+		for _, driverID := range shift.DriverIDs {
+			planned[driverID] = true
+			if _, ok := events[driverID]; !ok {
+				events[driverID] = []models.DriverStatusEvent{}
+			}
+		}
+	}
+
+	for driverID := range events {
+		if onlyPlanned && !planned[driverID] {
+			delete(events, driverID)
+			continue
+		}
+
+		// The status at the start of the period comes from the last change before it
+		query := `SELECT * FROM DriverStatusEvent WHERE driverId = $driverId AND createdAt < $from ORDER BY createdAt DESC LIMIT 1`
+		result, err := db.QuerySingle(query, map[string]interface{}{
+			"driverId": driverID,
+			"from":     from,
+		})
+		if err != nil {
+			continue
+		}
+		if eventData, ok := result.(map[string]interface{}); ok {
+			events[driverID] = append([]models.DriverStatusEvent{*parseDriverStatusEventFromMap(eventData)}, events[driverID]...)
+		}
+	}
+
+	return events, nil
+}
+
+func (s *DeliveryService) getShiftByID(shiftID string) (*models.Shift, error) {
+	result, err := db.QuerySingle(`SELECT * FROM Shift WHERE id = $shiftId LIMIT 1`, map[string]interface{}{
+		"shiftId": shiftID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("shift not found: %v", err)
+	}
+
+	shiftData, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("shift not found")
+	}
+
+	return parseShiftFromMap(shiftData), nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+func parseShifts(results []interface{}) []models.Shift {
+	shifts := make([]models.Shift, 0, len(results))
+	for _, result := range results {
+		if shiftData, ok := result.(map[string]interface{}); ok {
+			shifts = append(shifts, *parseShiftFromMap(shiftData))
+		}
+	}
+	return shifts
+}
+
+func parseAvailabilitySlots(results []interface{}) []models.AvailabilitySlot {
+	slots := make([]models.AvailabilitySlot, 0, len(results))
+	for _, result := range results {
+		if slotData, ok := result.(map[string]interface{}); ok {
+			slots = append(slots, *parseAvailabilitySlotFromMap(slotData))
+		}
+	}
+	return slots
+}
+
+func parseShiftFromMap(data map[string]interface{}) *models.Shift {
+	shift := &models.Shift{
+		ID:          parseString(data, "id"),
+		ZoneID:      parseString(data, "zoneId"),
+		MinDrivers:  int(parseFloat(data, "minDrivers")),
+		DriverIDs:   []string{},
+		Note:        parseStringPtr(data, "note"),
+		CreatedByID: parseString(data, "createdById"),
+	}
+
+	if rawDriverIDs, ok := data["driverIds"].([]interface{}); ok {
+		for _, rawDriverID := range rawDriverIDs {
+			if driverID, ok := rawDriverID.(string); ok {
+				shift.DriverIDs = append(shift.DriverIDs, driverID)
+			}
+		}
+	}
+	if startAt := parseTimePtr(data, "startAt"); startAt != nil {
+		shift.StartAt = *startAt
+	}
+	if endAt := parseTimePtr(data, "endAt"); endAt != nil {
+		shift.EndAt = *endAt
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		shift.CreatedAt = *createdAt
+	}
+	if updatedAt := parseTimePtr(data, "updatedAt"); updatedAt != nil {
+		shift.UpdatedAt = *updatedAt
+	}
+
+	return shift
+}
+
+func parseAvailabilitySlotFromMap(data map[string]interface{}) *models.AvailabilitySlot {
+	slot := &models.AvailabilitySlot{
+		ID:       parseString(data, "id"),
+		DriverID: parseString(data, "driverId"),
+		ZoneID:   parseStringPtr(data, "zoneId"),
+	}
+
+	if startAt := parseTimePtr(data, "startAt"); startAt != nil {
+		slot.StartAt = *startAt
+	}
+	if endAt := parseTimePtr(data, "endAt"); endAt != nil {
+		slot.EndAt = *endAt
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		slot.CreatedAt = *createdAt
+	}
+
+	return slot
+}
+
+func parseDriverStatusEventFromMap(data map[string]interface{}) *models.DriverStatusEvent {
+	event := &models.DriverStatusEvent{
+		ID:       parseString(data, "id"),
+		DriverID: parseString(data, "driverId"),
+		Status:   models.DriverStatus(parseString(data, "status")),
+	}
+
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		event.CreatedAt = *createdAt
+	}
+
+	return event
+}
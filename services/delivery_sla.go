@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// StartSLAWatchdog checks the active deliveries against their status SLA at the configured interval
+func (s *DeliveryService) StartSLAWatchdog() {
+	if s.config.SLAWatchdogIntervalSeconds <= 0 {
+		log.Println("SLA watchdog disabled")
+		return
+	}
+
+	policy, err := s.slaPolicy()
+	if err != nil {
+		log.Printf("Warning: SLA watchdog not started: %v", err)
+		return
+	}
+
+	interval := time.Duration(s.config.SLAWatchdogIntervalSeconds) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			s.checkSLAs(policy, now)
+		}
+	}()
+
+	log.Printf("SLA watchdog started, checking every %s", interval)
+}
+
+// checkSLAs runs one watchdog pass over the deliveries in a watched status
+func (s *DeliveryService) checkSLAs(policy *models.SLAPolicy, now time.Time) {
+	statuses := make([]string, 0, len(policy.Rules))
+	for _, status := range policy.Statuses() {
+		statuses = append(statuses, string(status))
+	}
+
+	query := `SELECT * FROM Delivery WHERE status IN $statuses AND livreurId != NONE`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"statuses": statuses,
+	})
+	if err != nil {
+		log.Printf("Warning: SLA watchdog failed to get deliveries: %v", err)
+		return
+	}
+
+	for _, result := range results {
+		if deliveryData, ok := result.(map[string]interface{}); ok {
+			s.checkDeliverySLA(policy, s.parseDeliveryFromMap(deliveryData), now)
+		}
+	}
+}
+
+// checkDeliverySLA evaluates one delivery, saves the watchdog state and acts on a breach
+func (s *DeliveryService) checkDeliverySLA(policy *models.SLAPolicy, delivery *models.Delivery, now time.Time) {
+	driver, err := s.getUserByID(*delivery.LivreurID)
+	if err != nil {
+		log.Printf("Warning: SLA watchdog failed to get driver of delivery %s: %v", delivery.ID, err)
+		return
+	}
+
+	check := policy.Evaluate(delivery, s.distanceToNextStop(delivery, driver), driver.LastSeenAt, now)
+	if check.State == nil {
+		return
+	}
+
+	if check.Action == models.SLAActionRedispatch {
+		s.redispatchDelivery(delivery, driver, check)
+		return
+	}
+
+	err = s.saveSLAState(delivery.ID, check.State)
+	if err != nil {
+		log.Printf("Warning: failed to save SLA state for delivery %s: %v", delivery.ID, err)
+		return
+	}
+
+	switch check.Action {
+	case models.SLAActionWarn:
+		note := fmt.Sprintf("SLA breach (%s): %s, driver pinged", check.Breach, check.Reason)
+		s.recordDeliveryEvent(delivery.ID, delivery.Status, delivery.Status, nil, nil, true, &note)
+		s.raiseSLAAlert(delivery, driver, "SLA breach: "+check.Reason)
+		s.pingDriver(driver, delivery.ID, check.Action)
+	case models.SLAActionEscalate:
+		note := fmt.Sprintf("SLA escalated (%s): %s, ops must step in", check.Breach, check.Reason)
+		s.recordDeliveryEvent(delivery.ID, delivery.Status, delivery.Status, nil, nil, true, &note)
+		s.raiseSLAAlert(delivery, driver, "SLA escalated, driver kept after warning: "+check.Reason)
+	}
+}
+
+// redispatchDelivery takes the delivery back from a silent driver and dispatches it again
+func (s *DeliveryService) redispatchDelivery(delivery *models.Delivery, driver *models.User, check models.SLACheck) {
+	now := time.Now()
+
+	// Only take the delivery back if the driver did not move it on since the check
+	query := `UPDATE Delivery SET
+		status = $pending,
+		` + unassignedDeliveryClauses + `,
+		statusChangedAt = $now,
+		updatedAt = $now
+		WHERE id = $deliveryId AND status = $status AND livreurId = $driverId`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"deliveryId": delivery.ID,
+		"driverId":   driver.ID,
+		"status":     string(delivery.Status),
+		"pending":    string(models.DeliveryStatusPending),
+		"now":        now,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to re-dispatch delivery %s: %v", delivery.ID, err)
+		return
+	}
+	if len(results) == 0 {
+		return
+	}
+
+	note := fmt.Sprintf("SLA re-dispatch (%s): %s, driver %s unassigned", check.Breach, check.Reason, driver.ID)
+	s.recordDeliveryEvent(delivery.ID, delivery.Status, models.DeliveryStatusPending, nil, nil, true, &note)
+
+	// The driver stops getting jobs until they come back online
+	err = s.updateDriverStatus(driver.ID, models.DriverStatusOffline)
+	if err != nil {
+		log.Printf("Warning: failed to update driver status: %v", err)
+	}
+	err = s.recordDriverCancellation(driver.ID)
+	if err != nil {
+		log.Printf("Warning: failed to record driver cancellation: %v", err)
+	}
+
+	s.raiseSLAAlert(delivery, driver, "SLA re-dispatch after escalation: "+check.Reason)
+	s.pingDriver(driver, delivery.ID, check.Action)
+
+	go func() {
+		if err := s.AutoAssignDelivery(delivery.ID); err != nil {
+			log.Printf("Warning: failed to re-dispatch delivery %s: %v", delivery.ID, err)
+		}
+	}()
+}
+
+// distanceToNextStop returns how far the driver last was from the stop they are heading to
+func (s *DeliveryService) distanceToNextStop(delivery *models.Delivery, driver *models.User) *float64 {
+	stopID, ok := delivery.NextStop()
+	if !ok || driver.LastKnownLat == nil || driver.LastKnownLng == nil {
+		return nil
+	}
+
+	stop, err := s.getLocationByID(stopID)
+	if err != nil || stop.Lat == nil || stop.Lng == nil {
+		return nil
+	}
+
+	distance := models.Coordinates{Lat: *driver.LastKnownLat, Lng: *driver.LastKnownLng}.DistanceKm(models.Coordinates{Lat: *stop.Lat, Lng: *stop.Lng})
+	return &distance
+}
+
+func (s *DeliveryService) raiseSLAAlert(delivery *models.Delivery, driver *models.User, message string) {
+	deliveryID := delivery.ID
+	driverID := driver.ID
+	alert := &models.OpsAlert{
+		ID:         uuid.New().String(),
+		Type:       models.AlertTypeSLABreach,
+		DeliveryID: &deliveryID,
+		DriverID:   &driverID,
+		Message:    message,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := saveOpsAlert(alert); err != nil {
+		log.Printf("Warning: failed to save SLA alert for delivery %s: %v", delivery.ID, err)
+		return
+	}
+
+	log.Printf("⚠️ Delivery %s: %s", delivery.ID, message)
+}
+
+func (s *DeliveryService) pingDriver(driver *models.User, deliveryID string, action models.SLAAction) {
+	if err := s.sms.Send(driver.Phone, models.SLADriverPing(action)); err != nil {
+		log.Printf("Warning: failed to ping driver of delivery %s: %v", deliveryID, err)
+	}
+}
+
+func (s *DeliveryService) slaPolicy() (*models.SLAPolicy, error) {
+	rules, err := models.ParseSLARules(s.config.SLARules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SLAPolicy{
+		Rules:          rules,
+		DriverSilence:  time.Duration(s.config.SLADriverSilenceMinutes) * time.Minute,
+		Escalation:     time.Duration(s.config.SLAEscalationMinutes) * time.Minute,
+		ProgressMeters: s.config.SLAProgressMeters,
+	}, nil
+}
+
+func (s *DeliveryService) saveSLAState(deliveryID string, state *models.SLAState) error {
+	query := `UPDATE Delivery SET sla = $sla WHERE id = $deliveryId`
+	_, err := db.Query(query, map[string]interface{}{
+		"deliveryId": deliveryID,
+		"sla":        state,
+	})
+	return err
+}
+
+func parseSLAState(data map[string]interface{}, key string) *models.SLAState {
+	stateData, ok := data[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	state := &models.SLAState{
+		Status:             models.DeliveryStatus(parseString(stateData, "status")),
+		ProgressDistanceKm: parseFloatPtr(stateData, "progressDistanceKm"),
+		BreachedAt:         parseTimePtr(stateData, "breachedAt"),
+		EscalatedAt:        parseTimePtr(stateData, "escalatedAt"),
+	}
+	if progressAt := parseTimePtr(stateData, "progressAt"); progressAt != nil {
+		state.ProgressAt = *progressAt
+	}
+	return state
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// Default and longest period of a driver earnings query
+const (
+	defaultEarningsPeriod = 7 * 24 * time.Hour
+	maxEarningsPeriod     = 93 * 24 * time.Hour
+)
+
+// TipDelivery records a tip from the client to the driver, charged with one of the delivery payment methods.
+// The tip is added to the delivery apart from the final price so the commission never applies to it.
+func (s *DeliveryService) TipDelivery(deliveryID, clientID string, userRole models.UserRole, req *models.CreateTipRequest) (*models.Tip, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if userRole != models.UserRoleClient {
+		return nil, fmt.Errorf("only the client can tip the driver")
+	}
+	if err := s.checkDeliveryAccess(delivery, clientID, userRole); err != nil {
+		return nil, err
+	}
+	if !req.PaymentMethod.IsValid() {
+		return nil, fmt.Errorf("invalid payment method: %s", req.PaymentMethod)
+	}
+
+	alreadyTipped := 0.0
+	if delivery.TipAmount != nil {
+		alreadyTipped = *delivery.TipAmount
+	}
+	if err := s.tipPolicy().CanTip(delivery, alreadyTipped, req.Amount, time.Now()); err != nil {
+		return nil, err
+	}
+
+	tip := &models.Tip{
+		ID:            uuid.New().String(),
+		DeliveryID:    deliveryID,
+		ClientID:      clientID,
+		DriverID:      *delivery.LivreurID,
+		Amount:        req.Amount,
+		PaymentMethod: req.PaymentMethod,
+		CreatedAt:     time.Now(),
+	}
+
+	if err := s.saveTip(tip); err != nil {
+		return nil, fmt.Errorf("failed to save tip: %v", err)
+	}
+
+	return tip, nil
+}
+
+// GetDeliveryReceipt returns the receipt of a delivery, with tips listed apart from the price
+func (s *DeliveryService) GetDeliveryReceipt(deliveryID, userID string, userRole models.UserRole) (*models.DeliveryReceipt, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	tips, err := s.getDeliveryTips(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewDeliveryReceipt(delivery, tips), nil
+}
+
+// GetDriverEarnings returns what a driver earned over a period, from the earnings ledger.
+// Without dates the last 7 days are returned.
+func (s *DeliveryService) GetDriverEarnings(driverID string, req *models.DriverEarningsRequest) (*models.DriverEarnings, error) {
+	to := req.To
+	if to.IsZero() {
+		now := time.Now()
+		to = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	}
+	from := req.From
+	if from.IsZero() {
+		from = to.Add(-defaultEarningsPeriod)
+	}
+
+	if !to.After(from) {
+		return nil, fmt.Errorf("period end must be after its start")
+	}
+	if to.Sub(from) > maxEarningsPeriod {
+		return nil, fmt.Errorf("period cannot be longer than %d days", int(maxEarningsPeriod.Hours()/24))
+	}
+
+	// Amounts are the ones recorded when each delivery was credited, not the current rates
+	entries, err := s.getEarningEntries(driverID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewLedgerEarnings(driverID, from, to, entries), nil
+}
+
+func (s *DeliveryService) tipPolicy() *models.TipPolicy {
+	return &models.TipPolicy{
+		Window:    time.Duration(s.config.TipWindowHours) * time.Hour,
+		MaxAmount: s.config.TipMaxAmount,
+	}
+}
+
+// saveTip saves a tip and adds it to the tipped total of the delivery in one transaction.
+// The limit is checked again on the committed total so concurrent tips cannot go over it.
+// Tips given before the delivery earnings are credited go with the fare, later ones are credited on their own.
+func (s *DeliveryService) saveTip(tip *models.Tip) error {
+	statements := []string{
+		`LET $delivery = (SELECT tipAmount, earningsCreditedAt FROM Delivery WHERE id = $deliveryId)[0]`,
+		`IF $maxAmount > 0 AND ($delivery.tipAmount ?? 0) + $amount > $maxAmount {
+			THROW $limitMessage
+		}`,
+		`CREATE Tip CONTENT $tip`,
+		`UPDATE Delivery SET tipAmount = (tipAmount ?? 0) + $amount WHERE id = $deliveryId`,
+		`IF $delivery.earningsCreditedAt != NONE {
+			CREATE EarningEntry CONTENT $tipEntry
+		}`,
+	}
+
+	params := map[string]interface{}{
+		"deliveryId":   tip.DeliveryID,
+		"amount":       tip.Amount,
+		"maxAmount":    s.config.TipMaxAmount,
+		"limitMessage": fmt.Sprintf("tips are limited to %.0f FCFA per delivery", s.config.TipMaxAmount),
+		"tip": map[string]interface{}{
+			"id":            tip.ID,
+			"deliveryId":    tip.DeliveryID,
+			"clientId":      tip.ClientID,
+			"driverId":      tip.DriverID,
+			"amount":        tip.Amount,
+			"paymentMethod": string(tip.PaymentMethod),
+			"createdAt":     tip.CreatedAt,
+		},
+		"tipEntry": earningEntryContent(&models.EarningEntry{
+			ID:         uuid.New().String(),
+			DriverID:   tip.DriverID,
+			DeliveryID: &tip.DeliveryID,
+			Type:       models.EarningEntryTip,
+			Amount:     tip.Amount,
+			CreatedAt:  tip.CreatedAt,
+		}),
+	}
+
+	return db.QueryTransaction(statements, params)
+}
+
+func (s *DeliveryService) getDeliveryTips(deliveryID string) ([]models.Tip, error) {
+	query := `SELECT * FROM Tip WHERE deliveryId = $deliveryId ORDER BY createdAt ASC`
+
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"deliveryId": deliveryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tips: %v", err)
+	}
+
+	tips := make([]models.Tip, 0, len(results))
+	for _, result := range results {
+		if tipData, ok := result.(map[string]interface{}); ok {
+			tips = append(tips, *parseTipFromMap(tipData))
+		}
+	}
+
+	return tips, nil
+}
+
+func parseTipFromMap(data map[string]interface{}) *models.Tip {
+	tip := &models.Tip{
+		ID:            parseString(data, "id"),
+		DeliveryID:    parseString(data, "deliveryId"),
+		ClientID:      parseString(data, "clientId"),
+		DriverID:      parseString(data, "driverId"),
+		Amount:        parseFloat(data, "amount"),
+		PaymentMethod: models.PaymentMethod(parseString(data, "paymentMethod")),
+	}
+
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		tip.CreatedAt = *createdAt
+	}
+
+	return tip
+}
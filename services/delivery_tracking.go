@@ -0,0 +1,439 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// UpdateDriverLocation records the driver position for an active delivery and refreshes its ETA
+func (s *DeliveryService) UpdateDriverLocation(driverID, deliveryID string, req *models.UpdateDriverLocationRequest) (*models.DeliveryETA, error) {
+	if req.Lat == nil || req.Lng == nil {
+		return nil, fmt.Errorf("lat and lng are required")
+	}
+
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if delivery.LivreurID == nil || *delivery.LivreurID != driverID {
+		return nil, fmt.Errorf("unauthorized: delivery not assigned to you")
+	}
+
+	now := time.Now()
+	location := &models.DriverLocation{
+		ID:          uuid.New().String(),
+		DriverID:    driverID,
+		Lat:         req.Lat,
+		Lng:         req.Lng,
+		Timestamp:   now,
+		IsAvailable: false, // Busy with this delivery
+		VehicleType: delivery.VehicleType,
+	}
+
+	if req.Status != nil {
+		if !req.Status.IsValid() {
+			return nil, fmt.Errorf("invalid driver status: %s", *req.Status)
+		}
+		location.IsAvailable = req.Status.IsAvailable()
+
+		err = s.updateDriverStatus(driverID, *req.Status)
+		if err != nil {
+			log.Printf("Warning: failed to update driver status: %v", err)
+		}
+	}
+
+	err = s.saveDriverLocation(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save driver location: %v", err)
+	}
+
+	// Keep every position for trip replay
+	err = s.recordLocationPoint(driverID, &delivery.ID, *req.Lat, *req.Lng, now)
+	if err != nil {
+		log.Printf("Warning: failed to record location history: %v", err)
+	}
+
+	// Emit arrival statuses automatically when the driver reaches a stop
+	s.evaluateGeofence(delivery, driverID, *req.Lat, *req.Lng, now)
+
+	// Only deliveries still on the road have an ETA
+	if !delivery.Status.IsBeforePickup() && !s.isHeadingToDropoff(delivery.Status) {
+		return nil, nil
+	}
+
+	eta, err := s.computeDeliveryETA(delivery, *req.Lat, *req.Lng, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute ETA: %v", err)
+	}
+
+	err = s.saveDeliveryETA(delivery, eta)
+	if err != nil {
+		log.Printf("Warning: failed to save ETA for delivery %s: %v", delivery.ID, err)
+	}
+
+	s.checkETASlip(delivery, eta)
+
+	go s.sendETAUpdateNotification(delivery, eta)
+
+	return eta, nil
+}
+
+// UpdateDriverPosition records the position of a driver between deliveries so dispatch can find them
+func (s *DeliveryService) UpdateDriverPosition(driverID string, req *models.UpdateDriverLocationRequest) error {
+	if req.Lat == nil || req.Lng == nil {
+		return fmt.Errorf("lat and lng are required")
+	}
+
+	driver, err := s.getUserByID(driverID)
+	if err != nil {
+		return fmt.Errorf("driver not found: %v", err)
+	}
+	if !driver.IsDriver() {
+		return fmt.Errorf("user is not a driver")
+	}
+
+	status := driver.DriverStatus
+	if req.Status != nil {
+		if !req.Status.IsValid() {
+			return fmt.Errorf("invalid driver status: %s", *req.Status)
+		}
+		status = *req.Status
+
+		err = s.updateDriverStatus(driverID, status)
+		if err != nil {
+			log.Printf("Warning: failed to update driver status: %v", err)
+		}
+	}
+
+	now := time.Now()
+	location := &models.DriverLocation{
+		ID:          uuid.New().String(),
+		DriverID:    driverID,
+		Lat:         req.Lat,
+		Lng:         req.Lng,
+		Timestamp:   now,
+		IsAvailable: status.IsAvailable(),
+	}
+	if vehicle, err := s.getDriverVehicle(driverID); err == nil {
+		location.VehicleType = vehicle.Type
+	}
+
+	err = s.saveDriverLocation(location)
+	if err != nil {
+		return fmt.Errorf("failed to save driver location: %v", err)
+	}
+
+	err = s.recordLocationPoint(driverID, nil, *req.Lat, *req.Lng, now)
+	if err != nil {
+		log.Printf("Warning: failed to record location history: %v", err)
+	}
+
+	return nil
+}
+
+// TrackDelivery returns the live position and ETA of a delivery
+func (s *DeliveryService) TrackDelivery(deliveryID, userID string, userRole models.UserRole) (*models.DeliveryTracking, error) {
+	delivery, err := s.getDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %v", err)
+	}
+
+	if err := s.checkDeliveryAccess(delivery, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	return s.buildTracking(delivery), nil
+}
+
+// buildTracking returns the driver position and ETA of a delivery, as far as they are known
+func (s *DeliveryService) buildTracking(delivery *models.Delivery) *models.DeliveryTracking {
+	tracking := &models.DeliveryTracking{
+		DeliveryID: delivery.ID,
+		Status:     delivery.Status,
+	}
+
+	if delivery.LivreurID == nil {
+		return tracking
+	}
+
+	location, err := s.getDriverLocation(*delivery.LivreurID)
+	if err != nil || location.Lat == nil || location.Lng == nil {
+		return tracking
+	}
+
+	tracking.DriverLat = location.Lat
+	tracking.DriverLng = location.Lng
+	tracking.LocationUpdate = &location.Timestamp
+
+	if delivery.Status.IsBeforePickup() || s.isHeadingToDropoff(delivery.Status) {
+		eta, err := s.computeDeliveryETA(delivery, *location.Lat, *location.Lng, time.Now())
+		if err == nil {
+			tracking.ETA = eta
+		}
+	}
+
+	return tracking
+}
+
+// computeDeliveryETA estimates pickup and dropoff arrival from the driver position, the remaining stops
+// and the vehicle speed profile at the current time of day
+func (s *DeliveryService) computeDeliveryETA(delivery *models.Delivery, driverLat, driverLng float64, now time.Time) (*models.DeliveryETA, error) {
+	pickup, err := s.getLocationByID(delivery.PickupID)
+	if err != nil {
+		return nil, fmt.Errorf("pickup location not found: %v", err)
+	}
+
+	dropoff, err := s.getLocationByID(delivery.DropoffID)
+	if err != nil {
+		return nil, fmt.Errorf("dropoff location not found: %v", err)
+	}
+
+	if dropoff.Lat == nil || dropoff.Lng == nil {
+		return nil, fmt.Errorf("dropoff location has no coordinates")
+	}
+
+	profile := models.GetSpeedProfile(delivery.VehicleType)
+	localNow := now.In(s.config.Location())
+
+	eta := &models.DeliveryETA{
+		DeliveryID: delivery.ID,
+		DriverLat:  driverLat,
+		DriverLng:  driverLng,
+		ComputedAt: now,
+	}
+
+	remainingMin := 0.0
+	fromLat, fromLng := driverLat, driverLng
+
+	// Remaining stop: pickup
+	if delivery.Status.IsBeforePickup() {
+		if pickup.Lat == nil || pickup.Lng == nil {
+			return nil, fmt.Errorf("pickup location has no coordinates")
+		}
+
+		toPickup := profile.TravelMinutes(models.Coordinates{Lat: fromLat, Lng: fromLng}.DistanceKm(models.Coordinates{Lat: *pickup.Lat, Lng: *pickup.Lng}), localNow)
+		pickupAt := now.Add(time.Duration(toPickup * float64(time.Minute)))
+		eta.PickupMin = &toPickup
+		eta.PickupETA = &pickupAt
+
+		remainingMin = toPickup + models.StopHandlingMin
+		fromLat, fromLng = *pickup.Lat, *pickup.Lng
+	}
+
+	// Remaining stop: dropoff
+	departure := localNow.Add(time.Duration(remainingMin * float64(time.Minute)))
+	remainingMin += profile.TravelMinutes(models.Coordinates{Lat: fromLat, Lng: fromLng}.DistanceKm(models.Coordinates{Lat: *dropoff.Lat, Lng: *dropoff.Lng}), departure)
+
+	eta.DropoffMin = remainingMin
+	eta.DropoffETA = now.Add(time.Duration(remainingMin * float64(time.Minute)))
+
+	if delivery.EtaBaselineAt != nil && eta.DropoffETA.After(*delivery.EtaBaselineAt) {
+		eta.SlippedMin = eta.DropoffETA.Sub(*delivery.EtaBaselineAt).Minutes()
+	}
+
+	return eta, nil
+}
+
+func (s *DeliveryService) isHeadingToDropoff(status models.DeliveryStatus) bool {
+	return status == models.DeliveryStatusPickedUp || status == models.DeliveryStatusInTransit
+}
+
+// checkETASlip alerts ops once when the dropoff ETA slips beyond the configured threshold
+func (s *DeliveryService) checkETASlip(delivery *models.Delivery, eta *models.DeliveryETA) {
+	if delivery.EtaSlipAlertedAt != nil || s.config.ETASlipAlertMinutes <= 0 {
+		return
+	}
+
+	if eta.SlippedMin <= float64(s.config.ETASlipAlertMinutes) {
+		return
+	}
+
+	driverID := delivery.LivreurID
+	deliveryID := delivery.ID
+	alert := &models.OpsAlert{
+		ID:         uuid.New().String(),
+		Type:       models.AlertTypeETASlipped,
+		DeliveryID: &deliveryID,
+		DriverID:   driverID,
+		Message:    fmt.Sprintf("ETA slipped by %.0f minutes (threshold %d)", eta.SlippedMin, s.config.ETASlipAlertMinutes),
+		CreatedAt:  time.Now(),
+	}
+
+	err := saveOpsAlert(alert)
+	if err != nil {
+		log.Printf("Warning: failed to save ETA alert for delivery %s: %v", delivery.ID, err)
+		return
+	}
+
+	_, err = db.Query(`UPDATE Delivery SET etaSlipAlertedAt = $alertedAt WHERE id = $deliveryId`, map[string]interface{}{
+		"deliveryId": delivery.ID,
+		"alertedAt":  alert.CreatedAt,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to mark ETA alert for delivery %s: %v", delivery.ID, err)
+	}
+
+	log.Printf("⚠️ Delivery %s: %s", delivery.ID, alert.Message)
+}
+
+func (s *DeliveryService) saveDeliveryETA(delivery *models.Delivery, eta *models.DeliveryETA) error {
+	query := `UPDATE Delivery SET etaPickupAt = $etaPickupAt, etaDropoffAt = $etaDropoffAt`
+	params := map[string]interface{}{
+		"deliveryId":   delivery.ID,
+		"etaPickupAt":  eta.PickupETA,
+		"etaDropoffAt": eta.DropoffETA,
+	}
+
+	// The first ETA is the promise made to the client, later ones are compared against it
+	if delivery.EtaBaselineAt == nil {
+		query += `, etaBaselineAt = $etaDropoffAt`
+	}
+	query += ` WHERE id = $deliveryId`
+
+	_, err := db.Query(query, params)
+	return err
+}
+
+func (s *DeliveryService) saveDriverLocation(location *models.DriverLocation) error {
+	// Keep only the latest position per driver
+	_, err := db.Query(`DELETE DriverLocation WHERE driverId = $driverId`, map[string]interface{}{
+		"driverId": location.DriverID,
+	})
+	if err != nil {
+		return err
+	}
+
+	query := `CREATE DriverLocation SET 
+		id = $id,
+		driverId = $driverId,
+		lat = $lat,
+		lng = $lng,
+		timestamp = $timestamp,
+		isAvailable = $isAvailable,
+		vehicleType = $vehicleType`
+
+	params := map[string]interface{}{
+		"id":          location.ID,
+		"driverId":    location.DriverID,
+		"lat":         location.Lat,
+		"lng":         location.Lng,
+		"timestamp":   location.Timestamp,
+		"isAvailable": location.IsAvailable,
+		"vehicleType": string(location.VehicleType),
+	}
+
+	_, err = db.Query(query, params)
+	if err != nil {
+		return err
+	}
+
+	if location.Lat != nil && location.Lng != nil {
+		s.driverIndex.Update(location.Indexed())
+	}
+
+	_, err = db.Query(`UPDATE User SET lastKnownLat = $lat, lastKnownLng = $lng, lastSeenAt = $timestamp WHERE id = $driverId`, params)
+	return err
+}
+
+func (s *DeliveryService) getDriverLocation(driverID string) (*models.DriverLocation, error) {
+	query := `SELECT * FROM DriverLocation WHERE driverId = $driverId ORDER BY timestamp DESC LIMIT 1`
+	params := map[string]interface{}{
+		"driverId": driverID,
+	}
+
+	result, err := db.QuerySingle(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no result found")
+	}
+
+	return parseDriverLocationFromMap(data), nil
+}
+
+// sendETAUpdateNotification texts the new ETA to the client, at most once per configured interval.
+// The send is claimed on the delivery first so concurrent position updates send it once.
+func (s *DeliveryService) sendETAUpdateNotification(delivery *models.Delivery, eta *models.DeliveryETA) {
+	interval := time.Duration(s.config.ETANotifyIntervalMinutes) * time.Minute
+	now := time.Now()
+	if !delivery.ShouldNotifyETA(interval, now) {
+		return
+	}
+
+	query := `UPDATE Delivery SET etaNotifiedAt = $now
+		WHERE id = $deliveryId AND (etaNotifiedAt = NONE OR etaNotifiedAt <= $since)`
+	results, err := db.QueryMultiple(query, map[string]interface{}{
+		"deliveryId": delivery.ID,
+		"now":        now,
+		"since":      now.Add(-interval),
+	})
+	if err != nil {
+		log.Printf("Warning: failed to claim ETA SMS for delivery %s: %v", delivery.ID, err)
+		return
+	}
+	if len(results) == 0 {
+		return
+	}
+
+	client, err := s.getUserByID(delivery.ClientID)
+	if err != nil {
+		log.Printf("Warning: failed to load client of delivery %s for ETA SMS: %v", delivery.ID, err)
+		return
+	}
+
+	if err := s.sms.Send(client.Phone, models.ETAUpdateMessage(eta)); err != nil {
+		log.Printf("Warning: failed to send ETA SMS for delivery %s: %v", delivery.ID, err)
+	}
+}
+
+func parseDriverLocationFromMap(data map[string]interface{}) *models.DriverLocation {
+	location := &models.DriverLocation{
+		ID:          parseString(data, "id"),
+		DriverID:    parseString(data, "driverId"),
+		Lat:         parseFloatPtr(data, "lat"),
+		Lng:         parseFloatPtr(data, "lng"),
+		VehicleType: models.VehicleType(parseString(data, "vehicleType")),
+	}
+
+	location.IsAvailable, _ = data["isAvailable"].(bool)
+	if timestamp := parseTimePtr(data, "timestamp"); timestamp != nil {
+		location.Timestamp = *timestamp
+	}
+
+	return location
+}
+
+// saveOpsAlert records an alert for the operations team
+func saveOpsAlert(alert *models.OpsAlert) error {
+	query := `CREATE OpsAlert SET 
+		id = $id,
+		type = $type,
+		deliveryId = $deliveryId,
+		driverId = $driverId,
+		userId = $userId,
+		message = $message,
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":         alert.ID,
+		"type":       string(alert.Type),
+		"deliveryId": alert.DeliveryID,
+		"driverId":   alert.DriverID,
+		"userId":     alert.UserID,
+		"message":    alert.Message,
+		"createdAt":  alert.CreatedAt,
+	}
+
+	_, err := db.Query(query, params)
+	return err
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// kmPerDegree is the length of one degree of latitude
+const kmPerDegree = 111.32
+
+type gridCell struct {
+	lat int
+	lng int
+}
+
+// DriverIndex keeps the last known driver positions in a grid of square cells so nearest-driver
+// lookups only look at the cells around the queried point. It is safe for concurrent use.
+type DriverIndex struct {
+	mu       sync.RWMutex
+	cellDeg  float64
+	cellKm   float64
+	cells    map[gridCell]map[string]*models.IndexedDriver
+	drivers  map[string]*models.IndexedDriver
+	minCell  gridCell // Bounds of the cells ever used, the search never goes past them
+	maxCell  gridCell
+	hasBound bool
+}
+
+// NewDriverIndex creates an empty index with cells of the given size
+func NewDriverIndex(cellKm float64) *DriverIndex {
+	if cellKm <= 0 {
+		cellKm = 1
+	}
+
+	return &DriverIndex{
+		cellDeg: cellKm / kmPerDegree,
+		cellKm:  cellKm,
+		cells:   make(map[gridCell]map[string]*models.IndexedDriver),
+		drivers: make(map[string]*models.IndexedDriver),
+	}
+}
+
+// Load replaces the content of the index
+func (idx *DriverIndex) Load(drivers []models.IndexedDriver) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.cells = make(map[gridCell]map[string]*models.IndexedDriver)
+	idx.drivers = make(map[string]*models.IndexedDriver)
+	idx.hasBound = false
+
+	for i := range drivers {
+		idx.put(drivers[i])
+	}
+}
+
+// Update sets the position of a driver, moving it to its new cell
+func (idx *DriverIndex) Update(driver models.IndexedDriver) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.put(driver)
+}
+
+// SetAvailable changes the availability of an indexed driver, unknown drivers are ignored
+func (idx *DriverIndex) SetAvailable(driverID string, available bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if driver, ok := idx.drivers[driverID]; ok {
+		driver.IsAvailable = available
+	}
+}
+
+// Remove takes a driver out of the index
+func (idx *DriverIndex) Remove(driverID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(driverID)
+}
+
+// Len returns the number of indexed drivers
+func (idx *DriverIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.drivers)
+}
+
+// Nearest returns up to k drivers passing the filter, closest first
+func (idx *DriverIndex) Nearest(lat, lng float64, k int, filter models.DriverFilter) []models.DriverMatch {
+	if k <= 0 {
+		return nil
+	}
+	return idx.search(lat, lng, k, 0, filter)
+}
+
+// Within returns the drivers passing the filter within radiusKm of the point, closest first
+func (idx *DriverIndex) Within(lat, lng, radiusKm float64, filter models.DriverFilter) []models.DriverMatch {
+	if radiusKm <= 0 {
+		return nil
+	}
+	return idx.search(lat, lng, 0, radiusKm, filter)
+}
+
+// search visits the cells ring by ring around the point. After each ring, every driver not seen yet
+// is at least the ring distance away, so the search stops once k closer drivers are found or the
+// ring is past the radius.
+func (idx *DriverIndex) search(lat, lng float64, k int, radiusKm float64, filter models.DriverFilter) []models.DriverMatch {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if !idx.hasBound {
+		return nil
+	}
+
+	center := idx.cellOf(lat, lng)
+	maxRing := idx.maxRing(center)
+	origin := models.Coordinates{Lat: lat, Lng: lng}
+
+	var matches []models.DriverMatch
+	for ring := 0; ring <= maxRing; ring++ {
+		idx.visitRing(center, ring, func(cell map[string]*models.IndexedDriver) {
+			for _, driver := range cell {
+				if !filter.Matches(driver) {
+					continue
+				}
+				distance := origin.DistanceKm(models.Coordinates{Lat: driver.Lat, Lng: driver.Lng})
+				if radiusKm > 0 && distance > radiusKm {
+					continue
+				}
+				matches = append(matches, models.DriverMatch{IndexedDriver: *driver, DistanceKm: distance})
+			}
+		})
+
+		reached := idx.ringDistanceKm(lat, ring)
+		if radiusKm > 0 && reached > radiusKm {
+			break
+		}
+		if k > 0 && len(matches) >= k {
+			sortMatches(matches)
+			if matches[k-1].DistanceKm <= reached {
+				break
+			}
+		}
+	}
+
+	sortMatches(matches)
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// visitRing calls fn on the occupied cells at exactly ring cells from the center
+func (idx *DriverIndex) visitRing(center gridCell, ring int, fn func(map[string]*models.IndexedDriver)) {
+	visit := func(lat, lng int) {
+		if cell, ok := idx.cells[gridCell{lat: lat, lng: lng}]; ok {
+			fn(cell)
+		}
+	}
+
+	if ring == 0 {
+		visit(center.lat, center.lng)
+		return
+	}
+
+	for lng := center.lng - ring; lng <= center.lng+ring; lng++ {
+		visit(center.lat-ring, lng)
+		visit(center.lat+ring, lng)
+	}
+	for lat := center.lat - ring + 1; lat <= center.lat+ring-1; lat++ {
+		visit(lat, center.lng-ring)
+		visit(lat, center.lng+ring)
+	}
+}
+
+// ringDistanceKm returns the shortest distance from the point to a cell outside the ring.
+// Longitude cells get narrower away from the equator, the poleward edge of the ring is the worst case.
+func (idx *DriverIndex) ringDistanceKm(lat float64, ring int) float64 {
+	worstLat := math.Min(math.Abs(lat)+float64(ring+1)*idx.cellDeg, 90)
+	return float64(ring) * idx.cellKm * math.Cos(worstLat*math.Pi/180)
+}
+
+// maxRing returns the ring past which no cell was ever used
+func (idx *DriverIndex) maxRing(center gridCell) int {
+	ring := 0
+	for _, distance := range []int{
+		center.lat - idx.minCell.lat, idx.maxCell.lat - center.lat,
+		center.lng - idx.minCell.lng, idx.maxCell.lng - center.lng,
+	} {
+		if distance > ring {
+			ring = distance
+		}
+	}
+	return ring
+}
+
+func (idx *DriverIndex) put(driver models.IndexedDriver) {
+	idx.remove(driver.DriverID)
+
+	cell := idx.cellOf(driver.Lat, driver.Lng)
+	if idx.cells[cell] == nil {
+		idx.cells[cell] = make(map[string]*models.IndexedDriver)
+	}
+	stored := driver
+	idx.cells[cell][driver.DriverID] = &stored
+	idx.drivers[driver.DriverID] = &stored
+
+	if !idx.hasBound {
+		idx.minCell, idx.maxCell, idx.hasBound = cell, cell, true
+		return
+	}
+	idx.minCell.lat = minInt(idx.minCell.lat, cell.lat)
+	idx.minCell.lng = minInt(idx.minCell.lng, cell.lng)
+	idx.maxCell.lat = maxInt(idx.maxCell.lat, cell.lat)
+	idx.maxCell.lng = maxInt(idx.maxCell.lng, cell.lng)
+}
+
+func (idx *DriverIndex) remove(driverID string) {
+	driver, ok := idx.drivers[driverID]
+	if !ok {
+		return
+	}
+
+	cell := idx.cellOf(driver.Lat, driver.Lng)
+	delete(idx.cells[cell], driverID)
+	if len(idx.cells[cell]) == 0 {
+		delete(idx.cells, cell)
+	}
+	delete(idx.drivers, driverID)
+}
+
+func (idx *DriverIndex) cellOf(lat, lng float64) gridCell {
+	return gridCell{
+		lat: int(math.Floor(lat / idx.cellDeg)),
+		lng: int(math.Floor(lng / idx.cellDeg)),
+	}
+}
+
+func sortMatches(matches []models.DriverMatch) {
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].DistanceKm < matches[j].DistanceKm
+	})
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
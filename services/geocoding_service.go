@@ -0,0 +1,265 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/config"
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// Geocoder converts free-text addresses to coordinates and back
+type Geocoder interface {
+	Geocode(address string) (*models.GeocodeResult, error)
+	Reverse(lat, lng float64) (*models.ReverseGeocodeResult, error)
+}
+
+// GeocodingService geocodes addresses against the admin-curated gazetteer of neighbourhoods and landmarks
+type GeocodingService struct {
+	config *config.Config
+}
+
+func NewGeocodingService(cfg *config.Config) *GeocodingService {
+	return &GeocodingService{
+		config: cfg,
+	}
+}
+
+// PinRequiredError is returned when an address cannot be located precisely enough to price a delivery
+type PinRequiredError struct {
+	Stop   string // pickup or dropoff
+	Result *models.GeocodeResult
+}
+
+func (e *PinRequiredError) Error() string {
+	return fmt.Sprintf("%s address could not be located precisely (confidence %.2f), please drop a pin", e.Stop, e.Result.Confidence)
+}
+
+// Geocode returns the coordinates of a free-text address with a confidence score
+func (s *GeocodingService) Geocode(address string) (*models.GeocodeResult, error) {
+	entries, err := s.GetEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	return models.Geocode(entries, address, s.config.GeocodingMinConfidence), nil
+}
+
+// Reverse returns the closest known place for coordinates
+func (s *GeocodingService) Reverse(lat, lng float64) (*models.ReverseGeocodeResult, error) {
+	entries, err := s.GetEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	return models.ReverseGeocode(entries, lat, lng), nil
+}
+
+// CreateEntry adds a neighbourhood or landmark to the gazetteer
+func (s *GeocodingService) CreateEntry(adminID string, req *models.CreateGazetteerEntryRequest) (*models.GazetteerEntry, error) {
+	entry := &models.GazetteerEntry{
+		ID:        uuid.New().String(),
+		Name:      strings.TrimSpace(req.Name),
+		Aliases:   req.Aliases,
+		Kind:      req.Kind,
+		City:      req.City,
+		Lat:       req.Lat,
+		Lng:       req.Lng,
+		RadiusM:   req.RadiusM,
+		IsActive:  true,
+		CreatedBy: adminID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	query := `CREATE GazetteerEntry SET 
+		id = $id,
+		name = $name,
+		aliases = $aliases,
+		kind = $kind,
+		city = $city,
+		lat = $lat,
+		lng = $lng,
+		radiusM = $radiusM,
+		isActive = $isActive,
+		createdBy = $createdBy,
+		createdAt = $createdAt,
+		updatedAt = $updatedAt`
+
+	params := map[string]interface{}{
+		"id":        entry.ID,
+		"name":      entry.Name,
+		"aliases":   entry.Aliases,
+		"kind":      string(entry.Kind),
+		"city":      entry.City,
+		"lat":       entry.Lat,
+		"lng":       entry.Lng,
+		"radiusM":   entry.RadiusM,
+		"isActive":  entry.IsActive,
+		"createdBy": entry.CreatedBy,
+		"createdAt": entry.CreatedAt,
+		"updatedAt": entry.UpdatedAt,
+	}
+
+	_, err := db.Query(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save gazetteer entry: %v", err)
+	}
+
+	log.Printf("Added gazetteer entry: %s (%s)", entry.Name, entry.Kind)
+	return entry, nil
+}
+
+// UpdateEntry updates the given fields of a gazetteer entry
+func (s *GeocodingService) UpdateEntry(entryID string, req *models.UpdateGazetteerEntryRequest) (*models.GazetteerEntry, error) {
+	entry, err := s.GetEntry(entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		entry.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Aliases != nil {
+		entry.Aliases = req.Aliases
+	}
+	if req.Kind != nil {
+		entry.Kind = *req.Kind
+	}
+	if req.City != nil {
+		entry.City = *req.City
+	}
+	if req.Lat != nil {
+		entry.Lat = *req.Lat
+	}
+	if req.Lng != nil {
+		entry.Lng = *req.Lng
+	}
+	if req.RadiusM != nil {
+		entry.RadiusM = *req.RadiusM
+	}
+	if req.IsActive != nil {
+		entry.IsActive = *req.IsActive
+	}
+	entry.UpdatedAt = time.Now()
+
+	query := `UPDATE GazetteerEntry SET 
+		name = $name, 
+		aliases = $aliases, 
+		kind = $kind, 
+		city = $city, 
+		lat = $lat, 
+		lng = $lng, 
+		radiusM = $radiusM, 
+		isActive = $isActive, 
+		updatedAt = $updatedAt 
+		WHERE id = $entryId`
+
+	params := map[string]interface{}{
+		"entryId":   entry.ID,
+		"name":      entry.Name,
+		"aliases":   entry.Aliases,
+		"kind":      string(entry.Kind),
+		"city":      entry.City,
+		"lat":       entry.Lat,
+		"lng":       entry.Lng,
+		"radiusM":   entry.RadiusM,
+		"isActive":  entry.IsActive,
+		"updatedAt": entry.UpdatedAt,
+	}
+
+	_, err = db.Query(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update gazetteer entry: %v", err)
+	}
+
+	return entry, nil
+}
+
+// DeleteEntry removes a gazetteer entry
+func (s *GeocodingService) DeleteEntry(entryID string) error {
+	if _, err := s.GetEntry(entryID); err != nil {
+		return err
+	}
+
+	_, err := db.Query(`DELETE GazetteerEntry WHERE id = $entryId`, map[string]interface{}{
+		"entryId": entryID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete gazetteer entry: %v", err)
+	}
+
+	return nil
+}
+
+// GetEntry returns a gazetteer entry by ID
+func (s *GeocodingService) GetEntry(entryID string) (*models.GazetteerEntry, error) {
+	query := `SELECT * FROM GazetteerEntry WHERE id = $entryId LIMIT 1`
+	params := map[string]interface{}{
+		"entryId": entryID,
+	}
+
+	result, err := db.QuerySingle(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("gazetteer entry not found: %v", err)
+	}
+
+	entryData, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("gazetteer entry not found")
+	}
+
+	return s.parseGazetteerEntryFromMap(entryData), nil
+}
+
+// GetEntries returns all gazetteer entries
+func (s *GeocodingService) GetEntries() ([]models.GazetteerEntry, error) {
+	results, err := db.QueryMultiple(`SELECT * FROM GazetteerEntry ORDER BY name ASC`, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gazetteer: %v", err)
+	}
+
+	entries := make([]models.GazetteerEntry, 0, len(results))
+	for _, result := range results {
+		if entryData, ok := result.(map[string]interface{}); ok {
+			entries = append(entries, *s.parseGazetteerEntryFromMap(entryData))
+		}
+	}
+
+	return entries, nil
+}
+
+func (s *GeocodingService) parseGazetteerEntryFromMap(data map[string]interface{}) *models.GazetteerEntry {
+	entry := &models.GazetteerEntry{
+		ID:        parseString(data, "id"),
+		Name:      parseString(data, "name"),
+		Kind:      models.GazetteerKind(parseString(data, "kind")),
+		City:      parseString(data, "city"),
+		Lat:       parseFloat(data, "lat"),
+		Lng:       parseFloat(data, "lng"),
+		RadiusM:   parseFloat(data, "radiusM"),
+		CreatedBy: parseString(data, "createdBy"),
+	}
+
+	entry.IsActive, _ = data["isActive"].(bool)
+	if aliases, ok := data["aliases"].([]interface{}); ok {
+		for _, alias := range aliases {
+			if value, ok := alias.(string); ok {
+				entry.Aliases = append(entry.Aliases, value)
+			}
+		}
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		entry.CreatedAt = *createdAt
+	}
+	if updatedAt := parseTimePtr(data, "updatedAt"); updatedAt != nil {
+		entry.UpdatedAt = *updatedAt
+	}
+
+	return entry
+}
@@ -0,0 +1,568 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/config"
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+type PricingService struct {
+	config *config.Config
+}
+
+func NewPricingService(cfg *config.Config) *PricingService {
+	return &PricingService{
+		config: cfg,
+	}
+}
+
+// CreateTimeSurcharge creates a new time-based surcharge rule
+func (s *PricingService) CreateTimeSurcharge(req *models.CreateTimeSurchargeRuleRequest) (*models.TimeSurchargeRule, error) {
+	rule := &models.TimeSurchargeRule{
+		ID:           uuid.New().String(),
+		Name:         req.Name,
+		VehicleType:  req.VehicleType,
+		DaysOfWeek:   req.DaysOfWeek,
+		StartTime:    req.StartTime,
+		EndTime:      req.EndTime,
+		HolidaysOnly: req.HolidaysOnly,
+		Percentage:   req.Percentage,
+		FixedAmount:  req.FixedAmount,
+		IsActive:     true,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+
+	if rule.VehicleType != nil && !rule.VehicleType.IsValid() {
+		return nil, fmt.Errorf("invalid vehicle type: %s", *rule.VehicleType)
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	query := `CREATE TimeSurchargeRule SET 
+		id = $id,
+		name = $name,
+		vehicleType = $vehicleType,
+		daysOfWeek = $daysOfWeek,
+		startTime = $startTime,
+		endTime = $endTime,
+		holidaysOnly = $holidaysOnly,
+		percentage = $percentage,
+		fixedAmount = $fixedAmount,
+		isActive = $isActive,
+		createdAt = $createdAt,
+		updatedAt = $updatedAt`
+
+	_, err := db.Query(query, s.timeSurchargeParams(rule))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save time surcharge: %v", err)
+	}
+
+	log.Printf("Created time surcharge: %s", rule.Name)
+	return rule, nil
+}
+
+// UpdateTimeSurcharge updates the given fields of a time surcharge rule
+func (s *PricingService) UpdateTimeSurcharge(ruleID string, req *models.UpdateTimeSurchargeRuleRequest) (*models.TimeSurchargeRule, error) {
+	rule, err := s.getTimeSurcharge(ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.DaysOfWeek != nil {
+		rule.DaysOfWeek = req.DaysOfWeek
+	}
+	if req.StartTime != nil {
+		rule.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		rule.EndTime = *req.EndTime
+	}
+	if req.HolidaysOnly != nil {
+		rule.HolidaysOnly = *req.HolidaysOnly
+	}
+	if req.Percentage != nil {
+		rule.Percentage = *req.Percentage
+	}
+	if req.FixedAmount != nil {
+		rule.FixedAmount = *req.FixedAmount
+	}
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	rule.UpdatedAt = time.Now()
+
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE TimeSurchargeRule SET 
+		name = $name, 
+		daysOfWeek = $daysOfWeek, 
+		startTime = $startTime, 
+		endTime = $endTime, 
+		holidaysOnly = $holidaysOnly, 
+		percentage = $percentage, 
+		fixedAmount = $fixedAmount, 
+		isActive = $isActive, 
+		updatedAt = $updatedAt 
+		WHERE id = $id`
+
+	_, err = db.Query(query, s.timeSurchargeParams(rule))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update time surcharge: %v", err)
+	}
+
+	return rule, nil
+}
+
+// DeleteTimeSurcharge removes a time surcharge rule
+func (s *PricingService) DeleteTimeSurcharge(ruleID string) error {
+	if _, err := s.getTimeSurcharge(ruleID); err != nil {
+		return err
+	}
+
+	_, err := db.Query(`DELETE TimeSurchargeRule WHERE id = $ruleId`, map[string]interface{}{
+		"ruleId": ruleID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete time surcharge: %v", err)
+	}
+	return nil
+}
+
+// GetTimeSurcharges returns all time surcharge rules
+func (s *PricingService) GetTimeSurcharges() ([]models.TimeSurchargeRule, error) {
+	results, err := db.QueryMultiple(`SELECT * FROM TimeSurchargeRule ORDER BY name ASC`, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time surcharges: %v", err)
+	}
+
+	rules := make([]models.TimeSurchargeRule, 0, len(results))
+	for _, result := range results {
+		if data, ok := result.(map[string]interface{}); ok {
+			rules = append(rules, *s.parseTimeSurchargeFromMap(data))
+		}
+	}
+
+	return rules, nil
+}
+
+// CreateHoliday adds a date to the holiday calendar
+func (s *PricingService) CreateHoliday(req *models.CreateHolidayRequest) (*models.Holiday, error) {
+	holiday := &models.Holiday{
+		ID:        uuid.New().String(),
+		Date:      req.Date,
+		Name:      req.Name,
+		CreatedAt: time.Now(),
+	}
+
+	exists, err := db.CountRecords("Holiday", "date = $date", map[string]interface{}{"date": holiday.Date})
+	if err == nil && exists > 0 {
+		return nil, fmt.Errorf("a holiday already exists on %s", holiday.Date)
+	}
+
+	query := `CREATE Holiday SET 
+		id = $id,
+		date = $date,
+		name = $name,
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":        holiday.ID,
+		"date":      holiday.Date,
+		"name":      holiday.Name,
+		"createdAt": holiday.CreatedAt,
+	}
+
+	_, err = db.Query(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save holiday: %v", err)
+	}
+
+	return holiday, nil
+}
+
+// DeleteHoliday removes a date from the holiday calendar
+func (s *PricingService) DeleteHoliday(holidayID string) error {
+	_, err := db.Query(`DELETE Holiday WHERE id = $holidayId`, map[string]interface{}{
+		"holidayId": holidayID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete holiday: %v", err)
+	}
+	return nil
+}
+
+// GetHolidays returns the holiday calendar
+func (s *PricingService) GetHolidays() ([]models.Holiday, error) {
+	results, err := db.QueryMultiple(`SELECT * FROM Holiday ORDER BY date ASC`, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holidays: %v", err)
+	}
+
+	holidays := make([]models.Holiday, 0, len(results))
+	for _, result := range results {
+		data, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		holiday := models.Holiday{
+			ID:   parseString(data, "id"),
+			Date: parseString(data, "date"),
+			Name: parseString(data, "name"),
+		}
+		if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+			holiday.CreatedAt = *createdAt
+		}
+		holidays = append(holidays, holiday)
+	}
+
+	return holidays, nil
+}
+
+func (s *PricingService) getTimeSurcharge(ruleID string) (*models.TimeSurchargeRule, error) {
+	query := `SELECT * FROM TimeSurchargeRule WHERE id = $ruleId LIMIT 1`
+	params := map[string]interface{}{
+		"ruleId": ruleID,
+	}
+
+	result, err := db.QuerySingle(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("time surcharge not found: %v", err)
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("time surcharge not found")
+	}
+
+	return s.parseTimeSurchargeFromMap(data), nil
+}
+
+func (s *PricingService) timeSurchargeParams(rule *models.TimeSurchargeRule) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           rule.ID,
+		"name":         rule.Name,
+		"vehicleType":  rule.VehicleType,
+		"daysOfWeek":   rule.DaysOfWeek,
+		"startTime":    rule.StartTime,
+		"endTime":      rule.EndTime,
+		"holidaysOnly": rule.HolidaysOnly,
+		"percentage":   rule.Percentage,
+		"fixedAmount":  rule.FixedAmount,
+		"isActive":     rule.IsActive,
+		"createdAt":    rule.CreatedAt,
+		"updatedAt":    rule.UpdatedAt,
+	}
+}
+
+func (s *PricingService) parseTimeSurchargeFromMap(data map[string]interface{}) *models.TimeSurchargeRule {
+	rule := &models.TimeSurchargeRule{
+		ID:          parseString(data, "id"),
+		Name:        parseString(data, "name"),
+		StartTime:   parseString(data, "startTime"),
+		EndTime:     parseString(data, "endTime"),
+		Percentage:  parseFloat(data, "percentage"),
+		FixedAmount: parseFloat(data, "fixedAmount"),
+	}
+
+	rule.HolidaysOnly, _ = data["holidaysOnly"].(bool)
+	rule.IsActive, _ = data["isActive"].(bool)
+	if vehicleType, ok := data["vehicleType"].(string); ok && vehicleType != "" {
+		vt := models.VehicleType(vehicleType)
+		rule.VehicleType = &vt
+	}
+	if days, ok := data["daysOfWeek"].([]interface{}); ok {
+		for _, day := range days {
+			if value, ok := day.(float64); ok {
+				rule.DaysOfWeek = append(rule.DaysOfWeek, int(value))
+			}
+		}
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		rule.CreatedAt = *createdAt
+	}
+	if updatedAt := parseTimePtr(data, "updatedAt"); updatedAt != nil {
+		rule.UpdatedAt = *updatedAt
+	}
+
+	return rule
+}
+
+// CreateRuleVersion schedules a new pricing rule version for a vehicle type
+func (s *PricingService) CreateRuleVersion(adminID string, req *models.CreatePricingRuleRequest) (*models.PricingRule, error) {
+	if !req.VehicleType.IsValid() {
+		return nil, fmt.Errorf("invalid vehicle type: %s", req.VehicleType)
+	}
+
+	now := time.Now()
+	effectiveFrom := now
+	if req.EffectiveFrom != nil {
+		if req.EffectiveFrom.Before(now.Add(-time.Minute)) {
+			return nil, fmt.Errorf("effective date cannot be in the past")
+		}
+		effectiveFrom = *req.EffectiveFrom
+	}
+
+	history, err := s.getStoredRules(req.VehicleType)
+	if err != nil {
+		return nil, err
+	}
+
+	latestVersion := 0
+	for _, rule := range history {
+		if rule.Version > latestVersion {
+			latestVersion = rule.Version
+		}
+	}
+
+	rule := models.NewPricingRule(req)
+	rule.ID = uuid.New().String()
+	rule.Version = latestVersion + 1
+	rule.EffectiveFrom = effectiveFrom
+	rule.CreatedBy = &adminID
+	rule.CreatedAt = now
+
+	query := `CREATE PricingRule SET 
+		id = $id,
+		vehicleType = $vehicleType,
+		basePrice = $basePrice,
+		includedKm = $includedKm,
+		perKm = $perKm,
+		waitingFree = $waitingFree,
+		waitingRate = $waitingRate,
+		version = $version,
+		effectiveFrom = $effectiveFrom,
+		notes = $notes,
+		createdBy = $createdBy,
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":            rule.ID,
+		"vehicleType":   string(rule.VehicleType),
+		"basePrice":     rule.BasePrice,
+		"includedKm":    rule.IncludedKm,
+		"perKm":         rule.PerKm,
+		"waitingFree":   rule.WaitingFree,
+		"waitingRate":   rule.WaitingRate,
+		"version":       rule.Version,
+		"effectiveFrom": rule.EffectiveFrom,
+		"notes":         rule.Notes,
+		"createdBy":     rule.CreatedBy,
+		"createdAt":     rule.CreatedAt,
+	}
+
+	_, err = db.Query(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save pricing rule: %v", err)
+	}
+
+	log.Printf("Pricing rule %s v%d scheduled from %s by %s", rule.VehicleType, rule.Version, rule.EffectiveFrom.Format(time.RFC3339), adminID)
+	return rule, nil
+}
+
+// CancelRuleVersion deletes a version that is not effective yet, effective versions are kept for history
+func (s *PricingService) CancelRuleVersion(ruleID string) error {
+	rule, err := s.GetRuleByID(ruleID)
+	if err != nil {
+		return err
+	}
+
+	if !rule.EffectiveFrom.After(time.Now()) {
+		return fmt.Errorf("only scheduled versions can be cancelled")
+	}
+
+	_, err = db.Query(`DELETE PricingRule WHERE id = $ruleId`, map[string]interface{}{
+		"ruleId": ruleID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel pricing rule: %v", err)
+	}
+	return nil
+}
+
+// GetCurrentRules returns the rule in effect for every vehicle type
+func (s *PricingService) GetCurrentRules() ([]models.PricingRule, error) {
+	vehicleTypes := []models.VehicleType{models.VehicleTypeMoto, models.VehicleTypeVoiture, models.VehicleTypeCamionnette}
+
+	rules := make([]models.PricingRule, 0, len(vehicleTypes))
+	for _, vehicleType := range vehicleTypes {
+		rule, err := s.GetEffectiveRule(vehicleType, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+
+	return rules, nil
+}
+
+// GetRuleHistory returns every version of a vehicle type rule with its status, newest first
+func (s *PricingService) GetRuleHistory(vehicleType models.VehicleType) ([]models.PricingRuleVersion, error) {
+	if !vehicleType.IsValid() {
+		return nil, fmt.Errorf("invalid vehicle type: %s", vehicleType)
+	}
+
+	rules, err := s.getStoredRules(vehicleType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Built-in defaults are the implicit first version
+	if rule := defaultPricingRule(vehicleType); rule != nil {
+		rules = append(rules, *rule)
+	}
+
+	return models.VersionStatuses(rules, time.Now()), nil
+}
+
+// GetEffectiveRule returns the latest version effective at the given time, falling back to the defaults
+// when no version is stored. A failed query is an error so deliveries are never priced with stale defaults.
+func (s *PricingService) GetEffectiveRule(vehicleType models.VehicleType, at time.Time) (*models.PricingRule, error) {
+	query := `SELECT * FROM PricingRule WHERE vehicleType = $vehicleType AND effectiveFrom <= $at ORDER BY effectiveFrom DESC, version DESC LIMIT 1`
+	params := map[string]interface{}{
+		"vehicleType": string(vehicleType),
+		"at":          at,
+	}
+
+	results, err := db.QueryMultiple(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pricing rules: %v", err)
+	}
+	if len(results) > 0 {
+		if data, ok := results[0].(map[string]interface{}); ok {
+			return s.parsePricingRuleFromMap(data), nil
+		}
+	}
+
+	if rule := defaultPricingRule(vehicleType); rule != nil {
+		return rule, nil
+	}
+	return nil, fmt.Errorf("pricing rule not found for vehicle type: %s", vehicleType)
+}
+
+// GetRuleByID returns a specific rule version, including the built-in defaults
+func (s *PricingService) GetRuleByID(ruleID string) (*models.PricingRule, error) {
+	for _, vehicleType := range []models.VehicleType{models.VehicleTypeMoto, models.VehicleTypeVoiture, models.VehicleTypeCamionnette} {
+		if rule := defaultPricingRule(vehicleType); rule.ID == ruleID {
+			return rule, nil
+		}
+	}
+
+	query := `SELECT * FROM PricingRule WHERE id = $ruleId LIMIT 1`
+	params := map[string]interface{}{
+		"ruleId": ruleID,
+	}
+
+	result, err := db.QuerySingle(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("pricing rule not found: %v", err)
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("pricing rule not found")
+	}
+
+	return s.parsePricingRuleFromMap(data), nil
+}
+
+func (s *PricingService) getStoredRules(vehicleType models.VehicleType) ([]models.PricingRule, error) {
+	query := `SELECT * FROM PricingRule WHERE vehicleType = $vehicleType ORDER BY effectiveFrom DESC, version DESC`
+	params := map[string]interface{}{
+		"vehicleType": string(vehicleType),
+	}
+
+	results, err := db.QueryMultiple(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing rules: %v", err)
+	}
+
+	rules := make([]models.PricingRule, 0, len(results))
+	for _, result := range results {
+		if data, ok := result.(map[string]interface{}); ok {
+			rules = append(rules, *s.parsePricingRuleFromMap(data))
+		}
+	}
+
+	return rules, nil
+}
+
+func (s *PricingService) parsePricingRuleFromMap(data map[string]interface{}) *models.PricingRule {
+	rule := &models.PricingRule{
+		ID:          parseString(data, "id"),
+		VehicleType: models.VehicleType(parseString(data, "vehicleType")),
+		BasePrice:   parseFloat(data, "basePrice"),
+		IncludedKm:  parseFloat(data, "includedKm"),
+		PerKm:       parseFloat(data, "perKm"),
+		WaitingFree: int(parseFloat(data, "waitingFree")),
+		WaitingRate: parseFloat(data, "waitingRate"),
+		Version:     int(parseFloat(data, "version")),
+	}
+
+	if effectiveFrom := parseTimePtr(data, "effectiveFrom"); effectiveFrom != nil {
+		rule.EffectiveFrom = *effectiveFrom
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		rule.CreatedAt = *createdAt
+	}
+	if notes, ok := data["notes"].(string); ok && notes != "" {
+		rule.Notes = &notes
+	}
+	if createdBy, ok := data["createdBy"].(string); ok && createdBy != "" {
+		rule.CreatedBy = &createdBy
+	}
+
+	return rule
+}
+
+// defaultPricingRule returns the built-in rules used until an admin creates a version
+func defaultPricingRule(vehicleType models.VehicleType) *models.PricingRule {
+	switch vehicleType {
+	case models.VehicleTypeMoto:
+		return &models.PricingRule{
+			ID:          "moto-rule",
+			VehicleType: models.VehicleTypeMoto,
+			BasePrice:   1000,
+			IncludedKm:  3,
+			PerKm:       200,
+			WaitingFree: 5,
+			WaitingRate: 50,
+		}
+	case models.VehicleTypeVoiture:
+		return &models.PricingRule{
+			ID:          "voiture-rule",
+			VehicleType: models.VehicleTypeVoiture,
+			BasePrice:   2000,
+			IncludedKm:  5,
+			PerKm:       300,
+			WaitingFree: 10,
+			WaitingRate: 100,
+		}
+	case models.VehicleTypeCamionnette:
+		return &models.PricingRule{
+			ID:          "camionnette-rule",
+			VehicleType: models.VehicleTypeCamionnette,
+			BasePrice:   5000,
+			IncludedKm:  10,
+			PerKm:       500,
+			WaitingFree: 15,
+			WaitingRate: 200,
+		}
+	}
+	return nil
+}
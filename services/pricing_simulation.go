@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// Default number of past deliveries re-priced by a simulation
+const defaultSimulationSampleSize = 50
+
+// SimulatePricingRule re-prices a sample of past completed deliveries under a draft rule.
+// Zone, surge and time adjustments are kept as they were charged, waiting time is re-billed
+// with the draft waiting rates.
+func (s *DeliveryService) SimulatePricingRule(req *models.SimulatePricingRequest) (*models.PricingSimulation, error) {
+	if !req.Rule.VehicleType.IsValid() {
+		return nil, fmt.Errorf("invalid vehicle type: %s", req.Rule.VehicleType)
+	}
+
+	sampleSize := req.SampleSize
+	if sampleSize == 0 {
+		sampleSize = defaultSimulationSampleSize
+	}
+
+	query := `SELECT * FROM Delivery WHERE vehicleType = $vehicleType AND status = 'DELIVERED'`
+	params := map[string]interface{}{
+		"vehicleType": string(req.Rule.VehicleType),
+		"limit":       sampleSize,
+	}
+	if req.Since != nil {
+		query += ` AND createdAt >= $since`
+		params["since"] = *req.Since
+	}
+	query += ` ORDER BY createdAt DESC LIMIT $limit`
+
+	results, err := db.QueryMultiple(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get past deliveries: %v", err)
+	}
+
+	draft := models.NewPricingRule(&req.Rule)
+	simulation := &models.PricingSimulation{
+		VehicleType: req.Rule.VehicleType,
+		Deliveries:  []models.SimulatedDelivery{},
+	}
+
+	for _, result := range results {
+		data, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delivery := s.parseDeliveryFromMap(data)
+		simulation.Deliveries = append(simulation.Deliveries, s.simulateDeliveryPrice(delivery, draft))
+	}
+
+	simulation.Summarize()
+	return simulation, nil
+}
+
+func (s *DeliveryService) simulateDeliveryPrice(delivery *models.Delivery, draft *models.PricingRule) models.SimulatedDelivery {
+	distance := 0.0
+	if delivery.DistanceKm != nil {
+		distance = *delivery.DistanceKm
+	}
+
+	calculation := s.calculateRulePrice(draft, distance, 0, delivery.Type)
+	price := calculation.SubTotal
+
+	// Waiting is billed per stop
+	if delivery.PickupWaitingMin != nil {
+		price += draft.CalculateWaitingPrice(*delivery.PickupWaitingMin)
+	}
+	if delivery.DropoffWaitingMin != nil {
+		price += draft.CalculateWaitingPrice(*delivery.DropoffWaitingMin)
+	}
+
+	for _, item := range delivery.PriceLineItems {
+		if !strings.HasPrefix(item.Code, "WAITING_") {
+			price += item.Amount
+		}
+	}
+
+	price = math.Round(price)
+	return models.SimulatedDelivery{
+		DeliveryID:     delivery.ID,
+		CreatedAt:      delivery.CreatedAt,
+		DistanceKm:     distance,
+		RuleVersion:    delivery.PricingRuleVersion,
+		CurrentPrice:   delivery.FinalPrice,
+		SimulatedPrice: price,
+		Difference:     price - delivery.FinalPrice,
+	}
+}
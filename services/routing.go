@@ -0,0 +1,287 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ambroise1219/livraison_go/config"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// Maximum number of routes kept in memory by the routing cache
+const routeCacheMaxEntries = 10000
+
+// RoutingProvider computes road distance and duration between two points
+type RoutingProvider interface {
+	Name() string
+	Route(from, to models.Coordinates) (*models.RouteResult, error)
+}
+
+// NewRoutingProvider builds the configured provider, falling back to Haversine on errors and
+// caching results by rounded coordinates
+func NewRoutingProvider(cfg *config.Config) RoutingProvider {
+	timeout := time.Duration(cfg.RoutingTimeoutMs) * time.Millisecond
+	fallback := NewHaversineRouter()
+
+	var provider RoutingProvider
+	switch cfg.RoutingProvider {
+	case models.RoutingProviderOSRM:
+		provider = NewFallbackRouter(NewOSRMRouter(cfg.RoutingBaseURL, cfg.RoutingProfile, timeout), fallback)
+	case models.RoutingProviderGraphHopper:
+		provider = NewFallbackRouter(NewGraphHopperRouter(cfg.RoutingBaseURL, cfg.RoutingAPIKey, cfg.RoutingProfile, timeout), fallback)
+	default:
+		provider = fallback
+	}
+
+	return NewCachedRouter(provider, time.Duration(cfg.RoutingCacheTTL)*time.Minute, cfg.RoutingCachePrecision)
+}
+
+// HaversineRouter uses the straight-line distance, as pricing did before road routing
+type HaversineRouter struct{}
+
+func NewHaversineRouter() *HaversineRouter {
+	return &HaversineRouter{}
+}
+
+func (r *HaversineRouter) Name() string {
+	return models.RoutingProviderHaversine
+}
+
+func (r *HaversineRouter) Route(from, to models.Coordinates) (*models.RouteResult, error) {
+	distance := from.DistanceKm(to)
+
+	return &models.RouteResult{
+		DistanceKm:  distance,
+		DurationMin: distance * 3, // Rough estimate: 3 minutes per km
+		Provider:    r.Name(),
+	}, nil
+}
+
+// OSRMRouter queries an OSRM-compatible /route/v1 HTTP API
+type OSRMRouter struct {
+	baseURL string
+	profile string
+	client  *http.Client
+}
+
+func NewOSRMRouter(baseURL, profile string, timeout time.Duration) *OSRMRouter {
+	if profile == "" {
+		profile = "driving"
+	}
+	return &OSRMRouter{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		profile: profile,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (r *OSRMRouter) Name() string {
+	return models.RoutingProviderOSRM
+}
+
+func (r *OSRMRouter) Route(from, to models.Coordinates) (*models.RouteResult, error) {
+	// OSRM expects lng,lat pairs
+	endpoint := fmt.Sprintf("%s/route/v1/%s/%f,%f;%f,%f?overview=false", r.baseURL, r.profile, from.Lng, from.Lat, to.Lng, to.Lat)
+
+	var response struct {
+		Code   string `json:"code"`
+		Routes []struct {
+			Distance float64 `json:"distance"` // meters
+			Duration float64 `json:"duration"` // seconds
+		} `json:"routes"`
+	}
+
+	if err := getJSON(r.client, endpoint, &response); err != nil {
+		return nil, fmt.Errorf("osrm request failed: %v", err)
+	}
+
+	if response.Code != "Ok" || len(response.Routes) == 0 {
+		return nil, fmt.Errorf("osrm returned no route: %s", response.Code)
+	}
+
+	return &models.RouteResult{
+		DistanceKm:  response.Routes[0].Distance / 1000,
+		DurationMin: response.Routes[0].Duration / 60,
+		Provider:    r.Name(),
+	}, nil
+}
+
+// GraphHopperRouter queries a GraphHopper-compatible /route HTTP API
+type GraphHopperRouter struct {
+	baseURL string
+	apiKey  string
+	profile string
+	client  *http.Client
+}
+
+func NewGraphHopperRouter(baseURL, apiKey, profile string, timeout time.Duration) *GraphHopperRouter {
+	if profile == "" || profile == "driving" {
+		profile = "car"
+	}
+	return &GraphHopperRouter{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		profile: profile,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (r *GraphHopperRouter) Name() string {
+	return models.RoutingProviderGraphHopper
+}
+
+func (r *GraphHopperRouter) Route(from, to models.Coordinates) (*models.RouteResult, error) {
+	query := url.Values{}
+	query.Add("point", fmt.Sprintf("%f,%f", from.Lat, from.Lng))
+	query.Add("point", fmt.Sprintf("%f,%f", to.Lat, to.Lng))
+	query.Set("profile", r.profile)
+	query.Set("calc_points", "false")
+	if r.apiKey != "" {
+		query.Set("key", r.apiKey)
+	}
+
+	var response struct {
+		Paths []struct {
+			Distance float64 `json:"distance"` // meters
+			Time     float64 `json:"time"`     // milliseconds
+		} `json:"paths"`
+		Message string `json:"message"`
+	}
+
+	if err := getJSON(r.client, r.baseURL+"/route?"+query.Encode(), &response); err != nil {
+		return nil, fmt.Errorf("graphhopper request failed: %v", err)
+	}
+
+	if len(response.Paths) == 0 {
+		return nil, fmt.Errorf("graphhopper returned no route: %s", response.Message)
+	}
+
+	return &models.RouteResult{
+		DistanceKm:  response.Paths[0].Distance / 1000,
+		DurationMin: response.Paths[0].Time / 60000,
+		Provider:    r.Name(),
+	}, nil
+}
+
+// FallbackRouter uses a secondary provider when the primary one fails or times out
+type FallbackRouter struct {
+	primary  RoutingProvider
+	fallback RoutingProvider
+}
+
+func NewFallbackRouter(primary, fallback RoutingProvider) *FallbackRouter {
+	return &FallbackRouter{
+		primary:  primary,
+		fallback: fallback,
+	}
+}
+
+func (r *FallbackRouter) Name() string {
+	return r.primary.Name()
+}
+
+func (r *FallbackRouter) Route(from, to models.Coordinates) (*models.RouteResult, error) {
+	result, err := r.primary.Route(from, to)
+	if err == nil {
+		return result, nil
+	}
+
+	log.Printf("Warning: routing provider %s failed, using %s: %v", r.primary.Name(), r.fallback.Name(), err)
+	return r.fallback.Route(from, to)
+}
+
+// CachedRouter caches routes by coordinates rounded to a number of decimals
+type CachedRouter struct {
+	next      RoutingProvider
+	ttl       time.Duration
+	precision int
+	mu        sync.Mutex
+	entries   map[string]cachedRoute
+}
+
+type cachedRoute struct {
+	result    models.RouteResult
+	expiresAt time.Time
+}
+
+func NewCachedRouter(next RoutingProvider, ttl time.Duration, precision int) *CachedRouter {
+	return &CachedRouter{
+		next:      next,
+		ttl:       ttl,
+		precision: precision,
+		entries:   make(map[string]cachedRoute),
+	}
+}
+
+func (r *CachedRouter) Name() string {
+	return r.next.Name()
+}
+
+func (r *CachedRouter) Route(from, to models.Coordinates) (*models.RouteResult, error) {
+	key := r.cacheKey(from, to)
+	now := time.Now()
+
+	r.mu.Lock()
+	entry, found := r.entries[key]
+	r.mu.Unlock()
+
+	if found && now.Before(entry.expiresAt) {
+		result := entry.result
+		result.Cached = true
+		return &result, nil
+	}
+
+	result, err := r.next.Route(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Haversine fallbacks are not cached so the real provider is retried next time
+	if result.Provider == r.next.Name() || r.next.Name() == models.RoutingProviderHaversine {
+		r.store(key, *result, now)
+	}
+
+	return result, nil
+}
+
+func (r *CachedRouter) store(key string, result models.RouteResult, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.entries) >= routeCacheMaxEntries {
+		for k, entry := range r.entries {
+			if now.After(entry.expiresAt) {
+				delete(r.entries, k)
+			}
+		}
+		if len(r.entries) >= routeCacheMaxEntries {
+			r.entries = make(map[string]cachedRoute)
+		}
+	}
+
+	r.entries[key] = cachedRoute{result: result, expiresAt: now.Add(r.ttl)}
+}
+
+func (r *CachedRouter) cacheKey(from, to models.Coordinates) string {
+	return fmt.Sprintf("%.*f,%.*f;%.*f,%.*f", r.precision, from.Lat, r.precision, from.Lng, r.precision, to.Lat, r.precision, to.Lng)
+}
+
+func getJSON(client *http.Client, endpoint string, target interface{}) error {
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
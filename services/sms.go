@@ -0,0 +1,27 @@
+package services
+
+import (
+	"log"
+
+	"github.com/ambroise1219/livraison_go/config"
+)
+
+// SMSSender sends text messages to phone numbers in international format
+type SMSSender interface {
+	Send(phone, message string) error
+}
+
+// NewSMSSender returns the SMS sender for the configuration.
+// Until an SMS gateway is plugged in, messages are written to the log like OTP codes.
+func NewSMSSender(cfg *config.Config) SMSSender {
+	return &LogSMSSender{}
+}
+
+// LogSMSSender logs messages instead of sending them, for development
+type LogSMSSender struct{}
+
+// Send writes the message to the log
+func (s *LogSMSSender) Send(phone, message string) error {
+	log.Printf("📱 SMS to %s: %s", phone, message)
+	return nil
+}
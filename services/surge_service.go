@@ -0,0 +1,331 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/config"
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// Driver positions older than this are not counted as available supply
+const availableDriverMaxAge = 5 * time.Minute
+
+type SurgeService struct {
+	config      *config.Config
+	zoneService *ZoneService
+}
+
+func NewSurgeService(cfg *config.Config, zoneService *ZoneService) *SurgeService {
+	return &SurgeService{
+		config:      cfg,
+		zoneService: zoneService,
+	}
+}
+
+// GetSurge returns the current multiplier of a zone, nil meaning outside any zone.
+// The multiplier is recomputed from pending deliveries and available drivers once stale.
+func (s *SurgeService) GetSurge(zone *models.ServiceZone) (*models.SurgeState, error) {
+	now := time.Now()
+	zoneID := models.GlobalSurgeZoneID
+	if zone != nil {
+		zoneID = zone.ID
+	}
+
+	if !s.config.SurgeEnabled {
+		return &models.SurgeState{ZoneID: zoneID, Multiplier: 1, RawMultiplier: 1, UpdatedAt: now}, nil
+	}
+
+	// Admin overrides win over the computed multiplier
+	override, err := s.getActiveOverride(zoneID, now)
+	if err != nil {
+		log.Printf("Warning: failed to check surge overrides for zone %s: %v", zoneID, err)
+	}
+	if override != nil {
+		return &models.SurgeState{
+			ZoneID:        zoneID,
+			Multiplier:    override.Multiplier,
+			RawMultiplier: override.Multiplier,
+			OverrideID:    &override.ID,
+			UpdatedAt:     override.CreatedAt,
+		}, nil
+	}
+
+	policy := s.policy()
+	previous, err := s.getSurgeState(zoneID)
+	if err == nil && !previous.IsStale(policy.Refresh, now) {
+		return previous, nil
+	}
+
+	pending, err := s.countPendingDeliveries(zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pending deliveries: %v", err)
+	}
+
+	available, err := s.countAvailableDrivers(zone, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count available drivers: %v", err)
+	}
+
+	previousMultiplier := 1.0
+	if previous != nil {
+		previousMultiplier = previous.Multiplier
+	}
+
+	raw := policy.RawMultiplier(pending, available)
+	state := &models.SurgeState{
+		ZoneID:            zoneID,
+		Multiplier:        policy.Smooth(previousMultiplier, raw),
+		RawMultiplier:     raw,
+		PendingDeliveries: pending,
+		AvailableDrivers:  available,
+		UpdatedAt:         now,
+	}
+
+	err = s.saveSurgeState(state)
+	if err != nil {
+		log.Printf("Warning: failed to save surge state for zone %s: %v", zoneID, err)
+	}
+
+	log.Printf("Surge zone %s: %d pending / %d available drivers, x%.2f (raw x%.2f)", zoneID, pending, available, state.Multiplier, raw)
+	return state, nil
+}
+
+// GetSurgeStates returns the current multiplier of every active zone, or the global one without zones
+func (s *SurgeService) GetSurgeStates() ([]models.SurgeState, error) {
+	zones, err := s.zoneService.GetZones()
+	if err != nil {
+		return nil, err
+	}
+
+	states := []models.SurgeState{}
+	for i := range zones {
+		if !zones[i].IsActive {
+			continue
+		}
+		state, err := s.GetSurge(&zones[i])
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, *state)
+	}
+
+	if len(states) == 0 {
+		state, err := s.GetSurge(nil)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, *state)
+	}
+
+	return states, nil
+}
+
+// CreateOverride forces the multiplier of a zone for a limited time
+func (s *SurgeService) CreateOverride(adminID string, req *models.CreateSurgeOverrideRequest) (*models.SurgeOverride, error) {
+	if req.ZoneID != models.GlobalSurgeZoneID {
+		if _, err := s.zoneService.GetZone(req.ZoneID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	override := &models.SurgeOverride{
+		ID:         uuid.New().String(),
+		ZoneID:     req.ZoneID,
+		Multiplier: req.Multiplier,
+		Reason:     req.Reason,
+		CreatedBy:  adminID,
+		ExpiresAt:  now.Add(time.Duration(req.DurationMin) * time.Minute),
+		CreatedAt:  now,
+	}
+
+	query := `CREATE SurgeOverride SET 
+		id = $id,
+		zoneId = $zoneId,
+		multiplier = $multiplier,
+		reason = $reason,
+		createdBy = $createdBy,
+		expiresAt = $expiresAt,
+		createdAt = $createdAt`
+
+	params := map[string]interface{}{
+		"id":         override.ID,
+		"zoneId":     override.ZoneID,
+		"multiplier": override.Multiplier,
+		"reason":     override.Reason,
+		"createdBy":  override.CreatedBy,
+		"expiresAt":  override.ExpiresAt,
+		"createdAt":  override.CreatedAt,
+	}
+
+	_, err := db.Query(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save surge override: %v", err)
+	}
+
+	log.Printf("Surge override x%.2f on zone %s by %s until %s", override.Multiplier, override.ZoneID, adminID, override.ExpiresAt.Format(time.RFC3339))
+	return override, nil
+}
+
+// DeleteOverride ends an override early
+func (s *SurgeService) DeleteOverride(overrideID string) error {
+	_, err := db.Query(`DELETE SurgeOverride WHERE id = $overrideId`, map[string]interface{}{
+		"overrideId": overrideID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete surge override: %v", err)
+	}
+	return nil
+}
+
+func (s *SurgeService) policy() *models.SurgePolicy {
+	return &models.SurgePolicy{
+		Threshold:     s.config.SurgeThreshold,
+		Sensitivity:   s.config.SurgeSensitivity,
+		MaxMultiplier: s.config.SurgeMaxMultiplier,
+		Smoothing:     s.config.SurgeSmoothing,
+		Refresh:       time.Duration(s.config.SurgeRefreshSeconds) * time.Second,
+	}
+}
+
+func (s *SurgeService) countPendingDeliveries(zone *models.ServiceZone) (int, error) {
+	conditions := "status = 'PENDING'"
+	params := map[string]interface{}{}
+	if zone != nil {
+		conditions += " AND pickupZoneId = $zoneId"
+		params["zoneId"] = zone.ID
+	}
+
+	count, err := db.CountRecords("Delivery", conditions, params)
+	return int(count), err
+}
+
+func (s *SurgeService) countAvailableDrivers(zone *models.ServiceZone, now time.Time) (int, error) {
+	query := `SELECT * FROM DriverLocation WHERE isAvailable = true AND timestamp > $since`
+	params := map[string]interface{}{
+		"since": now.Add(-availableDriverMaxAge),
+	}
+
+	results, err := db.QueryMultiple(query, params)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, result := range results {
+		data, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		location := parseDriverLocationFromMap(data)
+		if zone == nil || (location.Lat != nil && location.Lng != nil && zone.Polygon.Contains(*location.Lat, *location.Lng)) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *SurgeService) getActiveOverride(zoneID string, now time.Time) (*models.SurgeOverride, error) {
+	query := `SELECT * FROM SurgeOverride WHERE zoneId = $zoneId AND expiresAt > $now ORDER BY createdAt DESC LIMIT 1`
+	params := map[string]interface{}{
+		"zoneId": zoneID,
+		"now":    now,
+	}
+
+	results, err := db.QueryMultiple(query, params)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+
+	data, ok := results[0].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	override := &models.SurgeOverride{
+		ID:         parseString(data, "id"),
+		ZoneID:     parseString(data, "zoneId"),
+		Multiplier: parseFloat(data, "multiplier"),
+		CreatedBy:  parseString(data, "createdBy"),
+	}
+	if expiresAt := parseTimePtr(data, "expiresAt"); expiresAt != nil {
+		override.ExpiresAt = *expiresAt
+	}
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		override.CreatedAt = *createdAt
+	}
+	if reason, ok := data["reason"].(string); ok && reason != "" {
+		override.Reason = &reason
+	}
+
+	if !override.IsActive(now) {
+		return nil, nil
+	}
+	return override, nil
+}
+
+func (s *SurgeService) getSurgeState(zoneID string) (*models.SurgeState, error) {
+	query := `SELECT * FROM SurgeState WHERE zoneId = $zoneId LIMIT 1`
+	params := map[string]interface{}{
+		"zoneId": zoneID,
+	}
+
+	result, err := db.QuerySingle(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no result found")
+	}
+
+	state := &models.SurgeState{
+		ZoneID:            parseString(data, "zoneId"),
+		Multiplier:        parseFloat(data, "multiplier"),
+		RawMultiplier:     parseFloat(data, "rawMultiplier"),
+		PendingDeliveries: int(parseFloat(data, "pendingDeliveries")),
+		AvailableDrivers:  int(parseFloat(data, "availableDrivers")),
+	}
+	if updatedAt := parseTimePtr(data, "updatedAt"); updatedAt != nil {
+		state.UpdatedAt = *updatedAt
+	}
+
+	return state, nil
+}
+
+func (s *SurgeService) saveSurgeState(state *models.SurgeState) error {
+	// Keep only the latest state per zone
+	_, err := db.Query(`DELETE SurgeState WHERE zoneId = $zoneId`, map[string]interface{}{
+		"zoneId": state.ZoneID,
+	})
+	if err != nil {
+		return err
+	}
+
+	query := `CREATE SurgeState SET 
+		zoneId = $zoneId,
+		multiplier = $multiplier,
+		rawMultiplier = $rawMultiplier,
+		pendingDeliveries = $pendingDeliveries,
+		availableDrivers = $availableDrivers,
+		updatedAt = $updatedAt`
+
+	params := map[string]interface{}{
+		"zoneId":            state.ZoneID,
+		"multiplier":        state.Multiplier,
+		"rawMultiplier":     state.RawMultiplier,
+		"pendingDeliveries": state.PendingDeliveries,
+		"availableDrivers":  state.AvailableDrivers,
+		"updatedAt":         state.UpdatedAt,
+	}
+
+	_, err = db.Query(query, params)
+	return err
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ambroise1219/livraison_go/config"
+	"github.com/ambroise1219/livraison_go/db"
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+type ZoneService struct {
+	config *config.Config
+}
+
+func NewZoneService(cfg *config.Config) *ZoneService {
+	return &ZoneService{
+		config: cfg,
+	}
+}
+
+// CreateZone creates a new service zone
+func (s *ZoneService) CreateZone(req *models.CreateServiceZoneRequest) (*models.ServiceZone, error) {
+	if err := req.Polygon.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid polygon: %v", err)
+	}
+	if req.DispatchWeights != nil {
+		if err := req.DispatchWeights.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid dispatch weights: %v", err)
+		}
+	}
+
+	zone := &models.ServiceZone{
+		ID:              uuid.New().String(),
+		Name:            req.Name,
+		Polygon:         req.Polygon,
+		IsActive:        true,
+		PriceAdjustment: req.PriceAdjustment,
+		FixedAdjustment: req.FixedAdjustment,
+		DispatchWeights: req.DispatchWeights,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	if req.IsActive != nil {
+		zone.IsActive = *req.IsActive
+	}
+
+	query := `CREATE ServiceZone SET 
+		id = $id,
+		name = $name,
+		polygon = $polygon,
+		isActive = $isActive,
+		priceAdjustment = $priceAdjustment,
+		fixedAdjustment = $fixedAdjustment,
+		dispatchWeights = $dispatchWeights,
+		createdAt = $createdAt,
+		updatedAt = $updatedAt`
+
+	params := map[string]interface{}{
+		"id":              zone.ID,
+		"name":            zone.Name,
+		"polygon":         zone.Polygon,
+		"isActive":        zone.IsActive,
+		"priceAdjustment": zone.PriceAdjustment,
+		"fixedAdjustment": zone.FixedAdjustment,
+		"dispatchWeights": zone.DispatchWeights,
+		"createdAt":       zone.CreatedAt,
+		"updatedAt":       zone.UpdatedAt,
+	}
+
+	_, err := db.Query(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save zone: %v", err)
+	}
+
+	log.Printf("Created service zone: %s", zone.Name)
+	return zone, nil
+}
+
+// UpdateZone updates the given fields of a service zone
+func (s *ZoneService) UpdateZone(zoneID string, req *models.UpdateServiceZoneRequest) (*models.ServiceZone, error) {
+	zone, err := s.GetZone(zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		zone.Name = *req.Name
+	}
+	if req.Polygon != nil {
+		if err := req.Polygon.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid polygon: %v", err)
+		}
+		zone.Polygon = *req.Polygon
+	}
+	if req.IsActive != nil {
+		zone.IsActive = *req.IsActive
+	}
+	if req.PriceAdjustment != nil {
+		zone.PriceAdjustment = *req.PriceAdjustment
+	}
+	if req.FixedAdjustment != nil {
+		zone.FixedAdjustment = *req.FixedAdjustment
+	}
+	if req.DispatchWeights != nil {
+		if err := req.DispatchWeights.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid dispatch weights: %v", err)
+		}
+		zone.DispatchWeights = req.DispatchWeights
+	}
+	zone.UpdatedAt = time.Now()
+
+	query := `UPDATE ServiceZone SET 
+		name = $name, 
+		polygon = $polygon, 
+		isActive = $isActive, 
+		priceAdjustment = $priceAdjustment, 
+		fixedAdjustment = $fixedAdjustment, 
+		dispatchWeights = $dispatchWeights, 
+		updatedAt = $updatedAt 
+		WHERE id = $zoneId`
+
+	params := map[string]interface{}{
+		"zoneId":          zone.ID,
+		"name":            zone.Name,
+		"polygon":         zone.Polygon,
+		"isActive":        zone.IsActive,
+		"priceAdjustment": zone.PriceAdjustment,
+		"fixedAdjustment": zone.FixedAdjustment,
+		"dispatchWeights": zone.DispatchWeights,
+		"updatedAt":       zone.UpdatedAt,
+	}
+
+	_, err = db.Query(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update zone: %v", err)
+	}
+
+	return zone, nil
+}
+
+// DeleteZone removes a service zone
+func (s *ZoneService) DeleteZone(zoneID string) error {
+	if _, err := s.GetZone(zoneID); err != nil {
+		return err
+	}
+
+	_, err := db.Query(`DELETE ServiceZone WHERE id = $zoneId`, map[string]interface{}{
+		"zoneId": zoneID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete zone: %v", err)
+	}
+
+	return nil
+}
+
+// GetZone returns a service zone by ID
+func (s *ZoneService) GetZone(zoneID string) (*models.ServiceZone, error) {
+	query := `SELECT * FROM ServiceZone WHERE id = $zoneId LIMIT 1`
+	params := map[string]interface{}{
+		"zoneId": zoneID,
+	}
+
+	result, err := db.QuerySingle(query, params)
+	if err != nil {
+		return nil, fmt.Errorf("zone not found: %v", err)
+	}
+
+	zoneData, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("zone not found")
+	}
+
+	return s.parseZoneFromMap(zoneData), nil
+}
+
+// GetZones returns all service zones
+func (s *ZoneService) GetZones() ([]models.ServiceZone, error) {
+	results, err := db.QueryMultiple(`SELECT * FROM ServiceZone ORDER BY name ASC`, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zones: %v", err)
+	}
+
+	zones := make([]models.ServiceZone, 0, len(results))
+	for _, result := range results {
+		if zoneData, ok := result.(map[string]interface{}); ok {
+			zones = append(zones, *s.parseZoneFromMap(zoneData))
+		}
+	}
+
+	return zones, nil
+}
+
+// ResolveMembership finds the zones of a pickup and dropoff
+func (s *ZoneService) ResolveMembership(pickupLat, pickupLng, dropoffLat, dropoffLng *float64) (*models.ZoneMembership, *models.ServiceZone, *models.ServiceZone, error) {
+	zones, err := s.GetZones()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	membership, pickupZone, dropoffZone := models.ResolveZoneMembership(zones, pickupLat, pickupLng, dropoffLat, dropoffLng)
+	return membership, pickupZone, dropoffZone, nil
+}
+
+func (s *ZoneService) parseZoneFromMap(data map[string]interface{}) *models.ServiceZone {
+	zone := &models.ServiceZone{
+		ID:              parseString(data, "id"),
+		Name:            parseString(data, "name"),
+		PriceAdjustment: parseFloat(data, "priceAdjustment"),
+		FixedAdjustment: parseFloat(data, "fixedAdjustment"),
+	}
+
+	zone.IsActive, _ = data["isActive"].(bool)
+	if polygonData, ok := data["polygon"].(map[string]interface{}); ok {
+		zone.Polygon = parseGeoJSONPolygon(polygonData)
+	}
+	zone.DispatchWeights = parseDispatchWeights(data, "dispatchWeights")
+	if createdAt := parseTimePtr(data, "createdAt"); createdAt != nil {
+		zone.CreatedAt = *createdAt
+	}
+	if updatedAt := parseTimePtr(data, "updatedAt"); updatedAt != nil {
+		zone.UpdatedAt = *updatedAt
+	}
+
+	return zone
+}
+
+func parseGeoJSONPolygon(data map[string]interface{}) models.GeoJSONPolygon {
+	polygon := models.GeoJSONPolygon{Type: parseString(data, "type")}
+
+	rings, _ := data["coordinates"].([]interface{})
+	for _, rawRing := range rings {
+		positions, _ := rawRing.([]interface{})
+		ring := make([][]float64, 0, len(positions))
+		for _, rawPosition := range positions {
+			values, _ := rawPosition.([]interface{})
+			position := make([]float64, 0, len(values))
+			for _, value := range values {
+				if number, ok := value.(float64); ok {
+					position = append(position, number)
+				}
+			}
+			ring = append(ring, position)
+		}
+		polygon.Coordinates = append(polygon.Coordinates, ring)
+	}
+
+	return polygon
+}
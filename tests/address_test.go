@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestLocationKey(t *testing.T) {
+	lat, lng := 5.3600001, -3.9800002
+	nearLat := 5.3600004
+
+	assert.Equal(t, models.LocationKey("Cocody, Angré", &lat, &lng), models.LocationKey("cocody angre", &nearLat, &lng))
+	assert.NotEqual(t, models.LocationKey("Cocody Angré", &lat, &lng), models.LocationKey("Cocody Angré", nil, nil))
+	assert.Equal(t, "cocody angre", models.LocationKey("Cocody Angré", nil, nil))
+}
+
+func TestSuggestAddresses(t *testing.T) {
+	now := time.Now()
+	yesterday := now.Add(-24 * time.Hour)
+	lastWeek := now.Add(-7 * 24 * time.Hour)
+	homeLat, homeLng := 5.3950, -3.9890
+
+	saved := []models.SavedAddress{
+		{ID: "home", Label: "Maison", Address: "Angré 8e tranche", Lat: &homeLat, Lng: &homeLng, UseCount: 4, LastUsedAt: &lastWeek},
+		{ID: "shop", Label: "Boutique", Address: "Marché de Treichville", UseCount: 0},
+	}
+	recent := []models.AddressSuggestion{
+		{Address: "Angré 8e tranche", Lat: &homeLat, Lng: &homeLng, UseCount: 1, LastUsedAt: &now}, // Same place as home
+		{Address: "Plateau, Tour BIAO", UseCount: 1, LastUsedAt: &yesterday},
+		{Address: "plateau tour biao", UseCount: 1, LastUsedAt: &lastWeek}, // Same text, different case
+	}
+
+	suggestions := models.SuggestAddresses(saved, recent)
+
+	assert.Len(t, suggestions, 3)
+	assert.Equal(t, "Plateau, Tour BIAO", suggestions[0].Address)
+	assert.Nil(t, suggestions[0].SavedAddressID)
+	assert.Equal(t, "home", *suggestions[1].SavedAddressID)
+	assert.Equal(t, "shop", *suggestions[2].SavedAddressID) // Never used
+}
+
+func TestSuggestAddresses_Limit(t *testing.T) {
+	var recent []models.AddressSuggestion
+	for i := 0; i < 15; i++ {
+		usedAt := time.Now().Add(-time.Duration(i) * time.Hour)
+		recent = append(recent, models.AddressSuggestion{Address: "Rue " + string(rune('A'+i)), LastUsedAt: &usedAt})
+	}
+
+	suggestions := models.SuggestAddresses(nil, recent)
+	assert.Len(t, suggestions, models.MaxAddressSuggestions)
+	assert.Equal(t, "Rue A", suggestions[0].Address)
+}
+
+func TestCreateDeliveryRequest_SavedAddress(t *testing.T) {
+	validate := validator.New()
+	addressID := "home"
+
+	req := models.CreateDeliveryRequest{
+		Type:            models.DeliveryTypeSimple,
+		PickupAddressID: &addressID,
+		DropoffAddress:  "Riviera 3",
+		VehicleType:     models.VehicleTypeMoto,
+		PaymentMethod:   models.PaymentMethodCash,
+	}
+	assert.NoError(t, validate.Struct(req))
+
+	req.PickupAddressID = nil
+	assert.Error(t, validate.Struct(req))
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestDeliveryAttemptPolicy_NextAttempt(t *testing.T) {
+	policy := &models.DeliveryAttemptPolicy{MaxAttempts: 2, ReattemptDelay: 2 * time.Hour}
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		failedAttempts int
+		reason         models.FailedAttemptReason
+		reattempt      bool
+	}{
+		{"first absence", 1, models.FailedAttemptRecipientAbsent, true},
+		{"last attempt", 2, models.FailedAttemptRecipientAbsent, false},
+		{"refused", 1, models.FailedAttemptRecipientRefused, false},
+		{"wrong address", 1, models.FailedAttemptWrongAddress, false},
+		{"cash not paid", 1, models.FailedAttemptCODNotPaid, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := policy.NextAttempt(tt.failedAttempts, tt.reason, now)
+			if !tt.reattempt {
+				assert.Nil(t, next)
+				return
+			}
+			assert.NotNil(t, next)
+			assert.Equal(t, now.Add(2*time.Hour), *next)
+		})
+	}
+}
+
+func TestDelivery_CanFailAttempt(t *testing.T) {
+	assert.NoError(t, (&models.Delivery{Status: models.DeliveryStatusArrivedAtDropoff}).CanFailAttempt())
+	assert.NoError(t, (&models.Delivery{Status: models.DeliveryStatusInTransit}).CanFailAttempt())
+	assert.Error(t, (&models.Delivery{Status: models.DeliveryStatusAccepted}).CanFailAttempt())
+	assert.Error(t, (&models.Delivery{Status: models.DeliveryStatusFailedAttempt}).CanFailAttempt())
+}
+
+func TestDeliveryAttemptPolicy_ReturnLeg(t *testing.T) {
+	policy := &models.DeliveryAttemptPolicy{ReturnLegRate: 0.5}
+	delivery := &models.Delivery{FinalPrice: 2500}
+
+	_, charged := delivery.ReturnLegCharge()
+	assert.False(t, charged)
+
+	amount := policy.ReturnLegPrice(delivery)
+	assert.Equal(t, 1250.0, amount)
+
+	delivery.PriceLineItems = append(delivery.PriceLineItems, models.ReturnLegLineItem(amount))
+	charge, charged := delivery.ReturnLegCharge()
+	assert.True(t, charged)
+	assert.Equal(t, 1250.0, charge)
+}
+
+func TestFailedAttemptReason_IsValid(t *testing.T) {
+	assert.True(t, models.FailedAttemptRecipientAbsent.IsValid())
+	assert.True(t, models.FailedAttemptOther.IsValid())
+	assert.False(t, models.FailedAttemptReason("LOST").IsValid())
+}
+
+func TestClientAttemptMessage(t *testing.T) {
+	reason := models.FailedAttemptRecipientAbsent
+	next := time.Date(2024, 5, 1, 16, 30, 0, 0, time.UTC)
+
+	reattempt := models.ClientAttemptMessage(models.DeliveryStatusFailedAttempt, &reason, &next, 0)
+	assert.Contains(t, reattempt, "RECIPIENT_ABSENT")
+	assert.Contains(t, reattempt, "16:30")
+
+	lastAttempt := models.ClientAttemptMessage(models.DeliveryStatusFailedAttempt, &reason, nil, 0)
+	assert.Contains(t, lastAttempt, "retourné")
+
+	assert.Contains(t, models.ClientAttemptMessage(models.DeliveryStatusReturning, nil, nil, 1250), "1250 FCFA")
+	assert.NotEmpty(t, models.ClientAttemptMessage(models.DeliveryStatusReturned, nil, nil, 0))
+	assert.Empty(t, models.ClientAttemptMessage(models.DeliveryStatusDelivered, nil, nil, 0))
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestCancellationPolicy_Evaluate(t *testing.T) {
+	policy := &models.CancellationPolicy{
+		FreeWindowMin:          5,
+		EnRouteFee:             500,
+		DriverCompensationRate: 0.8,
+	}
+
+	now := time.Now()
+	driverID := "driver-1"
+	justAccepted := now.Add(-2 * time.Minute)
+	acceptedLongAgo := now.Add(-20 * time.Minute)
+
+	tests := []struct {
+		name         string
+		delivery     *models.Delivery
+		role         models.UserRole
+		expectAllow  bool
+		expectFree   bool
+		expectFee    float64
+		expectDriver float64
+	}{
+		{
+			name:        "Client cancels pending delivery",
+			delivery:    &models.Delivery{Status: models.DeliveryStatusPending, FinalPrice: 2000},
+			role:        models.UserRoleClient,
+			expectAllow: true,
+			expectFree:  true,
+		},
+		{
+			name:        "Client cancels within free window",
+			delivery:    &models.Delivery{Status: models.DeliveryStatusAccepted, LivreurID: &driverID, AcceptedAt: &justAccepted, FinalPrice: 2000},
+			role:        models.UserRoleClient,
+			expectAllow: true,
+			expectFree:  true,
+		},
+		{
+			name:         "Client cancels after free window",
+			delivery:     &models.Delivery{Status: models.DeliveryStatusAccepted, LivreurID: &driverID, AcceptedAt: &acceptedLongAgo, FinalPrice: 2000},
+			role:         models.UserRoleClient,
+			expectAllow:  true,
+			expectFee:    500,
+			expectDriver: 400,
+		},
+		{
+			name:         "Client cancels while driver en route",
+			delivery:     &models.Delivery{Status: models.DeliveryStatusPickupInProgress, LivreurID: &driverID, AcceptedAt: &justAccepted, FinalPrice: 2000},
+			role:         models.UserRoleClient,
+			expectAllow:  true,
+			expectFee:    500,
+			expectDriver: 400,
+		},
+		{
+			name:         "Fee capped at delivery price",
+			delivery:     &models.Delivery{Status: models.DeliveryStatusPickupInProgress, LivreurID: &driverID, FinalPrice: 300},
+			role:         models.UserRoleClient,
+			expectAllow:  true,
+			expectFee:    300,
+			expectDriver: 240,
+		},
+		{
+			name:        "Client cannot cancel after pickup",
+			delivery:    &models.Delivery{Status: models.DeliveryStatusInTransit, LivreurID: &driverID, FinalPrice: 2000},
+			role:        models.UserRoleClient,
+			expectAllow: false,
+			expectFree:  true,
+		},
+		{
+			name:        "Driver withdraws before pickup",
+			delivery:    &models.Delivery{Status: models.DeliveryStatusAccepted, LivreurID: &driverID, FinalPrice: 2000},
+			role:        models.UserRoleLivreur,
+			expectAllow: true,
+			expectFree:  true,
+		},
+		{
+			name:        "Delivered delivery cannot be cancelled",
+			delivery:    &models.Delivery{Status: models.DeliveryStatusDelivered, FinalPrice: 2000},
+			role:        models.UserRoleAdmin,
+			expectAllow: false,
+			expectFree:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := policy.Evaluate(tt.delivery, tt.role, now)
+			assert.Equal(t, tt.expectAllow, quote.Allowed)
+			assert.Equal(t, tt.expectFree, quote.Free)
+			assert.InDelta(t, tt.expectFee, quote.Fee, 0.001)
+			assert.InDelta(t, tt.expectDriver, quote.DriverCompensation, 0.001)
+		})
+	}
+}
+
+func TestCancellationReason_IsValidFor(t *testing.T) {
+	assert.True(t, models.CancellationReasonClientChangedMind.IsValidFor(models.UserRoleClient))
+	assert.False(t, models.CancellationReasonClientChangedMind.IsValidFor(models.UserRoleLivreur))
+	assert.True(t, models.CancellationReasonDriverVehicleIssue.IsValidFor(models.UserRoleLivreur))
+	assert.False(t, models.CancellationReasonDriverVehicleIssue.IsValidFor(models.UserRoleClient))
+	assert.True(t, models.CancellationReasonAdminDecision.IsValidFor(models.UserRoleAdmin))
+	assert.True(t, models.CancellationReasonAdminDecision.IsValidFor(models.UserRoleGestionnaire))
+	assert.True(t, models.CancellationReasonClientOther.RequiresComment())
+}
+
+func TestWithdrawnDriverIDs(t *testing.T) {
+	driverID := "driver-1"
+	withdrawn := models.WithdrawnDriverIDs([]models.DeliveryCancellation{
+		{CancelledByID: "driver-1", CancelledByRole: models.UserRoleLivreur, DriverID: &driverID},
+		{CancelledByID: "driver-2", CancelledByRole: models.UserRoleLivreur},
+		{CancelledByID: "client-1", CancelledByRole: models.UserRoleClient, DriverID: &driverID},
+	})
+
+	assert.Equal(t, map[string]bool{"driver-1": true, "driver-2": true}, withdrawn)
+	assert.Empty(t, models.WithdrawnDriverIDs(nil))
+}
+
+func TestUserModel_ComputeReliabilityScore(t *testing.T) {
+	assert.Equal(t, 100.0, (&models.User{}).ComputeReliabilityScore())
+	assert.Equal(t, 80.0, (&models.User{DriverAcceptedCount: 10, DriverCancelledCount: 2}).ComputeReliabilityScore())
+	assert.Equal(t, 0.0, (&models.User{DriverAcceptedCount: 1, DriverCancelledCount: 3}).ComputeReliabilityScore())
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestDelivery_CanCollectCOD(t *testing.T) {
+	cod := 25000.0
+	collectedAt := time.Now()
+
+	tests := []struct {
+		name     string
+		delivery *models.Delivery
+		amount   float64
+		wantErr  bool
+	}{
+		{
+			name:     "at dropoff",
+			delivery: &models.Delivery{Status: models.DeliveryStatusArrivedAtDropoff, CODAmount: &cod},
+			amount:   25000,
+		},
+		{
+			name:     "no cash on delivery",
+			delivery: &models.Delivery{Status: models.DeliveryStatusArrivedAtDropoff},
+			amount:   25000,
+			wantErr:  true,
+		},
+		{
+			name:     "before dropoff",
+			delivery: &models.Delivery{Status: models.DeliveryStatusInTransit, CODAmount: &cod},
+			amount:   25000,
+			wantErr:  true,
+		},
+		{
+			name:     "wrong amount",
+			delivery: &models.Delivery{Status: models.DeliveryStatusArrivedAtDropoff, CODAmount: &cod},
+			amount:   20000,
+			wantErr:  true,
+		},
+		{
+			name:     "already collected",
+			delivery: &models.Delivery{Status: models.DeliveryStatusArrivedAtDropoff, CODAmount: &cod, CODCollectedAt: &collectedAt},
+			amount:   25000,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.delivery.CanCollectCOD(tt.amount)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDelivery_RequiresCODCollection(t *testing.T) {
+	cod := 25000.0
+	collectedAt := time.Now()
+
+	assert.False(t, (&models.Delivery{}).RequiresCODCollection())
+	assert.True(t, (&models.Delivery{CODAmount: &cod}).RequiresCODCollection())
+	assert.False(t, (&models.Delivery{CODAmount: &cod, CODCollectedAt: &collectedAt}).RequiresCODCollection())
+}
+
+func TestDelivery_CashFareEntries(t *testing.T) {
+	driverID := "driver-1"
+	now := time.Now()
+
+	cash := &models.Delivery{ID: "d1", LivreurID: &driverID, PaymentMethod: models.PaymentMethodCash, FinalPrice: 2000}
+	entries := cash.CashFareEntries(0.15, 0, now)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, models.CashEntryCashFare, entries[0].Type)
+	assert.Equal(t, 2000.0, entries[0].Amount)
+	assert.Equal(t, models.CashEntryCommission, entries[1].Type)
+	assert.Equal(t, 300.0, entries[1].Amount)
+
+	mobileMoney := &models.Delivery{ID: "d2", LivreurID: &driverID, PaymentMethod: models.PaymentMethodMobileMoneyOrange, FinalPrice: 2000}
+	assert.Empty(t, mobileMoney.CashFareEntries(0.15, 0, now))
+}
+
+func TestNewDriverCashBalance(t *testing.T) {
+	totals := map[models.CashEntryType]float64{
+		models.CashEntryCODCollected: 80000,
+		models.CashEntryCashFare:     20000,
+		models.CashEntryCommission:   3000,
+		models.CashEntryRemittance:   30000,
+	}
+
+	balance := models.NewDriverCashBalance("driver-1", totals, 50000)
+	assert.Equal(t, 70000.0, balance.CashHeld)
+	assert.Equal(t, 53000.0, balance.AmountDue)
+	assert.True(t, balance.OverLimit)
+
+	assert.NoError(t, balance.CanRemit(53000))
+	assert.Error(t, balance.CanRemit(60000))
+
+	noLimit := models.NewDriverCashBalance("driver-1", totals, 0)
+	assert.False(t, noLimit.OverLimit)
+}
+
+func TestCashLedgerEntry_Due(t *testing.T) {
+	tests := []struct {
+		entryType models.CashEntryType
+		expected  float64
+	}{
+		{models.CashEntryCODCollected, 1000},
+		{models.CashEntryCashFare, 0},
+		{models.CashEntryCommission, 1000},
+		{models.CashEntryRemittance, -1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.entryType), func(t *testing.T) {
+			entry := &models.CashLedgerEntry{Type: tt.entryType, Amount: 1000}
+			assert.Equal(t, tt.expected, entry.Due())
+		})
+	}
+}
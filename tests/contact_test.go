@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name     string
+		phone    string
+		expected string
+		wantErr  bool
+	}{
+		{"local number gets the country code", "07 08 09 10 11", "+2250708091011", false},
+		{"international with plus", "+225 07.08.09.10.11", "+2250708091011", false},
+		{"international with 00", "0033 6 12 34 56 78", "+33612345678", false},
+		{"letters", "07 AB 09", "", true},
+		{"empty", "  ", "", true},
+		{"too short", "+12345", "", true},
+		{"too long", "+1234567890123456", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phone, err := models.NormalizePhone(tt.phone, "225")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, phone)
+		})
+	}
+}
+
+func TestDeliveryStatusRecipientNotice(t *testing.T) {
+	tests := []struct {
+		status   models.DeliveryStatus
+		expected models.RecipientNotice
+		ok       bool
+	}{
+		{models.DeliveryStatusPending, "", false},
+		{models.DeliveryStatusAccepted, "", false},
+		{models.DeliveryStatusPickedUp, models.RecipientNoticeEnRoute, true},
+		{models.DeliveryStatusInTransit, models.RecipientNoticeEnRoute, true},
+		{models.DeliveryStatusArrivedAtDropoff, models.RecipientNoticeArrived, true},
+		{models.DeliveryStatusDelivered, models.RecipientNoticeDelivered, true},
+		{models.DeliveryStatusCancelled, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			notice, ok := tt.status.RecipientNotice()
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, notice)
+		})
+	}
+}
+
+func TestDeliveryHasSentNotice(t *testing.T) {
+	delivery := &models.Delivery{RecipientNotices: []models.RecipientNotice{models.RecipientNoticeEnRoute}}
+
+	assert.True(t, delivery.HasSentNotice(models.RecipientNoticeEnRoute))
+	assert.False(t, delivery.HasSentNotice(models.RecipientNoticeArrived))
+}
+
+func TestDeliveryContactsFor(t *testing.T) {
+	driverID := "driver-1"
+	newDelivery := func(status models.DeliveryStatus) *models.Delivery {
+		return &models.Delivery{
+			Status:         status,
+			LivreurID:      &driverID,
+			PickupContact:  &models.ContactPerson{Name: "Awa", Phone: "+2250708091011"},
+			DropoffContact: &models.ContactPerson{Name: "Koffi", Phone: "+2250102030405"},
+		}
+	}
+
+	t.Run("driver during the delivery", func(t *testing.T) {
+		contacts := newDelivery(models.DeliveryStatusInTransit).ContactsFor(models.UserRoleLivreur)
+		assert.True(t, contacts.CanCall)
+		assert.Equal(t, "+2250102030405", contacts.DropoffContact.Phone)
+	})
+
+	t.Run("driver after the delivery", func(t *testing.T) {
+		contacts := newDelivery(models.DeliveryStatusDelivered).ContactsFor(models.UserRoleLivreur)
+		assert.False(t, contacts.CanCall)
+		assert.Equal(t, "Koffi", contacts.DropoffContact.Name)
+		assert.Empty(t, contacts.DropoffContact.Phone)
+		assert.Empty(t, contacts.PickupContact.Phone)
+	})
+
+	t.Run("client keeps the phones", func(t *testing.T) {
+		contacts := newDelivery(models.DeliveryStatusDelivered).ContactsFor(models.UserRoleClient)
+		assert.False(t, contacts.CanCall)
+		assert.Equal(t, "+2250708091011", contacts.PickupContact.Phone)
+	})
+}
+
+func TestRecipientNoticeMessage(t *testing.T) {
+	url := "https://ilex.ci/track/abc"
+
+	withLink := models.RecipientNoticeMessage(models.RecipientNoticeEnRoute, "Moussa", &url)
+	assert.Contains(t, withLink, "Moussa")
+	assert.Contains(t, withLink, url)
+
+	withoutLink := models.RecipientNoticeMessage(models.RecipientNoticeEnRoute, "", nil)
+	assert.Contains(t, withoutLink, "Votre livreur")
+	assert.NotContains(t, withoutLink, "http")
+
+	assert.NotEmpty(t, models.RecipientNoticeMessage(models.RecipientNoticeDelivered, "Moussa", nil))
+}
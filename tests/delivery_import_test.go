@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestParseDeliveryImport_CSV(t *testing.T) {
+	file := "type,vehicleType,paymentMethod,pickupAddress,pickupLat,pickupLng,dropoffAddress\n" +
+		"simple,moto,cash,Cocody Riviera 3,5.35,-3.98,Plateau\n" +
+		"\n" +
+		"EXPRESS,VOITURE,MOBILE_MONEY_WAVE,Yopougon,abc,,Marcory\n"
+
+	rows, err := models.ParseDeliveryImport(models.ImportFormatCSV, strings.NewReader(file), 10)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Empty(t, rows[0].Errors)
+	assert.Equal(t, models.DeliveryTypeSimple, rows[0].Request.Type)
+	assert.Equal(t, models.VehicleTypeMoto, rows[0].Request.VehicleType)
+	assert.Equal(t, 5.35, *rows[0].Request.PickupLat)
+	assert.Equal(t, "Plateau", rows[0].Request.DropoffAddress)
+
+	assert.Equal(t, 4, rows[1].Line)
+	assert.Equal(t, models.ImportRowStatusInvalid, rows[1].Status)
+	assert.Len(t, rows[1].Errors, 1)
+}
+
+func TestParseDeliveryImport_SemicolonCSV(t *testing.T) {
+	file := "type;vehicleType;paymentMethod;pickupAddress;pickupLat;dropoffAddress\n" +
+		"SIMPLE;MOTO;CASH;Treichville;5,30;Adjamé\n"
+
+	rows, err := models.ParseDeliveryImport(models.ImportFormatCSV, strings.NewReader(file), 10)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, 5.30, *rows[0].Request.PickupLat)
+}
+
+func TestParseDeliveryImport_FileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"unknown column", "type,vehicleType,paymentMethod,colour\nSIMPLE,MOTO,CASH,red\n"},
+		{"missing column", "type,pickupAddress\nSIMPLE,Plateau\n"},
+		{"no rows", "type,vehicleType,paymentMethod\n"},
+		{"too many rows", "type,vehicleType,paymentMethod\nSIMPLE,MOTO,CASH\nSIMPLE,MOTO,CASH\nSIMPLE,MOTO,CASH\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := models.ParseDeliveryImport(models.ImportFormatCSV, strings.NewReader(tt.file), 2)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseDeliveryImport_JSONL(t *testing.T) {
+	file := `{"type":"SIMPLE","vehicleType":"MOTO","paymentMethod":"CASH","pickupAddress":"Plateau","dropoffAddress":"Cocody"}
+not json
+
+{"type":"EXPRESS","vehicleType":"VOITURE","paymentMethod":"CASH","pickupAddressId":"addr-1","dropoffAddress":"Marcory"}
+`
+
+	rows, err := models.ParseDeliveryImport(models.ImportFormatJSONL, strings.NewReader(file), 10)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.NotNil(t, rows[0].Request)
+	assert.Equal(t, models.ImportRowStatusInvalid, rows[1].Status)
+	assert.Equal(t, 4, rows[2].Line)
+	assert.Equal(t, "addr-1", *rows[2].Request.PickupAddressID)
+}
+
+func TestCreateDeliveryRequest_Validate(t *testing.T) {
+	req := &models.CreateDeliveryRequest{Type: models.DeliveryTypeSimple, VehicleType: models.VehicleTypeMoto, PaymentMethod: models.PaymentMethodCash}
+	assert.NoError(t, req.Validate())
+
+	req.VehicleType = "BUS"
+	assert.Error(t, req.Validate())
+}
+
+func TestDetectImportFormat(t *testing.T) {
+	assert.Equal(t, models.ImportFormatJSONL, models.DetectImportFormat("orders.jsonl", ""))
+	assert.Equal(t, models.ImportFormatJSONL, models.DetectImportFormat("", "application/x-ndjson"))
+	assert.Equal(t, models.ImportFormatCSV, models.DetectImportFormat("Orders.CSV", "text/csv"))
+}
+
+func TestDeliveryImportJob_Record(t *testing.T) {
+	job := models.NewDeliveryImportJob("job-1", "client-1", models.ImportFormatCSV, false, 4, time.Now())
+
+	deliveryID := "delivery-1"
+	job.Record(models.DeliveryImportRow{Line: 2, Status: models.ImportRowStatusCreated, DeliveryID: &deliveryID})
+	job.Record(models.DeliveryImportRow{Line: 3, Status: models.ImportRowStatusInvalid, Errors: []string{"Type: failed on required"}})
+	job.Record(models.DeliveryImportRow{Line: 4, Status: models.ImportRowStatusFailed})
+
+	assert.Equal(t, 3, job.ProcessedRows)
+	assert.Equal(t, 1, job.CreatedRows)
+	assert.Equal(t, 1, job.InvalidRows)
+	assert.Equal(t, 1, job.FailedRows)
+	assert.Equal(t, 0.75, job.Progress)
+	assert.Len(t, job.Rows, 3)
+	assert.False(t, job.IsFinished())
+}
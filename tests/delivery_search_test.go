@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+	"github.com/ambroise1219/livraison_go/services"
+)
+
+func TestDeliverySearchRequest_Normalize(t *testing.T) {
+	req := &models.DeliverySearchRequest{Limit: 500}
+	req.Normalize()
+
+	assert.Equal(t, models.DeliverySortCreatedAt, req.Sort)
+	assert.Equal(t, models.SortOrderDesc, req.Order)
+	assert.Equal(t, models.MaxDeliveryPageSize, req.Limit)
+}
+
+func TestDeliverySearchRequest_Validate(t *testing.T) {
+	minPrice, maxPrice := 5000.0, 1000.0
+
+	tests := []struct {
+		name    string
+		req     *models.DeliverySearchRequest
+		wantErr bool
+	}{
+		{"no filters", &models.DeliverySearchRequest{}, false},
+		{"valid status", &models.DeliverySearchRequest{Status: []models.DeliveryStatus{models.DeliveryStatusDelivered}}, false},
+		{"unknown status", &models.DeliverySearchRequest{Status: []models.DeliveryStatus{"LOST"}}, true},
+		{"unknown vehicle", &models.DeliverySearchRequest{VehicleType: "BUS"}, true},
+		{"price range inverted", &models.DeliverySearchRequest{MinPrice: &minPrice, MaxPrice: &maxPrice}, true},
+		{"date range inverted", &models.DeliverySearchRequest{From: time.Now(), To: time.Now().Add(-time.Hour)}, true},
+		{"unknown sort field", &models.DeliverySearchRequest{Sort: "createdAt; DELETE Delivery"}, true},
+		{"unknown sort order", &models.DeliverySearchRequest{Order: "sideways"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeliveryCursor_RoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+	delivery := &models.Delivery{ID: "delivery-1", CreatedAt: createdAt, FinalPrice: 2500}
+
+	cursor, err := models.EncodeDeliveryCursor(models.DeliverySortCreatedAt, delivery)
+	assert.NoError(t, err)
+
+	value, id, err := models.DecodeDeliveryCursor(models.DeliverySortCreatedAt, cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, "delivery-1", id)
+	assert.True(t, createdAt.Equal(value.(time.Time)))
+
+	// A cursor cannot be reused with another sort
+	_, _, err = models.DecodeDeliveryCursor(models.DeliverySortFinalPrice, cursor)
+	assert.Error(t, err)
+
+	_, _, err = models.DecodeDeliveryCursor(models.DeliverySortCreatedAt, "not-a-cursor")
+	assert.Error(t, err)
+}
+
+func TestBuildDeliverySearchQuery(t *testing.T) {
+	minPrice := 1000.0
+	req := &models.DeliverySearchRequest{
+		Status:   []models.DeliveryStatus{models.DeliveryStatusDelivered, models.DeliveryStatusCancelled},
+		ClientID: "client-1",
+		ZoneID:   "zone-1",
+		MinPrice: &minPrice,
+		Query:    "cocody",
+		Order:    models.SortOrderAsc,
+	}
+	req.Normalize()
+
+	query, params, err := services.BuildDeliverySearchQuery(req)
+	assert.NoError(t, err)
+
+	assert.Contains(t, query, "status IN $statuses")
+	assert.Contains(t, query, "clientId = $clientId")
+	assert.Contains(t, query, "(pickupZoneId = $zoneId OR dropoffZoneId = $zoneId)")
+	assert.Contains(t, query, "finalPrice >= $minPrice")
+	assert.Contains(t, query, "address @@ $search")
+	assert.Contains(t, query, "ORDER BY createdAt ASC, id ASC LIMIT 21")
+	assert.Equal(t, []string{"DELIVERED", "CANCELLED"}, params["statuses"])
+	assert.Equal(t, "cocody", params["search"])
+}
+
+func TestBuildDeliverySearchQuery_Cursor(t *testing.T) {
+	delivery := &models.Delivery{ID: "delivery-9", FinalPrice: 4200}
+	cursor, err := models.EncodeDeliveryCursor(models.DeliverySortFinalPrice, delivery)
+	assert.NoError(t, err)
+
+	req := &models.DeliverySearchRequest{Sort: models.DeliverySortFinalPrice, Cursor: cursor, Limit: 10}
+	req.Normalize()
+
+	query, params, err := services.BuildDeliverySearchQuery(req)
+	assert.NoError(t, err)
+
+	assert.Contains(t, query, "(finalPrice < $cursorValue OR (finalPrice = $cursorValue AND id < $cursorId))")
+	assert.Contains(t, query, "ORDER BY finalPrice DESC, id DESC LIMIT 11")
+	assert.Equal(t, 4200.0, params["cursorValue"])
+	assert.Equal(t, "delivery-9", params["cursorId"])
+}
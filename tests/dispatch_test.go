@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func newDispatchScoring() *models.DispatchScoring {
+	return &models.DispatchScoring{
+		Weights:    models.DispatchWeights{ETA: 0.4, Rating: 0.15, Reliability: 0.15, Idle: 0.15, Vehicle: 0.05, Preference: 0.1},
+		MaxEtaMin:  30,
+		IdleCapMin: 60,
+	}
+}
+
+func TestParseDispatchWeights(t *testing.T) {
+	weights, err := models.ParseDispatchWeights("eta:0.5, rating:0.2,idle:0.3")
+	assert.NoError(t, err)
+	assert.Equal(t, models.DispatchWeights{ETA: 0.5, Rating: 0.2, Idle: 0.3}, weights)
+
+	_, err = models.ParseDispatchWeights("speed:1")
+	assert.Error(t, err)
+	_, err = models.ParseDispatchWeights("eta:-1,rating:2")
+	assert.Error(t, err)
+	_, err = models.ParseDispatchWeights("eta:0")
+	assert.Error(t, err)
+	_, err = models.ParseDispatchWeights("eta")
+	assert.Error(t, err)
+}
+
+func TestDispatchScoring_Score(t *testing.T) {
+	scoring := newDispatchScoring()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	zoneID := "zone-cocody"
+	delivery := &models.Delivery{ID: "d1", Type: models.DeliveryTypeSimple, VehicleType: models.VehicleTypeMoto, PickupZoneID: &zoneID}
+	vehicle := &models.Vehicle{Type: models.VehicleTypeMoto}
+
+	rating := 4.8
+	justFinished := now.Add(-5 * time.Minute)
+	longIdle := now.Add(-2 * time.Hour)
+
+	// Same distance: the idle, well rated driver who likes the zone wins
+	busy := &models.User{ID: "busy", RatingAverage: &rating, RatingCount: 20, LastJobEndedAt: &justFinished}
+	idle := &models.User{ID: "idle", RatingAverage: &rating, RatingCount: 20, LastJobEndedAt: &longIdle,
+		DispatchPreferences: &models.DriverDispatchPreferences{PreferredZoneIDs: []string{zoneID}}}
+
+	busyScore := scoring.Score(delivery, models.DispatchCandidate{Driver: busy, Vehicle: vehicle, DistanceKm: 2}, now)
+	idleScore := scoring.Score(delivery, models.DispatchCandidate{Driver: idle, Vehicle: vehicle, DistanceKm: 2}, now)
+
+	assert.False(t, idleScore.Excluded)
+	assert.Equal(t, 1.0, idleScore.Factors.Idle)
+	assert.Equal(t, 1.0, idleScore.Factors.Preference)
+	assert.Equal(t, 0.5, busyScore.Factors.Preference)
+	assert.InDelta(t, 0.96, idleScore.Factors.Rating, 0.001)
+	assert.Equal(t, 0.5, idleScore.Factors.Vehicle) // Papers not on file
+	assert.Greater(t, idleScore.EtaPickupMin, 0.0)
+	assert.Greater(t, idleScore.Score, busyScore.Score)
+
+	// A much closer driver still wins on ETA
+	closeScore := scoring.Score(delivery, models.DispatchCandidate{Driver: busy, Vehicle: vehicle, DistanceKm: 0.2}, now)
+	far := scoring.Score(delivery, models.DispatchCandidate{Driver: idle, Vehicle: vehicle, DistanceKm: 15}, now)
+	assert.Greater(t, closeScore.Score, far.Score)
+	assert.Equal(t, 0.0, far.Factors.ETA)
+}
+
+func TestDispatchScoring_Exclusions(t *testing.T) {
+	scoring := newDispatchScoring()
+	now := time.Now()
+	maxDistance := 3.0
+	driver := &models.User{ID: "driver-1", DispatchPreferences: &models.DriverDispatchPreferences{MaxPickupDistanceKm: &maxDistance}}
+	moving := &models.Delivery{Type: models.DeliveryTypeDemenagement, VehicleType: models.VehicleTypeMoto}
+	simple := &models.Delivery{Type: models.DeliveryTypeSimple, VehicleType: models.VehicleTypeMoto}
+	moto := &models.Vehicle{Type: models.VehicleTypeMoto}
+
+	assert.True(t, scoring.Score(simple, models.DispatchCandidate{Driver: driver, DistanceKm: 1}, now).Excluded)
+	assert.True(t, scoring.Score(moving, models.DispatchCandidate{Driver: driver, Vehicle: moto, DistanceKm: 1}, now).Excluded)
+
+	tooFar := scoring.Score(simple, models.DispatchCandidate{Driver: driver, Vehicle: moto, DistanceKm: 4}, now)
+	assert.True(t, tooFar.Excluded)
+	assert.Contains(t, tooFar.Reason, "3.0 km")
+	assert.Contains(t, tooFar.Explain(), "excluded")
+
+	assert.False(t, scoring.Score(simple, models.DispatchCandidate{Driver: driver, Vehicle: moto, DistanceKm: 2}, now).Excluded)
+}
+
+func TestRankDriverScores(t *testing.T) {
+	scores := []models.DriverScore{
+		models.ExcludeDriver("excluded", 0.1, "over the cash limit"),
+		{DriverID: "low", Score: 40, DistanceKm: 1},
+		{DriverID: "far-tie", Score: 80, DistanceKm: 3},
+		{DriverID: "near-tie", Score: 80, DistanceKm: 2},
+	}
+
+	models.RankDriverScores(scores)
+	assert.Equal(t, "near-tie", scores[0].DriverID)
+	assert.Equal(t, "far-tie", scores[1].DriverID)
+	assert.Equal(t, "low", scores[2].DriverID)
+	assert.Equal(t, "excluded", scores[3].DriverID)
+	assert.Contains(t, scores[0].Explain(), "scored 80.0")
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+	"github.com/ambroise1219/livraison_go/services"
+)
+
+// Abidjan, drivers are spread over about 30 km around it
+const (
+	abidjanLat = 5.3600
+	abidjanLng = -4.0083
+)
+
+func randomDrivers(count int, seed int64) []models.IndexedDriver {
+	random := rand.New(rand.NewSource(seed))
+	vehicleTypes := []models.VehicleType{models.VehicleTypeMoto, models.VehicleTypeVoiture, models.VehicleTypeCamionnette}
+
+	drivers := make([]models.IndexedDriver, count)
+	for i := range drivers {
+		drivers[i] = models.IndexedDriver{
+			DriverID:    fmt.Sprintf("driver-%d", i),
+			Lat:         abidjanLat + (random.Float64()-0.5)*0.27,
+			Lng:         abidjanLng + (random.Float64()-0.5)*0.27,
+			VehicleType: vehicleTypes[random.Intn(len(vehicleTypes))],
+			IsAvailable: random.Intn(4) != 0,
+			UpdatedAt:   time.Now(),
+		}
+	}
+	return drivers
+}
+
+// bruteForceNearest is the reference the index must agree with
+func bruteForceNearest(drivers []models.IndexedDriver, lat, lng float64, filter models.DriverFilter) []models.DriverMatch {
+	origin := models.Coordinates{Lat: lat, Lng: lng}
+	var matches []models.DriverMatch
+	for i := range drivers {
+		if filter.Matches(&drivers[i]) {
+			distance := origin.DistanceKm(models.Coordinates{Lat: drivers[i].Lat, Lng: drivers[i].Lng})
+			matches = append(matches, models.DriverMatch{IndexedDriver: drivers[i], DistanceKm: distance})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].DistanceKm < matches[j].DistanceKm })
+	return matches
+}
+
+func TestDriverIndex_NearestMatchesBruteForce(t *testing.T) {
+	drivers := randomDrivers(2000, 1)
+	index := services.NewDriverIndex(1)
+	index.Load(drivers)
+	assert.Equal(t, 2000, index.Len())
+
+	random := rand.New(rand.NewSource(2))
+	filter := models.DriverFilter{VehicleType: models.VehicleTypeMoto, AvailableOnly: true}
+	for i := 0; i < 50; i++ {
+		// Some queries fall outside the area covered by drivers
+		lat := abidjanLat + (random.Float64()-0.5)*0.6
+		lng := abidjanLng + (random.Float64()-0.5)*0.6
+
+		expected := bruteForceNearest(drivers, lat, lng, filter)[:5]
+		matches := index.Nearest(lat, lng, 5, filter)
+		assert.Len(t, matches, 5)
+		for j := range matches {
+			assert.Equal(t, expected[j].DriverID, matches[j].DriverID)
+		}
+	}
+}
+
+func TestDriverIndex_Within(t *testing.T) {
+	drivers := randomDrivers(2000, 3)
+	index := services.NewDriverIndex(0.5)
+	index.Load(drivers)
+
+	filter := models.DriverFilter{AvailableOnly: true}
+	var expected []string
+	for _, match := range bruteForceNearest(drivers, abidjanLat, abidjanLng, filter) {
+		if match.DistanceKm <= 3 {
+			expected = append(expected, match.DriverID)
+		}
+	}
+
+	matches := index.Within(abidjanLat, abidjanLng, 3, filter)
+	assert.Len(t, matches, len(expected))
+	for i := range matches {
+		assert.Equal(t, expected[i], matches[i].DriverID)
+		assert.True(t, matches[i].IsAvailable)
+	}
+}
+
+func TestDriverIndex_UpdateAndAvailability(t *testing.T) {
+	index := services.NewDriverIndex(1)
+	filter := models.DriverFilter{VehicleType: models.VehicleTypeMoto, AvailableOnly: true}
+
+	assert.Empty(t, index.Nearest(abidjanLat, abidjanLng, 3, filter))
+
+	index.Update(models.IndexedDriver{DriverID: "near", Lat: abidjanLat, Lng: abidjanLng, VehicleType: models.VehicleTypeMoto, IsAvailable: true})
+	index.Update(models.IndexedDriver{DriverID: "far", Lat: abidjanLat + 0.1, Lng: abidjanLng, VehicleType: models.VehicleTypeMoto, IsAvailable: true})
+	index.Update(models.IndexedDriver{DriverID: "car", Lat: abidjanLat, Lng: abidjanLng, VehicleType: models.VehicleTypeVoiture, IsAvailable: true})
+
+	matches := index.Nearest(abidjanLat, abidjanLng, 3, filter)
+	assert.Len(t, matches, 2)
+	assert.Equal(t, "near", matches[0].DriverID)
+
+	// Moving the driver away changes the order
+	index.Update(models.IndexedDriver{DriverID: "near", Lat: abidjanLat + 0.2, Lng: abidjanLng, VehicleType: models.VehicleTypeMoto, IsAvailable: true})
+	assert.Equal(t, 3, index.Len())
+	assert.Equal(t, "far", index.Nearest(abidjanLat, abidjanLng, 1, filter)[0].DriverID)
+
+	index.SetAvailable("far", false)
+	assert.Equal(t, "near", index.Nearest(abidjanLat, abidjanLng, 1, filter)[0].DriverID)
+
+	index.Remove("near")
+	assert.Empty(t, index.Nearest(abidjanLat, abidjanLng, 1, filter))
+	assert.Len(t, index.Nearest(abidjanLat, abidjanLng, 1, models.DriverFilter{}), 1)
+}
+
+func TestDriverFilter_Matches(t *testing.T) {
+	now := time.Now()
+	driver := &models.IndexedDriver{VehicleType: models.VehicleTypeMoto, IsAvailable: false, UpdatedAt: now.Add(-10 * time.Minute)}
+
+	assert.True(t, models.DriverFilter{}.Matches(driver))
+	assert.True(t, models.DriverFilter{VehicleType: models.VehicleTypeMoto}.Matches(driver))
+	assert.False(t, models.DriverFilter{VehicleType: models.VehicleTypeVoiture}.Matches(driver))
+	assert.False(t, models.DriverFilter{AvailableOnly: true}.Matches(driver))
+	assert.False(t, models.DriverFilter{UpdatedSince: now.Add(-5 * time.Minute)}.Matches(driver))
+}
+
+func TestDriverStatus_IsAvailable(t *testing.T) {
+	assert.True(t, models.DriverStatusOnline.IsAvailable())
+	assert.True(t, models.DriverStatusAvailable.IsAvailable())
+	assert.False(t, models.DriverStatusBusy.IsAvailable())
+	assert.False(t, models.DriverStatusOffline.IsAvailable())
+}
+
+func BenchmarkDriverIndex_Nearest10k(b *testing.B) {
+	index := services.NewDriverIndex(1)
+	index.Load(randomDrivers(10000, 4))
+	filter := models.DriverFilter{VehicleType: models.VehicleTypeMoto, AvailableOnly: true}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Nearest(abidjanLat, abidjanLng, 10, filter)
+	}
+}
+
+func BenchmarkDriverIndex_Within10k(b *testing.B) {
+	index := services.NewDriverIndex(1)
+	index.Load(randomDrivers(10000, 4))
+	filter := models.DriverFilter{VehicleType: models.VehicleTypeMoto, AvailableOnly: true}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Within(abidjanLat, abidjanLng, 3, filter)
+	}
+}
+
+func BenchmarkDriverIndex_Update10k(b *testing.B) {
+	drivers := randomDrivers(10000, 4)
+	index := services.NewDriverIndex(1)
+	index.Load(drivers)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		driver := drivers[i%len(drivers)]
+		driver.Lat += 0.001
+		index.Update(driver)
+	}
+}
+
+func BenchmarkBruteForceNearest10k(b *testing.B) {
+	drivers := randomDrivers(10000, 4)
+	filter := models.DriverFilter{VehicleType: models.VehicleTypeMoto, AvailableOnly: true}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bruteForceNearest(drivers, abidjanLat, abidjanLng, filter)
+	}
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestDeliveryEarning_ApplyServiceFee(t *testing.T) {
+	earning := models.NewDeliveryEarning(&models.Delivery{ID: "d1", FinalPrice: 2000}, 0.15)
+	earning.ApplyServiceFee(500)
+	assert.Equal(t, 300.0, earning.Commission)
+	assert.Equal(t, 500.0, earning.ServiceFee)
+	assert.Equal(t, 1200.0, earning.Net)
+
+	// The fee never takes the fare below zero
+	small := models.NewDeliveryEarning(&models.Delivery{ID: "d2", FinalPrice: 400}, 0.15)
+	small.ApplyServiceFee(500)
+	assert.Equal(t, 340.0, small.ServiceFee)
+	assert.Equal(t, 0.0, small.Net)
+}
+
+func TestDeliveryEarning_LedgerEntries(t *testing.T) {
+	tip := 1000.0
+	now := time.Now()
+	earning := models.NewDeliveryEarning(&models.Delivery{ID: "d1", FinalPrice: 4000, TipAmount: &tip}, 0.15)
+	earning.ApplyServiceFee(500)
+
+	entries := earning.LedgerEntries("driver-1", now)
+	assert.Len(t, entries, 4)
+	expected := map[models.EarningEntryType]float64{
+		models.EarningEntryFare:       4000,
+		models.EarningEntryCommission: 600,
+		models.EarningEntryServiceFee: 500,
+		models.EarningEntryTip:        1000,
+	}
+	for _, entry := range entries {
+		assert.Equal(t, expected[entry.Type], entry.Amount)
+		assert.Equal(t, "driver-1", entry.DriverID)
+		assert.Equal(t, "d1", *entry.DeliveryID)
+	}
+
+	// A free delivery still records its fare so it is only credited once
+	free := models.NewDeliveryEarning(&models.Delivery{ID: "d2"}, 0.15)
+	free.ApplyServiceFee(500)
+	entries = free.LedgerEntries("driver-1", now)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, models.EarningEntryFare, entries[0].Type)
+}
+
+func TestEarningsPeriod_Start(t *testing.T) {
+	wednesday := time.Date(2024, 5, 8, 15, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC), models.EarningsPeriodDaily.Start(wednesday))
+	assert.Equal(t, time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), models.EarningsPeriodWeekly.Start(wednesday))
+
+	sunday := time.Date(2024, 5, 12, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), models.EarningsPeriodWeekly.Start(sunday))
+	assert.Equal(t, time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), models.EarningsPeriodWeekly.Next(models.EarningsPeriodWeekly.Start(sunday)))
+}
+
+func TestNewEarningsStatement(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2024, 5, d, hour, 0, 0, 0, time.UTC) }
+	deliveryID := "d1"
+	entries := []models.EarningEntry{
+		{Type: models.EarningEntryFare, Amount: 4000, DeliveryID: &deliveryID, CreatedAt: day(6, 10)},
+		{Type: models.EarningEntryCommission, Amount: 600, DeliveryID: &deliveryID, CreatedAt: day(6, 10)},
+		{Type: models.EarningEntryServiceFee, Amount: 500, DeliveryID: &deliveryID, CreatedAt: day(6, 10)},
+		{Type: models.EarningEntryTip, Amount: 1000, DeliveryID: &deliveryID, CreatedAt: day(7, 9)},
+		{Type: models.EarningEntryBonus, Amount: 2000, CreatedAt: day(8, 18)},
+		{Type: models.EarningEntryPenalty, Amount: 500, CreatedAt: day(13, 8)},
+	}
+
+	daily, err := models.NewEarningsStatement("driver-1", models.EarningsPeriodDaily, day(6, 0), day(9, 0), entries)
+	assert.NoError(t, err)
+	assert.Len(t, daily.Buckets, 3)
+	assert.Equal(t, 1, daily.Buckets[0].DeliveryCount)
+	assert.Equal(t, 2900.0, daily.Buckets[0].Net)
+	assert.Equal(t, 1000.0, daily.Buckets[1].Net)
+	assert.Equal(t, 2000.0, daily.Buckets[2].Bonuses)
+	assert.Equal(t, 5900.0, daily.Totals.Net) // The penalty falls outside the period
+
+	weekly, err := models.NewEarningsStatement("driver-1", models.EarningsPeriodWeekly, day(6, 0), day(20, 0), entries)
+	assert.NoError(t, err)
+	assert.Len(t, weekly.Buckets, 2)
+	assert.Equal(t, 5900.0, weekly.Buckets[0].Net)
+	assert.Equal(t, -500.0, weekly.Buckets[1].Net)
+	assert.Equal(t, 600.0, weekly.Totals.Commission)
+	assert.Equal(t, 500.0, weekly.Totals.ServiceFees)
+	assert.Equal(t, 5400.0, weekly.Totals.Net)
+
+	_, err = models.NewEarningsStatement("driver-1", models.EarningsPeriod("MONTHLY"), day(6, 0), day(20, 0), entries)
+	assert.Error(t, err)
+}
+
+func TestNewLedgerEarnings(t *testing.T) {
+	now := time.Now()
+	deliveryID := "d1"
+	entries := []models.EarningEntry{
+		{Type: models.EarningEntryFare, Amount: 4000, DeliveryID: &deliveryID, CreatedAt: now},
+		{Type: models.EarningEntryCommission, Amount: 600, DeliveryID: &deliveryID, CreatedAt: now},
+		{Type: models.EarningEntryServiceFee, Amount: 500, DeliveryID: &deliveryID, CreatedAt: now},
+		{Type: models.EarningEntryTip, Amount: 1000, DeliveryID: &deliveryID, CreatedAt: now.Add(time.Hour)},
+		{Type: models.EarningEntryPenalty, Amount: 300, DeliveryID: &deliveryID, CreatedAt: now.Add(2 * time.Hour)},
+		{Type: models.EarningEntryBonus, Amount: 2000, CreatedAt: now.Add(3 * time.Hour)},
+	}
+
+	earnings := models.NewLedgerEarnings("driver-1", now.Add(-time.Hour), now.Add(4*time.Hour), entries)
+	assert.Equal(t, 1, earnings.DeliveryCount)
+	assert.Len(t, earnings.Deliveries, 1)
+	assert.Equal(t, 2900.0, earnings.Deliveries[0].Net)
+	assert.Equal(t, 1000.0, earnings.Deliveries[0].Tips)
+	assert.Equal(t, 2900.0, earnings.Net)
+	assert.Equal(t, 2000.0, earnings.Bonuses)
+	assert.Equal(t, 300.0, earnings.Penalties)
+	assert.Equal(t, 5600.0, earnings.Total)
+}
+
+func TestNewPlatformRevenue(t *testing.T) {
+	totals := map[models.EarningEntryType]float64{
+		models.EarningEntryFare:       100000,
+		models.EarningEntryCommission: 15000,
+		models.EarningEntryServiceFee: 10000,
+		models.EarningEntryTip:        3000,
+	}
+	counts := map[models.EarningEntryType]int{models.EarningEntryFare: 20}
+
+	revenue := models.NewPlatformRevenue(time.Now().Add(-24*time.Hour), time.Now(), totals, counts)
+	assert.Equal(t, 25000.0, revenue.Revenue)
+	assert.Equal(t, 20, revenue.Totals.DeliveryCount)
+	assert.Equal(t, 78000.0, revenue.Totals.Net)
+}
+
+func TestDelivery_CashFareEntriesWithServiceFee(t *testing.T) {
+	driverID := "driver-1"
+	cash := &models.Delivery{ID: "d1", LivreurID: &driverID, PaymentMethod: models.PaymentMethodCash, FinalPrice: 2000}
+
+	entries := cash.CashFareEntries(0.15, 500, time.Now())
+	assert.Len(t, entries, 3)
+	assert.Equal(t, models.CashEntryServiceFee, entries[2].Type)
+	assert.Equal(t, 500.0, entries[2].Amount)
+	assert.Equal(t, 500.0, entries[2].Due())
+
+	balance := models.NewDriverCashBalance(driverID, map[models.CashEntryType]float64{
+		models.CashEntryCashFare:   2000,
+		models.CashEntryCommission: 300,
+		models.CashEntryServiceFee: 500,
+	}, 0)
+	assert.Equal(t, 800.0, balance.AmountDue)
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestSpeedProfile_SpeedAt(t *testing.T) {
+	abidjan, err := time.LoadLocation("Africa/Abidjan")
+	assert.NoError(t, err)
+
+	profile := models.GetSpeedProfile(models.VehicleTypeVoiture)
+
+	// Tuesday
+	rushHour := time.Date(2024, 3, 12, 8, 0, 0, 0, abidjan)
+	midDay := time.Date(2024, 3, 12, 13, 0, 0, 0, abidjan)
+	night := time.Date(2024, 3, 12, 23, 30, 0, 0, abidjan)
+	// Saturday morning is not rush hour
+	saturday := time.Date(2024, 3, 16, 8, 0, 0, 0, abidjan)
+
+	assert.Equal(t, profile.BaseSpeedKmh*profile.RushHourFactor, profile.SpeedAt(rushHour))
+	assert.Equal(t, profile.BaseSpeedKmh, profile.SpeedAt(midDay))
+	assert.Equal(t, profile.BaseSpeedKmh*profile.NightFactor, profile.SpeedAt(night))
+	assert.Equal(t, profile.BaseSpeedKmh, profile.SpeedAt(saturday))
+	assert.Greater(t, profile.TravelMinutes(5, rushHour), profile.TravelMinutes(5, midDay))
+}
+
+func TestDeliveryStatus_IsBeforePickup(t *testing.T) {
+	assert.True(t, models.DeliveryStatusAccepted.IsBeforePickup())
+	assert.True(t, models.DeliveryStatusArrivedAtPickup.IsBeforePickup())
+	assert.False(t, models.DeliveryStatusInTransit.IsBeforePickup())
+	assert.False(t, models.DeliveryStatusPending.IsBeforePickup())
+}
+
+func TestDelivery_ShouldNotifyETA(t *testing.T) {
+	now := time.Now()
+	justNotified := now.Add(-3 * time.Minute)
+	notifiedLongAgo := now.Add(-15 * time.Minute)
+
+	assert.True(t, (&models.Delivery{}).ShouldNotifyETA(10*time.Minute, now))
+	assert.False(t, (&models.Delivery{EtaNotifiedAt: &justNotified}).ShouldNotifyETA(10*time.Minute, now))
+	assert.True(t, (&models.Delivery{EtaNotifiedAt: &notifiedLongAgo}).ShouldNotifyETA(10*time.Minute, now))
+	assert.False(t, (&models.Delivery{}).ShouldNotifyETA(0, now)) // ETA SMS disabled
+}
+
+func TestETAUpdateMessage(t *testing.T) {
+	dropoffAt := time.Date(2024, 3, 12, 14, 35, 0, 0, time.UTC)
+	pickupAt := time.Date(2024, 3, 12, 14, 5, 0, 0, time.UTC)
+	pickupMin := 6.0
+
+	assert.Equal(t, "ILEX : votre colis arrive vers 14h35 (dans 12 min).",
+		models.ETAUpdateMessage(&models.DeliveryETA{DropoffETA: dropoffAt, DropoffMin: 12}))
+	assert.Equal(t, "ILEX : votre livreur arrive au point de retrait vers 14h05 (dans 6 min).",
+		models.ETAUpdateMessage(&models.DeliveryETA{PickupETA: &pickupAt, PickupMin: &pickupMin, DropoffETA: dropoffAt, DropoffMin: 40}))
+}
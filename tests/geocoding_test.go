@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func gazetteer() []models.GazetteerEntry {
+	return []models.GazetteerEntry{
+		{ID: "cocody", Name: "Cocody", Kind: models.GazetteerKindCommune, City: "Abidjan", Lat: 5.3600, Lng: -3.9800, RadiusM: 6000, IsActive: true},
+		{ID: "angre-8", Name: "Angré 8e tranche", Aliases: []string{"8eme tranche"}, Kind: models.GazetteerKindNeighbourhood, City: "Abidjan", Lat: 5.3950, Lng: -3.9890, RadiusM: 800, IsActive: true},
+		{ID: "pharmacie-angre", Name: "Pharmacie Angré 8e tranche", Kind: models.GazetteerKindLandmark, City: "Abidjan", Lat: 5.3962, Lng: -3.9878, RadiusM: 50, IsActive: true},
+		{ID: "riviera-3", Name: "Riviera 3", Kind: models.GazetteerKindNeighbourhood, City: "Abidjan", Lat: 5.3700, Lng: -3.9650, RadiusM: 700, IsActive: true},
+		{ID: "old", Name: "Ancien marché", Kind: models.GazetteerKindLandmark, Lat: 5.30, Lng: -4.01, RadiusM: 100, IsActive: false},
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	assert.Equal(t, "cocody angre 8e tranche pres de la pharmacie", models.NormalizeAddress("Cocody Angré 8e tranche, près de la pharmacie"))
+	assert.Equal(t, "rue 12", models.NormalizeAddress("  Rue-12 !"))
+}
+
+func TestGeocode(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		entryID  string
+		needsPin bool
+	}{
+		{"neighbourhood with landmark", "Cocody Angré 8e tranche, près de la pharmacie", "pharmacie-angre", false},
+		{"alias", "8ème tranche", "angre-8", false},
+		{"commune only", "Cocody", "cocody", true},
+		{"unknown place", "Yamoussoukro centre", "", true},
+		{"inactive entry", "Ancien marché", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := models.Geocode(gazetteer(), tt.address, 0.6)
+
+			assert.Equal(t, tt.needsPin, result.NeedsPin)
+			if tt.entryID == "" {
+				assert.Nil(t, result.Match)
+				assert.Nil(t, result.Lat)
+				return
+			}
+			assert.Equal(t, tt.entryID, result.Match.EntryID)
+			assert.NotNil(t, result.Lat)
+		})
+	}
+}
+
+func TestGeocode_CorroboratedMatchRaisesConfidence(t *testing.T) {
+	alone := models.Geocode(gazetteer(), "Riviera 3", 0.6)
+	inCommune := models.Geocode(gazetteer(), "Cocody Riviera 3", 0.6)
+
+	assert.Equal(t, "riviera-3", inCommune.Match.EntryID)
+	assert.Greater(t, inCommune.Confidence, alone.Confidence)
+
+	landmark := models.Geocode(gazetteer(), "Cocody Angré 8e tranche, pharmacie Angré 8e tranche", 0.6)
+	assert.Equal(t, 1.0, landmark.Confidence)
+}
+
+func TestGeocode_AmbiguousMatchLowersConfidence(t *testing.T) {
+	entries := []models.GazetteerEntry{
+		{ID: "a", Name: "Carrefour Duncan", Kind: models.GazetteerKindLandmark, Lat: 5.30, Lng: -4.00, RadiusM: 100, IsActive: true},
+		{ID: "b", Name: "Carrefour Duncan", Kind: models.GazetteerKindLandmark, Lat: 5.40, Lng: -3.90, RadiusM: 100, IsActive: true},
+	}
+
+	result := models.Geocode(entries, "carrefour duncan", 0.6)
+	assert.True(t, result.NeedsPin)
+	assert.Len(t, result.Candidates, 2)
+}
+
+func TestReverseGeocode(t *testing.T) {
+	// Next to the pharmacy: the most specific place containing the point wins
+	result := models.ReverseGeocode(gazetteer(), 5.3962, -3.9879)
+	assert.Equal(t, "pharmacie-angre", result.Match.EntryID)
+	assert.Equal(t, "Pharmacie Angré 8e tranche, Abidjan", result.Address)
+	assert.Greater(t, result.Confidence, 0.6)
+
+	// Inside the neighbourhood but away from the pharmacy
+	result = models.ReverseGeocode(gazetteer(), 5.3930, -3.9900)
+	assert.Equal(t, "angre-8", result.Match.EntryID)
+
+	// Far from everything
+	result = models.ReverseGeocode(gazetteer(), 6.8200, -5.2700)
+	assert.Nil(t, result.Match)
+	assert.Equal(t, 0.0, result.Confidence)
+}
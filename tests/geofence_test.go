@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestGeofenceConfig_Evaluate(t *testing.T) {
+	geofence := &models.GeofenceConfig{PickupRadiusM: 80, DropoffRadiusM: 50, Dwell: 45 * time.Second, UndoWindow: 2 * time.Minute}
+	start := time.Now()
+
+	// Entering the fence starts the dwell timer without arriving
+	state, arrived := geofence.Evaluate(models.GeofenceState{}, models.WaitingStopPickup, 60, start)
+	assert.False(t, arrived)
+	assert.NotNil(t, state.EnteredAt)
+
+	// GPS jitter outside the fence resets the timer
+	jittered, arrived := geofence.Evaluate(state, models.WaitingStopPickup, 120, start.Add(20*time.Second))
+	assert.False(t, arrived)
+	assert.Nil(t, jittered.EnteredAt)
+
+	// Staying inside for the dwell time emits the arrival
+	_, arrived = geofence.Evaluate(state, models.WaitingStopPickup, 30, start.Add(50*time.Second))
+	assert.True(t, arrived)
+
+	// The dropoff fence uses its own radius
+	_, arrived = geofence.Evaluate(models.GeofenceState{}, models.WaitingStopDropoff, 60, start)
+	assert.False(t, arrived)
+}
+
+func TestGeofenceConfig_DisarmedAfterUndo(t *testing.T) {
+	geofence := &models.GeofenceConfig{PickupRadiusM: 80, DropoffRadiusM: 80, Dwell: 0, UndoWindow: 2 * time.Minute}
+	now := time.Now()
+
+	state := models.GeofenceState{Stop: models.WaitingStopPickup, Disarmed: true}
+	state, arrived := geofence.Evaluate(state, models.WaitingStopPickup, 10, now)
+	assert.False(t, arrived)
+
+	// Leaving the fence re-arms it
+	state, _ = geofence.Evaluate(state, models.WaitingStopPickup, 200, now)
+	assert.False(t, state.Disarmed)
+	_, arrived = geofence.Evaluate(state, models.WaitingStopPickup, 10, now)
+	assert.True(t, arrived)
+}
+
+func TestGeofenceConfig_CanUndoAutoArrival(t *testing.T) {
+	geofence := &models.GeofenceConfig{UndoWindow: 2 * time.Minute}
+	now := time.Now()
+	arrivedAt := now.Add(-time.Minute)
+	previous := models.DeliveryStatusPickupInProgress
+
+	state := &models.GeofenceState{AutoArrivalAt: &arrivedAt, PreviousStatus: &previous}
+	assert.True(t, geofence.CanUndoAutoArrival(state, now))
+	assert.False(t, geofence.CanUndoAutoArrival(state, now.Add(2*time.Minute)))
+	assert.False(t, geofence.CanUndoAutoArrival(&models.GeofenceState{}, now))
+	assert.False(t, geofence.CanUndoAutoArrival(nil, now))
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestNewTripReplay(t *testing.T) {
+	deliveryID := "d1"
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	points := []models.LocationPoint{
+		{DriverID: "driver-1", DeliveryID: &deliveryID, Lat: 5.3600, Lng: -4.0083, Timestamp: start},
+		{DriverID: "driver-1", DeliveryID: &deliveryID, Lat: 5.3690, Lng: -4.0083, Timestamp: start.Add(2 * time.Minute)},
+		// GPS jump: 100 km in a minute
+		{DriverID: "driver-1", DeliveryID: &deliveryID, Lat: 6.2690, Lng: -4.0083, Timestamp: start.Add(3 * time.Minute)},
+		{DriverID: "driver-2", DeliveryID: &deliveryID, Lat: 5.3780, Lng: -4.0083, Timestamp: start.Add(4 * time.Minute)},
+	}
+
+	replay := models.NewTripReplay(points)
+	assert.Equal(t, "Feature", replay.Type)
+	assert.Equal(t, "LineString", replay.Geometry.Type)
+	assert.Equal(t, 3, replay.Properties.PointCount)
+	assert.Equal(t, 1, replay.Properties.DroppedPoints)
+	assert.Equal(t, []float64{-4.0083, 5.3600}, replay.Geometry.Coordinates[0])
+	assert.Len(t, replay.Properties.CoordTimes, 3)
+	assert.Equal(t, []string{"driver-1", "driver-2"}, replay.Properties.DriverIDs)
+	assert.Equal(t, start, *replay.Properties.StartedAt)
+	assert.Equal(t, start.Add(4*time.Minute), *replay.Properties.EndedAt)
+	assert.InDelta(t, 2.0, replay.Properties.DistanceKm, 0.01)
+
+	empty := models.NewTripReplay(nil)
+	assert.Equal(t, 0, empty.Properties.PointCount)
+	assert.NotNil(t, empty.Geometry.Coordinates)
+	assert.Nil(t, empty.Properties.StartedAt)
+}
+
+func TestDownsampleTrack(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var track []models.LocationPoint
+	for i := 0; i < 13; i++ {
+		track = append(track, models.LocationPoint{ID: string(rune('a' + i)), Timestamp: start.Add(time.Duration(i*10) * time.Second)})
+	}
+
+	// One point per minute, plus the last one
+	dropped := models.DownsampleTrack(track, time.Minute)
+	assert.Len(t, dropped, 10)
+	for _, point := range dropped {
+		assert.NotEqual(t, "a", point.ID)
+		assert.NotEqual(t, "g", point.ID)
+		assert.NotEqual(t, "m", point.ID)
+	}
+
+	assert.Empty(t, models.DownsampleTrack(track[:2], time.Minute))
+}
+
+func TestIsPlausibleMove(t *testing.T) {
+	start := time.Now()
+	from := models.LocationPoint{Lat: 5.36, Lng: -4.0083, Timestamp: start}
+
+	assert.True(t, models.IsPlausibleMove(from, models.LocationPoint{Lat: 5.369, Lng: -4.0083, Timestamp: start.Add(time.Minute)}))
+	assert.False(t, models.IsPlausibleMove(from, models.LocationPoint{Lat: 5.46, Lng: -4.0083, Timestamp: start.Add(time.Minute)}))
+	assert.False(t, models.IsPlausibleMove(from, models.LocationPoint{Lat: 5.369, Lng: -4.0083, Timestamp: start}))
+}
@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestVersionStatuses(t *testing.T) {
+	now := time.Now()
+	rules := []models.PricingRule{
+		{ID: "v3", Version: 3, EffectiveFrom: now.Add(24 * time.Hour)},
+		{ID: "v2", Version: 2, EffectiveFrom: now.Add(-24 * time.Hour)},
+		{ID: "v1", Version: 1, EffectiveFrom: now.Add(-48 * time.Hour)},
+		{ID: "moto-rule", Version: 0},
+	}
+
+	versions := models.VersionStatuses(rules, now)
+
+	assert.Len(t, versions, 4)
+	assert.Equal(t, models.PricingRuleStatusScheduled, versions[0].Status)
+	assert.Equal(t, models.PricingRuleStatusActive, versions[1].Status)
+	assert.Equal(t, models.PricingRuleStatusSuperseded, versions[2].Status)
+	assert.Equal(t, models.PricingRuleStatusSuperseded, versions[3].Status)
+}
+
+func TestPricingSimulation_Summarize(t *testing.T) {
+	simulation := &models.PricingSimulation{
+		Deliveries: []models.SimulatedDelivery{
+			{CurrentPrice: 2000, SimulatedPrice: 2200},
+			{CurrentPrice: 3000, SimulatedPrice: 3300},
+		},
+	}
+
+	simulation.Summarize()
+
+	assert.Equal(t, 2, simulation.SampleSize)
+	assert.Equal(t, 5000.0, simulation.TotalCurrent)
+	assert.Equal(t, 5500.0, simulation.TotalSimulated)
+	assert.InDelta(t, 10.0, simulation.AverageChangePct, 0.001)
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestRatingPolicy_CanRate(t *testing.T) {
+	policy := &models.RatingPolicy{Window: 72 * time.Hour}
+	now := time.Now()
+	driverID := "driver-1"
+	deliveredAt := now.Add(-2 * time.Hour)
+
+	tests := []struct {
+		name     string
+		delivery *models.Delivery
+		role     models.UserRole
+		wantErr  bool
+	}{
+		{
+			name:     "delivered within window",
+			delivery: &models.Delivery{Status: models.DeliveryStatusDelivered, LivreurID: &driverID, DeliveredAt: &deliveredAt},
+			role:     models.UserRoleClient,
+		},
+		{
+			name:     "not delivered yet",
+			delivery: &models.Delivery{Status: models.DeliveryStatusPending, LivreurID: &driverID},
+			role:     models.UserRoleClient,
+			wantErr:  true,
+		},
+		{
+			name:     "client without driver",
+			delivery: &models.Delivery{Status: models.DeliveryStatusDelivered, DeliveredAt: &deliveredAt},
+			role:     models.UserRoleClient,
+			wantErr:  true,
+		},
+		{
+			name:     "window closed",
+			delivery: &models.Delivery{Status: models.DeliveryStatusDelivered, LivreurID: &driverID, UpdatedAt: now.Add(-73 * time.Hour)},
+			role:     models.UserRoleLivreur,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.CanRate(tt.delivery, tt.role, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRatingTag_IsValidFor(t *testing.T) {
+	assert.True(t, models.RatingTagLate.IsValidFor(models.UserRoleClient))
+	assert.False(t, models.RatingTagLate.IsValidFor(models.UserRoleLivreur))
+	assert.True(t, models.RatingTagUnreachable.IsValidFor(models.UserRoleLivreur))
+	assert.False(t, models.RatingTagUnreachable.IsValidFor(models.UserRoleClient))
+	assert.False(t, models.RatingTagFast.IsValidFor(models.UserRoleAdmin))
+}
+
+func TestAddScore(t *testing.T) {
+	average, count := models.AddScore(nil, 0, 4)
+	assert.Equal(t, 4.0, average)
+	assert.Equal(t, 1, count)
+
+	average, count = models.AddScore(&average, count, 2)
+	assert.Equal(t, 3.0, average)
+	assert.Equal(t, 2, count)
+}
+
+func TestRatingPolicy_NeedsReview(t *testing.T) {
+	policy := &models.RatingPolicy{ReviewThreshold: 3.5, ReviewMinCount: 10}
+
+	assert.False(t, policy.NeedsReview(2.0, 5))
+	assert.True(t, policy.NeedsReview(3.2, 10))
+	assert.False(t, policy.NeedsReview(4.1, 20))
+}
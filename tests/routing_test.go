@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+	"github.com/ambroise1219/livraison_go/services"
+)
+
+var (
+	plateau = models.Coordinates{Lat: 5.3197, Lng: -4.0168}
+	cocody  = models.Coordinates{Lat: 5.3599, Lng: -3.9870}
+)
+
+// countingRouter is a stub provider recording how many times it was called
+type countingRouter struct {
+	name  string
+	calls int32
+	err   error
+}
+
+func (r *countingRouter) Name() string { return r.name }
+
+func (r *countingRouter) Route(from, to models.Coordinates) (*models.RouteResult, error) {
+	atomic.AddInt32(&r.calls, 1)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &models.RouteResult{DistanceKm: 7.5, DurationMin: 18, Provider: r.name}, nil
+}
+
+func TestHaversineRouter_Route(t *testing.T) {
+	result, err := services.NewHaversineRouter().Route(plateau, cocody)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoutingProviderHaversine, result.Provider)
+	assert.InDelta(t, 5.5, result.DistanceKm, 0.2)
+	assert.InDelta(t, result.DistanceKm*3, result.DurationMin, 0.001)
+}
+
+func TestOSRMRouter_Route(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.URL.Path, "/route/v1/driving/-4.016800,5.319700;-3.987000,5.359900"))
+		fmt.Fprint(w, `{"code":"Ok","routes":[{"distance":8250.0,"duration":1260.0}]}`)
+	}))
+	defer server.Close()
+
+	result, err := services.NewOSRMRouter(server.URL, "driving", time.Second).Route(plateau, cocody)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoutingProviderOSRM, result.Provider)
+	assert.InDelta(t, 8.25, result.DistanceKm, 0.001)
+	assert.InDelta(t, 21.0, result.DurationMin, 0.001)
+}
+
+func TestOSRMRouter_NoRoute(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code":"NoRoute","routes":[]}`)
+	}))
+	defer server.Close()
+
+	_, err := services.NewOSRMRouter(server.URL, "driving", time.Second).Route(plateau, cocody)
+	assert.Error(t, err)
+}
+
+func TestGraphHopperRouter_Route(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/route", r.URL.Path)
+		assert.Equal(t, []string{"5.319700,-4.016800", "5.359900,-3.987000"}, r.URL.Query()["point"])
+		assert.Equal(t, "car", r.URL.Query().Get("profile"))
+		assert.Equal(t, "secret", r.URL.Query().Get("key"))
+		fmt.Fprint(w, `{"paths":[{"distance":9100.0,"time":1500000}]}`)
+	}))
+	defer server.Close()
+
+	result, err := services.NewGraphHopperRouter(server.URL, "secret", "driving", time.Second).Route(plateau, cocody)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoutingProviderGraphHopper, result.Provider)
+	assert.InDelta(t, 9.1, result.DistanceKm, 0.001)
+	assert.InDelta(t, 25.0, result.DurationMin, 0.001)
+}
+
+func TestRouter_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, `{"code":"Ok","routes":[{"distance":1000.0,"duration":60.0}]}`)
+	}))
+	defer server.Close()
+
+	router := services.NewFallbackRouter(services.NewOSRMRouter(server.URL, "driving", 50*time.Millisecond), services.NewHaversineRouter())
+	result, err := router.Route(plateau, cocody)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoutingProviderHaversine, result.Provider)
+}
+
+func TestCachedRouter_RoundsCoordinates(t *testing.T) {
+	stub := &countingRouter{name: models.RoutingProviderOSRM}
+	router := services.NewCachedRouter(stub, time.Hour, 3)
+
+	first, err := router.Route(plateau, cocody)
+	assert.NoError(t, err)
+	assert.False(t, first.Cached)
+
+	// Within ~10 meters of the first request
+	nearby := models.Coordinates{Lat: plateau.Lat + 0.0001, Lng: plateau.Lng}
+	second, err := router.Route(nearby, cocody)
+	assert.NoError(t, err)
+	assert.True(t, second.Cached)
+	assert.Equal(t, first.DistanceKm, second.DistanceKm)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&stub.calls))
+
+	// Reverse direction is a different route
+	_, err = router.Route(cocody, plateau)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&stub.calls))
+}
+
+func TestCachedRouter_Expires(t *testing.T) {
+	stub := &countingRouter{name: models.RoutingProviderOSRM}
+	router := services.NewCachedRouter(stub, time.Millisecond, 4)
+
+	router.Route(plateau, cocody)
+	time.Sleep(5 * time.Millisecond)
+	router.Route(plateau, cocody)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&stub.calls))
+}
+
+func TestCachedRouter_SkipsFallbackResults(t *testing.T) {
+	failing := &countingRouter{name: models.RoutingProviderOSRM, err: fmt.Errorf("connection refused")}
+	router := services.NewCachedRouter(services.NewFallbackRouter(failing, services.NewHaversineRouter()), time.Hour, 4)
+
+	first, err := router.Route(plateau, cocody)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoutingProviderHaversine, first.Provider)
+
+	router.Route(plateau, cocody)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&failing.calls))
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+var shiftDay = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func shiftAt(hour, minute int) time.Time {
+	return shiftDay.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func statusEvent(driverID string, status models.DriverStatus, createdAt time.Time) models.DriverStatusEvent {
+	return models.DriverStatusEvent{DriverID: driverID, Status: status, CreatedAt: createdAt}
+}
+
+func TestValidateShiftPeriod(t *testing.T) {
+	assert.NoError(t, models.ValidateShiftPeriod(shiftAt(8, 0), shiftAt(16, 0)))
+	assert.Error(t, models.ValidateShiftPeriod(shiftAt(8, 0), shiftAt(8, 0)))
+	assert.Error(t, models.ValidateShiftPeriod(shiftAt(8, 0), shiftAt(7, 0)))
+	assert.Error(t, models.ValidateShiftPeriod(shiftAt(6, 0), shiftAt(19, 0)))
+}
+
+func TestShift_IsActiveAndOverlaps(t *testing.T) {
+	shift := &models.Shift{StartAt: shiftAt(8, 0), EndAt: shiftAt(12, 0), DriverIDs: []string{"d1"}}
+
+	assert.True(t, shift.IsActive(shiftAt(8, 0)))
+	assert.True(t, shift.IsActive(shiftAt(11, 59)))
+	assert.False(t, shift.IsActive(shiftAt(12, 0)))
+	assert.True(t, shift.HasDriver("d1"))
+	assert.False(t, shift.HasDriver("d2"))
+
+	assert.True(t, shift.Overlaps(shiftAt(11, 0), shiftAt(13, 0)))
+	assert.False(t, shift.Overlaps(shiftAt(12, 0), shiftAt(14, 0)))
+	assert.False(t, shift.Overlaps(shiftAt(6, 0), shiftAt(8, 0)))
+}
+
+func TestNewShiftStaffing(t *testing.T) {
+	zone := "zone-plateau"
+	otherZone := "zone-yopougon"
+	shift := models.Shift{ZoneID: zone, StartAt: shiftAt(8, 0), EndAt: shiftAt(12, 0), MinDrivers: 3, DriverIDs: []string{"assigned", "no-slot"}}
+	slots := []models.AvailabilitySlot{
+		{DriverID: "assigned", ZoneID: &zone, StartAt: shiftAt(7, 0), EndAt: shiftAt(13, 0)},
+		{DriverID: "anywhere", StartAt: shiftAt(8, 0), EndAt: shiftAt(12, 0)},
+		{DriverID: "other-zone", ZoneID: &otherZone, StartAt: shiftAt(8, 0), EndAt: shiftAt(12, 0)},
+		{DriverID: "leaves-early", ZoneID: &zone, StartAt: shiftAt(8, 0), EndAt: shiftAt(11, 0)},
+	}
+
+	staffing := models.NewShiftStaffing(shift, slots)
+	assert.Equal(t, []string{"anywhere"}, staffing.AvailableDriverIDs)
+	assert.Equal(t, []string{"no-slot"}, staffing.UnavailableDriverIDs)
+	assert.Equal(t, 1, staffing.Missing)
+	assert.True(t, staffing.Understaffed)
+
+	shift.MinDrivers = 2
+	staffing = models.NewShiftStaffing(shift, slots)
+	assert.Equal(t, 0, staffing.Missing)
+	assert.False(t, staffing.Understaffed)
+}
+
+func TestOnlineIntervals(t *testing.T) {
+	events := []models.DriverStatusEvent{
+		statusEvent("d1", models.DriverStatusOnline, shiftAt(7, 0)), // Before the period
+		statusEvent("d1", models.DriverStatusBusy, shiftAt(9, 0)),
+		statusEvent("d1", models.DriverStatusOffline, shiftAt(10, 0)),
+		statusEvent("d1", models.DriverStatusAvailable, shiftAt(11, 0)),
+	}
+
+	intervals := models.OnlineIntervals(events, shiftAt(8, 0), shiftAt(12, 0))
+	assert.Equal(t, []models.TimeInterval{
+		{Start: shiftAt(8, 0), End: shiftAt(10, 0)},
+		{Start: shiftAt(11, 0), End: shiftAt(12, 0)},
+	}, intervals)
+	assert.Equal(t, 3.0, models.OverlapHours(intervals, shiftAt(8, 0), shiftAt(12, 0)))
+	assert.Equal(t, 1.0, models.OverlapHours(intervals, shiftAt(9, 30), shiftAt(11, 30)))
+
+	// Offline before the period and nothing since
+	offline := []models.DriverStatusEvent{statusEvent("d1", models.DriverStatusOffline, shiftAt(6, 0))}
+	assert.Empty(t, models.OnlineIntervals(offline, shiftAt(8, 0), shiftAt(12, 0)))
+}
+
+func TestNewShiftReport(t *testing.T) {
+	shifts := []models.Shift{
+		{ID: "morning", StartAt: shiftAt(8, 0), EndAt: shiftAt(12, 0), DriverIDs: []string{"planned", "absent"}},
+		{ID: "evening", StartAt: shiftAt(18, 0), EndAt: shiftAt(22, 0), DriverIDs: []string{"planned"}},
+	}
+	events := map[string][]models.DriverStatusEvent{
+		"planned": {
+			statusEvent("planned", models.DriverStatusOnline, shiftAt(8, 30)),
+			statusEvent("planned", models.DriverStatusOffline, shiftAt(13, 0)),
+			statusEvent("planned", models.DriverStatusOnline, shiftAt(18, 0)),
+			statusEvent("planned", models.DriverStatusOffline, shiftAt(20, 0)),
+		},
+		"unplanned": {
+			statusEvent("unplanned", models.DriverStatusAvailable, shiftAt(14, 0)),
+			statusEvent("unplanned", models.DriverStatusOffline, shiftAt(15, 30)),
+		},
+		"never-online": {
+			statusEvent("never-online", models.DriverStatusOffline, shiftAt(9, 0)),
+		},
+	}
+
+	// The report stops at 21:00, in the middle of the evening shift
+	report := models.NewShiftReport(shiftAt(0, 0), shiftAt(21, 0), shifts, events)
+	assert.Len(t, report.Drivers, 3)
+
+	absent := report.Drivers[0]
+	assert.Equal(t, "absent", absent.DriverID)
+	assert.Equal(t, 4.0, absent.PlannedHours)
+	assert.Equal(t, 0.0, absent.ActualHours)
+	assert.Equal(t, 0.0, *absent.Adherence)
+
+	planned := report.Drivers[1]
+	assert.Equal(t, "planned", planned.DriverID)
+	assert.Equal(t, 2, planned.ShiftCount)
+	assert.Equal(t, 7.0, planned.PlannedHours)
+	assert.Equal(t, 6.5, planned.ActualHours)
+	assert.Equal(t, 5.5, planned.OnShiftHours)
+	assert.InDelta(t, 78.57, *planned.Adherence, 0.01)
+
+	unplanned := report.Drivers[2]
+	assert.Equal(t, "unplanned", unplanned.DriverID)
+	assert.Equal(t, 1.5, unplanned.ActualHours)
+	assert.Nil(t, unplanned.Adherence)
+
+	assert.Equal(t, 11.0, report.PlannedHours)
+	assert.Equal(t, 8.0, report.ActualHours)
+	assert.Equal(t, 5.5, report.OnShiftHours)
+}
+
+func TestDispatchScoring_PrefersOnShiftDrivers(t *testing.T) {
+	scoring := newDispatchScoring()
+	scoring.Weights.Shift = 0.1
+	now := time.Now()
+	delivery := &models.Delivery{Type: models.DeliveryTypeSimple, VehicleType: models.VehicleTypeMoto}
+	vehicle := &models.Vehicle{Type: models.VehicleTypeMoto}
+	driver := &models.User{ID: "driver-1"}
+
+	onShift := scoring.Score(delivery, models.DispatchCandidate{Driver: driver, Vehicle: vehicle, DistanceKm: 2, OnShift: true}, now)
+	offShift := scoring.Score(delivery, models.DispatchCandidate{Driver: driver, Vehicle: vehicle, DistanceKm: 2}, now)
+
+	assert.Equal(t, 1.0, onShift.Factors.Shift)
+	assert.Equal(t, 0.0, offShift.Factors.Shift)
+	assert.Greater(t, onShift.Score, offShift.Score)
+
+	weights, err := models.ParseDispatchWeights("eta:0.5,shift:0.5")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, weights.Shift)
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func newSLAPolicy() *models.SLAPolicy {
+	return &models.SLAPolicy{
+		Rules: map[models.DeliveryStatus]models.SLARule{
+			models.DeliveryStatusAccepted:  {Status: models.DeliveryStatusAccepted, MaxDurationMin: 30, NoProgressMin: 10},
+			models.DeliveryStatusInTransit: {Status: models.DeliveryStatusInTransit, NoProgressMin: 15},
+		},
+		DriverSilence:  10 * time.Minute,
+		Escalation:     5 * time.Minute,
+		ProgressMeters: 100,
+	}
+}
+
+func TestParseSLARules(t *testing.T) {
+	rules, err := models.ParseSLARules("ACCEPTED:30:10, in_transit:0:15,")
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, 30, rules[models.DeliveryStatusAccepted].MaxDurationMin)
+	assert.Equal(t, 15, rules[models.DeliveryStatusInTransit].NoProgressMin)
+
+	_, err = models.ParseSLARules("ACCEPTED:30")
+	assert.Error(t, err)
+	_, err = models.ParseSLARules("LOST:30:10")
+	assert.Error(t, err)
+	_, err = models.ParseSLARules("ACCEPTED:-1:10")
+	assert.Error(t, err)
+}
+
+func TestSLAPolicy_Evaluate(t *testing.T) {
+	policy := newSLAPolicy()
+	driverID := "driver-1"
+	acceptedAt := time.Now().Add(-12 * time.Minute)
+	delivery := &models.Delivery{ID: "d1", Status: models.DeliveryStatusAccepted, LivreurID: &driverID, StatusChangedAt: &acceptedAt}
+
+	far, closer := 3.0, 2.5
+	seenAt := time.Now().Add(-time.Minute)
+
+	// Moving toward the pickup
+	check := policy.Evaluate(delivery, &far, &seenAt, acceptedAt.Add(5*time.Minute))
+	assert.Equal(t, models.SLAActionNone, check.Action)
+	delivery.SLA = check.State
+	check = policy.Evaluate(delivery, &closer, &seenAt, acceptedAt.Add(8*time.Minute))
+	assert.Equal(t, models.SLAActionNone, check.Action)
+	delivery.SLA = check.State
+
+	// Stuck for more than 10 minutes: warn, then re-dispatch after the escalation delay
+	now := acceptedAt.Add(19 * time.Minute)
+	check = policy.Evaluate(delivery, &closer, &seenAt, now)
+	assert.Equal(t, models.SLAActionWarn, check.Action)
+	assert.Equal(t, models.SLABreachNoProgress, check.Breach)
+	delivery.SLA = check.State
+
+	check = policy.Evaluate(delivery, &closer, &seenAt, now.Add(2*time.Minute))
+	assert.Equal(t, models.SLAActionNone, check.Action)
+	delivery.SLA = check.State
+
+	check = policy.Evaluate(delivery, &closer, &seenAt, now.Add(5*time.Minute))
+	assert.Equal(t, models.SLAActionRedispatch, check.Action)
+}
+
+func TestSLAPolicy_EvaluateAfterPickup(t *testing.T) {
+	policy := newSLAPolicy()
+	driverID := "driver-1"
+	since := time.Now().Add(-30 * time.Minute)
+	lastSeenAt := since
+	delivery := &models.Delivery{ID: "d1", Status: models.DeliveryStatusInTransit, LivreurID: &driverID, StatusChangedAt: &since}
+
+	// The driver has the parcel: a silent driver is escalated to ops, never re-dispatched
+	now := time.Now()
+	check := policy.Evaluate(delivery, nil, &lastSeenAt, now)
+	assert.Equal(t, models.SLAActionWarn, check.Action)
+	assert.Equal(t, models.SLABreachSilent, check.Breach)
+	delivery.SLA = check.State
+
+	check = policy.Evaluate(delivery, nil, &lastSeenAt, now.Add(5*time.Minute))
+	assert.Equal(t, models.SLAActionEscalate, check.Action)
+	delivery.SLA = check.State
+
+	check = policy.Evaluate(delivery, nil, &lastSeenAt, now.Add(10*time.Minute))
+	assert.Equal(t, models.SLAActionNone, check.Action)
+}
+
+func TestSLAPolicy_EvaluateStatusChange(t *testing.T) {
+	policy := newSLAPolicy()
+	driverID := "driver-1"
+	breachedAt := time.Now().Add(-20 * time.Minute)
+	changedAt := time.Now()
+	delivery := &models.Delivery{
+		Status:          models.DeliveryStatusInTransit,
+		LivreurID:       &driverID,
+		StatusChangedAt: &changedAt,
+		SLA:             &models.SLAState{Status: models.DeliveryStatusAccepted, BreachedAt: &breachedAt},
+	}
+
+	// The state of the previous status does not carry over
+	check := policy.Evaluate(delivery, nil, &changedAt, changedAt.Add(time.Minute))
+	assert.Equal(t, models.SLAActionNone, check.Action)
+	assert.Equal(t, models.DeliveryStatusInTransit, check.State.Status)
+	assert.Nil(t, check.State.BreachedAt)
+
+	// Unwatched statuses and unassigned deliveries are skipped
+	assert.Nil(t, policy.Evaluate(&models.Delivery{Status: models.DeliveryStatusPending}, nil, nil, time.Now()).State)
+	assert.Nil(t, policy.Evaluate(&models.Delivery{Status: models.DeliveryStatusAccepted}, nil, nil, time.Now()).State)
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestSurgePolicy_RawMultiplier(t *testing.T) {
+	policy := &models.SurgePolicy{Threshold: 1.5, Sensitivity: 0.25, MaxMultiplier: 2.0, Smoothing: 0.5, Refresh: time.Minute}
+
+	tests := []struct {
+		name      string
+		pending   int
+		available int
+		expected  float64
+	}{
+		{"no demand", 0, 0, 1.0},
+		{"balanced", 6, 5, 1.0},
+		{"busy", 15, 5, 1.375},
+		{"no drivers", 3, 0, 1.375},
+		{"capped at rush hour", 40, 5, 2.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, policy.RawMultiplier(tt.pending, tt.available), 0.001)
+		})
+	}
+}
+
+func TestSurgePolicy_Smooth(t *testing.T) {
+	policy := &models.SurgePolicy{Smoothing: 0.5}
+
+	assert.Equal(t, 1.5, policy.Smooth(1.0, 2.0))
+	assert.Equal(t, 1.75, policy.Smooth(1.5, 2.0))
+	assert.Equal(t, 1.25, policy.Smooth(1.5, 1.0))
+	assert.Equal(t, 1.5, policy.Smooth(0, 2.0)) // No previous state starts from 1
+}
+
+func TestPriceCalculation_ApplySurge(t *testing.T) {
+	calculation := models.PriceCalculation{SubTotal: 2000, FinalPrice: 2000}
+	calculation.ApplySurge(1.5)
+
+	assert.Equal(t, 1.5, calculation.SurgeMultiplier)
+	assert.Equal(t, 3000.0, calculation.FinalPrice)
+	assert.Len(t, calculation.LineItems, 1)
+	assert.Equal(t, "SURGE", calculation.LineItems[0].Code)
+
+	calculation = models.PriceCalculation{SubTotal: 2000, FinalPrice: 2000}
+	calculation.ApplySurge(1.0)
+	assert.Empty(t, calculation.LineItems)
+	assert.Equal(t, 2000.0, calculation.FinalPrice)
+}
+
+func TestSurgeOverride_IsActive(t *testing.T) {
+	now := time.Now()
+	override := &models.SurgeOverride{ExpiresAt: now.Add(time.Minute)}
+
+	assert.True(t, override.IsActive(now))
+	assert.False(t, override.IsActive(now.Add(2*time.Minute)))
+}
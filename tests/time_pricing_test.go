@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestTimeSurchargeRule_Matches(t *testing.T) {
+	abidjan, err := time.LoadLocation("Africa/Abidjan")
+	assert.NoError(t, err)
+
+	night := &models.TimeSurchargeRule{Name: "Nuit", StartTime: "22:00", EndTime: "06:00", Percentage: 20, IsActive: true}
+	fridayNight := &models.TimeSurchargeRule{Name: "Vendredi soir", DaysOfWeek: []int{5}, StartTime: "22:00", EndTime: "06:00", FixedAmount: 500, IsActive: true}
+	sunday := &models.TimeSurchargeRule{Name: "Dimanche", DaysOfWeek: []int{0}, Percentage: 10, IsActive: true}
+	holiday := &models.TimeSurchargeRule{Name: "Jour férié", HolidaysOnly: true, Percentage: 25, IsActive: true}
+	holidays := []models.Holiday{{Date: "2026-08-07", Name: "Fête de l'Indépendance"}}
+
+	tests := []struct {
+		name     string
+		rule     *models.TimeSurchargeRule
+		at       time.Time
+		expected bool
+	}{
+		{"night before midnight", night, time.Date(2026, 10, 14, 23, 0, 0, 0, abidjan), true},
+		{"night after midnight", night, time.Date(2026, 10, 15, 5, 59, 0, 0, abidjan), true},
+		{"daytime", night, time.Date(2026, 10, 15, 6, 0, 0, 0, abidjan), false},
+		{"overnight window belongs to friday", fridayNight, time.Date(2026, 10, 17, 2, 0, 0, 0, abidjan), true},
+		{"thursday night", fridayNight, time.Date(2026, 10, 15, 23, 0, 0, 0, abidjan), false},
+		{"sunday", sunday, time.Date(2026, 10, 18, 12, 0, 0, 0, abidjan), true},
+		{"monday", sunday, time.Date(2026, 10, 19, 12, 0, 0, 0, abidjan), false},
+		{"holiday", holiday, time.Date(2026, 8, 7, 12, 0, 0, 0, abidjan), true},
+		{"not a holiday", holiday, time.Date(2026, 8, 8, 12, 0, 0, 0, abidjan), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.rule.Matches(tt.at, models.VehicleTypeMoto, holidays))
+		})
+	}
+}
+
+func TestTimeSurchargeRule_Validate(t *testing.T) {
+	assert.NoError(t, (&models.TimeSurchargeRule{StartTime: "22:00", EndTime: "06:00", Percentage: 20}).Validate())
+	assert.Error(t, (&models.TimeSurchargeRule{StartTime: "22:00", Percentage: 20}).Validate())
+	assert.Error(t, (&models.TimeSurchargeRule{StartTime: "25:00", EndTime: "06:00", Percentage: 20}).Validate())
+	assert.Error(t, (&models.TimeSurchargeRule{}).Validate())
+}
+
+func TestPriceCalculation_ApplyTimeSurcharges(t *testing.T) {
+	moto := models.VehicleTypeMoto
+	rules := []models.TimeSurchargeRule{
+		{Name: "Nuit", StartTime: "22:00", EndTime: "06:00", Percentage: 20, IsActive: true},
+		{Name: "Dimanche", DaysOfWeek: []int{0}, FixedAmount: 300, IsActive: true},
+		{Name: "Nuit moto", VehicleType: &moto, StartTime: "22:00", EndTime: "06:00", FixedAmount: 100, IsActive: false},
+	}
+
+	calculation := models.PriceCalculation{SubTotal: 2000, FinalPrice: 2000}
+	sundayNight := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+	calculation.ApplyTimeSurcharges(rules, models.VehicleTypeMoto, sundayNight, nil)
+
+	assert.Len(t, calculation.LineItems, 2)
+	assert.Equal(t, 2700.0, calculation.FinalPrice) // 2000 + 20% + 300
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestTipPolicy_CanTip(t *testing.T) {
+	policy := &models.TipPolicy{Window: 24 * time.Hour, MaxAmount: 5000}
+	now := time.Now()
+	driverID := "driver-1"
+	deliveredAt := now.Add(-3 * time.Hour)
+
+	tests := []struct {
+		name          string
+		delivery      *models.Delivery
+		alreadyTipped float64
+		amount        float64
+		wantErr       bool
+	}{
+		{
+			name:     "during delivery",
+			delivery: &models.Delivery{Status: models.DeliveryStatusInTransit, LivreurID: &driverID},
+			amount:   1000,
+		},
+		{
+			name:     "after delivery within window",
+			delivery: &models.Delivery{Status: models.DeliveryStatusDelivered, LivreurID: &driverID, DeliveredAt: &deliveredAt},
+			amount:   1000,
+		},
+		{
+			name:     "window closed",
+			delivery: &models.Delivery{Status: models.DeliveryStatusDelivered, LivreurID: &driverID, UpdatedAt: now.Add(-25 * time.Hour)},
+			amount:   1000,
+			wantErr:  true,
+		},
+		{
+			name:     "returned to sender",
+			delivery: &models.Delivery{Status: models.DeliveryStatusReturned, LivreurID: &driverID, UpdatedAt: now.Add(-time.Hour)},
+			amount:   1000,
+			wantErr:  true,
+		},
+		{
+			name:     "no driver",
+			delivery: &models.Delivery{Status: models.DeliveryStatusPending},
+			amount:   1000,
+			wantErr:  true,
+		},
+		{
+			name:     "cancelled",
+			delivery: &models.Delivery{Status: models.DeliveryStatusCancelled, LivreurID: &driverID},
+			amount:   1000,
+			wantErr:  true,
+		},
+		{
+			name:          "over the limit",
+			delivery:      &models.Delivery{Status: models.DeliveryStatusDelivered, LivreurID: &driverID, DeliveredAt: &deliveredAt},
+			alreadyTipped: 4500,
+			amount:        1000,
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.CanTip(tt.delivery, tt.alreadyTipped, tt.amount, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewDeliveryReceipt(t *testing.T) {
+	delivery := &models.Delivery{ID: "delivery-1", FinalPrice: 3000, PaymentMethod: models.PaymentMethodCash}
+	tips := []models.Tip{
+		{Amount: 500, PaymentMethod: models.PaymentMethodCash},
+		{Amount: 1000, PaymentMethod: models.PaymentMethodMobileMoneyWave},
+	}
+
+	receipt := models.NewDeliveryReceipt(delivery, tips)
+	assert.Equal(t, 3000.0, receipt.DeliveryTotal)
+	assert.Equal(t, 1500.0, receipt.TipTotal)
+	assert.Equal(t, 4500.0, receipt.Total)
+}
+
+func TestDriverEarnings_TipsOutsideCommission(t *testing.T) {
+	tip := 1000.0
+	deliveries := []*models.Delivery{
+		{ID: "delivery-1", FinalPrice: 4000, TipAmount: &tip},
+		{ID: "delivery-2", FinalPrice: 2000},
+	}
+
+	var earnings []models.DeliveryEarning
+	for _, delivery := range deliveries {
+		earnings = append(earnings, models.NewDeliveryEarning(delivery, 0.15))
+	}
+
+	assert.Equal(t, 600.0, earnings[0].Commission)
+	assert.Equal(t, 3400.0, earnings[0].Net)
+	assert.Equal(t, 1000.0, earnings[0].Tips)
+
+	var entries []models.EarningEntry
+	for _, earning := range earnings {
+		entries = append(entries, earning.LedgerEntries("driver-1", time.Now())...)
+	}
+
+	summary := models.NewLedgerEarnings("driver-1", time.Now().Add(-24*time.Hour), time.Now(), entries)
+	assert.Equal(t, 2, summary.DeliveryCount)
+	assert.Len(t, summary.Deliveries, 2)
+	assert.Equal(t, earnings[0].Net, summary.Deliveries[0].Net)
+	assert.Equal(t, 6000.0, summary.Gross)
+	assert.Equal(t, 900.0, summary.Commission)
+	assert.Equal(t, 5100.0, summary.Net)
+	assert.Equal(t, 1000.0, summary.Tips)
+	assert.Equal(t, 6100.0, summary.Total)
+}
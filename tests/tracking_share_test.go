@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestGenerateShareToken(t *testing.T) {
+	token, hash, err := models.GenerateShareToken()
+	assert.NoError(t, err)
+	assert.Len(t, token, 32)
+	assert.Equal(t, models.HashShareToken(token), hash)
+	assert.NotEqual(t, token, hash)
+
+	other, _, err := models.GenerateShareToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestTrackingShare_IsActive(t *testing.T) {
+	now := time.Now()
+	revokedAt := now.Add(-time.Minute)
+
+	assert.True(t, (&models.TrackingShare{ExpiresAt: now.Add(time.Hour)}).IsActive(now))
+	assert.False(t, (&models.TrackingShare{ExpiresAt: now.Add(-time.Hour)}).IsActive(now))
+	assert.False(t, (&models.TrackingShare{ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt}).IsActive(now))
+}
+
+func TestNewPublicTracking(t *testing.T) {
+	share := &models.TrackingShare{ExpiresAt: time.Now().Add(time.Hour)}
+	driver := &models.User{FirstName: "Koffi", LastName: "Yao", Phone: "+2250700000000"}
+	marque := "Yamaha"
+	vehicle := &models.Vehicle{Type: models.VehicleTypeMoto, Marque: &marque}
+	lat, lng := 5.35, -3.98
+	tracking := &models.DeliveryTracking{
+		DriverLat: &lat,
+		DriverLng: &lng,
+		ETA:       &models.DeliveryETA{DropoffETA: time.Now().Add(20 * time.Minute), DropoffMin: 20},
+	}
+
+	// Before pickup the recipient sees the driver and ETA but not the position
+	delivery := &models.Delivery{Status: models.DeliveryStatusAccepted}
+	public := models.NewPublicTracking(delivery, driver, vehicle, tracking, share)
+	assert.Equal(t, "Koffi", *public.DriverFirstName)
+	assert.Equal(t, models.VehicleTypeMoto, public.Vehicle.Type)
+	assert.Equal(t, 20.0, *public.DropoffMin)
+	assert.Nil(t, public.DriverLat)
+
+	// In transit the live position is shared
+	delivery.Status = models.DeliveryStatusInTransit
+	public = models.NewPublicTracking(delivery, driver, vehicle, tracking, share)
+	assert.Equal(t, lat, *public.DriverLat)
+
+	// Once delivered only the outcome is shown
+	delivery.Status = models.DeliveryStatusDelivered
+	public = models.NewPublicTracking(delivery, driver, vehicle, tracking, share)
+	assert.Nil(t, public.DriverFirstName)
+	assert.Nil(t, public.Vehicle)
+	assert.Nil(t, public.DriverLat)
+}
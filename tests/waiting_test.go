@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+func TestPricingRule_CalculateWaitingPrice(t *testing.T) {
+	rule := &models.PricingRule{WaitingFree: 5, WaitingRate: 50}
+
+	assert.Equal(t, 0.0, rule.CalculateWaitingPrice(3))
+	assert.Equal(t, 0.0, rule.CalculateWaitingPrice(5))
+	assert.Equal(t, 250.0, rule.CalculateWaitingPrice(10))
+	assert.Equal(t, 5.0, rule.BillableWaitingMinutes(10))
+}
+
+func TestNewWaitingTimer(t *testing.T) {
+	rule := &models.PricingRule{WaitingFree: 10, WaitingRate: 100}
+	now := time.Now()
+
+	timer := models.NewWaitingTimer("delivery-1", rule, models.WaitingStopPickup, now.Add(-4*time.Minute), now)
+	assert.True(t, timer.Active)
+	assert.InDelta(t, 6.0, timer.RemainingFreeMin, 0.001)
+	assert.Equal(t, 0.0, timer.CurrentCharge)
+	assert.Nil(t, timer.Warning)
+
+	timer = models.NewWaitingTimer("delivery-1", rule, models.WaitingStopDropoff, now.Add(-13*time.Minute), now)
+	assert.Equal(t, 0.0, timer.RemainingFreeMin)
+	assert.InDelta(t, 300.0, timer.CurrentCharge, 0.001)
+	assert.NotNil(t, timer.Warning)
+}
+
+func TestDelivery_WaitingMinutes(t *testing.T) {
+	pickup, dropoff := 7.0, 3.5
+	delivery := &models.Delivery{PickupWaitingMin: &pickup, DropoffWaitingMin: &dropoff}
+	assert.Equal(t, 10.5, delivery.WaitingMinutes())
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ambroise1219/livraison_go/models"
+)
+
+// square returns a closed square ring of [lng, lat] positions
+func square(minLng, minLat, maxLng, maxLat float64) [][]float64 {
+	return [][]float64{{minLng, minLat}, {maxLng, minLat}, {maxLng, maxLat}, {minLng, maxLat}, {minLng, minLat}}
+}
+
+func TestGeoJSONPolygon_Contains(t *testing.T) {
+	polygon := models.GeoJSONPolygon{
+		Type:        "Polygon",
+		Coordinates: [][][]float64{square(-4.05, 5.30, -3.95, 5.40), square(-4.01, 5.34, -3.99, 5.36)},
+	}
+	assert.NoError(t, polygon.Validate())
+
+	assert.True(t, polygon.Contains(5.32, -4.02))
+	assert.False(t, polygon.Contains(5.35, -4.00)) // Inside the hole
+	assert.False(t, polygon.Contains(5.45, -4.00)) // Outside
+}
+
+func TestGeoJSONPolygon_Validate(t *testing.T) {
+	open := models.GeoJSONPolygon{Type: "Polygon", Coordinates: [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}}
+	assert.Error(t, open.Validate())
+
+	point := models.GeoJSONPolygon{Type: "Point"}
+	assert.Error(t, point.Validate())
+}
+
+func TestResolveZoneMembership(t *testing.T) {
+	zones := []models.ServiceZone{
+		{ID: "plateau", Name: "Plateau", IsActive: true, Polygon: models.GeoJSONPolygon{Type: "Polygon", Coordinates: [][][]float64{square(-4.03, 5.31, -4.01, 5.33)}}},
+		{ID: "cocody", Name: "Cocody", IsActive: true, Polygon: models.GeoJSONPolygon{Type: "Polygon", Coordinates: [][][]float64{square(-4.00, 5.33, -3.95, 5.38)}}},
+	}
+	plateauLat, plateauLng := 5.32, -4.02
+	cocodyLat, cocodyLng := 5.35, -3.97
+	outsideLat, outsideLng := 6.80, -5.27
+
+	membership, pickup, dropoff := models.ResolveZoneMembership(zones, &plateauLat, &plateauLng, &cocodyLat, &cocodyLng)
+	assert.True(t, membership.Covered)
+	assert.True(t, membership.CrossZone)
+	assert.Equal(t, "plateau", pickup.ID)
+	assert.Equal(t, "cocody", dropoff.ID)
+
+	membership, _, _ = models.ResolveZoneMembership(zones, &plateauLat, &plateauLng, &outsideLat, &outsideLng)
+	assert.False(t, membership.Covered)
+	assert.False(t, membership.DropoffCovered)
+	assert.NotEmpty(t, membership.Message)
+
+	// Without any active zone every address is covered
+	membership, _, _ = models.ResolveZoneMembership(nil, &outsideLat, &outsideLng, &outsideLat, &outsideLng)
+	assert.True(t, membership.Covered)
+}
+
+func TestPriceCalculation_ApplyZoneAdjustments(t *testing.T) {
+	plateau := &models.ServiceZone{ID: "plateau", Name: "Plateau", PriceAdjustment: 10}
+	cocody := &models.ServiceZone{ID: "cocody", Name: "Cocody", FixedAdjustment: 200}
+
+	calculation := models.PriceCalculation{SubTotal: 2000, FinalPrice: 2000}
+	calculation.ApplyZoneAdjustments(plateau, cocody, 300)
+
+	assert.Len(t, calculation.LineItems, 3)
+	assert.Equal(t, 2700.0, calculation.SubTotal) // 2000 + 10% + 200 + 300 cross-zone
+	assert.Equal(t, 2700.0, calculation.FinalPrice)
+
+	calculation = models.PriceCalculation{SubTotal: 2000, FinalPrice: 2000}
+	calculation.ApplyZoneAdjustments(plateau, plateau, 300)
+	assert.Len(t, calculation.LineItems, 1)
+	assert.Equal(t, 2200.0, calculation.FinalPrice)
+
+	// A discount larger than the price does not make it negative
+	promoZone := &models.ServiceZone{ID: "promo", Name: "Promo", FixedAdjustment: -3000}
+	calculation = models.PriceCalculation{SubTotal: 2000, FinalPrice: 2000}
+	calculation.ApplyZoneAdjustments(promoZone, promoZone, 0)
+	assert.Equal(t, 0.0, calculation.FinalPrice)
+}